@@ -67,6 +67,8 @@ type HTTPResponse struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message,omitempty"`
 	Warning string      `json:"warning,omitempty"`
+	// Currency describes the currency conversion applied to the costs of the data, if any
+	Currency interface{} `json:"currency,omitempty"`
 }
 
 // ToResponse accepts a data payload and/or error to encode into a new HTTPResponse instance. Responses
@@ -126,6 +128,27 @@ func (hp HTTPProtocol) WriteDataWithWarning(w http.ResponseWriter, data interfac
 	w.Write(resp)
 }
 
+// WriteDataWithCurrency writes the data payload similiar to WriteData except it provides an additional description of
+// the currency conversion applied to the data.
+func (hp HTTPProtocol) WriteDataWithCurrency(w http.ResponseWriter, data interface{}, currency interface{}) {
+	status := http.StatusOK
+	resp, err := json.Marshal(&HTTPResponse{
+		Code:     status,
+		Data:     data,
+		Currency: currency,
+	})
+	if err != nil {
+		status = http.StatusInternalServerError
+		resp, _ = json.Marshal(&HTTPResponse{
+			Code:    status,
+			Message: fmt.Sprintf("Error: %s", err),
+		})
+	}
+
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
 // WriteDataWithMessage writes the data payload similiar to WriteData except it provides an additional string message.
 func (hp HTTPProtocol) WriteDataWithMessage(w http.ResponseWriter, data interface{}, message string) {
 	status := http.StatusOK
@@ -0,0 +1,107 @@
+package protocol
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/opencost/opencost/core/pkg/util/json"
+)
+
+// NDJSONContentType is the media type of newline delimited JSON, in which each line of the response body is a
+// complete JSON value
+const NDJSONContentType = "application/x-ndjson"
+
+// AcceptsNDJSON returns true if the request's Accept header asks for a newline delimited JSON response
+func (hp HTTPProtocol) AcceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && mediaType == NDJSONContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NDJSONWriter streams values to an http.ResponseWriter as newline delimited JSON, so that large responses can be
+// written as they are produced rather than marshalled into memory at once.
+type NDJSONWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+}
+
+// NewNDJSONWriter creates an NDJSONWriter and sets the Content-Type of the response
+func (hp HTTPProtocol) NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	w.Header().Set("Content-Type", NDJSONContentType)
+	return &NDJSONWriter{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Write encodes the value as a single line of the response
+func (nw *NDJSONWriter) Write(v any) error {
+	nw.started = true
+	return nw.enc.Encode(v)
+}
+
+// Flush sends any buffered lines to the client
+func (nw *NDJSONWriter) Flush() {
+	if f, ok := nw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Started returns true once any line has been written, after which the status of the response can no longer change
+func (nw *NDJSONWriter) Started() bool {
+	return nw.started
+}
+
+// WriteError writes the error as the final line of the response. If no lines have been written yet, the status of
+// the response is also set from the HTTPError. Otherwise, clients must check the last line of the response for an
+// error, since the status has already been sent.
+func (nw *NDJSONWriter) WriteError(err HTTPError) {
+	status := err.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if !nw.started {
+		nw.w.WriteHeader(status)
+	}
+
+	nw.Write(&HTTPResponse{
+		Code:    status,
+		Message: err.Body,
+	})
+	nw.Flush()
+}
+
+// WriteCurrency writes the currency conversion applied to the costs of the preceding lines as the final line of the
+// response, in the "currency" property as in a JSON response. Rates are only known once every line has been
+// converted, so they cannot be sent ahead of the lines.
+func (nw *NDJSONWriter) WriteCurrency(currency interface{}) error {
+	err := nw.Write(&HTTPResponse{
+		Code:     http.StatusOK,
+		Currency: currency,
+	})
+	nw.Flush()
+	return err
+}
+
+// CurrencyHeader is the response header which describes, as JSON, the currency conversion applied to the costs of a
+// response which has no envelope to report it in, e.g. a CSV or Parquet file
+const CurrencyHeader = "X-Currency-Conversion"
+
+// SetCurrencyHeader sets the CurrencyHeader of the response to the currency conversion, which must be set before the
+// response is written
+func (hp HTTPProtocol) SetCurrencyHeader(w http.ResponseWriter, currency interface{}) error {
+	b, err := json.Marshal(currency)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(CurrencyHeader, string(b))
+	return nil
+}
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProtocol_AcceptsNDJSON(t *testing.T) {
+	tests := map[string]struct {
+		accept string
+		want   bool
+	}{
+		"none":       {accept: "", want: false},
+		"json":       {accept: "application/json", want: false},
+		"ndjson":     {accept: "application/x-ndjson", want: true},
+		"list":       {accept: "text/html, application/x-ndjson;q=0.9", want: true},
+		"wildcard":   {accept: "*/*", want: false},
+		"prefixOnly": {accept: "application/x-ndjson-seq", want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := HTTP().AcceptsNDJSON(r); got != tt.want {
+				t.Errorf("AcceptsNDJSON() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	nw := HTTP().NewNDJSONWriter(rec)
+
+	nw.Write(map[string]int{"a": 1})
+	nw.Write(map[string]int{"b": 2})
+	nw.WriteError(HTTP().InternalServerError("boom"))
+
+	// The status was sent with the first line, so the error only appears in the body
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != NDJSONContentType {
+		t.Errorf("expected Content-Type %s, got %s", NDJSONContentType, ct)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 || lines[0] != `{"a":1}` || lines[1] != `{"b":2}` || !strings.Contains(lines[2], `"message":"boom"`) {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+
+	// An error before any lines are written sets the status
+	rec = httptest.NewRecorder()
+	nw = HTTP().NewNDJSONWriter(rec)
+	nw.WriteError(HTTP().BadRequest("bad"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestNDJSONWriter_WriteCurrency(t *testing.T) {
+	rec := httptest.NewRecorder()
+	nw := HTTP().NewNDJSONWriter(rec)
+
+	nw.Write(map[string]int{"a": 1})
+	err := nw.WriteCurrency(map[string]string{"to": "EUR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"currency":{"to":"EUR"}`) {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+}
+
+func TestHTTPProtocol_SetCurrencyHeader(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := HTTP().SetCurrencyHeader(rec, map[string]string{"to": "EUR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h := rec.Header().Get(CurrencyHeader); h != `{"to":"EUR"}` {
+		t.Errorf("unexpected header: %s", h)
+	}
+}
@@ -17,6 +17,9 @@ var Unmarshal = jsoniter.Unmarshal
 var NewEncoder = json.NewEncoder
 var NewDecoder = json.NewDecoder
 
+type Encoder = json.Encoder
+type Decoder = json.Decoder
+
 type Marshaler = json.Marshaler
 type Unmarshaler = json.Unmarshaler
 
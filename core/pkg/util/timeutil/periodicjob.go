@@ -0,0 +1,82 @@
+package timeutil
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PeriodicJob runs a job in the background immediately when started, and
+// then again each interval after the previous run completes, until stopped.
+// The zero value is ready to use.
+type PeriodicJob struct {
+	lock sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start runs the job periodically at the given interval, which must be
+// positive, so that the job is not run continuously. Starting a job which is
+// already running has no effect.
+func (pj *PeriodicJob) Start(interval time.Duration, job func()) error {
+	if interval <= 0 {
+		return fmt.Errorf("PeriodicJob: invalid interval %s: must be positive", interval)
+	}
+
+	pj.lock.Lock()
+	defer pj.lock.Unlock()
+
+	if pj.stop != nil {
+		return nil
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	pj.stop = stop
+	pj.done = done
+
+	go func() {
+		defer close(done)
+
+		// the timer is only reset once the job completes, so runs never overlap
+		// and slow runs delay, rather than drop, the next
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-timer.C:
+			}
+
+			job()
+
+			timer.Reset(interval)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the job, waiting for any run in progress to complete. Stopping a
+// job which is not running has no effect.
+func (pj *PeriodicJob) Stop() {
+	pj.lock.Lock()
+	defer pj.lock.Unlock()
+
+	if pj.stop == nil {
+		return
+	}
+	close(pj.stop)
+	<-pj.done
+	pj.stop = nil
+	pj.done = nil
+}
+
+// IsRunning returns true if the job has been started and not stopped
+func (pj *PeriodicJob) IsRunning() bool {
+	pj.lock.Lock()
+	defer pj.lock.Unlock()
+
+	return pj.stop != nil
+}
@@ -0,0 +1,72 @@
+package timeutil
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeriodicJob(t *testing.T) {
+	var pj PeriodicJob
+	var runs atomic.Int64
+
+	err := pj.Start(10*time.Millisecond, func() {
+		runs.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// starting a running job has no effect
+	err = pj.Start(time.Millisecond, func() {
+		t.Errorf("job should not be replaced while running")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !pj.IsRunning() {
+		t.Fatalf("expected job to be running")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if runs.Load() < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", runs.Load())
+	}
+
+	pj.Stop()
+	if pj.IsRunning() {
+		t.Fatalf("expected job to be stopped")
+	}
+
+	stopped := runs.Load()
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("expected no runs after stop, got %d more", runs.Load()-stopped)
+	}
+
+	// a stopped job can be started again, and stopping twice has no effect
+	pj.Start(time.Hour, func() {
+		runs.Add(1)
+	})
+	pj.Stop()
+	pj.Stop()
+}
+
+func TestPeriodicJob_InvalidInterval(t *testing.T) {
+	var pj PeriodicJob
+
+	// a non-positive interval would run the job continuously, so is rejected
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		err := pj.Start(interval, func() {
+			t.Errorf("job should not run with interval %s", interval)
+		})
+		if err == nil {
+			t.Errorf("expected error starting job with interval %s", interval)
+		}
+		if pj.IsRunning() {
+			t.Errorf("expected job with interval %s not to be running", interval)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package anomaly detects spikes in daily aggregated costs, by comparing the
+// cost of each aggregation key on a day to its costs over a rolling baseline
+// of the preceding days.
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+// minStdDevRatio is the minimum standard deviation of a baseline, relative to
+// its mean, so that a key with nearly constant costs is not flagged for every
+// small increase
+const minStdDevRatio = 0.05
+
+// Severity is the severity of an Anomaly, by how far its cost is from the
+// baseline
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// ParseSeverity returns the Severity with the given name
+func ParseSeverity(severity string) (Severity, bool) {
+	switch Severity(severity) {
+	case SeverityLow, SeverityMedium, SeverityHigh:
+		return Severity(severity), true
+	default:
+		return "", false
+	}
+}
+
+// rank orders severities from least to most severe
+func (s Severity) rank() int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// AtLeast returns true if the severity is at least as severe as that
+func (s Severity) AtLeast(that Severity) bool {
+	return s.rank() >= that.rank()
+}
+
+// Config configures the detection of anomalies
+type Config struct {
+	// BaselineDays is the number of days preceding a day which its costs are
+	// compared to
+	BaselineDays int
+
+	// MinBaselineDays is the number of days of the baseline on which a key
+	// must have costs for it to be evaluated
+	MinBaselineDays int
+
+	// Threshold is the number of standard deviations above the mean of the
+	// baseline a cost must be to be anomalous. Costs twice and four times as
+	// far from the mean are of medium and high severity.
+	Threshold float64
+
+	// MinDelta is the amount a cost must exceed the mean of the baseline by to
+	// be anomalous, so that spikes in negligible costs are ignored
+	MinDelta float64
+
+	// MaxContributors is the maximum number of contributors to the breakdown
+	// of an anomaly
+	MaxContributors int
+}
+
+// DefaultConfig returns the default anomaly detection Config
+func DefaultConfig() Config {
+	return Config{
+		BaselineDays:    14,
+		MinBaselineDays: 7,
+		Threshold:       3,
+		MinDelta:        1,
+		MaxContributors: 10,
+	}
+}
+
+// Cost is the cost of an aggregation key on a day, and the breakdown of that
+// cost by what contributed to it, e.g. by resource type or by resource
+type Cost struct {
+	Total     float64
+	Breakdown map[string]float64
+}
+
+// DailyCosts is the Cost of each aggregation key over a window of one day
+type DailyCosts struct {
+	Window opencost.Window
+	Costs  map[string]*Cost
+}
+
+// Contributor is the cost of a single contributor to an anomalous cost,
+// compared to its mean cost over the baseline
+type Contributor struct {
+	Name     string  `json:"name"`
+	Expected float64 `json:"expected"`
+	Actual   float64 `json:"actual"`
+	Delta    float64 `json:"delta"`
+}
+
+// Anomaly is a cost of an aggregation key on a day which is significantly
+// above its baseline. Expected is the mean cost of the baseline, and Score
+// the number of standard deviations of the baseline the cost is above it.
+// Breakdown lists the contributors whose costs increased the most, in order.
+type Anomaly struct {
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	AggregateBy []string        `json:"aggregateBy"`
+	Key         string          `json:"key"`
+	Window      opencost.Window `json:"window"`
+	Expected    float64         `json:"expected"`
+	Actual      float64         `json:"actual"`
+	Delta       float64         `json:"delta"`
+	Score       float64         `json:"score"`
+	Severity    Severity        `json:"severity"`
+	Breakdown   []*Contributor  `json:"breakdown"`
+	DetectedAt  time.Time       `json:"detectedAt"`
+}
+
+// Detect compares the cost of each key on the day to its costs on each day of
+// the baseline, returning an Anomaly for each whose cost is a significant
+// spike. A key missing from a day of the baseline is considered to have cost
+// nothing that day. The returned anomalies are in order of descending score,
+// and do not have their source set.
+func Detect(day *DailyCosts, baseline []*DailyCosts, config Config) []*Anomaly {
+	anomalies := []*Anomaly{}
+	if day == nil || len(baseline) == 0 {
+		return anomalies
+	}
+
+	for key, cost := range day.Costs {
+		totals := make([]float64, 0, len(baseline))
+		present := 0
+		for _, dc := range baseline {
+			if c, ok := dc.Costs[key]; ok {
+				totals = append(totals, c.Total)
+				present++
+			} else {
+				totals = append(totals, 0)
+			}
+		}
+		if present < config.MinBaselineDays {
+			continue
+		}
+
+		mean, stdDev := meanStdDev(totals)
+		stdDev = math.Max(stdDev, minStdDevRatio*math.Abs(mean))
+
+		delta := cost.Total - mean
+		if delta < config.MinDelta || stdDev == 0 {
+			continue
+		}
+
+		score := delta / stdDev
+		if score < config.Threshold {
+			continue
+		}
+
+		severity := SeverityLow
+		if score >= 4*config.Threshold {
+			severity = SeverityHigh
+		} else if score >= 2*config.Threshold {
+			severity = SeverityMedium
+		}
+
+		anomalies = append(anomalies, &Anomaly{
+			Key:       key,
+			Window:    day.Window.Clone(),
+			Expected:  mean,
+			Actual:    cost.Total,
+			Delta:     delta,
+			Score:     score,
+			Severity:  severity,
+			Breakdown: contributors(key, cost, baseline, config.MaxContributors),
+		})
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Score != anomalies[j].Score {
+			return anomalies[i].Score > anomalies[j].Score
+		}
+		return anomalies[i].Key < anomalies[j].Key
+	})
+
+	return anomalies
+}
+
+// contributors returns the contributors to the cost of the key whose costs
+// increased over their baseline mean, by descending increase
+func contributors(key string, cost *Cost, baseline []*DailyCosts, max int) []*Contributor {
+	expected := map[string]float64{}
+	for _, dc := range baseline {
+		if c, ok := dc.Costs[key]; ok {
+			for name, value := range c.Breakdown {
+				expected[name] += value
+			}
+		}
+	}
+	for name := range expected {
+		expected[name] /= float64(len(baseline))
+	}
+
+	result := []*Contributor{}
+	for name, actual := range cost.Breakdown {
+		delta := actual - expected[name]
+		if delta <= 0 {
+			continue
+		}
+		result = append(result, &Contributor{
+			Name:     name,
+			Expected: expected[name],
+			Actual:   actual,
+			Delta:    delta,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Delta != result[j].Delta {
+			return result[i].Delta > result[j].Delta
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if max > 0 && len(result) > max {
+		result = result[:max]
+	}
+	return result
+}
+
+// meanStdDev returns the mean and sample standard deviation of the values
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var ss float64
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(values)-1))
+}
@@ -0,0 +1,158 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+var start = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// dailyCosts creates the DailyCosts of the ith day after start, with the given
+// cost per key, broken down entirely into a contributor named after the key
+func dailyCosts(i int, costs map[string]float64) *DailyCosts {
+	dayStart := start.Add(time.Duration(i) * day)
+	dc := &DailyCosts{
+		Window: opencost.NewClosedWindow(dayStart, dayStart.Add(day)),
+		Costs:  map[string]*Cost{},
+	}
+	for key, cost := range costs {
+		dc.Costs[key] = &Cost{
+			Total:     cost,
+			Breakdown: map[string]float64{key + "-resource": cost},
+		}
+	}
+	return dc
+}
+
+// baseline creates 14 days of costs which alternate around the given means
+func baseline(means map[string]float64) []*DailyCosts {
+	days := []*DailyCosts{}
+	for i := 0; i < 14; i++ {
+		costs := map[string]float64{}
+		for key, mean := range means {
+			costs[key] = mean + math.Pow(-1, float64(i))
+		}
+		days = append(days, dailyCosts(i, costs))
+	}
+	return days
+}
+
+func TestDetect(t *testing.T) {
+	config := DefaultConfig()
+	history := baseline(map[string]float64{"steady": 100, "spike": 100, "huge": 100, "small": 0.1})
+
+	day := dailyCosts(14, map[string]float64{
+		"steady": 101,
+		"spike":  120,
+		"huge":   200,
+		"small":  0.9, // a spike, but below the minimum delta
+		"new":    50,  // no baseline
+	})
+
+	anomalies := Detect(day, history, config)
+	if len(anomalies) != 2 {
+		t.Fatalf("expected 2 anomalies, got %d: %+v", len(anomalies), anomalies)
+	}
+
+	// Anomalies are ordered by descending score
+	huge, spike := anomalies[0], anomalies[1]
+	if huge.Key != "huge" || spike.Key != "spike" {
+		t.Fatalf("expected anomalies huge and spike, got %s and %s", huge.Key, spike.Key)
+	}
+
+	if huge.Severity != SeverityHigh {
+		t.Errorf("expected huge to be of high severity, got %s (score %f)", huge.Severity, huge.Score)
+	}
+	if !approxEqual(huge.Expected, 100) || huge.Actual != 200 || !approxEqual(huge.Delta, 100) {
+		t.Errorf("unexpected huge costs: expected %f, actual %f, delta %f", huge.Expected, huge.Actual, huge.Delta)
+	}
+	if !huge.Window.Equal(day.Window) {
+		t.Errorf("expected window %s, got %s", day.Window, huge.Window)
+	}
+
+	// The standard deviation of the baseline is the minimum of 5% of its mean
+	if !approxEqual(spike.Score, 4) || spike.Severity != SeverityLow {
+		t.Errorf("expected spike to have score 4 and low severity, got %f and %s", spike.Score, spike.Severity)
+	}
+	if len(spike.Breakdown) != 1 || spike.Breakdown[0].Name != "spike-resource" || !approxEqual(spike.Breakdown[0].Delta, 20) {
+		t.Errorf("unexpected spike breakdown: %+v", spike.Breakdown)
+	}
+}
+
+func TestDetect_MinBaselineDays(t *testing.T) {
+	config := DefaultConfig()
+
+	// The key has costs on only the last 3 days of the baseline
+	history := []*DailyCosts{}
+	for i := 0; i < 14; i++ {
+		costs := map[string]float64{}
+		if i >= 11 {
+			costs["recent"] = 10
+		}
+		history = append(history, dailyCosts(i, costs))
+	}
+
+	anomalies := Detect(dailyCosts(14, map[string]float64{"recent": 100}), history, config)
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies, got %+v", anomalies)
+	}
+
+	config.MinBaselineDays = 3
+	anomalies = Detect(dailyCosts(14, map[string]float64{"recent": 100}), history, config)
+	if len(anomalies) != 1 {
+		t.Errorf("expected 1 anomaly, got %+v", anomalies)
+	}
+}
+
+func TestDetect_Contributors(t *testing.T) {
+	history := []*DailyCosts{}
+	for i := 0; i < 14; i++ {
+		dc := dailyCosts(i, nil)
+		dc.Costs["ns"] = &Cost{
+			Total:     30,
+			Breakdown: map[string]float64{"cpu": 10, "ram": 10, "gpu": 10},
+		}
+		history = append(history, dc)
+	}
+
+	day := dailyCosts(14, nil)
+	day.Costs["ns"] = &Cost{
+		Total:     100,
+		Breakdown: map[string]float64{"cpu": 20, "ram": 5, "gpu": 70, "pv": 5},
+	}
+
+	config := DefaultConfig()
+	config.MaxContributors = 2
+
+	anomalies := Detect(day, history, config)
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d", len(anomalies))
+	}
+
+	breakdown := anomalies[0].Breakdown
+	if len(breakdown) != 2 {
+		t.Fatalf("expected 2 contributors, got %+v", breakdown)
+	}
+	if breakdown[0].Name != "gpu" || breakdown[0].Expected != 10 || breakdown[0].Actual != 70 || breakdown[0].Delta != 60 {
+		t.Errorf("unexpected first contributor: %+v", breakdown[0])
+	}
+	if breakdown[1].Name != "cpu" || breakdown[1].Delta != 10 {
+		t.Errorf("unexpected second contributor: %+v", breakdown[1])
+	}
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	if !SeverityHigh.AtLeast(SeverityMedium) || !SeverityMedium.AtLeast(SeverityMedium) || SeverityLow.AtLeast(SeverityMedium) {
+		t.Errorf("unexpected severity ordering")
+	}
+	if _, ok := ParseSeverity("critical"); ok {
+		t.Errorf("expected unknown severity to be invalid")
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,81 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+)
+
+// CloudCostSource is a Source of daily CloudCost totals, such as by service
+// or account. The cost of each key is broken down by the provider ID of the
+// resources which contributed to it.
+type CloudCostSource struct {
+	querier        cloudcost.Querier
+	aggregateBy    []string
+	costMetricName opencost.CostMetricName
+}
+
+// NewCloudCostSource creates a CloudCostSource of the given cost metric of the
+// cloud costs from the querier, aggregated by the given properties
+func NewCloudCostSource(querier cloudcost.Querier, aggregateBy []string, costMetricName opencost.CostMetricName) *CloudCostSource {
+	return &CloudCostSource{
+		querier:        querier,
+		aggregateBy:    aggregateBy,
+		costMetricName: costMetricName,
+	}
+}
+
+func (ccs *CloudCostSource) Name() string {
+	return "cloudCost"
+}
+
+func (ccs *CloudCostSource) AggregateBy() []string {
+	return ccs.aggregateBy
+}
+
+func (ccs *CloudCostSource) Costs(ctx context.Context, window opencost.Window) ([]*DailyCosts, error) {
+	// Query each cloud cost without aggregating, so that costs can be broken
+	// down by resource within each aggregation key
+	ccsr, err := ccs.querier.Query(ctx, cloudcost.QueryRequest{
+		Start:      *window.Start(),
+		End:        *window.End(),
+		Accumulate: opencost.AccumulateOptionNone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*DailyCosts, 0, len(ccsr.CloudCostSets))
+	for _, set := range ccsr.CloudCostSets {
+		dc := &DailyCosts{
+			Window: set.Window.Clone(),
+			Costs:  map[string]*Cost{},
+		}
+
+		for _, cc := range set.CloudCosts {
+			if cc.Properties == nil {
+				continue
+			}
+
+			costMetric, err := cc.GetCostMetric(ccs.costMetricName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cost metric: %w", err)
+			}
+
+			key := cc.Properties.GenerateKey(ccs.aggregateBy)
+			cost, ok := dc.Costs[key]
+			if !ok {
+				cost = &Cost{Breakdown: map[string]float64{}}
+				dc.Costs[key] = cost
+			}
+			cost.Total += costMetric.Cost
+			cost.Breakdown[cc.Properties.GenerateKey([]string{opencost.CloudCostProviderIDProp})] += costMetric.Cost
+		}
+
+		result = append(result, dc)
+	}
+
+	return result, nil
+}
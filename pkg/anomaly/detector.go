@@ -0,0 +1,134 @@
+package anomaly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+)
+
+// Source provides the daily aggregated costs which anomalies are detected in
+type Source interface {
+	// Name is the type of cost the Source provides, e.g. "allocation"
+	Name() string
+
+	// AggregateBy is the properties the costs are aggregated by
+	AggregateBy() []string
+
+	// Costs returns the costs of each aggregation key on each day of the
+	// window
+	Costs(ctx context.Context, window opencost.Window) ([]*DailyCosts, error)
+}
+
+// sourceID uniquely identifies a Source by its name and aggregation
+func sourceID(source Source) string {
+	return source.Name() + ":" + strings.Join(source.AggregateBy(), ",")
+}
+
+// anomalyID uniquely identifies the anomaly of a key of a source on a day, so
+// that repeated detection of the same anomaly has the same ID
+func anomalyID(source, key string, day time.Time) string {
+	sum := sha256.Sum256([]byte(source + "/" + key + "/" + day.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Detector periodically detects anomalies in the costs of each of its
+// sources, saving them to its Store.
+type Detector struct {
+	store   Store
+	config  Config
+	sources []Source
+
+	job timeutil.PeriodicJob
+}
+
+// NewDetector creates a Detector of anomalies in the costs of the sources
+func NewDetector(store Store, config Config, sources ...Source) *Detector {
+	return &Detector{
+		store:   store,
+		config:  config,
+		sources: sources,
+	}
+}
+
+// Start detects anomalies in each of the given number of most recent complete
+// days at each interval, starting immediately, so that late cost data is
+// taken into account
+func (d *Detector) Start(interval time.Duration, days int) error {
+	return d.job.Start(interval, func() {
+		d.detectRecent(days)
+	})
+}
+
+// Stop stops the periodic detection of anomalies
+func (d *Detector) Stop() {
+	d.job.Stop()
+}
+
+func (d *Detector) detectRecent(days int) {
+	today := time.Now().UTC().Truncate(day)
+	for i := days; i > 0; i-- {
+		err := d.DetectDay(context.Background(), today.Add(-time.Duration(i)*day))
+		if err != nil {
+			log.Warnf("Anomaly: %s", err)
+		}
+	}
+}
+
+// DetectDay detects anomalies in the costs of each source on the day starting
+// at the given time, replacing any previously detected for the day. An error
+// from one source does not prevent the detection of the others.
+func (d *Detector) DetectDay(ctx context.Context, start time.Time) error {
+	start = start.UTC()
+	end := start.Add(day)
+	window := opencost.NewClosedWindow(start.Add(-time.Duration(d.config.BaselineDays)*day), end)
+
+	var errs []error
+	for _, source := range d.sources {
+		id := sourceID(source)
+
+		costs, err := source.Costs(ctx, window)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to query %s costs for %s: %w", id, window, err))
+			continue
+		}
+
+		var current *DailyCosts
+		baseline := make([]*DailyCosts, 0, len(costs))
+		for _, dc := range costs {
+			if dc.Window.Start() != nil && dc.Window.Start().Equal(start) {
+				current = dc
+			} else {
+				baseline = append(baseline, dc)
+			}
+		}
+
+		now := time.Now().UTC()
+		anomalies := Detect(current, baseline, d.config)
+		for _, anomaly := range anomalies {
+			anomaly.ID = anomalyID(id, anomaly.Key, start)
+			anomaly.Source = source.Name()
+			anomaly.AggregateBy = source.AggregateBy()
+			anomaly.DetectedAt = now
+		}
+
+		err = d.store.Save(id, start, anomalies)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to save %s anomalies for %s: %w", id, start, err))
+			continue
+		}
+
+		if len(anomalies) > 0 {
+			log.Infof("Anomaly: detected %d %s anomalies on %s", len(anomalies), id, start.Format("2006-01-02"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
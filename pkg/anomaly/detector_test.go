@@ -0,0 +1,278 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// mockSource is a Source whose costs are the same every day, except for the
+// costs of spike days
+type mockSource struct {
+	name   string
+	costs  map[string]float64
+	spikes map[time.Time]map[string]float64
+	err    error
+}
+
+func (ms *mockSource) Name() string {
+	return ms.name
+}
+
+func (ms *mockSource) AggregateBy() []string {
+	return []string{"namespace"}
+}
+
+func (ms *mockSource) Costs(ctx context.Context, window opencost.Window) ([]*DailyCosts, error) {
+	if ms.err != nil {
+		return nil, ms.err
+	}
+
+	result := []*DailyCosts{}
+	for d := *window.Start(); d.Before(*window.End()); d = d.Add(day) {
+		costs := ms.costs
+		if spike, ok := ms.spikes[d]; ok {
+			costs = spike
+		}
+		result = append(result, dailyCosts(int(d.Sub(start)/day), costs))
+	}
+	return result, nil
+}
+
+func TestDetector_DetectDay(t *testing.T) {
+	spikeDay := start.Add(20 * day)
+
+	source := &mockSource{
+		name:   "allocation",
+		costs:  map[string]float64{"a": 10, "b": 10},
+		spikes: map[time.Time]map[string]float64{spikeDay: {"a": 100, "b": 10}},
+	}
+	failing := &mockSource{name: "cloudCost", err: fmt.Errorf("unavailable")}
+
+	store := NewMemoryStore()
+	detector := NewDetector(store, DefaultConfig(), source, failing)
+
+	err := detector.DetectDay(context.Background(), spikeDay)
+	if err == nil {
+		t.Errorf("expected error from failing source")
+	}
+
+	anomalies, err := store.Query(spikeDay, spikeDay.Add(day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d", len(anomalies))
+	}
+
+	a := anomalies[0]
+	if a.Key != "a" || a.Source != "allocation" || len(a.AggregateBy) != 1 || a.AggregateBy[0] != "namespace" {
+		t.Errorf("unexpected anomaly: %+v", a)
+	}
+	if a.ID == "" || a.DetectedAt.IsZero() {
+		t.Errorf("expected anomaly to have an ID and detection time")
+	}
+
+	// Detecting the day again replaces its anomalies, with the same ID
+	id := a.ID
+	err = detector.DetectDay(context.Background(), spikeDay)
+	if err == nil {
+		t.Errorf("expected error from failing source")
+	}
+	anomalies, _ = store.Query(spikeDay, spikeDay.Add(day))
+	if len(anomalies) != 1 || anomalies[0].ID != id {
+		t.Errorf("expected the same anomaly after repeated detection, got %+v", anomalies)
+	}
+
+	// Once the data no longer has a spike, the anomaly is removed
+	delete(source.spikes, spikeDay)
+	detector.DetectDay(context.Background(), spikeDay)
+	anomalies, _ = store.Query(spikeDay, spikeDay.Add(day))
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies, got %+v", anomalies)
+	}
+}
+
+func TestStorageStore(t *testing.T) {
+	store := NewStorageStore(storage.NewFileStorage(t.TempDir()))
+	day1 := start
+	day2 := start.Add(day)
+
+	anomaly := func(key string, d time.Time, score float64) *Anomaly {
+		return &Anomaly{
+			ID:       key,
+			Key:      key,
+			Window:   opencost.NewClosedWindow(d, d.Add(day)),
+			Score:    score,
+			Severity: SeverityLow,
+		}
+	}
+
+	err := store.Save("allocation:namespace", day1, []*Anomaly{anomaly("a", day1, 3)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = store.Save("cloudCost:service", day1, []*Anomaly{anomaly("b", day1, 5)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = store.Save("allocation:namespace", day2, []*Anomaly{anomaly("c", day2, 10)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	anomalies, err := store.Query(day1, day2.Add(day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	keys := []string{}
+	for _, a := range anomalies {
+		keys = append(keys, a.Key)
+	}
+	if fmt.Sprint(keys) != "[b a c]" {
+		t.Errorf("expected anomalies ordered by day then score, got %v", keys)
+	}
+
+	// Saving a source replaces only its anomalies for the day
+	err = store.Save("allocation:namespace", day1, []*Anomaly{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	anomalies, err = store.Query(day1, day2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(anomalies) != 1 || anomalies[0].Key != "b" {
+		t.Errorf("expected only anomaly b, got %+v", anomalies)
+	}
+	if !anomalies[0].Window.Equal(opencost.NewClosedWindow(day1, day2)) {
+		t.Errorf("expected window to be persisted, got %s", anomalies[0].Window)
+	}
+
+	// Days with no anomalies saved have none
+	anomalies, err = store.Query(day2.Add(day), day2.Add(3*day))
+	if err != nil || len(anomalies) != 0 {
+		t.Errorf("expected no anomalies, got %+v, %v", anomalies, err)
+	}
+}
+
+func TestCloudCostSource(t *testing.T) {
+	repo := cloudcost.NewMemoryRepository()
+	for i := 0; i < 2; i++ {
+		dayStart := start.Add(time.Duration(i) * day)
+		err := repo.Put(cloudcost.DefaultMockCloudCostSet(dayStart, dayStart.Add(day), "gcp", "integration"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	source := NewCloudCostSource(cloudcost.NewRepositoryQuerier(repo), []string{opencost.CloudCostServiceProp}, opencost.CostMetricAmortizedNetCost)
+	costs, err := source.Costs(context.Background(), opencost.NewClosedWindow(start, start.Add(2*day)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(costs) != 2 {
+		t.Fatalf("expected 2 days of costs, got %d", len(costs))
+	}
+
+	for _, dc := range costs {
+		if len(dc.Costs) == 0 {
+			t.Fatalf("expected costs on %s", dc.Window)
+		}
+		for key, cost := range dc.Costs {
+			var breakdown float64
+			for _, c := range cost.Breakdown {
+				breakdown += c
+			}
+			if !approxEqual(breakdown, cost.Total) {
+				t.Errorf("%s: expected breakdown to sum to total %f, got %f", key, cost.Total, breakdown)
+			}
+		}
+	}
+}
+
+func TestQueryService_GetAnomaliesHandler(t *testing.T) {
+	store := NewMemoryStore()
+	window := opencost.NewClosedWindow(start, start.Add(day))
+	store.Save("allocation:namespace", start, []*Anomaly{
+		{ID: "1", Source: "allocation", AggregateBy: []string{"namespace"}, Key: "a", Window: window, Score: 20, Severity: SeverityHigh},
+		{ID: "2", Source: "allocation", AggregateBy: []string{"namespace"}, Key: "b", Window: window, Score: 4, Severity: SeverityLow},
+	})
+	store.Save("cloudCost:service", start, []*Anomaly{
+		{ID: "3", Source: "cloudCost", AggregateBy: []string{"service"}, Key: "c", Window: window, Score: 7, Severity: SeverityMedium},
+	})
+
+	qs := NewQueryService(store)
+	queryWindow := start.Format(time.RFC3339) + "," + start.Add(day).Format(time.RFC3339)
+
+	testCases := map[string]struct {
+		params   url.Values
+		expected []string
+		status   int
+	}{
+		"all": {
+			params:   url.Values{},
+			expected: []string{"1", "3", "2"},
+		},
+		"source": {
+			params:   url.Values{"source": {"cloudCost"}},
+			expected: []string{"3"},
+		},
+		"aggregate": {
+			params:   url.Values{"aggregate": {"namespace"}},
+			expected: []string{"1", "2"},
+		},
+		"severity": {
+			params:   url.Values{"severity": {"medium"}},
+			expected: []string{"1", "3"},
+		},
+		"invalid severity": {
+			params: url.Values{"severity": {"critical"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.params.Set("window", queryWindow)
+			req := httptest.NewRequest(http.MethodGet, "/anomalies?"+tc.params.Encode(), nil)
+			rec := httptest.NewRecorder()
+			qs.GetAnomaliesHandler()(rec, req, nil)
+
+			if tc.status != 0 {
+				if rec.Code != tc.status {
+					t.Errorf("expected status %d, got %d", tc.status, rec.Code)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var resp struct {
+				Data []*Anomaly `json:"data"`
+			}
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("failed to unmarshal response: %s", err)
+			}
+
+			ids := []string{}
+			for _, a := range resp.Data {
+				ids = append(ids, a.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.expected) {
+				t.Errorf("expected anomalies %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
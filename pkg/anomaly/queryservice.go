@@ -0,0 +1,82 @@
+package anomaly
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+)
+
+var protocol = proto.HTTP()
+
+// QueryService surfaces the anomalies held by a Store
+type QueryService struct {
+	Store Store
+}
+
+func NewQueryService(store Store) *QueryService {
+	return &QueryService{
+		Store: store,
+	}
+}
+
+// GetAnomaliesHandler returns the anomalies of each day starting within the
+// 'window', defaulting to the last 7 days. Anomalies may be restricted to a
+// 'source' (e.g. "allocation" or "cloudCost"), an 'aggregate' (e.g.
+// "namespace"), and a minimum 'severity'.
+func (s *QueryService) GetAnomaliesHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Query Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Anomaly Query Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		qp := httputil.NewQueryParams(r.URL.Query())
+
+		window, err := opencost.ParseWindowUTC(qp.Get("window", "7d"))
+		if err != nil || window.IsOpen() || window.IsNegative() {
+			http.Error(w, fmt.Sprintf("invalid window parameter: %s", qp.Get("window", "")), http.StatusBadRequest)
+			return
+		}
+
+		var minSeverity Severity
+		if qp.Has("severity") {
+			var ok bool
+			minSeverity, ok = ParseSeverity(qp.Get("severity", ""))
+			if !ok {
+				http.Error(w, fmt.Sprintf("invalid severity parameter: %s, must be one of '%s', '%s' or '%s'", qp.Get("severity", ""), SeverityLow, SeverityMedium, SeverityHigh), http.StatusBadRequest)
+				return
+			}
+		}
+		source := qp.Get("source", "")
+		aggregate := qp.Get("aggregate", "")
+
+		anomalies, err := s.Store.Query(*window.Start(), *window.End())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]*Anomaly, 0, len(anomalies))
+		for _, anomaly := range anomalies {
+			if source != "" && !strings.EqualFold(anomaly.Source, source) {
+				continue
+			}
+			if aggregate != "" && strings.Join(anomaly.AggregateBy, ",") != aggregate {
+				continue
+			}
+			if minSeverity != "" && !anomaly.Severity.AtLeast(minSeverity) {
+				continue
+			}
+			resp = append(resp, anomaly)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, resp)
+	}
+}
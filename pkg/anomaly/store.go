@@ -0,0 +1,175 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+const storageDir = "anomalies"
+
+const day = 24 * time.Hour
+
+// Store persists the anomalies detected by each source on each day
+type Store interface {
+	// Save replaces the anomalies detected by the source on the day starting
+	// at the given time, so that detection can be repeated as late data
+	// arrives
+	Save(source string, day time.Time, anomalies []*Anomaly) error
+
+	// Query returns the anomalies of every source on each day starting
+	// within [start, end), ordered by day then descending score
+	Query(start, end time.Time) ([]*Anomaly, error)
+}
+
+// dayAnomalies is the anomalies of each source on a single day
+type dayAnomalies map[string][]*Anomaly
+
+// sortAnomalies sorts the anomalies of a range of days by day, then by
+// descending score
+func sortAnomalies(anomalies []*Anomaly) {
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		si, sj := anomalies[i].Window.Start(), anomalies[j].Window.Start()
+		if si != nil && sj != nil && !si.Equal(*sj) {
+			return si.Before(*sj)
+		}
+		return anomalies[i].Score > anomalies[j].Score
+	})
+}
+
+// MemoryStore is a Store which holds anomalies in memory
+type MemoryStore struct {
+	lock sync.RWMutex
+	days map[time.Time]dayAnomalies
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		days: map[time.Time]dayAnomalies{},
+	}
+}
+
+func (ms *MemoryStore) Save(source string, day time.Time, anomalies []*Anomaly) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	day = day.UTC()
+	if _, ok := ms.days[day]; !ok {
+		ms.days[day] = dayAnomalies{}
+	}
+	ms.days[day][source] = anomalies
+	return nil
+}
+
+func (ms *MemoryStore) Query(start, end time.Time) ([]*Anomaly, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	result := []*Anomaly{}
+	for d, sources := range ms.days {
+		if d.Before(start) || !d.Before(end) {
+			continue
+		}
+		for _, anomalies := range sources {
+			result = append(result, anomalies...)
+		}
+	}
+
+	sortAnomalies(result)
+	return result, nil
+}
+
+// StorageStore is a Store which persists anomalies to a storage.Storage, with
+// one file per day holding the anomalies of every source
+type StorageStore struct {
+	lock  sync.Mutex
+	store storage.Storage
+}
+
+// NewStorageStore creates a StorageStore which writes to the given storage
+func NewStorageStore(store storage.Storage) *StorageStore {
+	return &StorageStore{
+		store: store,
+	}
+}
+
+func (ss *StorageStore) path(day time.Time) string {
+	return path.Join(storageDir, day.UTC().Format("2006-01-02")+".json")
+}
+
+// read returns the anomalies of the day, which are empty if none have been
+// saved
+func (ss *StorageStore) read(day time.Time) (dayAnomalies, error) {
+	p := ss.path(day)
+
+	exists, err := ss.store.Exists(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", p, err)
+	}
+	if !exists {
+		return dayAnomalies{}, nil
+	}
+
+	data, err := ss.store.Read(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	da := dayAnomalies{}
+	err = json.Unmarshal(data, &da)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", p, err)
+	}
+	return da, nil
+}
+
+func (ss *StorageStore) Save(source string, day time.Time, anomalies []*Anomaly) error {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	da, err := ss.read(day)
+	if err != nil {
+		return fmt.Errorf("StorageStore: Save: %w", err)
+	}
+	da[source] = anomalies
+
+	data, err := json.Marshal(da)
+	if err != nil {
+		return fmt.Errorf("StorageStore: Save: failed to marshal anomalies: %w", err)
+	}
+
+	err = ss.store.Write(ss.path(day), data)
+	if err != nil {
+		return fmt.Errorf("StorageStore: Save: failed to write %s: %w", ss.path(day), err)
+	}
+	return nil
+}
+
+func (ss *StorageStore) Query(start, end time.Time) ([]*Anomaly, error) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	result := []*Anomaly{}
+	for d := start.UTC().Truncate(day); d.Before(end); d = d.Add(day) {
+		if d.Before(start) {
+			continue
+		}
+
+		da, err := ss.read(d)
+		if err != nil {
+			return nil, fmt.Errorf("StorageStore: Query: %w", err)
+		}
+		for _, anomalies := range da {
+			result = append(result, anomalies...)
+		}
+	}
+
+	sortAnomalies(result)
+	return result, nil
+}
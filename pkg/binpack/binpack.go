@@ -0,0 +1,250 @@
+package binpack
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultHeadroom is the fraction of each node's capacity which is left
+	// unrequested, unless otherwise specified
+	DefaultHeadroom = 0.1
+
+	// DefaultMaxPods is the number of pods which fit on a node, unless
+	// otherwise specified
+	DefaultMaxPods = 110
+)
+
+// Resources are an amount of CPU, in cores, and RAM, in bytes
+type Resources struct {
+	CPU float64 `json:"cpu"`
+	RAM float64 `json:"ram"`
+}
+
+// Add returns the sum of the resources
+func (r Resources) Add(that Resources) Resources {
+	return Resources{
+		CPU: r.CPU + that.CPU,
+		RAM: r.RAM + that.RAM,
+	}
+}
+
+// Max returns the larger of each resource
+func (r Resources) Max(that Resources) Resources {
+	return Resources{
+		CPU: math.Max(r.CPU, that.CPU),
+		RAM: math.Max(r.RAM, that.RAM),
+	}
+}
+
+// Scale returns the resources multiplied by the factor
+func (r Resources) Scale(factor float64) Resources {
+	return Resources{
+		CPU: r.CPU * factor,
+		RAM: r.RAM * factor,
+	}
+}
+
+// FitsIn returns true if neither resource exceeds that of the capacity
+func (r Resources) FitsIn(capacity Resources) bool {
+	return r.CPU <= capacity.CPU && r.RAM <= capacity.RAM
+}
+
+// NodeType is a type of node, such as a cloud instance type, which can be
+// provisioned
+type NodeType struct {
+	Name       string    `json:"name"`
+	Capacity   Resources `json:"capacity"`
+	HourlyCost float64   `json:"hourlyCost"`
+}
+
+// Options configure how pods are packed onto nodes
+type Options struct {
+	// Headroom is the fraction, in [0, 1), of each node's capacity which is
+	// left unrequested
+	Headroom float64
+	// MaxPods is the number of pods, including those of DaemonSets, which fit
+	// on a node. Zero is DefaultMaxPods.
+	MaxPods int
+	// DaemonSetOverhead is the resources requested on every node by the pods
+	// of DaemonSets
+	DaemonSetOverhead Resources
+	// DaemonSetPods is the number of pods of DaemonSets on every node
+	DaemonSetPods int
+}
+
+// DefaultOptions returns the default options, without DaemonSets
+func DefaultOptions() Options {
+	return Options{
+		Headroom: DefaultHeadroom,
+		MaxPods:  DefaultMaxPods,
+	}
+}
+
+func (o Options) maxPods() int {
+	if o.MaxPods <= 0 {
+		return DefaultMaxPods
+	}
+	return o.MaxPods
+}
+
+// available returns the resources of a node of the type which pods may
+// request, after the headroom and DaemonSets
+func (o Options) available(nt NodeType) Resources {
+	usable := nt.Capacity.Scale(1 - o.Headroom)
+	return Resources{
+		CPU: usable.CPU - o.DaemonSetOverhead.CPU,
+		RAM: usable.RAM - o.DaemonSetOverhead.RAM,
+	}
+}
+
+// Node is a node of a plan and the pods packed onto it
+type Node struct {
+	Type       string    `json:"type"`
+	Pods       int       `json:"pods"`
+	Requested  Resources `json:"requested"`
+	HourlyCost float64   `json:"hourlyCost"`
+}
+
+// Plan is a set of nodes onto which pods are packed, which may mix node
+// types. Requested includes the requests of the pods of DaemonSets on every
+// node.
+type Plan struct {
+	Nodes      []*Node        `json:"nodes"`
+	Counts     map[string]int `json:"counts"`
+	Requested  Resources      `json:"requested"`
+	Capacity   Resources      `json:"capacity"`
+	HourlyCost float64        `json:"hourlyCost"`
+}
+
+// bin is a node being packed
+type bin struct {
+	requested Resources
+	pods      int
+}
+
+// Pack finds the cheapest set of nodes, of the given types, onto which the
+// pods fit. Pods are packed first-fit decreasing onto nodes of each type in
+// turn, then each node is replaced by the cheapest type which fits its pods.
+// The cheapest of the resulting plans is returned. An error is returned if
+// any pod fits no node type.
+func Pack(pods []Resources, nodeTypes []NodeType, opts Options) (*Plan, error) {
+	if opts.Headroom < 0 || opts.Headroom >= 1 {
+		return nil, fmt.Errorf("headroom must be in [0, 1): %f", opts.Headroom)
+	}
+	if len(nodeTypes) == 0 {
+		return nil, fmt.Errorf("no node types")
+	}
+	if opts.DaemonSetPods >= opts.maxPods() {
+		return nil, fmt.Errorf("no pods fit alongside %d DaemonSet pods", opts.DaemonSetPods)
+	}
+
+	// Sort pods by their largest share of the largest node type's resources,
+	// largest first
+	var largest Resources
+	for _, nt := range nodeTypes {
+		largest = largest.Max(nt.Capacity)
+	}
+	share := func(r Resources) float64 {
+		var s float64
+		if largest.CPU > 0 {
+			s = r.CPU / largest.CPU
+		}
+		if largest.RAM > 0 {
+			s = math.Max(s, r.RAM/largest.RAM)
+		}
+		return s
+	}
+	sorted := append([]Resources{}, pods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return share(sorted[i]) > share(sorted[j])
+	})
+
+	var best *Plan
+	for _, nt := range nodeTypes {
+		bins, ok := packFirstFitDecreasing(sorted, nt, opts)
+		if !ok {
+			continue
+		}
+
+		plan, ok := planBins(bins, nodeTypes, opts)
+		if !ok {
+			continue
+		}
+		if best == nil || plan.HourlyCost < best.HourlyCost || (plan.HourlyCost == best.HourlyCost && len(plan.Nodes) < len(best.Nodes)) {
+			best = plan
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("pods do not fit any node type")
+	}
+	return best, nil
+}
+
+// packFirstFitDecreasing packs the sorted pods onto nodes of the type,
+// returning false if any pod does not fit an empty node
+func packFirstFitDecreasing(pods []Resources, nt NodeType, opts Options) ([]*bin, bool) {
+	available := opts.available(nt)
+	maxPods := opts.maxPods() - opts.DaemonSetPods
+
+	bins := []*bin{}
+	for _, pod := range pods {
+		if !pod.FitsIn(available) {
+			return nil, false
+		}
+
+		var fit *bin
+		for _, b := range bins {
+			if b.pods < maxPods && b.requested.Add(pod).FitsIn(available) {
+				fit = b
+				break
+			}
+		}
+		if fit == nil {
+			fit = &bin{}
+			bins = append(bins, fit)
+		}
+		fit.requested = fit.requested.Add(pod)
+		fit.pods++
+	}
+	return bins, true
+}
+
+// planBins assigns each packed node the cheapest type which fits its pods
+func planBins(bins []*bin, nodeTypes []NodeType, opts Options) (*Plan, bool) {
+	plan := &Plan{
+		Nodes:  make([]*Node, 0, len(bins)),
+		Counts: map[string]int{},
+	}
+
+	for _, b := range bins {
+		var cheapest *NodeType
+		for i, nt := range nodeTypes {
+			if !b.requested.FitsIn(opts.available(nt)) {
+				continue
+			}
+			if cheapest == nil || nt.HourlyCost < cheapest.HourlyCost {
+				cheapest = &nodeTypes[i]
+			}
+		}
+		if cheapest == nil {
+			return nil, false
+		}
+
+		requested := b.requested.Add(opts.DaemonSetOverhead)
+		plan.Nodes = append(plan.Nodes, &Node{
+			Type:       cheapest.Name,
+			Pods:       b.pods + opts.DaemonSetPods,
+			Requested:  requested,
+			HourlyCost: cheapest.HourlyCost,
+		})
+		plan.Counts[cheapest.Name]++
+		plan.Requested = plan.Requested.Add(requested)
+		plan.Capacity = plan.Capacity.Add(cheapest.Capacity)
+		plan.HourlyCost += cheapest.HourlyCost
+	}
+
+	return plan, true
+}
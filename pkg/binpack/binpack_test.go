@@ -0,0 +1,103 @@
+package binpack
+
+import (
+	"math"
+	"testing"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func TestPack(t *testing.T) {
+	small := NodeType{Name: "small", Capacity: Resources{CPU: 2, RAM: 8 * gib}, HourlyCost: 0.1}
+	large := NodeType{Name: "large", Capacity: Resources{CPU: 8, RAM: 32 * gib}, HourlyCost: 0.35}
+
+	pod := Resources{CPU: 1, RAM: 2 * gib}
+
+	testCases := map[string]struct {
+		pods      []Resources
+		nodeTypes []NodeType
+		opts      Options
+		expected  map[string]int
+		cost      float64
+		err       bool
+	}{
+		"no pods": {
+			pods:      nil,
+			nodeTypes: []NodeType{small},
+			opts:      DefaultOptions(),
+			expected:  map[string]int{},
+		},
+		// 7 of 8 cores fit a large node with 10% headroom, and the 8th pod
+		// fits a small node
+		"mixed": {
+			pods:      []Resources{pod, pod, pod, pod, pod, pod, pod, pod},
+			nodeTypes: []NodeType{small, large},
+			opts:      DefaultOptions(),
+			expected:  map[string]int{"large": 1, "small": 1},
+			cost:      0.45,
+		},
+		// Only 1 pod fits a small node alongside the DaemonSet, so large
+		// nodes are cheaper
+		"daemonsets": {
+			pods:      []Resources{pod, pod, pod, pod},
+			nodeTypes: []NodeType{small, large},
+			opts:      Options{Headroom: 0, DaemonSetOverhead: Resources{CPU: 0.5, RAM: gib}, DaemonSetPods: 1},
+			expected:  map[string]int{"large": 1},
+			cost:      0.35,
+		},
+		"max pods": {
+			pods:      []Resources{{CPU: 0.1}, {CPU: 0.1}, {CPU: 0.1}},
+			nodeTypes: []NodeType{large},
+			opts:      Options{MaxPods: 3, DaemonSetPods: 1},
+			expected:  map[string]int{"large": 2},
+			cost:      0.7,
+		},
+		"too large": {
+			pods:      []Resources{{CPU: 16}},
+			nodeTypes: []NodeType{small, large},
+			opts:      DefaultOptions(),
+			err:       true,
+		},
+		"invalid headroom": {
+			pods:      []Resources{pod},
+			nodeTypes: []NodeType{small},
+			opts:      Options{Headroom: 1},
+			err:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			plan, err := Pack(tc.pods, tc.nodeTypes, tc.opts)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got plan %+v", plan)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(plan.Counts) != len(tc.expected) {
+				t.Errorf("expected counts %v, got %v", tc.expected, plan.Counts)
+			}
+			for nt, count := range tc.expected {
+				if plan.Counts[nt] != count {
+					t.Errorf("expected counts %v, got %v", tc.expected, plan.Counts)
+				}
+			}
+			if math.Abs(plan.HourlyCost-tc.cost) > 1e-9 {
+				t.Errorf("expected hourly cost %f, got %f", tc.cost, plan.HourlyCost)
+			}
+
+			var pods int
+			for _, node := range plan.Nodes {
+				pods += node.Pods - tc.opts.DaemonSetPods
+			}
+			if pods != len(tc.pods) {
+				t.Errorf("expected %d pods to be packed, got %d", len(tc.pods), pods)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package budget defines spending budgets over allocations or cloud costs,
+// which are evaluated periodically against actual and forecast spend, sending
+// webhook notifications as their thresholds are crossed.
+package budget
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/filter"
+	"github.com/opencost/opencost/core/pkg/filter/allocation"
+	"github.com/opencost/opencost/core/pkg/filter/cloudcost"
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+const day = 24 * time.Hour
+
+// Scope is the type of cost a Budget applies to
+type Scope string
+
+const (
+	ScopeAllocation Scope = "allocation"
+	ScopeCloudCost  Scope = "cloudCost"
+)
+
+// Period is the recurring window of time a Budget's amount applies to
+type Period string
+
+const (
+	PeriodMonthly Period = "monthly"
+	PeriodWeekly  Period = "weekly"
+)
+
+// Window returns the window of the period which contains t. Monthly periods
+// start on the 1st of the month and weekly periods on Monday, both at
+// midnight UTC.
+func (p Period) Window(t time.Time) opencost.Window {
+	t = t.UTC()
+	switch p {
+	case PeriodWeekly:
+		start := t.Truncate(day)
+		// Days since Monday, as time.Weekday begins on Sunday
+		start = start.Add(-time.Duration((int(start.Weekday())+6)%7) * day)
+		return opencost.NewClosedWindow(start, start.Add(7*day))
+	default:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return opencost.NewClosedWindow(start, start.AddDate(0, 1, 0))
+	}
+}
+
+// Budget is an amount which the spend on the allocations or cloud costs
+// matching its filter should not exceed in each period. Thresholds are
+// percentages of the amount; a notification is sent to each of the webhooks
+// the first time in a period that the actual or forecast spend reaches each
+// threshold. The CostMetric is the cost of cloud costs which is budgeted,
+// defaulting to the amortized net cost.
+type Budget struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scope      Scope     `json:"scope"`
+	Filter     string    `json:"filter"`
+	Period     Period    `json:"period"`
+	Amount     float64   `json:"amount"`
+	Thresholds []float64 `json:"thresholds"`
+	CostMetric string    `json:"costMetric,omitempty"`
+	Webhooks   []string  `json:"webhooks"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// DefaultThresholds are the thresholds of a Budget if none are given
+var DefaultThresholds = []float64{50, 80, 100}
+
+// Validate returns an error if the Budget is invalid, and otherwise sets
+// defaults for any optional fields which are not set
+func (b *Budget) Validate() error {
+	if b.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch b.Scope {
+	case ScopeAllocation, ScopeCloudCost:
+	default:
+		return fmt.Errorf("invalid scope '%s', must be one of '%s' or '%s'", b.Scope, ScopeAllocation, ScopeCloudCost)
+	}
+
+	_, err := b.ParseFilter()
+	if err != nil {
+		return err
+	}
+
+	switch b.Period {
+	case PeriodMonthly, PeriodWeekly:
+	case "":
+		b.Period = PeriodMonthly
+	default:
+		return fmt.Errorf("invalid period '%s', must be one of '%s' or '%s'", b.Period, PeriodMonthly, PeriodWeekly)
+	}
+
+	if b.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+
+	if len(b.Thresholds) == 0 {
+		b.Thresholds = append([]float64{}, DefaultThresholds...)
+	}
+	for _, threshold := range b.Thresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("invalid threshold %f, must be a percentage greater than 0", threshold)
+		}
+	}
+
+	if b.Scope == ScopeCloudCost {
+		if b.CostMetric == "" {
+			b.CostMetric = string(opencost.CostMetricAmortizedNetCost)
+		}
+		_, err := opencost.ParseCostMetricName(b.CostMetric)
+		if err != nil {
+			return fmt.Errorf("invalid costMetric: %w", err)
+		}
+	}
+
+	for _, webhook := range b.Webhooks {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook '%s', must be an http or https URL", webhook)
+		}
+	}
+
+	return nil
+}
+
+// ParseFilter parses the Budget's v2 filter, for its scope. An empty filter
+// is nil, matching all costs.
+func (b *Budget) ParseFilter() (filter.Filter, error) {
+	if b.Filter == "" {
+		return nil, nil
+	}
+
+	var f filter.Filter
+	var err error
+	switch b.Scope {
+	case ScopeCloudCost:
+		f, err = cloudcost.NewCloudCostFilterParser().Parse(b.Filter)
+	default:
+		f, err = allocation.NewAllocationFilterParser().Parse(b.Filter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %w", b.Filter, err)
+	}
+	return f, nil
+}
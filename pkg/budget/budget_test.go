@@ -0,0 +1,146 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func TestBudget_Validate(t *testing.T) {
+	valid := func() *Budget {
+		return &Budget{
+			Name:     "team-a",
+			Scope:    ScopeAllocation,
+			Filter:   `namespace:"team-a"`,
+			Amount:   1000,
+			Webhooks: []string{"https://example.com/hook"},
+		}
+	}
+
+	b := valid()
+	err := b.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if b.Period != PeriodMonthly {
+		t.Errorf("expected default period %s, got %s", PeriodMonthly, b.Period)
+	}
+	if len(b.Thresholds) != len(DefaultThresholds) {
+		t.Errorf("expected default thresholds, got %v", b.Thresholds)
+	}
+
+	cc := valid()
+	cc.Scope = ScopeCloudCost
+	cc.Filter = `service:"AmazonEC2"`
+	err = cc.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cc.CostMetric != string(opencost.CostMetricAmortizedNetCost) {
+		t.Errorf("expected default cost metric, got %s", cc.CostMetric)
+	}
+
+	invalid := map[string]func(b *Budget){
+		"no name":           func(b *Budget) { b.Name = "" },
+		"invalid scope":     func(b *Budget) { b.Scope = "assets" },
+		"invalid filter":    func(b *Budget) { b.Filter = `namespace:` },
+		"invalid period":    func(b *Budget) { b.Period = "daily" },
+		"zero amount":       func(b *Budget) { b.Amount = 0 },
+		"invalid threshold": func(b *Budget) { b.Thresholds = []float64{80, -1} },
+		"invalid webhook":   func(b *Budget) { b.Webhooks = []string{"ftp://example.com"} },
+		"invalid metric": func(b *Budget) {
+			b.Scope = ScopeCloudCost
+			b.Filter = ""
+			b.CostMetric = "unknown"
+		},
+	}
+	for name, modify := range invalid {
+		t.Run(name, func(t *testing.T) {
+			b := valid()
+			modify(b)
+			if err := b.Validate(); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestPeriod_Window(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, time.February, 14, 15, 0, 0, 0, time.UTC)
+
+	monthly := PeriodMonthly.Window(now)
+	expected := opencost.NewClosedWindow(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if !monthly.Equal(expected) {
+		t.Errorf("expected monthly window %s, got %s", expected, monthly)
+	}
+
+	weekly := PeriodWeekly.Window(now)
+	expected = opencost.NewClosedWindow(time.Date(2024, time.February, 12, 0, 0, 0, 0, time.UTC), time.Date(2024, time.February, 19, 0, 0, 0, 0, time.UTC))
+	if !weekly.Equal(expected) {
+		t.Errorf("expected weekly window %s, got %s", expected, weekly)
+	}
+
+	// Sunday is the last day of the week
+	sunday := time.Date(2024, time.February, 18, 23, 0, 0, 0, time.UTC)
+	if w := PeriodWeekly.Window(sunday); !w.Equal(expected) {
+		t.Errorf("expected weekly window %s for Sunday, got %s", expected, w)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(storage.NewFileStorage(t.TempDir()))
+
+	budgets, err := store.List()
+	if err != nil || len(budgets) != 0 {
+		t.Fatalf("expected no budgets, got %v, %v", budgets, err)
+	}
+
+	for _, b := range []*Budget{{ID: "2", Name: "b"}, {ID: "1", Name: "a"}} {
+		err := store.Put(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	err = store.PutStatus(&Status{BudgetID: "1", Actual: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	budgets, err = store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(budgets) != 2 || budgets[0].Name != "a" || budgets[1].Name != "b" {
+		t.Errorf("expected budgets a and b, got %+v", budgets)
+	}
+
+	status, err := store.GetStatus("1")
+	if err != nil || status == nil || status.Actual != 10 {
+		t.Errorf("expected status of budget 1, got %+v, %v", status, err)
+	}
+	status, err = store.GetStatus("2")
+	if err != nil || status != nil {
+		t.Errorf("expected no status for budget 2, got %+v, %v", status, err)
+	}
+
+	err = store.Delete("1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := store.Get("1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted budget to be not found, got %v", err)
+	}
+	if status, _ := store.GetStatus("1"); status != nil {
+		t.Errorf("expected status of deleted budget to be removed")
+	}
+	if err := store.Delete("1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleting a missing budget to be not found, got %v", err)
+	}
+	if _, err := store.Get("../1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected invalid ID to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+)
+
+// CloudCostQuerier is a Querier of the spend of budgets over cloud costs
+type CloudCostQuerier struct {
+	querier cloudcost.Querier
+}
+
+// NewCloudCostQuerier creates a CloudCostQuerier of the cloud costs of the
+// given querier
+func NewCloudCostQuerier(querier cloudcost.Querier) *CloudCostQuerier {
+	return &CloudCostQuerier{
+		querier: querier,
+	}
+}
+
+func (ccq *CloudCostQuerier) DailyCosts(ctx context.Context, budget *Budget, window opencost.Window) ([]float64, error) {
+	f, err := budget.ParseFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	costMetricName, err := opencost.ParseCostMetricName(budget.CostMetric)
+	if err != nil {
+		return nil, err
+	}
+
+	ccsr, err := ccq.querier.Query(ctx, cloudcost.QueryRequest{
+		Start:       *window.Start(),
+		End:         *window.End(),
+		AggregateBy: []string{opencost.CloudCostProviderProp},
+		Accumulate:  opencost.AccumulateOptionNone,
+		Filter:      f,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	costs := make([]float64, 0, len(ccsr.CloudCostSets))
+	for _, ccs := range ccsr.CloudCostSets {
+		var total float64
+		for _, cc := range ccs.CloudCosts {
+			costMetric, err := cc.GetCostMetric(costMetricName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cost metric: %w", err)
+			}
+			total += costMetric.Cost
+		}
+		costs = append(costs, total)
+	}
+	return costs, nil
+}
@@ -0,0 +1,221 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/forecast"
+)
+
+// Querier provides the spend of the costs within the scope of budgets
+type Querier interface {
+	// DailyCosts returns the total cost of the allocations or cloud costs
+	// matching the budget's filter on each day of the window, in order. The
+	// last day may be partial.
+	DailyCosts(ctx context.Context, budget *Budget, window opencost.Window) ([]float64, error)
+}
+
+// Status is the result of the most recent evaluation of a Budget over its
+// current period. Forecast is the projected spend at the end of the period,
+// which is nil until the period has at least 2 complete days to forecast
+// from. Notified lists the notifications which have been sent to each
+// webhook during the period, e.g. "actual:80:https://example.com/hook", so
+// that a failed webhook is retried without notifying the others again.
+type Status struct {
+	BudgetID        string          `json:"budgetId"`
+	Window          opencost.Window `json:"window"`
+	Actual          float64         `json:"actual"`
+	ActualPercent   float64         `json:"actualPercent"`
+	Forecast        *float64        `json:"forecast"`
+	ForecastPercent *float64        `json:"forecastPercent"`
+	Notified        []string        `json:"notified"`
+	EvaluatedAt     time.Time       `json:"evaluatedAt"`
+}
+
+func (s *Status) notified(key string) bool {
+	for _, n := range s.Notified {
+		if n == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluator periodically evaluates every budget in a Store against the spend
+// of its scope, sending notifications as its thresholds are crossed.
+type Evaluator struct {
+	store    *Store
+	notifier Notifier
+	queriers map[Scope]Querier
+
+	job timeutil.PeriodicJob
+}
+
+// NewEvaluator creates an Evaluator of the budgets in the store, using the
+// querier for the scope of each budget. Budgets of a scope with no querier
+// are not evaluated.
+func NewEvaluator(store *Store, notifier Notifier, queriers map[Scope]Querier) *Evaluator {
+	return &Evaluator{
+		store:    store,
+		notifier: notifier,
+		queriers: queriers,
+	}
+}
+
+// Start evaluates every budget immediately, and then again at each interval
+// until the Evaluator is stopped
+func (e *Evaluator) Start(interval time.Duration) error {
+	return e.job.Start(interval, func() {
+		err := e.EvaluateAll(context.Background(), time.Now())
+		if err != nil {
+			log.Warnf("Budget: %s", err)
+		}
+	})
+}
+
+// Stop stops the periodic evaluation of budgets
+func (e *Evaluator) Stop() {
+	e.job.Stop()
+}
+
+// EvaluateAll evaluates every budget as of now. An error evaluating one
+// budget does not prevent the evaluation of the others.
+func (e *Evaluator) EvaluateAll(ctx context.Context, now time.Time) error {
+	budgets, err := e.store.List()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, b := range budgets {
+		if _, ok := e.queriers[b.Scope]; !ok {
+			continue
+		}
+
+		_, err := e.Evaluate(ctx, b, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to evaluate budget %s: %w", b.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate evaluates the budget's spend over its period as of now, sending a
+// notification for each threshold which the actual or forecast spend has
+// reached for the first time in the period, and saves its status. A
+// notification which fails to send to a webhook is retried at the next
+// evaluation, for that webhook only.
+func (e *Evaluator) Evaluate(ctx context.Context, b *Budget, now time.Time) (*Status, error) {
+	querier, ok := e.queriers[b.Scope]
+	if !ok {
+		return nil, fmt.Errorf("%s costs are not available", b.Scope)
+	}
+
+	window := b.Period.Window(now)
+	status := &Status{
+		BudgetID:    b.ID,
+		Window:      window,
+		Notified:    []string{},
+		EvaluatedAt: now.UTC(),
+	}
+
+	// Notifications sent earlier in the same period are not repeated
+	prev, err := e.store.GetStatus(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.Window.Equal(window) {
+		status.Notified = append(status.Notified, prev.Notified...)
+	}
+
+	var costs []float64
+	if now.After(*window.Start()) {
+		costs, err = querier.DailyCosts(ctx, b, opencost.NewClosedWindow(*window.Start(), now))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, cost := range costs {
+		status.Actual += cost
+	}
+	status.ActualPercent = status.Actual / b.Amount * 100
+
+	// Forecast the remainder of the period from its complete days
+	today := now.UTC().Truncate(day)
+	complete := min(int(today.Sub(*window.Start())/day), len(costs))
+	if complete >= 2 {
+		model, err := forecast.Fit(*window.Start(), costs[:complete])
+		if err != nil {
+			return nil, err
+		}
+		remaining := int(math.Ceil(float64(window.End().Sub(today)) / float64(day)))
+		f, err := model.Forecast(remaining, forecast.DefaultConfidence)
+		if err != nil {
+			return nil, err
+		}
+
+		projected := f.Total.Cost
+		for _, cost := range costs[:complete] {
+			projected += cost
+		}
+		percent := projected / b.Amount * 100
+		status.Forecast = &projected
+		status.ForecastPercent = &percent
+	}
+
+	thresholds := append([]float64{}, b.Thresholds...)
+	sort.Float64s(thresholds)
+
+	var errs []error
+	for _, threshold := range thresholds {
+		notify := func(kind NotificationType, spend float64) {
+			if spend < b.Amount*threshold/100 {
+				return
+			}
+
+			notification := &Notification{
+				BudgetID:        b.ID,
+				BudgetName:      b.Name,
+				Type:            kind,
+				Threshold:       threshold,
+				ThresholdAmount: b.Amount * threshold / 100,
+				Amount:          b.Amount,
+				Spend:           spend,
+				Window:          window,
+				Timestamp:       now.UTC(),
+			}
+			for _, webhook := range b.Webhooks {
+				key := fmt.Sprintf("%s:%g:%s", kind, threshold, webhook)
+				if status.notified(key) {
+					continue
+				}
+
+				err := e.notifier.Notify(ctx, webhook, notification)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to send %s:%g notification: %w", kind, threshold, err))
+					continue
+				}
+				status.Notified = append(status.Notified, key)
+			}
+		}
+
+		notify(NotificationActual, status.Actual)
+		if status.Forecast != nil {
+			notify(NotificationForecast, *status.Forecast)
+		}
+	}
+
+	err = e.store.PutStatus(status)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return status, errors.Join(errs...)
+}
@@ -0,0 +1,336 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// mockQuerier is a Querier whose cost is the same on every day
+type mockQuerier struct {
+	daily float64
+}
+
+func (mq *mockQuerier) DailyCosts(ctx context.Context, b *Budget, window opencost.Window) ([]float64, error) {
+	costs := []float64{}
+	for d := *window.Start(); d.Before(*window.End()); d = d.Add(day) {
+		costs = append(costs, mq.daily)
+	}
+	return costs, nil
+}
+
+// webhook is a local stand-in for a webhook receiver, recording the
+// notifications it receives
+type webhook struct {
+	lock          sync.Mutex
+	status        int
+	notifications []*Notification
+}
+
+func newWebhook(t *testing.T) (*webhook, string) {
+	wh := &webhook{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wh.lock.Lock()
+		defer wh.lock.Unlock()
+
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		if wh.status == http.StatusOK {
+			n := &Notification{}
+			err := json.NewDecoder(r.Body).Decode(n)
+			if err != nil {
+				t.Errorf("failed to decode notification: %s", err)
+			}
+			wh.notifications = append(wh.notifications, n)
+		}
+		w.WriteHeader(wh.status)
+	}))
+	t.Cleanup(server.Close)
+
+	return wh, server.URL
+}
+
+func (wh *webhook) received() []string {
+	wh.lock.Lock()
+	defer wh.lock.Unlock()
+
+	keys := []string{}
+	for _, n := range wh.notifications {
+		keys = append(keys, string(n.Type)+":"+formatThreshold(n.Threshold))
+	}
+	return keys
+}
+
+func formatThreshold(threshold float64) string {
+	b, _ := json.Marshal(threshold)
+	return string(b)
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	wh, url := newWebhook(t)
+
+	store := NewStore(storage.NewFileStorage(t.TempDir()))
+	querier := &mockQuerier{daily: 10}
+	evaluator := NewEvaluator(store, NewWebhookNotifier(time.Second), map[Scope]Querier{ScopeAllocation: querier})
+
+	b := &Budget{
+		ID:         "budget",
+		Name:       "team-a",
+		Scope:      ScopeAllocation,
+		Amount:     300,
+		Thresholds: []float64{50, 100},
+		Webhooks:   []string{url},
+	}
+	err := b.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = store.Put(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 10 days into a 30 day month at 10 per day, the actual spend is 100 and
+	// the forecast spend is 300
+	now := time.Date(2024, time.April, 11, 0, 0, 0, 0, time.UTC)
+	status, err := evaluator.Evaluate(context.Background(), b, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if status.Actual != 100 {
+		t.Errorf("expected actual spend 100, got %f", status.Actual)
+	}
+	if status.Forecast == nil || !approxEqual(*status.Forecast, 300) {
+		t.Fatalf("expected forecast spend 300, got %v", status.Forecast)
+	}
+	if got := wh.received(); len(got) != 2 || got[0] != "forecast:50" || got[1] != "forecast:100" {
+		t.Errorf("expected forecast notifications for 50 and 100, got %v", got)
+	}
+
+	// Notifications are not repeated within the period
+	now = now.Add(6 * day)
+	_, err = evaluator.Evaluate(context.Background(), b, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := wh.received(); len(got) != 3 || got[2] != "actual:50" {
+		t.Errorf("expected a single actual notification for 50, got %v", got)
+	}
+
+	// Notifications which fail are retried
+	wh.status = http.StatusInternalServerError
+	now = time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC)
+	_, err = evaluator.Evaluate(context.Background(), b, now)
+	if err == nil {
+		t.Errorf("expected error from failing webhook")
+	}
+	wh.status = http.StatusOK
+	status, err = evaluator.Evaluate(context.Background(), b, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(status.Notified) != 3 {
+		t.Errorf("expected 3 notifications in the new period, got %v", status.Notified)
+	}
+	if got := wh.received(); len(got) != 6 {
+		t.Errorf("expected 3 more notifications after retry, got %v", got)
+	}
+
+	saved, err := store.GetStatus(b.ID)
+	if err != nil || saved == nil || !saved.Window.Equal(PeriodMonthly.Window(now)) {
+		t.Errorf("expected status of the current period to be saved, got %+v, %v", saved, err)
+	}
+}
+
+func TestEvaluator_Evaluate_FailingWebhook(t *testing.T) {
+	healthy, healthyURL := newWebhook(t)
+	failing, failingURL := newWebhook(t)
+	failing.status = http.StatusInternalServerError
+
+	store := NewStore(storage.NewFileStorage(t.TempDir()))
+	evaluator := NewEvaluator(store, NewWebhookNotifier(time.Second), map[Scope]Querier{ScopeAllocation: &mockQuerier{daily: 100}})
+
+	b := &Budget{
+		ID:         "budget",
+		Name:       "team-a",
+		Scope:      ScopeAllocation,
+		Amount:     100,
+		Thresholds: []float64{50},
+		Webhooks:   []string{failingURL, healthyURL},
+	}
+	err := b.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = store.Put(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// On the second day, the actual spend of 200 reaches the
+	// threshold, and there is not yet a complete day to forecast from
+	now := time.Date(2024, time.April, 2, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		_, err = evaluator.Evaluate(context.Background(), b, now)
+		if err == nil {
+			t.Errorf("expected error from failing webhook")
+		}
+	}
+	if got := healthy.received(); len(got) != 1 || got[0] != "actual:50" {
+		t.Errorf("expected the healthy webhook to be notified once, got %v", got)
+	}
+
+	// Once it recovers, only the failing webhook is sent the notification
+	failing.lock.Lock()
+	failing.status = http.StatusOK
+	failing.lock.Unlock()
+	status, err := evaluator.Evaluate(context.Background(), b, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := failing.received(); len(got) != 1 || got[0] != "actual:50" {
+		t.Errorf("expected the recovered webhook to be notified once, got %v", got)
+	}
+	if got := healthy.received(); len(got) != 1 {
+		t.Errorf("expected the healthy webhook not to be notified again, got %v", got)
+	}
+	if len(status.Notified) != 2 {
+		t.Errorf("expected a notification to each webhook, got %v", status.Notified)
+	}
+}
+
+func TestEvaluator_EvaluateAll(t *testing.T) {
+	store := NewStore(storage.NewFileStorage(t.TempDir()))
+	evaluator := NewEvaluator(store, NewWebhookNotifier(time.Second), map[Scope]Querier{ScopeAllocation: &mockQuerier{daily: 1}})
+
+	store.Put(&Budget{ID: "alloc", Name: "alloc", Scope: ScopeAllocation, Period: PeriodWeekly, Amount: 100, Thresholds: DefaultThresholds})
+	store.Put(&Budget{ID: "cloud", Name: "cloud", Scope: ScopeCloudCost, Period: PeriodWeekly, Amount: 100, Thresholds: DefaultThresholds})
+
+	err := evaluator.EvaluateAll(context.Background(), time.Date(2024, time.April, 11, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Three complete days and the partial current day of the week
+	if status, _ := store.GetStatus("alloc"); status == nil || status.Actual != 4 {
+		t.Errorf("expected allocation budget to be evaluated, got %+v", status)
+	}
+	if status, _ := store.GetStatus("cloud"); status != nil {
+		t.Errorf("expected cloud cost budget without a querier to be skipped, got %+v", status)
+	}
+}
+
+func TestCloudCostQuerier(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	repo := cloudcost.NewMemoryRepository()
+	for i := 0; i < 2; i++ {
+		dayStart := start.Add(time.Duration(i) * day)
+		err := repo.Put(cloudcost.DefaultMockCloudCostSet(dayStart, dayStart.Add(day), "gcp", "integration"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	b := &Budget{Name: "compute", Scope: ScopeCloudCost, Amount: 1, CostMetric: string(opencost.CostMetricNetCost)}
+	err := b.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	costs, err := NewCloudCostQuerier(cloudcost.NewRepositoryQuerier(repo)).DailyCosts(context.Background(), b, opencost.NewClosedWindow(start, start.Add(2*day)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(costs) != 2 || costs[0] <= 0 || costs[0] != costs[1] {
+		t.Errorf("expected the same positive cost on both days, got %v", costs)
+	}
+}
+
+func TestService(t *testing.T) {
+	service := NewService(NewStore(storage.NewFileStorage(t.TempDir())))
+
+	router := httprouter.New()
+	router.GET("/budgets", service.GetBudgetsHandler())
+	router.POST("/budgets", service.CreateBudgetHandler())
+	router.GET("/budgets/:id", service.GetBudgetHandler())
+	router.PUT("/budgets/:id", service.UpdateBudgetHandler())
+	router.DELETE("/budgets/:id", service.DeleteBudgetHandler())
+
+	do := func(method, path string, body any) (*httptest.ResponseRecorder, *BudgetResponse) {
+		var reader *bytes.Reader
+		if body != nil {
+			data, _ := json.Marshal(body)
+			reader = bytes.NewReader(data)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, reader))
+
+		var resp struct {
+			Data *BudgetResponse `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp.Data
+	}
+
+	rec, _ := do(http.MethodPost, "/budgets", map[string]any{"name": "bad", "scope": "allocation"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for budget without amount, got %d", rec.Code)
+	}
+
+	rec, created := do(http.MethodPost, "/budgets", map[string]any{"name": "team-a", "scope": "allocation", "filter": `namespace:"a"`, "amount": 100})
+	if rec.Code != http.StatusOK || created == nil || created.ID == "" {
+		t.Fatalf("expected budget to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec, _ = do(http.MethodPut, "/budgets/"+created.ID, map[string]any{"name": "team-a", "scope": "allocation", "amount": 200, "period": "weekly"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected budget to be updated, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec, got := do(http.MethodGet, "/budgets/"+created.ID, nil)
+	if rec.Code != http.StatusOK || got.Amount != 200 || got.Period != PeriodWeekly || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected updated budget, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/budgets", nil))
+	var list struct {
+		Data []*BudgetResponse `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list.Data) != 1 {
+		t.Errorf("expected 1 budget, got %s", rec.Body.String())
+	}
+
+	rec, _ = do(http.MethodDelete, "/budgets/"+created.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected budget to be deleted, got %d", rec.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		rec, _ = do(method, "/budgets/"+created.ID, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected %s of deleted budget to be not found, got %d", method, rec.Code)
+		}
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-6 && d > -1e-6
+}
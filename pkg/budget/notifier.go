@@ -0,0 +1,77 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+// NotificationType is whether a notification is for actual or forecast spend
+type NotificationType string
+
+const (
+	NotificationActual   NotificationType = "actual"
+	NotificationForecast NotificationType = "forecast"
+)
+
+// Notification is sent when the actual or forecast spend of a budget reaches
+// one of its thresholds during a period
+type Notification struct {
+	BudgetID        string           `json:"budgetId"`
+	BudgetName      string           `json:"budgetName"`
+	Type            NotificationType `json:"type"`
+	Threshold       float64          `json:"threshold"`
+	ThresholdAmount float64          `json:"thresholdAmount"`
+	Amount          float64          `json:"amount"`
+	Spend           float64          `json:"spend"`
+	Window          opencost.Window  `json:"window"`
+	Timestamp       time.Time        `json:"timestamp"`
+}
+
+// Notifier sends notifications to webhooks
+type Notifier interface {
+	Notify(ctx context.Context, webhook string, notification *Notification) error
+}
+
+// WebhookNotifier is a Notifier which POSTs each notification as JSON to the
+// webhook URL, expecting a 2xx response
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier whose requests time out after
+// the given duration
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (wn *WebhookNotifier) Notify(ctx context.Context, webhook string, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", webhook, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to %s: %w", webhook, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with status %d", webhook, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
+)
+
+var protocol = proto.HTTP()
+
+// BudgetResponse is a Budget with the status of its most recent evaluation,
+// which is nil if it has not yet been evaluated
+type BudgetResponse struct {
+	*Budget
+	Status *Status `json:"status"`
+}
+
+// Service surfaces endpoints to create, read, update and delete budgets
+type Service struct {
+	Store *Store
+}
+
+func NewService(store *Store) *Service {
+	return &Service{
+		Store: store,
+	}
+}
+
+func (s *Service) response(b *Budget) (*BudgetResponse, error) {
+	status, err := s.Store.GetStatus(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &BudgetResponse{Budget: b, Status: status}, nil
+}
+
+// decodeBudget decodes and validates the budget in the request body
+func decodeBudget(r *http.Request) (*Budget, error) {
+	b := &Budget{}
+	err := json.NewDecoder(r.Body).Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget: %w", err)
+	}
+
+	err = b.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget: %w", err)
+	}
+	return b, nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+}
+
+// GetBudgetsHandler returns every budget with its status
+func (s *Service) GetBudgetsHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Budget Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		budgets, err := s.Store.List()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		resp := make([]*BudgetResponse, 0, len(budgets))
+		for _, b := range budgets {
+			br, err := s.response(b)
+			if err != nil {
+				writeStoreError(w, err)
+				return
+			}
+			resp = append(resp, br)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, resp)
+	}
+}
+
+// GetBudgetHandler returns the budget with the ID in the path, with its status
+func (s *Service) GetBudgetHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Budget Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		b, err := s.Store.Get(ps.ByName("id"))
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		resp, err := s.response(b)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, resp)
+	}
+}
+
+// CreateBudgetHandler creates the budget in the request body, assigning it an
+// ID, and returns it
+func (s *Service) CreateBudgetHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Budget Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		b, err := decodeBudget(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		b.ID = uuid.NewString()
+		b.CreatedAt = now
+		b.UpdatedAt = now
+
+		err = s.Store.Put(b)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, &BudgetResponse{Budget: b})
+	}
+}
+
+// UpdateBudgetHandler replaces the budget with the ID in the path with the
+// budget in the request body, and returns it
+func (s *Service) UpdateBudgetHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Budget Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		existing, err := s.Store.Get(ps.ByName("id"))
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		b, err := decodeBudget(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b.ID = existing.ID
+		b.CreatedAt = existing.CreatedAt
+		b.UpdatedAt = time.Now().UTC()
+
+		err = s.Store.Put(b)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		resp, err := s.response(b)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, resp)
+	}
+}
+
+// DeleteBudgetHandler deletes the budget with the ID in the path
+func (s *Service) DeleteBudgetHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If Service is nil, always return 501
+		if s == nil || s.Store == nil {
+			http.Error(w, "Budget Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		err := s.Store.Delete(ps.ByName("id"))
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		protocol.WriteData(w, nil)
+	}
+}
@@ -0,0 +1,208 @@
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+const (
+	storageDir       = "budgets"
+	statusStorageDir = "budgets/status"
+)
+
+// ErrNotFound is returned for a budget which does not exist
+var ErrNotFound = errors.New("budget not found")
+
+// Store persists budgets, and the status of their most recent evaluation, to
+// a storage.Storage with one file per budget
+type Store struct {
+	lock  sync.RWMutex
+	store storage.Storage
+}
+
+// NewStore creates a Store which writes to the given storage
+func NewStore(store storage.Storage) *Store {
+	return &Store{
+		store: store,
+	}
+}
+
+// validID returns true if the ID can be used as a file name
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, "/\\")
+}
+
+func budgetPath(id string) string {
+	return path.Join(storageDir, id+".json")
+}
+
+func statusPath(id string) string {
+	return path.Join(statusStorageDir, id+".json")
+}
+
+// read unmarshals the file at the path into v, returning ErrNotFound if it
+// does not exist
+func (s *Store) read(p string, v any) error {
+	exists, err := s.store.Exists(p)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", p, err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	data, err := s.store.Read(p)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", p, err)
+	}
+	return nil
+}
+
+func (s *Store) write(p string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", p, err)
+	}
+
+	err = s.store.Write(p, data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", p, err)
+	}
+	return nil
+}
+
+// List returns every budget, ordered by name
+func (s *Store) List() ([]*Budget, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	files, err := s.store.List(storageDir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return []*Budget{}, nil
+		}
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+
+	budgets := make([]*Budget, 0, len(files))
+	for _, file := range files {
+		name := path.Base(file.Name)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		b := &Budget{}
+		err := s.read(budgetPath(strings.TrimSuffix(name, ".json")), b)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	sort.Slice(budgets, func(i, j int) bool {
+		if budgets[i].Name != budgets[j].Name {
+			return budgets[i].Name < budgets[j].Name
+		}
+		return budgets[i].ID < budgets[j].ID
+	})
+	return budgets, nil
+}
+
+// Get returns the budget with the given ID, or ErrNotFound
+func (s *Store) Get(id string) (*Budget, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	b := &Budget{}
+	err := s.read(budgetPath(id), b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Put creates or replaces the budget with the budget's ID
+func (s *Store) Put(b *Budget) error {
+	if !validID(b.ID) {
+		return fmt.Errorf("invalid budget ID '%s'", b.ID)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.write(budgetPath(b.ID), b)
+}
+
+// Delete removes the budget with the given ID, and its status, returning
+// ErrNotFound if it does not exist
+func (s *Store) Delete(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	exists, err := s.store.Exists(budgetPath(id))
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", budgetPath(id), err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	err = s.store.Remove(budgetPath(id))
+	if err != nil && !storage.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", budgetPath(id), err)
+	}
+
+	err = s.store.Remove(statusPath(id))
+	if err != nil && !storage.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", statusPath(id), err)
+	}
+	return nil
+}
+
+// GetStatus returns the status of the most recent evaluation of the budget
+// with the given ID, or nil if it has not been evaluated
+func (s *Store) GetStatus(id string) (*Status, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	status := &Status{}
+	err := s.read(statusPath(id), status)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// PutStatus saves the status of the evaluation of a budget
+func (s *Store) PutStatus(status *Status) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.write(statusPath(status.BudgetID), status)
+}
@@ -13,6 +13,8 @@ type BOAConfiguration struct {
 	Account    string     `json:"account"`
 	Region     string     `json:"region"`
 	Authorizer Authorizer `json:"authorizer"`
+	// Endpoint optionally overrides the scheme and host of the BSS Open API
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
 func (bc *BOAConfiguration) Validate() error {
@@ -34,6 +36,11 @@ func (bc *BOAConfiguration) Validate() error {
 	if bc.Account == "" {
 		return fmt.Errorf("BOAConfiguration: missing account")
 	}
+
+	_, err = cloud.ParseEndpoint(bc.Endpoint)
+	if err != nil {
+		return fmt.Errorf("BOAConfiguration: %w", err)
+	}
 	return nil
 }
 
@@ -63,6 +70,10 @@ func (bc *BOAConfiguration) Equals(config cloud.Config) bool {
 	if bc.Region != thatConfig.Region {
 		return false
 	}
+
+	if bc.Endpoint != thatConfig.Endpoint {
+		return false
+	}
 	return true
 }
 
@@ -71,6 +82,7 @@ func (bc *BOAConfiguration) Sanitize() cloud.Config {
 		Account:    bc.Account,
 		Region:     bc.Region,
 		Authorizer: bc.Authorizer.Sanitize().(Authorizer),
+		Endpoint:   bc.Endpoint,
 	}
 }
 
@@ -113,6 +125,14 @@ func (bc *BOAConfiguration) UnmarshalJSON(b []byte) error {
 	}
 	bc.Authorizer = authorizer
 
+	if _, ok := fmap["endpoint"]; ok {
+		endpoint, err := cloud.GetInterfaceValue[string](fmap, "endpoint")
+		if err != nil {
+			return fmt.Errorf("BOAConfiguration: UnmarshalJSON: %s", err.Error())
+		}
+		bc.Endpoint = endpoint
+	}
+
 	return nil
 }
 
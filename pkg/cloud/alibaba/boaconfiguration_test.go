@@ -74,6 +74,30 @@ func TestBoaConfiguration_Validate(t *testing.T) {
 			},
 			expected: fmt.Errorf("BOAConfiguration: missing region"),
 		},
+		"valid endpoint": {
+			config: BOAConfiguration{
+				Account: "Account",
+				Region:  "Region",
+				Authorizer: &AccessKey{
+					AccessKeyID:     "accessKeyID",
+					AccessKeySecret: "accessKeySecret",
+				},
+				Endpoint: "http://localhost:8080",
+			},
+			expected: nil,
+		},
+		"invalid endpoint": {
+			config: BOAConfiguration{
+				Account: "Account",
+				Region:  "Region",
+				Authorizer: &AccessKey{
+					AccessKeyID:     "accessKeyID",
+					AccessKeySecret: "accessKeySecret",
+				},
+				Endpoint: "localhost:8080",
+			},
+			expected: fmt.Errorf("BOAConfiguration: invalid endpoint 'localhost:8080': scheme must be http or https"),
+		},
 	}
 
 	for name, testCase := range testCases {
@@ -265,6 +289,17 @@ func TestBOAConfiguration_JSON(t *testing.T) {
 				},
 			},
 		},
+		"Endpoint": {
+			config: BOAConfiguration{
+				Region:  "region",
+				Account: "account",
+				Authorizer: &AccessKey{
+					AccessKeyID:     "id",
+					AccessKeySecret: "secret",
+				},
+				Endpoint: "https://business.aliyuncs.com",
+			},
+		},
 	}
 
 	for name, testCase := range testCases {
@@ -0,0 +1,151 @@
+package alibaba
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+type BoaIntegration struct {
+	BoaQuerier
+}
+
+// GetCloudCost queries the daily instance bill of each billing date in the window. The bill of a
+// day is only complete once it is over, so the window is cut off at the start of the current day.
+func (bi *BoaIntegration) GetCloudCost(start time.Time, end time.Time) (*opencost.CloudCostSetRange, error) {
+	log.Infof("BoaIntegration[%s]: GetCloudCost: %s", bi.Key(), opencost.NewWindow(&start, &end).String())
+
+	today := opencost.RoundBack(time.Now().UTC(), timeutil.Day)
+	if end.After(today) {
+		end = today
+	}
+
+	ccsr, err := opencost.NewCloudCostSetRange(start, end, opencost.AccumulateOptionDay, bi.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	hasResults := false
+	for day := opencost.RoundBack(start.UTC(), timeutil.Day); day.Before(end); day = day.Add(timeutil.Day) {
+		billingDate := day.Format(boaBillingDateLayout)
+		fn := GetBoaQueryInstanceBillFunc(func(item bssopenapi.Item) error {
+			cc := boaItemToCloudCost(item, day)
+			if cc == nil {
+				return nil
+			}
+			hasResults = true
+			ccsr.LoadCloudCost(cc)
+			return nil
+		}, billingDate)
+
+		err = bi.QueryBillingDate(day, fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bi.ConnectionStatus = cloud.ResultStatus(hasResults, bi.ConnectionStatus)
+	return ccsr, nil
+}
+
+// boaItemToCloudCost creates the CloudCost of an item of the daily instance bill, or nil if the
+// item has no cost
+func boaItemToCloudCost(item bssopenapi.Item, day time.Time) *opencost.CloudCost {
+	// The bill of a day holds the pretax cost before discounts as the gross amount, and the cost
+	// after discounts and coupons as the pretax amount
+	listCost := item.PretaxGrossAmount
+	netCost := item.PretaxAmount
+	if listCost == 0 && netCost == 0 {
+		return nil
+	}
+
+	labels := ParseBoaTags(item.Tag)
+
+	k8sPct := 0.0
+	if BoaIsK8s(labels) {
+		k8sPct = 1.0
+	}
+
+	accountID := item.BillAccountID
+	if accountID == "" {
+		accountID = item.OwnerID
+	}
+
+	s := day
+	e := day.Add(timeutil.Day)
+
+	// The bill does not amortize subscriptions, so the NetCost is used as the
+	// amortized and invoiced costs
+	return &opencost.CloudCost{
+		Properties: &opencost.CloudCostProperties{
+			ProviderID:      item.InstanceID,
+			Provider:        opencost.AlibabaProvider,
+			AccountID:       accountID,
+			InvoiceEntityID: item.PayerAccount,
+			Service:         item.ProductCode,
+			Category:        SelectAlibabaCategory(item),
+			Labels:          labels,
+		},
+		Window: opencost.NewWindow(&s, &e),
+		ListCost: opencost.CostMetric{
+			Cost:              listCost,
+			KubernetesPercent: k8sPct,
+		},
+		NetCost: opencost.CostMetric{
+			Cost:              netCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedNetCost: opencost.CostMetric{
+			Cost:              netCost,
+			KubernetesPercent: k8sPct,
+		},
+		InvoicedCost: opencost.CostMetric{
+			Cost:              netCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedCost: opencost.CostMetric{
+			Cost:              netCost,
+			KubernetesPercent: k8sPct,
+		},
+	}
+}
+
+// ParseBoaTags parses the tags of an item of the instance bill, which are formatted as
+// "key:k1 value:v1; key:k2 value:v2"
+func ParseBoaTags(tag string) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(tag, ";") {
+		pair = strings.TrimSpace(pair)
+		if !strings.HasPrefix(pair, "key:") {
+			continue
+		}
+		key, value, _ := strings.Cut(strings.TrimPrefix(pair, "key:"), " value:")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels
+}
+
+// BoaIsK8s checks for the presence of the tags which ACK adds to the resources of a cluster
+func BoaIsK8s(labels map[string]string) bool {
+	for key := range labels {
+		if strings.HasPrefix(key, "ack.aliyun.com") {
+			return true
+		}
+		if strings.HasPrefix(key, "k8s.aliyun.com") {
+			return true
+		}
+		if strings.HasPrefix(key, "kubernetes.") {
+			return true
+		}
+	}
+	return false
+}
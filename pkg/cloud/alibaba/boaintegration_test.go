@@ -0,0 +1,191 @@
+package alibaba
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// newTestBoaServer serves the recorded instance bill of billingDate, page by page
+func newTestBoaServer(t *testing.T, billingDate string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("Action") != "QueryInstanceBill" {
+			http.Error(w, "unexpected action", http.StatusBadRequest)
+			return
+		}
+		if query.Get("BillingDate") != billingDate {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Code":"Success","Success":true,"Data":{"TotalCount":0,"PageNum":1,"PageSize":20,"Items":{"Item":[]}}}`))
+			return
+		}
+
+		data, err := os.ReadFile(fmt.Sprintf("test/boa_instance_bill_page_%s.json", query.Get("PageNum")))
+		if err != nil {
+			t.Errorf("unexpected page %s", query.Get("PageNum"))
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+}
+
+func TestBoaIntegration_GetCloudCost(t *testing.T) {
+	server := newTestBoaServer(t, "2024-05-01")
+	defer server.Close()
+
+	bi := &BoaIntegration{
+		BoaQuerier: BoaQuerier{
+			BOAConfiguration: BOAConfiguration{
+				Account: "1234567890123456",
+				Region:  "us-east-1",
+				Authorizer: &AccessKey{
+					AccessKeyID:     "accessKeyID",
+					AccessKeySecret: "accessKeySecret",
+				},
+				Endpoint: server.URL,
+			},
+		},
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)
+	ccsr, err := bi.GetCloudCost(start, end)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bi.GetStatus() != cloud.SuccessfulConnection {
+		t.Errorf("expected status %s, got %s", cloud.SuccessfulConnection, bi.GetStatus())
+	}
+	if len(ccsr.CloudCostSets) != 2 {
+		t.Fatalf("expected 2 cloud cost sets, got %d", len(ccsr.CloudCostSets))
+	}
+	if len(ccsr.CloudCostSets[1].CloudCosts) != 0 {
+		t.Errorf("expected no cloud costs on the second day, got %d", len(ccsr.CloudCostSets[1].CloudCosts))
+	}
+
+	ccs := ccsr.CloudCostSets[0]
+	if len(ccs.CloudCosts) != 3 {
+		t.Fatalf("expected 3 cloud costs, got %d", len(ccs.CloudCosts))
+	}
+
+	expected := map[string]struct {
+		category  string
+		listCost  float64
+		netCost   float64
+		k8sPct    float64
+		labelTeam string
+	}{
+		"i-bp1abcdefghijk0001":  {opencost.ComputeCategory, 12.5, 10.0, 1, "platform"},
+		"d-bp1abcdefghijk0002":  {opencost.StorageCategory, 1.2, 1.2, 0, ""},
+		"lb-0xiabcdefghijk0003": {opencost.NetworkCategory, 3.0, 2.7, 1, ""},
+	}
+	for _, cc := range ccs.CloudCosts {
+		exp, ok := expected[cc.Properties.ProviderID]
+		if !ok {
+			t.Errorf("unexpected provider ID %s", cc.Properties.ProviderID)
+			continue
+		}
+		if cc.Properties.Provider != opencost.AlibabaProvider {
+			t.Errorf("%s: expected provider %s, got %s", cc.Properties.ProviderID, opencost.AlibabaProvider, cc.Properties.Provider)
+		}
+		if cc.Properties.AccountID != "1234567890123456" || cc.Properties.InvoiceEntityID != "9876543210987654" {
+			t.Errorf("%s: unexpected account %s and invoice entity %s", cc.Properties.ProviderID, cc.Properties.AccountID, cc.Properties.InvoiceEntityID)
+		}
+		if cc.Properties.Category != exp.category {
+			t.Errorf("%s: expected category %s, got %s", cc.Properties.ProviderID, exp.category, cc.Properties.Category)
+		}
+		if cc.Properties.Labels["team"] != exp.labelTeam {
+			t.Errorf("%s: expected team label %s, got %s", cc.Properties.ProviderID, exp.labelTeam, cc.Properties.Labels["team"])
+		}
+		if math.Abs(cc.ListCost.Cost-exp.listCost) > 1e-9 {
+			t.Errorf("%s: expected list cost %f, got %f", cc.Properties.ProviderID, exp.listCost, cc.ListCost.Cost)
+		}
+		for name, cm := range map[string]opencost.CostMetric{
+			"net":           cc.NetCost,
+			"amortized net": cc.AmortizedNetCost,
+			"invoiced":      cc.InvoicedCost,
+			"amortized":     cc.AmortizedCost,
+		} {
+			if math.Abs(cm.Cost-exp.netCost) > 1e-9 {
+				t.Errorf("%s: expected %s cost %f, got %f", cc.Properties.ProviderID, name, exp.netCost, cm.Cost)
+			}
+			if cm.KubernetesPercent != exp.k8sPct {
+				t.Errorf("%s: expected %s kubernetes percent %f, got %f", cc.Properties.ProviderID, name, exp.k8sPct, cm.KubernetesPercent)
+			}
+		}
+	}
+}
+
+func TestBoaIntegration_GetCloudCost_MissingData(t *testing.T) {
+	server := newTestBoaServer(t, "2024-05-01")
+	defer server.Close()
+
+	bi := &BoaIntegration{
+		BoaQuerier: BoaQuerier{
+			BOAConfiguration: BOAConfiguration{
+				Account: "1234567890123456",
+				Region:  "us-east-1",
+				Authorizer: &AccessKey{
+					AccessKeyID:     "accessKeyID",
+					AccessKeySecret: "accessKeySecret",
+				},
+				Endpoint: server.URL,
+			},
+		},
+	}
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err := bi.GetCloudCost(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bi.GetStatus() != cloud.MissingData {
+		t.Errorf("expected status %s, got %s", cloud.MissingData, bi.GetStatus())
+	}
+}
+
+func TestParseBoaTags(t *testing.T) {
+	testCases := map[string]struct {
+		tag      string
+		expected map[string]string
+	}{
+		"empty": {
+			tag:      "",
+			expected: map[string]string{},
+		},
+		"single": {
+			tag:      "key:team value:platform",
+			expected: map[string]string{"team": "platform"},
+		},
+		"multiple": {
+			tag:      "key:ack.aliyun.com value:c1a2b3; key:team value:platform",
+			expected: map[string]string{"ack.aliyun.com": "c1a2b3", "team": "platform"},
+		},
+		"empty value": {
+			tag:      "key:env value:",
+			expected: map[string]string{"env": ""},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			labels := ParseBoaTags(tc.tag)
+			if len(labels) != len(tc.expected) {
+				t.Fatalf("expected %d labels, got %d: %v", len(tc.expected), len(labels), labels)
+			}
+			for k, v := range tc.expected {
+				if labels[k] != v {
+					t.Errorf("expected label %s=%s, got %s", k, v, labels[k])
+				}
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package alibaba
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
 	"github.com/opencost/opencost/core/pkg/log"
@@ -17,6 +19,13 @@ const (
 	boaIsNetwork = "piece" //usage unit of network resource in Alibaba is Piece
 )
 
+const (
+	boaInvocationScheme   = "HTTPS"
+	boaDailyGranularity   = "DAILY"
+	boaBillingCycleLayout = "2006-01"
+	boaBillingDateLayout  = "2006-01-02"
+)
+
 type BoaQuerier struct {
 	BOAConfiguration
 	ConnectionStatus cloud.ConnectionStatus
@@ -39,6 +48,57 @@ func (bq *BoaQuerier) Equals(config cloud.Config) bool {
 	return bq.BOAConfiguration.Equals(&thatConfig.BOAConfiguration)
 }
 
+// GetBoaClient creates a BSS Open API client for the region of the configuration
+func (bq *BoaQuerier) GetBoaClient() (*bssopenapi.Client, error) {
+	credential, err := bq.Authorizer.GetCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := bssopenapi.NewClientWithOptions(bq.Region, sdk.NewConfig(), credential)
+	if err != nil {
+		return nil, fmt.Errorf("GetBoaClient: failed to create BSS Open API client: %w", err)
+	}
+	endpoint, err := cloud.ParseEndpoint(bq.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("GetBoaClient: %w", err)
+	}
+	if endpoint != nil {
+		client.Domain = endpoint.Host
+	}
+	return client, nil
+}
+
+// QueryBillingDate calls fn with each page of the daily instance bill of the
+// billing date, setting the ConnectionStatus of the querier if the query fails
+func (bq *BoaQuerier) QueryBillingDate(date time.Time, fn func(*bssopenapi.QueryInstanceBillResponse) bool) error {
+	err := bq.Validate()
+	if err != nil {
+		bq.ConnectionStatus = cloud.InvalidConfiguration
+		return err
+	}
+
+	client, err := bq.GetBoaClient()
+	if err != nil {
+		bq.ConnectionStatus = cloud.FailedConnection
+		return err
+	}
+
+	scheme := boaInvocationScheme
+	if bq.Endpoint != "" {
+		// the endpoint has been validated above
+		endpoint, _ := cloud.ParseEndpoint(bq.Endpoint)
+		scheme = strings.ToUpper(endpoint.Scheme)
+	}
+
+	err = bq.QueryBoaPaginated(client, true, scheme, boaDailyGranularity, date.Format(boaBillingCycleLayout), date.Format(boaBillingDateLayout), fn)
+	if err != nil {
+		bq.ConnectionStatus = cloud.FailedConnection
+		return err
+	}
+	return nil
+}
+
 // QueryInstanceBill performs the request to the BSS client and get the response for the current page number
 func (bq *BoaQuerier) QueryInstanceBill(client *bssopenapi.Client, isBillingItem bool, invocationScheme, granularity, billingCycle, billingDate string, pageNum int) (*bssopenapi.QueryInstanceBillResponse, error) {
 	log.Debugf("QueryInstanceBill: query for BSS Open API for billing date: %s with pageNum: %d ", billingDate, pageNum)
@@ -69,7 +129,14 @@ func (bq *BoaQuerier) QueryBoaPaginated(client *bssopenapi.Client, isBillingItem
 		if err != nil {
 			return fmt.Errorf("QueryBoaPaginated for billing cycle : %s, billing date: %s, page num %d: %v", billingCycle, billingDate, pageNum, err)
 		}
+		if !response.Success {
+			return fmt.Errorf("QueryBoaPaginated for billing cycle : %s, billing date: %s, page num %d: %s: %s", billingCycle, billingDate, pageNum, response.Code, response.Message)
+		}
 		fn(response)
+		// guard against looping forever on an empty page
+		if response.Data.PageSize <= 0 {
+			break
+		}
 		totalItem = response.Data.TotalCount
 		processedItem += response.Data.PageSize
 		pageNum += 1
@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// DataExportConfiguration is the location of the Parquet files of a CUR 2.0 Data Export, or a legacy CUR with Parquet
+// output, in an S3 bucket. Prefix is the S3 path prefix of the export, under which the files of each billing period
+// are written.
+type DataExportConfiguration struct {
+	S3Configuration
+	Prefix string `json:"prefix"`
+}
+
+func (dec *DataExportConfiguration) Validate() error {
+	err := dec.S3Configuration.Validate()
+	if err != nil {
+		return fmt.Errorf("DataExportConfiguration: %s", strings.TrimPrefix(err.Error(), "S3Configuration: "))
+	}
+
+	return nil
+}
+
+func (dec *DataExportConfiguration) Equals(config cloud.Config) bool {
+	if config == nil {
+		return false
+	}
+	thatConfig, ok := config.(*DataExportConfiguration)
+	if !ok {
+		return false
+	}
+
+	if !dec.S3Configuration.Equals(&thatConfig.S3Configuration) {
+		return false
+	}
+
+	if dec.Prefix != thatConfig.Prefix {
+		return false
+	}
+
+	return true
+}
+
+func (dec *DataExportConfiguration) Sanitize() cloud.Config {
+	return &DataExportConfiguration{
+		S3Configuration: *dec.S3Configuration.Sanitize().(*S3Configuration),
+		Prefix:          dec.Prefix,
+	}
+}
+
+// Key includes the prefix, as a bucket may hold several exports
+func (dec *DataExportConfiguration) Key() string {
+	return dataExportKey(&dec.S3Configuration, dec.Prefix)
+}
+
+func dataExportKey(s3c *S3Configuration, prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return s3c.Key()
+	}
+	return fmt.Sprintf("%s/%s", s3c.Key(), prefix)
+}
+
+func (dec *DataExportConfiguration) UnmarshalJSON(b []byte) error {
+	err := dec.S3Configuration.UnmarshalJSON(b)
+	if err != nil {
+		return fmt.Errorf("DataExportConfiguration: UnmarshalJSON: %s", strings.TrimPrefix(err.Error(), "S3Configuration: UnmarshalJSON: "))
+	}
+
+	var f interface{}
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return err
+	}
+
+	fmap, ok := f.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("DataExportConfiguration: UnmarshalJSON: could not cast interface as map")
+	}
+
+	if _, ok := fmap["prefix"]; ok {
+		prefix, err := cloud.GetInterfaceValue[string](fmap, "prefix")
+		if err != nil {
+			return fmt.Errorf("DataExportConfiguration: UnmarshalJSON: %s", err.Error())
+		}
+		dec.Prefix = prefix
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// Data Export columns, which share their names with the columns of the Athena integration
+const (
+	DataExportPayerAccountIDColumn = "bill_payer_account_id"
+	DataExportUsageAccountIDColumn = "line_item_usage_account_id"
+	DataExportLineItemTypeColumn   = "line_item_line_item_type"
+	DataExportProductCodeColumn    = "line_item_product_code"
+	DataExportResourceIDColumn     = "line_item_resource_id"
+	DataExportUsageTypeColumn      = "line_item_usage_type"
+)
+
+// DataExportTagsColumn is the map column of resource tags of CUR 2.0. Legacy CUR Parquet files instead have a column
+// per tag, named with the "resource_tags_" prefix.
+const DataExportTagsColumn = "resource_tags"
+
+const dataExportTagColumnPrefix = "resource_tags_"
+
+// dataExportUserTagPrefix is the prefix of the keys of user defined tags, which is removed from labels
+const dataExportUserTagPrefix = "user_"
+
+// dataExportK8sTags are the tags whose presence indicates that a resource is part of a kubernetes cluster
+var dataExportK8sTags = []string{
+	"aws_eks_cluster_name",
+	"user_eks_cluster_name",
+	"user_alpha_eksctl_io_cluster_name",
+	"user_kubernetes_io_service_name",
+	"user_kubernetes_io_created_for_pvc_name",
+	"user_kubernetes_io_created_for_pv_name",
+}
+
+// DataExportIntegration reads the cloud costs of the Parquet files of a CUR 2.0 Data Export, or a legacy CUR with
+// Parquet output, directly from S3
+type DataExportIntegration struct {
+	DataExportQuerier
+}
+
+// GetCloudCost reads the line items of the billing periods which overlap the window and aggregates those in the window
+// by day. Costs are mapped in the same way as the AthenaIntegration.
+func (dei *DataExportIntegration) GetCloudCost(start, end time.Time) (*opencost.CloudCostSetRange, error) {
+	log.Infof("DataExportIntegration[%s]: GetCloudCost: %s", dei.Key(), opencost.NewWindow(&start, &end).String())
+
+	ccsr, err := opencost.NewCloudCostSetRange(start, end, opencost.AccumulateOptionDay, dei.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	err = dei.Validate()
+	if err != nil {
+		dei.ConnectionStatus = cloud.InvalidConfiguration
+		return nil, err
+	}
+
+	client, err := dei.GetS3Client()
+	if err != nil {
+		dei.ConnectionStatus = cloud.FailedConnection
+		return nil, err
+	}
+
+	keys, err := dei.GetDataExportKeys(start, end, client)
+	if err != nil {
+		dei.ConnectionStatus = cloud.FailedConnection
+		return nil, err
+	}
+
+	hasResults := false
+	for _, key := range keys {
+		file, err := dei.GetDataExportFile(key, client)
+		if err != nil {
+			dei.ConnectionStatus = cloud.FailedConnection
+			return nil, err
+		}
+
+		err = parseDataExportFile(file, func(row tabular.Row) error {
+			cc, err := DataExportRowToCloudCost(row)
+			if err != nil {
+				return err
+			}
+			if cc == nil {
+				return nil
+			}
+			// skip line items outside the window
+			if cc.Window.Start().Before(start) || !cc.Window.Start().Before(end) {
+				return nil
+			}
+			hasResults = true
+			ccsr.LoadCloudCost(cc)
+			return nil
+		})
+		file.Close()
+		os.Remove(file.Name())
+		if err != nil {
+			dei.ConnectionStatus = cloud.ParseError
+			return nil, fmt.Errorf("DataExportIntegration: failed to parse %s: %w", key, err)
+		}
+	}
+
+	dei.ConnectionStatus = cloud.ResultStatus(hasResults, dei.ConnectionStatus)
+	return ccsr, nil
+}
+
+// parseDataExportFile calls fn with each row of the Parquet file
+func parseDataExportFile(r tabular.ReadSeekerAt, fn func(tabular.Row) error) error {
+	reader, err := tabular.NewParquetReader(r)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DataExportRowToCloudCost creates the CloudCost of a line item of a Data Export, or nil if the line item is not
+// usage or has no cost. The window of the CloudCost is the day of the line item.
+func DataExportRowToCloudCost(row tabular.Row) (*opencost.CloudCost, error) {
+	lineItemType := row.String(DataExportLineItemTypeColumn)
+	switch lineItemType {
+	case "Usage", "DiscountedUsage", "SavingsPlanCoveredUsage", "EdpDiscount", "PrivateRateDiscount":
+	default:
+		return nil, nil
+	}
+
+	unblendedCost, err := row.Float(AthenaPricingColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	listCost := unblendedCost
+	if lineItemType == "EdpDiscount" || lineItemType == "PrivateRateDiscount" {
+		listCost = 0
+	}
+
+	netCost, err := dataExportCoalesce(row, AthenaNetPricingColumn, AthenaPricingColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	amortizedCost := unblendedCost
+	amortizedNetCost := netCost
+	switch lineItemType {
+	case "DiscountedUsage":
+		if _, ok := row[AthenaRIPricingColumn]; ok {
+			amortizedCost, err = row.Float(AthenaRIPricingColumn)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := row[AthenaNetRIPricingColumn]; ok {
+			amortizedNetCost, err = dataExportCoalesce(row, AthenaNetRIPricingColumn, AthenaRIPricingColumn)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case "SavingsPlanCoveredUsage":
+		if _, ok := row[AthenaSPPricingColumn]; ok {
+			amortizedCost, err = row.Float(AthenaSPPricingColumn)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := row[AthenaNetSPPricingColumn]; ok {
+			amortizedNetCost, err = dataExportCoalesce(row, AthenaNetSPPricingColumn, AthenaSPPricingColumn)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Without net pricing, amortized net cost is amortized cost
+	if _, ok := row[AthenaNetPricingColumn]; !ok {
+		amortizedNetCost = amortizedCost
+	}
+
+	if listCost == 0 && netCost == 0 && amortizedCost == 0 && amortizedNetCost == 0 {
+		return nil, nil
+	}
+
+	usageStart, err := row.Time(AthenaDateColumn)
+	if err != nil {
+		return nil, err
+	}
+	start := usageStart.Truncate(24 * time.Hour)
+	end := start.AddDate(0, 0, 1)
+
+	tags, err := dataExportTags(row)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := opencost.CloudCostLabels{}
+	for tag, value := range tags {
+		if value != "" && strings.HasPrefix(tag, dataExportUserTagPrefix) {
+			labels[strings.TrimPrefix(tag, dataExportUserTagPrefix)] = value
+		}
+	}
+
+	providerID := row.String(DataExportResourceIDColumn)
+	productCode := row.String(DataExportProductCodeColumn)
+	usageType := row.String(DataExportUsageTypeColumn)
+
+	k8sPct := 0.0
+	if DataExportIsK8s(productCode, tags) {
+		k8sPct = 1.0
+	}
+
+	// Identify resource category in the CUR
+	category := SelectAWSCategory(providerID, usageType, productCode)
+
+	// Retrieve final stanza of product code for ProviderID
+	if productCode == "AWSELB" || productCode == "AmazonFSx" {
+		providerID = ParseARN(providerID)
+	}
+
+	if productCode == "AmazonEKS" && category == opencost.ComputeCategory {
+		if strings.Contains(usageType, "CPU") {
+			providerID = fmt.Sprintf("%s/CPU", providerID)
+		} else if strings.Contains(usageType, "GB") {
+			providerID = fmt.Sprintf("%s/RAM", providerID)
+		}
+	}
+
+	properties := opencost.CloudCostProperties{
+		ProviderID:      providerID,
+		Provider:        opencost.AWSProvider,
+		AccountID:       row.String(DataExportUsageAccountIDColumn),
+		InvoiceEntityID: row.String(DataExportPayerAccountIDColumn),
+		Service:         productCode,
+		Category:        category,
+		Labels:          labels,
+	}
+
+	return &opencost.CloudCost{
+		Properties: &properties,
+		Window:     opencost.NewWindow(&start, &end),
+		ListCost: opencost.CostMetric{
+			Cost:              listCost,
+			KubernetesPercent: k8sPct,
+		},
+		NetCost: opencost.CostMetric{
+			Cost:              netCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedNetCost: opencost.CostMetric{
+			Cost:              amortizedNetCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedCost: opencost.CostMetric{
+			Cost:              amortizedCost,
+			KubernetesPercent: k8sPct,
+		},
+		InvoicedCost: opencost.CostMetric{
+			Cost:              netCost, // We are using Net Cost for Invoiced Cost for now as it is the closest approximation
+			KubernetesPercent: k8sPct,
+		},
+	}, nil
+}
+
+// dataExportCoalesce returns the value of the first column with a value, or 0 if none have one
+func dataExportCoalesce(row tabular.Row, columns ...string) (float64, error) {
+	for _, column := range columns {
+		if row[column] != nil {
+			return row.Float(column)
+		}
+	}
+	return 0, nil
+}
+
+// dataExportTags returns the resource tags of a line item, keyed by the names of the tag columns of a legacy CUR
+// without the "resource_tags_" prefix, e.g. "user_team" or "aws_eks_cluster_name"
+func dataExportTags(row tabular.Row) (map[string]string, error) {
+	tags, err := row.Map(DataExportTagsColumn)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	for column := range row {
+		if strings.HasPrefix(column, dataExportTagColumnPrefix) {
+			tags[strings.TrimPrefix(column, dataExportTagColumnPrefix)] = row.String(column)
+		}
+	}
+	return tags, nil
+}
+
+// DataExportIsK8s returns true if the line item is of EKS or of a resource tagged as part of a kubernetes cluster
+func DataExportIsK8s(productCode string, tags map[string]string) bool {
+	// EKS is always kubernetes
+	if productCode == "AmazonEKS" {
+		return true
+	}
+	for _, tag := range dataExportK8sTags {
+		if tags[tag] != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,459 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// testLineItem is a line item of a CUR 2.0 Data Export. Nil costs are null.
+type testLineItem struct {
+	lineItemType     string
+	usageStart       time.Time
+	resourceID       string
+	productCode      string
+	usageType        string
+	unblendedCost    float64
+	netUnblendedCost *float64
+	riEffectiveCost  *float64
+	riNetEffective   *float64
+	spEffectiveCost  *float64
+	spNetEffective   *float64
+	resourceTags     map[string]string
+}
+
+func testCost(f float64) *float64 {
+	return &f
+}
+
+// testDataExportFile writes the line items as a Parquet file with the column types of a CUR 2.0 Data Export
+func testDataExportFile(t *testing.T, items []testLineItem) []byte {
+	t.Helper()
+
+	fields := []arrow.Field{
+		{Name: DataExportPayerAccountIDColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: DataExportUsageAccountIDColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: DataExportLineItemTypeColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: AthenaDateColumn, Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, Nullable: true},
+		{Name: DataExportResourceIDColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: DataExportProductCodeColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: DataExportUsageTypeColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: AthenaPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AthenaNetPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AthenaRIPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AthenaNetRIPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AthenaSPPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AthenaNetSPPricingColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: DataExportTagsColumn, Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String), Nullable: true},
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+
+	appendCost := func(b *array.Float64Builder, cost *float64) {
+		if cost == nil {
+			b.AppendNull()
+			return
+		}
+		b.Append(*cost)
+	}
+
+	for _, item := range items {
+		builder.Field(0).(*array.StringBuilder).Append("payer")
+		builder.Field(1).(*array.StringBuilder).Append("usage")
+		builder.Field(2).(*array.StringBuilder).Append(item.lineItemType)
+		ts, err := arrow.TimestampFromTime(item.usageStart, arrow.Millisecond)
+		if err != nil {
+			t.Fatalf("invalid usage start: %s", err)
+		}
+		builder.Field(3).(*array.TimestampBuilder).Append(ts)
+		builder.Field(4).(*array.StringBuilder).Append(item.resourceID)
+		builder.Field(5).(*array.StringBuilder).Append(item.productCode)
+		builder.Field(6).(*array.StringBuilder).Append(item.usageType)
+		builder.Field(7).(*array.Float64Builder).Append(item.unblendedCost)
+		appendCost(builder.Field(8).(*array.Float64Builder), item.netUnblendedCost)
+		appendCost(builder.Field(9).(*array.Float64Builder), item.riEffectiveCost)
+		appendCost(builder.Field(10).(*array.Float64Builder), item.riNetEffective)
+		appendCost(builder.Field(11).(*array.Float64Builder), item.spEffectiveCost)
+		appendCost(builder.Field(12).(*array.Float64Builder), item.spNetEffective)
+
+		mapBuilder := builder.Field(13).(*array.MapBuilder)
+		if item.resourceTags == nil {
+			mapBuilder.AppendNull()
+			continue
+		}
+		mapBuilder.Append(true)
+		keys := make([]string, 0, len(item.resourceTags))
+		for key := range item.resourceTags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			mapBuilder.KeyBuilder().(*array.StringBuilder).Append(key)
+			mapBuilder.ItemBuilder().(*array.StringBuilder).Append(item.resourceTags[key])
+		}
+	}
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	buf := &bytes.Buffer{}
+	writer, err := pqarrow.NewFileWriter(schema, buf, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	if err != nil {
+		t.Fatalf("failed to create parquet writer: %s", err)
+	}
+	err = writer.Write(record)
+	if err != nil {
+		t.Fatalf("failed to write parquet: %s", err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("failed to close parquet writer: %s", err)
+	}
+	return buf.Bytes()
+}
+
+type testS3Object struct {
+	data         []byte
+	lastModified time.Time
+}
+
+type testListBucketResult struct {
+	XMLName               xml.Name             `xml:"ListBucketResult"`
+	Name                  string               `xml:"Name"`
+	Prefix                string               `xml:"Prefix"`
+	KeyCount              int                  `xml:"KeyCount"`
+	MaxKeys               int                  `xml:"MaxKeys"`
+	IsTruncated           bool                 `xml:"IsTruncated"`
+	NextContinuationToken string               `xml:"NextContinuationToken,omitempty"`
+	Contents              []testListBucketItem `xml:"Contents"`
+}
+
+type testListBucketItem struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	Size         int    `xml:"Size"`
+}
+
+// newTestS3Server is a stand-in for S3 which serves the objects of a bucket with path style addressing. Listings are
+// paginated to two keys per page.
+func newTestS3Server(t *testing.T, bucket string, objects map[string]testS3Object) *httptest.Server {
+	t.Helper()
+
+	const maxKeys = 2
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if path == bucket {
+			query := r.URL.Query()
+			prefix := query.Get("prefix")
+			var keys []string
+			for key := range objects {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			offset := 0
+			if token := query.Get("continuation-token"); token != "" {
+				offset, _ = strconv.Atoi(token)
+			}
+			result := testListBucketResult{
+				Name:    bucket,
+				Prefix:  prefix,
+				MaxKeys: maxKeys,
+			}
+			for i := offset; i < len(keys) && i < offset+maxKeys; i++ {
+				result.Contents = append(result.Contents, testListBucketItem{
+					Key:          keys[i],
+					LastModified: objects[keys[i]].lastModified.Format("2006-01-02T15:04:05.000Z"),
+					Size:         len(objects[keys[i]].data),
+				})
+			}
+			result.KeyCount = len(result.Contents)
+			if offset+maxKeys < len(keys) {
+				result.IsTruncated = true
+				result.NextContinuationToken = strconv.Itoa(offset + maxKeys)
+			}
+
+			w.Header().Set("Content-Type", "application/xml")
+			err := xml.NewEncoder(w).Encode(result)
+			if err != nil {
+				t.Errorf("failed to encode listing: %s", err)
+			}
+			return
+		}
+
+		obj, ok := objects[strings.TrimPrefix(path, bucket+"/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Write(obj.data)
+	}))
+}
+
+func TestDataExportIntegration_GetCloudCost(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	k8sTags := map[string]string{
+		"user_team":            "platform",
+		"aws_eks_cluster_name": "cluster1",
+	}
+
+	items := []testLineItem{
+		// two hours of on demand usage are aggregated into one day
+		{lineItemType: "Usage", usageStart: day.Add(time.Hour), resourceID: "i-ondemand", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 10, netUnblendedCost: testCost(9), resourceTags: k8sTags},
+		{lineItemType: "Usage", usageStart: day.Add(2 * time.Hour), resourceID: "i-ondemand", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 10, netUnblendedCost: testCost(9), resourceTags: k8sTags},
+		// discounts reduce net costs, but not list cost
+		{lineItemType: "EdpDiscount", usageStart: day.Add(2 * time.Hour), resourceID: "i-ondemand", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: -1, netUnblendedCost: testCost(-1), resourceTags: k8sTags},
+		// reserved instance usage is amortized by its effective cost
+		{lineItemType: "DiscountedUsage", usageStart: day.Add(3 * time.Hour), resourceID: "i-reserved", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 0, netUnblendedCost: testCost(0), riEffectiveCost: testCost(4), riNetEffective: testCost(3.5)},
+		// savings plan usage is amortized by its effective cost, falling back to it for amortized net cost
+		{lineItemType: "SavingsPlanCoveredUsage", usageStart: day.Add(4 * time.Hour), resourceID: "i-savingsplan", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 8, netUnblendedCost: testCost(7.5), spEffectiveCost: testCost(5)},
+		// negations and fees are not usage
+		{lineItemType: "SavingsPlanNegation", usageStart: day.Add(4 * time.Hour), resourceID: "i-savingsplan", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: -8, netUnblendedCost: testCost(-8)},
+		// usage without net pricing uses unblended cost
+		{lineItemType: "Usage", usageStart: day.Add(5 * time.Hour), resourceID: "vol-1", productCode: "AmazonEC2", usageType: "EBS:VolumeUsage.gp3", unblendedCost: 2, resourceTags: map[string]string{"user_kubernetes_io_created_for_pvc_name": "data"}},
+		// usage after the window
+		{lineItemType: "Usage", usageStart: day.Add(timeutil.Day), resourceID: "i-ondemand", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 10, netUnblendedCost: testCost(9), resourceTags: k8sTags},
+	}
+
+	// the files of an older version of the billing period, which are superseded
+	staleItems := []testLineItem{
+		{lineItemType: "Usage", usageStart: day, resourceID: "i-stale", productCode: "AmazonEC2", usageType: "BoxUsage:m5.large", unblendedCost: 100},
+	}
+
+	bucket := "cur-bucket"
+	objects := map[string]testS3Object{
+		"exports/cur2/cur2/metadata/BILLING_PERIOD=2024-05/cur2-Manifest.json": {
+			data:         []byte("{}"),
+			lastModified: day.AddDate(0, 0, 3),
+		},
+		"exports/cur2/cur2/data/BILLING_PERIOD=2024-05/2024-05-03T01:00:00.000Z-abc/cur2-00001.snappy.parquet": {
+			data:         testDataExportFile(t, items[:4]),
+			lastModified: day.AddDate(0, 0, 3),
+		},
+		"exports/cur2/cur2/data/BILLING_PERIOD=2024-05/2024-05-03T01:00:00.000Z-abc/cur2-00002.snappy.parquet": {
+			data:         testDataExportFile(t, items[4:]),
+			lastModified: day.AddDate(0, 0, 3),
+		},
+		"exports/cur2/cur2/data/BILLING_PERIOD=2024-05/2024-05-02T01:00:00.000Z-def/cur2-00001.snappy.parquet": {
+			data:         testDataExportFile(t, staleItems),
+			lastModified: day.AddDate(0, 0, 2),
+		},
+		// billing periods outside the window are not read
+		"exports/cur2/cur2/data/BILLING_PERIOD=2024-04/2024-05-01T01:00:00.000Z-ghi/cur2-00001.snappy.parquet": {
+			data:         []byte("not parquet"),
+			lastModified: day,
+		},
+	}
+
+	server := newTestS3Server(t, bucket, objects)
+	defer server.Close()
+
+	dei := &DataExportIntegration{
+		DataExportQuerier: DataExportQuerier{
+			S3Connection: S3Connection{
+				S3Configuration: S3Configuration{
+					Bucket:  bucket,
+					Region:  "us-east-1",
+					Account: "123456789012",
+					Authorizer: &AccessKey{
+						ID:     "id",
+						Secret: "secret",
+					},
+					Endpoint: server.URL,
+				},
+			},
+			Prefix: "exports/cur2",
+		},
+	}
+
+	ccsr, err := dei.GetCloudCost(day, day.Add(timeutil.Day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dei.GetStatus() != cloud.SuccessfulConnection {
+		t.Errorf("expected status %s, got %s", cloud.SuccessfulConnection, dei.GetStatus())
+	}
+	if len(ccsr.CloudCostSets) != 1 {
+		t.Fatalf("expected 1 cloud cost set, got %d", len(ccsr.CloudCostSets))
+	}
+
+	byProviderID := map[string]*opencost.CloudCost{}
+	for _, cc := range ccsr.CloudCostSets[0].CloudCosts {
+		if _, ok := byProviderID[cc.Properties.ProviderID]; ok {
+			t.Errorf("unexpected duplicate cloud cost %s", cc.Properties.ProviderID)
+		}
+		byProviderID[cc.Properties.ProviderID] = cc
+	}
+
+	expected := map[string]struct {
+		list, net, amortized, amortizedNet, k8sPct float64
+	}{
+		"i-ondemand":    {list: 20, net: 17, amortized: 19, amortizedNet: 17, k8sPct: 1},
+		"i-reserved":    {list: 0, net: 0, amortized: 4, amortizedNet: 3.5, k8sPct: 0},
+		"i-savingsplan": {list: 8, net: 7.5, amortized: 5, amortizedNet: 5, k8sPct: 0},
+		"vol-1":         {list: 2, net: 2, amortized: 2, amortizedNet: 2, k8sPct: 1},
+	}
+	if len(byProviderID) != len(expected) {
+		t.Errorf("expected %d cloud costs, got %d", len(expected), len(byProviderID))
+	}
+
+	for providerID, exp := range expected {
+		cc, ok := byProviderID[providerID]
+		if !ok {
+			t.Errorf("missing cloud cost %s", providerID)
+			continue
+		}
+		for metric, values := range map[string][2]float64{
+			"list":          {exp.list, cc.ListCost.Cost},
+			"net":           {exp.net, cc.NetCost.Cost},
+			"amortized":     {exp.amortized, cc.AmortizedCost.Cost},
+			"amortized net": {exp.amortizedNet, cc.AmortizedNetCost.Cost},
+			"invoiced":      {exp.net, cc.InvoicedCost.Cost},
+		} {
+			if math.Abs(values[0]-values[1]) > 1e-9 {
+				t.Errorf("%s: expected %s cost %f, got %f", providerID, metric, values[0], values[1])
+			}
+		}
+		if cc.NetCost.KubernetesPercent != exp.k8sPct {
+			t.Errorf("%s: expected kubernetes percent %f, got %f", providerID, exp.k8sPct, cc.NetCost.KubernetesPercent)
+		}
+		if cc.Properties.AccountID != "usage" || cc.Properties.InvoiceEntityID != "payer" {
+			t.Errorf("%s: unexpected account %s and invoice entity %s", providerID, cc.Properties.AccountID, cc.Properties.InvoiceEntityID)
+		}
+	}
+
+	if labels := byProviderID["i-ondemand"].Properties.Labels; len(labels) != 1 || labels["team"] != "platform" {
+		t.Errorf("expected user tags as labels, got %v", labels)
+	}
+	if category := byProviderID["vol-1"].Properties.Category; category != opencost.StorageCategory {
+		t.Errorf("expected category %s, got %s", opencost.StorageCategory, category)
+	}
+
+	// A window without line items is missing data
+	dei.ConnectionStatus = cloud.InitialStatus
+	start := day.AddDate(0, 0, 10)
+	_, err = dei.GetCloudCost(start, start.Add(timeutil.Day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dei.GetStatus() != cloud.MissingData {
+		t.Errorf("expected status %s, got %s", cloud.MissingData, dei.GetStatus())
+	}
+}
+
+func TestDataExportConfiguration_Key(t *testing.T) {
+	testCases := map[string]struct {
+		prefix   string
+		expected string
+	}{
+		"no prefix": {
+			prefix:   "",
+			expected: "account/bucket",
+		},
+		"prefix": {
+			prefix:   "/exports/cur2/",
+			expected: "account/bucket/exports/cur2",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dec := &DataExportConfiguration{
+				S3Configuration: S3Configuration{
+					Bucket:  "bucket",
+					Region:  "region",
+					Account: "account",
+				},
+				Prefix: testCase.prefix,
+			}
+			if key := dec.Key(); key != testCase.expected {
+				t.Errorf("expected key %s, got %s", testCase.expected, key)
+			}
+		})
+	}
+}
+
+func TestDataExportConfiguration_Endpoint(t *testing.T) {
+	testCases := map[string]struct {
+		endpoint string
+		expected string
+	}{
+		"no endpoint": {
+			endpoint: "",
+			expected: "nil",
+		},
+		"endpoint": {
+			endpoint: "http://minio.local:9000",
+			expected: "nil",
+		},
+		"endpoint missing host": {
+			endpoint: "https://",
+			expected: "DataExportConfiguration: invalid endpoint 'https://': missing host",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dec := &DataExportConfiguration{
+				S3Configuration: S3Configuration{
+					Bucket:  "bucket",
+					Region:  "region",
+					Account: "account",
+					Authorizer: &AccessKey{
+						ID:     "id",
+						Secret: "secret",
+					},
+					Endpoint: testCase.endpoint,
+				},
+				Prefix: "exports/cur2",
+			}
+			actual := "nil"
+			if err := dec.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != testCase.expected {
+				t.Fatalf("expected error '%s', got '%s'", testCase.expected, actual)
+			}
+
+			data, err := json.Marshal(dec)
+			if err != nil {
+				t.Fatalf("failed to marshal config: %s", err)
+			}
+			unmarshalled := &DataExportConfiguration{}
+			err = json.Unmarshal(data, unmarshalled)
+			if err != nil {
+				t.Fatalf("failed to unmarshal config: %s", err)
+			}
+			if !dec.Equals(unmarshalled) {
+				t.Errorf("expected %+v, got %+v", dec, unmarshalled)
+			}
+		})
+	}
+}
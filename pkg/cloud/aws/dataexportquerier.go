@@ -0,0 +1,157 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// dataExportPeriodRegex matches the billing period partition of the keys of Data Export files, e.g. "BILLING_PERIOD=2024-05"
+var dataExportPeriodRegex = regexp.MustCompile(`BILLING_PERIOD=(\d{4})-(\d{2})`)
+
+// curParquetPeriodRegex matches the partitions of the keys of legacy CUR Parquet files, e.g. "year=2024/month=5"
+var curParquetPeriodRegex = regexp.MustCompile(`year=(\d{4})/month=(\d{1,2})`)
+
+// DataExportQuerier reads the Parquet files of a Data Export from S3 using an S3Connection
+type DataExportQuerier struct {
+	S3Connection
+	Prefix string
+}
+
+func (deq *DataExportQuerier) Validate() error {
+	config := deq.configuration()
+	return config.Validate()
+}
+
+func (deq *DataExportQuerier) Equals(config cloud.Config) bool {
+	thatConfig, ok := config.(*DataExportQuerier)
+	if !ok {
+		return false
+	}
+
+	thisConfig := deq.configuration()
+	return thisConfig.Equals(thatConfig.configuration())
+}
+
+func (deq *DataExportQuerier) Sanitize() cloud.Config {
+	config := deq.configuration()
+	return config.Sanitize()
+}
+
+func (deq *DataExportQuerier) Key() string {
+	return dataExportKey(&deq.S3Configuration, deq.Prefix)
+}
+
+func (deq *DataExportQuerier) configuration() *DataExportConfiguration {
+	return &DataExportConfiguration{
+		S3Configuration: deq.S3Configuration,
+		Prefix:          deq.Prefix,
+	}
+}
+
+// GetDataExportKeys returns the keys of the Parquet files of the billing periods which overlap the window. A billing
+// period may have several versions if the export does not overwrite its files, in which case only the files of the
+// most recently written version are returned. Files whose keys do not identify a billing period are always returned.
+func (deq *DataExportQuerier) GetDataExportKeys(start, end time.Time, cli *s3.Client) ([]string, error) {
+	objs, err := deq.ListObjectsWithPrefix(cli, deq.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	startMonth := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	// the files of each version of each billing period, by billing period and then directory
+	versions := map[string]map[string][]s3Types.Object{}
+	for _, obj := range objs {
+		key := aws.ToString(obj.Key)
+		if !strings.HasSuffix(strings.ToLower(key), ".parquet") {
+			continue
+		}
+
+		period := ""
+		if month, ok := dataExportBillingPeriod(key); ok {
+			if month.Before(startMonth) || !month.Before(end) {
+				continue
+			}
+			period = month.Format("2006-01")
+		}
+
+		if _, ok := versions[period]; !ok {
+			versions[period] = map[string][]s3Types.Object{}
+		}
+		dir := path.Dir(key)
+		versions[period][dir] = append(versions[period][dir], obj)
+	}
+
+	var keys []string
+	for period, dirs := range versions {
+		// files without a billing period cannot be versioned
+		if period == "" {
+			for _, dirObjs := range dirs {
+				for _, obj := range dirObjs {
+					keys = append(keys, aws.ToString(obj.Key))
+				}
+			}
+			continue
+		}
+
+		var latestDir string
+		var latest time.Time
+		for dir, dirObjs := range dirs {
+			for _, obj := range dirObjs {
+				modified := aws.ToTime(obj.LastModified)
+				if latestDir == "" || modified.After(latest) {
+					latestDir = dir
+					latest = modified
+				}
+			}
+		}
+		for _, obj := range dirs[latestDir] {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// dataExportBillingPeriod returns the first day of the billing period of a Data Export or legacy CUR file, from the
+// partitions of its key
+func dataExportBillingPeriod(key string) (time.Time, bool) {
+	match := dataExportPeriodRegex.FindStringSubmatch(key)
+	if match == nil {
+		match = curParquetPeriodRegex.FindStringSubmatch(key)
+	}
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	year, err := strconv.Atoi(match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	month, err := strconv.Atoi(match[2])
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// GetDataExportFile downloads the file with the given key to a temporary file, which the caller must close and remove
+func (deq *DataExportQuerier) GetDataExportFile(key string, cli *s3.Client) (*os.File, error) {
+	file, err := deq.DownloadObject(cli, key)
+	if err != nil {
+		return nil, fmt.Errorf("DataExportQuerier: %w", err)
+	}
+	return file, nil
+}
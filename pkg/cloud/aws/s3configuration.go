@@ -14,6 +14,8 @@ type S3Configuration struct {
 	Region     string     `json:"region"`
 	Account    string     `json:"account"`
 	Authorizer Authorizer `json:"authorizer"`
+	// Endpoint optionally overrides the S3 endpoint, with path style addressing, for S3 compatible stores
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
 func (s3c *S3Configuration) Validate() error {
@@ -40,6 +42,11 @@ func (s3c *S3Configuration) Validate() error {
 		return fmt.Errorf("S3Configuration: missing account")
 	}
 
+	_, err = cloud.ParseEndpoint(s3c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("S3Configuration: %w", err)
+	}
+
 	return nil
 }
 
@@ -74,6 +81,10 @@ func (s3c *S3Configuration) Equals(config cloud.Config) bool {
 		return false
 	}
 
+	if s3c.Endpoint != thatConfig.Endpoint {
+		return false
+	}
+
 	return true
 }
 
@@ -83,6 +94,7 @@ func (s3c *S3Configuration) Sanitize() cloud.Config {
 		Region:     s3c.Region,
 		Account:    s3c.Account,
 		Authorizer: s3c.Authorizer.Sanitize().(Authorizer),
+		Endpoint:   s3c.Endpoint,
 	}
 }
 
@@ -131,6 +143,14 @@ func (s3c *S3Configuration) UnmarshalJSON(b []byte) error {
 	}
 	s3c.Authorizer = authorizer
 
+	if _, ok := fmap["endpoint"]; ok {
+		endpoint, err := cloud.GetInterfaceValue[string](fmap, "endpoint")
+		if err != nil {
+			return fmt.Errorf("S3Configuration: UnmarshalJSON: %s", err.Error())
+		}
+		s3c.Endpoint = endpoint
+	}
+
 	return nil
 }
 
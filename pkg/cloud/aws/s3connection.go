@@ -2,9 +2,13 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/opencost/opencost/pkg/cloud"
 )
 
@@ -35,7 +39,17 @@ func (s3c *S3Connection) GetS3Client() (*s3.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return s3.NewFromConfig(cfg), nil
+	endpoint, err := cloud.ParseEndpoint(s3c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil {
+		return s3.NewFromConfig(cfg), nil
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(endpoint.String())
+		o.UsePathStyle = true
+	}), nil
 }
 
 func (s3c *S3Connection) ListObjects(cli *s3.Client) (*s3.ListObjectsOutput, error) {
@@ -47,3 +61,46 @@ func (s3c *S3Connection) ListObjects(cli *s3.Client) (*s3.ListObjectsOutput, err
 	}
 	return objs, err
 }
+
+// ListObjectsWithPrefix returns all objects in the bucket whose keys begin with the prefix, following pagination
+func (s3c *S3Connection) ListObjectsWithPrefix(cli *s3.Client, prefix string) ([]s3Types.Object, error) {
+	var objs []s3Types.Object
+	paginator := s3.NewListObjectsV2Paginator(cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s3c.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in %s/%s: %w", s3c.Bucket, prefix, err)
+		}
+		objs = append(objs, page.Contents...)
+	}
+	return objs, nil
+}
+
+// DownloadObject copies the object with the given key to a temporary file, so that it can be read without holding the
+// whole object in memory. The caller must close and remove the file.
+func (s3c *S3Connection) DownloadObject(cli *s3.Client, key string) (*os.File, error) {
+	out, err := cli.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s3c.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s/%s: %w", s3c.Bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	file, err := os.CreateTemp("", "s3-object-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file for object %s/%s: %w", s3c.Bucket, key, err)
+	}
+
+	_, err = io.Copy(file, out.Body)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to read object %s/%s: %w", s3c.Bucket, key, err)
+	}
+	return file, nil
+}
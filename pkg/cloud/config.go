@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"fmt"
+	"net/url"
 )
 
 const Redacted = "REDACTED"
@@ -36,3 +37,25 @@ func GetInterfaceValue[T any](fmap map[string]interface{}, key string) (T, error
 	}
 	return typedValue, nil
 }
+
+// ParseEndpoint parses the optional endpoint of a configuration, which overrides the default endpoint of the
+// provider API, for example to use an API compatible service or a private endpoint. An empty endpoint returns nil.
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s': %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint '%s': scheme must be http or https", endpoint)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint '%s': missing host", endpoint)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s': must only have a scheme, host and port", endpoint)
+	}
+	return u, nil
+}
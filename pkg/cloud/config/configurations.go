@@ -9,7 +9,9 @@ import (
 	"github.com/opencost/opencost/pkg/cloud/alibaba"
 	"github.com/opencost/opencost/pkg/cloud/aws"
 	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/focus"
 	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
 )
 
 // MultiCloudConfig struct is used to unmarshal cloud configs for each provider out of cloud-integration file
@@ -66,22 +68,49 @@ type Configurations struct {
 	GCP     *GCPConfigs     `json:"gcp,omitempty"`
 	Azure   *AzureConfigs   `json:"azure,omitempty"`
 	Alibaba *AlibabaConfigs `json:"alibaba,omitempty"`
+	OCI     *OCIConfigs     `json:"oci,omitempty"`
+	FOCUS   *FOCUSConfigs   `json:"focus,omitempty"`
+}
+
+// multiCloudConfigKeys are the top level properties of a MultiCloudConfig
+var multiCloudConfigKeys = map[string]bool{
+	"azure":   true,
+	"gcp":     true,
+	"aws":     true,
+	"alibaba": true,
+}
+
+// isMultiCloudConfig returns true if every top level property of the object is a property of the legacy
+// MultiCloudConfig format, so that configurations of newer providers are not silently dropped by it
+func isMultiCloudConfig(bytes []byte) bool {
+	var properties map[string]json.RawMessage
+	err := json.Unmarshal(bytes, &properties)
+	if err != nil {
+		return false
+	}
+	for key := range properties {
+		if !multiCloudConfigKeys[key] {
+			return false
+		}
+	}
+	return true
 }
 
 // UnmarshalJSON custom json unmarshalling to maintain support for MultiCloudConfig format
 func (c *Configurations) UnmarshalJSON(bytes []byte) error {
 	// Attempt to unmarshal into old config object
-	multiConfig := &MultiCloudConfig{}
-	err := json.Unmarshal(bytes, multiConfig)
-	// If unmarshal is successful, move values into config and return
-	if err == nil {
-		multiConfig.loadConfigurations(c)
-		return nil
-	}
-	// Create inline type to gain access to default Unmarshalling
-	type ConfUnmarshaller *Configurations
-	var conf ConfUnmarshaller = c
-	return json.Unmarshal(bytes, conf)
+	if isMultiCloudConfig(bytes) {
+		multiConfig := &MultiCloudConfig{}
+		err := json.Unmarshal(bytes, multiConfig)
+		// If unmarshal is successful, move values into config and return
+		if err == nil {
+			multiConfig.loadConfigurations(c)
+			return nil
+		}
+	}
+	// Create inline type without methods to gain access to default Unmarshalling
+	type ConfUnmarshaller Configurations
+	return json.Unmarshal(bytes, (*ConfUnmarshaller)(c))
 }
 
 func (c *Configurations) Equals(that *Configurations) bool {
@@ -108,6 +137,14 @@ func (c *Configurations) Equals(that *Configurations) bool {
 		return false
 	}
 
+	if !c.OCI.Equals(that.OCI) {
+		return false
+	}
+
+	if !c.FOCUS.Equals(that.FOCUS) {
+		return false
+	}
+
 	return true
 }
 
@@ -123,6 +160,11 @@ func (c *Configurations) Insert(keyedConfig cloud.Config) error {
 			c.AWS = &AWSConfigs{}
 		}
 		c.AWS.S3 = append(c.AWS.S3, keyedConfig.(*aws.S3Configuration))
+	case *aws.DataExportConfiguration:
+		if c.AWS == nil {
+			c.AWS = &AWSConfigs{}
+		}
+		c.AWS.DataExport = append(c.AWS.DataExport, keyedConfig.(*aws.DataExportConfiguration))
 	case *gcp.BigQueryConfiguration:
 		if c.GCP == nil {
 			c.GCP = &GCPConfigs{}
@@ -138,6 +180,16 @@ func (c *Configurations) Insert(keyedConfig cloud.Config) error {
 			c.Alibaba = &AlibabaConfigs{}
 		}
 		c.Alibaba.BOA = append(c.Alibaba.BOA, keyedConfig.(*alibaba.BOAConfiguration))
+	case *oracle.CostReportConfiguration:
+		if c.OCI == nil {
+			c.OCI = &OCIConfigs{}
+		}
+		c.OCI.CostReport = append(c.OCI.CostReport, keyedConfig.(*oracle.CostReportConfiguration))
+	case *focus.StorageConfiguration:
+		if c.FOCUS == nil {
+			c.FOCUS = &FOCUSConfigs{}
+		}
+		c.FOCUS.Storage = append(c.FOCUS.Storage, keyedConfig.(*focus.StorageConfiguration))
 	default:
 		return fmt.Errorf("Configurations: Insert: failed to insert config of type: %T", keyedConfig)
 	}
@@ -154,6 +206,10 @@ func (c *Configurations) ToSlice() []cloud.KeyedConfig {
 		for _, s3Config := range c.AWS.S3 {
 			keyedConfigs = append(keyedConfigs, s3Config)
 		}
+
+		for _, dataExportConfig := range c.AWS.DataExport {
+			keyedConfigs = append(keyedConfigs, dataExportConfig)
+		}
 	}
 
 	if c.GCP != nil {
@@ -174,13 +230,26 @@ func (c *Configurations) ToSlice() []cloud.KeyedConfig {
 		}
 	}
 
+	if c.OCI != nil {
+		for _, costReportConfig := range c.OCI.CostReport {
+			keyedConfigs = append(keyedConfigs, costReportConfig)
+		}
+	}
+
+	if c.FOCUS != nil {
+		for _, focusStorageConfig := range c.FOCUS.Storage {
+			keyedConfigs = append(keyedConfigs, focusStorageConfig)
+		}
+	}
+
 	return keyedConfigs
 
 }
 
 type AWSConfigs struct {
-	Athena []*aws.AthenaConfiguration `json:"athena,omitempty"`
-	S3     []*aws.S3Configuration     `json:"s3,omitempty"`
+	Athena     []*aws.AthenaConfiguration     `json:"athena,omitempty"`
+	S3         []*aws.S3Configuration         `json:"s3,omitempty"`
+	DataExport []*aws.DataExportConfiguration `json:"dataExport,omitempty"`
 }
 
 func (ac *AWSConfigs) Equals(that *AWSConfigs) bool {
@@ -212,6 +281,17 @@ func (ac *AWSConfigs) Equals(that *AWSConfigs) bool {
 		}
 	}
 
+	// Check Data Export
+	if len(ac.DataExport) != len(that.DataExport) {
+		return false
+	}
+	for i, thisDataExport := range ac.DataExport {
+		thatDataExport := that.DataExport[i]
+		if !thisDataExport.Equals(thatDataExport) {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -289,3 +369,53 @@ func (ac *AlibabaConfigs) Equals(that *AlibabaConfigs) bool {
 
 	return true
 }
+
+type OCIConfigs struct {
+	CostReport []*oracle.CostReportConfiguration `json:"costReport,omitempty"`
+}
+
+func (oc *OCIConfigs) Equals(that *OCIConfigs) bool {
+	if oc == nil && that == nil {
+		return true
+	}
+	if oc == nil || that == nil {
+		return false
+	}
+	// Check Cost Report
+	if len(oc.CostReport) != len(that.CostReport) {
+		return false
+	}
+	for i, thisCostReport := range oc.CostReport {
+		thatCostReport := that.CostReport[i]
+		if !thisCostReport.Equals(thatCostReport) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type FOCUSConfigs struct {
+	Storage []*focus.StorageConfiguration `json:"storage,omitempty"`
+}
+
+func (fc *FOCUSConfigs) Equals(that *FOCUSConfigs) bool {
+	if fc == nil && that == nil {
+		return true
+	}
+	if fc == nil || that == nil {
+		return false
+	}
+	// Check Storage
+	if len(fc.Storage) != len(that.Storage) {
+		return false
+	}
+	for i, thisStorage := range fc.Storage {
+		thatStorage := that.Storage[i]
+		if !thisStorage.Equals(thatStorage) {
+			return false
+		}
+	}
+
+	return true
+}
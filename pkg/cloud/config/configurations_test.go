@@ -6,7 +6,9 @@ import (
 
 	"github.com/opencost/opencost/pkg/cloud/aws"
 	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/focus"
 	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
 )
 
 var (
@@ -209,6 +211,102 @@ var (
 	}
 )
 
+func TestConfigurations_UnmarshalJSON_OCI(t *testing.T) {
+	// A file with only newer providers must not be taken for a MultiCloudConfig
+	input := `{
+		"oci": {
+			"costReport": [
+				{
+					"tenancyID": "ocid1.tenancy.oc1..aaaa",
+					"region": "us-ashburn-1",
+					"authorizer": {
+						"authorizerType": "OCIAPIKey",
+						"tenancyID": "ocid1.tenancy.oc1..aaaa",
+						"userID": "ocid1.user.oc1..aaaa",
+						"fingerprint": "20:3b:97:13:55:1c:5b:0d:d3:37:d8:50:4e:c5:3a:34",
+						"privateKey": "privateKey"
+					}
+				}
+			]
+		}
+	}`
+	expected := &Configurations{
+		OCI: &OCIConfigs{
+			CostReport: []*oracle.CostReportConfiguration{
+				{
+					TenancyID: "ocid1.tenancy.oc1..aaaa",
+					Region:    "us-ashburn-1",
+					Authorizer: &oracle.APIKey{
+						TenancyID:   "ocid1.tenancy.oc1..aaaa",
+						UserID:      "ocid1.user.oc1..aaaa",
+						Fingerprint: "20:3b:97:13:55:1c:5b:0d:d3:37:d8:50:4e:c5:3a:34",
+						PrivateKey:  "privateKey",
+					},
+				},
+			},
+		},
+	}
+
+	actual := &Configurations{}
+	err := json.Unmarshal([]byte(input), actual)
+	if err != nil {
+		t.Fatalf("Unmarshal failed with error %s", err.Error())
+	}
+	if !expected.Equals(actual) {
+		t.Fatalf("actual Configuration did not match expected: %+v", actual)
+	}
+
+	// and must round trip
+	b, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("failed to marshal input")
+	}
+	actual = &Configurations{}
+	err = json.Unmarshal(b, actual)
+	if err != nil {
+		t.Fatalf("Unmarshal failed with error %s", err.Error())
+	}
+	if !expected.Equals(actual) {
+		t.Fatalf("actual Configuration did not match expected")
+	}
+}
+
+func TestConfigurations_UnmarshalJSON_FOCUS(t *testing.T) {
+	expected := &Configurations{
+		FOCUS: &FOCUSConfigs{
+			Storage: []*focus.StorageConfiguration{
+				{
+					Name: "local",
+					Path: "/var/focus",
+				},
+				{
+					Name: "bucket",
+					Path: "exports",
+					BucketConfig: map[string]any{
+						"type": "S3",
+						"config": map[string]any{
+							"bucket": "focus-exports",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("failed to marshal input")
+	}
+	actual := &Configurations{}
+	err = json.Unmarshal(b, actual)
+	if err != nil {
+		t.Fatalf("Unmarshal failed with error %s", err.Error())
+	}
+	if !expected.Equals(actual) {
+		t.Fatalf("actual Configuration did not match expected: %s", string(b))
+	}
+}
+
 func TestConfigurations_UnmarshalJSON(t *testing.T) {
 	tests := map[string]struct {
 		input    any
@@ -13,7 +13,9 @@ import (
 	"github.com/opencost/opencost/pkg/cloud"
 	"github.com/opencost/opencost/pkg/cloud/aws"
 	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/focus"
 	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
 )
 
 var protocol = proto.HTTP()
@@ -104,6 +106,13 @@ func parseConfig(configType string, body io.Reader) (cloud.KeyedConfig, error) {
 			return nil, fmt.Errorf("error unmarshalling Athena Configuration: %w", err)
 		}
 		return config, nil
+	case DataExportConfigType:
+		config := &aws.DataExportConfiguration{}
+		err = json.Unmarshal(bytes, config)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling Data Export Configuration: %w", err)
+		}
+		return config, nil
 	case BigQueryConfigType:
 		config := &gcp.BigQueryConfiguration{}
 		err = json.Unmarshal(bytes, config)
@@ -118,6 +127,20 @@ func parseConfig(configType string, body io.Reader) (cloud.KeyedConfig, error) {
 			return nil, fmt.Errorf("error unmarshalling Azure Storage Configuration: %w", err)
 		}
 		return config, nil
+	case OCICostReportConfigType:
+		config := &oracle.CostReportConfiguration{}
+		err = json.Unmarshal(bytes, config)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling OCI Cost Report Configuration: %w", err)
+		}
+		return config, nil
+	case FOCUSStorageConfigType:
+		config := &focus.StorageConfiguration{}
+		err = json.Unmarshal(bytes, config)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling FOCUS Storage Configuration: %w", err)
+		}
+		return config, nil
 
 	}
 	return nil, fmt.Errorf("provided config type was not recognised %s", configType)
@@ -8,14 +8,19 @@ import (
 	"github.com/opencost/opencost/pkg/cloud"
 	"github.com/opencost/opencost/pkg/cloud/aws"
 	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/focus"
 	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
 )
 
 const (
-	S3ConfigType           = "s3"
-	AthenaConfigType       = "athena"
-	BigQueryConfigType     = "bigquery"
-	AzureStorageConfigType = "azurestorage"
+	S3ConfigType            = "s3"
+	AthenaConfigType        = "athena"
+	BigQueryConfigType      = "bigquery"
+	AzureStorageConfigType  = "azurestorage"
+	OCICostReportConfigType = "ocicostreport"
+	FOCUSStorageConfigType  = "focusstorage"
+	DataExportConfigType    = "dataexport"
 )
 
 func ConfigTypeFromConfig(config cloud.KeyedConfig) (string, error) {
@@ -24,10 +29,16 @@ func ConfigTypeFromConfig(config cloud.KeyedConfig) (string, error) {
 		return S3ConfigType, nil
 	case *aws.AthenaConfiguration:
 		return AthenaConfigType, nil
+	case *aws.DataExportConfiguration:
+		return DataExportConfigType, nil
 	case *gcp.BigQueryConfiguration:
 		return BigQueryConfigType, nil
 	case *azure.StorageConfiguration:
 		return AzureStorageConfigType, nil
+	case *oracle.CostReportConfiguration:
+		return OCICostReportConfigType, nil
+	case *focus.StorageConfiguration:
+		return FOCUSStorageConfigType, nil
 	}
 	return "", fmt.Errorf("failed to config type for config with key: %s, type %T", config.Key(), config)
 }
@@ -110,10 +121,16 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 		config = &aws.S3Configuration{}
 	case AthenaConfigType:
 		config = &aws.AthenaConfiguration{}
+	case DataExportConfigType:
+		config = &aws.DataExportConfiguration{}
 	case BigQueryConfigType:
 		config = &gcp.BigQueryConfiguration{}
 	case AzureStorageConfigType:
 		config = &azure.StorageConfiguration{}
+	case OCICostReportConfigType:
+		config = &oracle.CostReportConfiguration{}
+	case FOCUSStorageConfigType:
+		config = &focus.StorageConfiguration{}
 	default:
 		return fmt.Errorf("Status: UnmarshalJSON: config type '%s' is not recognized", configType)
 	}
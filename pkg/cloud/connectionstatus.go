@@ -45,3 +45,13 @@ func (cs ConnectionStatus) String() string {
 type EmptyChecker interface {
 	IsEmpty() bool
 }
+
+// ResultStatus returns the status of a connection after a successful query, given whether it returned any results. An
+// empty result sets the MissingData status, unless the connection already has the SuccessfulConnection status, in which
+// case only the specific query was empty.
+func ResultStatus(hasResults bool, current ConnectionStatus) ConnectionStatus {
+	if !hasResults && current != SuccessfulConnection {
+		return MissingData
+	}
+	return SuccessfulConnection
+}
@@ -0,0 +1,327 @@
+package focus
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// FOCUS columns which are only written by exports. Columns prefixed with "x_" are extensions to the specification.
+const (
+	ContractedCostColumnName     = "ContractedCost"
+	BillingAccountNameColumnName = "BillingAccountName"
+	BillingCurrencyColumnName    = "BillingCurrency"
+	BillingPeriodStartColumnName = "BillingPeriodStart"
+	BillingPeriodEndColumnName   = "BillingPeriodEnd"
+	ChargeFrequencyColumnName    = "ChargeFrequency"
+	InvoiceIssuerNameColumnName  = "InvoiceIssuerName"
+	PublisherNameColumnName      = "PublisherName"
+	ResourceNameColumnName       = "ResourceName"
+	ResourceTypeColumnName       = "ResourceType"
+	SubAccountNameColumnName     = "SubAccountName"
+
+	KubernetesPercentColumnName = "x_KubernetesPercent"
+	ClusterColumnName           = "x_Cluster"
+	NodeColumnName              = "x_Node"
+	NamespaceColumnName         = "x_Namespace"
+	ControllerKindColumnName    = "x_ControllerKind"
+	ControllerColumnName        = "x_Controller"
+	PodColumnName               = "x_Pod"
+	ContainerColumnName         = "x_Container"
+	CPUCostColumnName           = "x_CPUCost"
+	GPUCostColumnName           = "x_GPUCost"
+	RAMCostColumnName           = "x_RAMCost"
+	PVCostColumnName            = "x_PVCost"
+	NetworkCostColumnName       = "x_NetworkCost"
+	LoadBalancerCostColumnName  = "x_LoadBalancerCost"
+	SharedCostColumnName        = "x_SharedCost"
+)
+
+// Values of the FOCUS columns of exported charges
+const (
+	UsageChargeCategory       = "Usage"
+	UsageBasedChargeFrequency = "Usage-Based"
+
+	// KubernetesProviderName is the provider, publisher and service of the allocations of Kubernetes resources
+	KubernetesProviderName = "Kubernetes"
+	// OpenCostInvoiceIssuerName is the invoice issuer of the allocations of Kubernetes resources, which are not
+	// invoiced by a provider
+	OpenCostInvoiceIssuerName = "OpenCost"
+)
+
+// Charge is a row of a FOCUS export. Costs are in the BillingCurrency. The Kubernetes fields are the extension
+// columns of the allocations of Kubernetes resources, and are empty for cloud costs.
+type Charge struct {
+	BilledCost         float64
+	EffectiveCost      float64
+	ListCost           float64
+	ContractedCost     float64
+	BillingAccountID   string
+	BillingAccountName string
+	BillingCurrency    string
+	BillingPeriodStart time.Time
+	BillingPeriodEnd   time.Time
+	ChargeCategory     string
+	ChargeFrequency    string
+	ChargePeriodStart  time.Time
+	ChargePeriodEnd    time.Time
+	InvoiceIssuerName  string
+	ProviderName       string
+	PublisherName      string
+	ResourceID         string
+	ResourceName       string
+	ResourceType       string
+	ServiceCategory    string
+	ServiceName        string
+	SubAccountID       string
+	SubAccountName     string
+	Tags               map[string]string
+
+	KubernetesPercent float64
+	Cluster           string
+	Node              string
+	Namespace         string
+	ControllerKind    string
+	Controller        string
+	Pod               string
+	Container         string
+	CPUCost           float64
+	GPUCost           float64
+	RAMCost           float64
+	PVCost            float64
+	NetworkCost       float64
+	LoadBalancerCost  float64
+	SharedCost        float64
+}
+
+// NewCloudCostCharge creates the Charge of a CloudCost. This is the inverse of FOCUSRowToCloudCost:
+//   - BilledCost is the InvoicedCost
+//   - EffectiveCost is the AmortizedNetCost
+//   - ListCost is the ListCost
+//   - ContractedCost is the NetCost
+func NewCloudCostCharge(cc *opencost.CloudCost, currency string) *Charge {
+	properties := cc.Properties
+	if properties == nil {
+		properties = &opencost.CloudCostProperties{}
+	}
+
+	charge := &Charge{
+		BilledCost:        cc.InvoicedCost.Cost,
+		EffectiveCost:     cc.AmortizedNetCost.Cost,
+		ListCost:          cc.ListCost.Cost,
+		ContractedCost:    cc.NetCost.Cost,
+		BillingAccountID:  properties.InvoiceEntityID,
+		BillingCurrency:   currency,
+		ChargeCategory:    UsageChargeCategory,
+		ChargeFrequency:   UsageBasedChargeFrequency,
+		InvoiceIssuerName: properties.Provider,
+		ProviderName:      properties.Provider,
+		PublisherName:     properties.Provider,
+		ResourceID:        properties.ProviderID,
+		ServiceCategory:   FOCUSServiceCategory(properties.Category),
+		ServiceName:       properties.Service,
+		SubAccountID:      properties.AccountID,
+		Tags:              properties.Labels,
+		KubernetesPercent: cc.NetCost.KubernetesPercent,
+	}
+	charge.setPeriods(cc.Window)
+	return charge
+}
+
+// NewAllocationCharge creates the Charge of the allocation of a Kubernetes resource, with the cluster as the billing
+// account and the namespace as the sub account. All costs are the total cost of the allocation.
+func NewAllocationCharge(alloc *opencost.Allocation, currency string) *Charge {
+	properties := alloc.Properties
+	if properties == nil {
+		properties = &opencost.AllocationProperties{}
+	}
+
+	resourceType := "Container"
+	switch {
+	case alloc.IsIdle():
+		resourceType = "Idle"
+	case alloc.IsUnmounted():
+		resourceType = "Unmounted Volume"
+	}
+
+	totalCost := alloc.TotalCost()
+	charge := &Charge{
+		BilledCost:         totalCost,
+		EffectiveCost:      totalCost,
+		ListCost:           totalCost,
+		ContractedCost:     totalCost,
+		BillingAccountID:   properties.Cluster,
+		BillingAccountName: properties.Cluster,
+		BillingCurrency:    currency,
+		ChargeCategory:     UsageChargeCategory,
+		ChargeFrequency:    UsageBasedChargeFrequency,
+		InvoiceIssuerName:  OpenCostInvoiceIssuerName,
+		ProviderName:       KubernetesProviderName,
+		PublisherName:      KubernetesProviderName,
+		ResourceID:         alloc.Name,
+		ResourceName:       alloc.Name,
+		ResourceType:       resourceType,
+		ServiceCategory:    "Compute",
+		ServiceName:        KubernetesProviderName,
+		SubAccountID:       properties.Namespace,
+		SubAccountName:     properties.Namespace,
+		Tags:               properties.Labels,
+		KubernetesPercent:  1.0,
+		Cluster:            properties.Cluster,
+		Node:               properties.Node,
+		Namespace:          properties.Namespace,
+		ControllerKind:     properties.ControllerKind,
+		Controller:         properties.Controller,
+		Pod:                properties.Pod,
+		Container:          properties.Container,
+		CPUCost:            alloc.CPUTotalCost(),
+		GPUCost:            alloc.GPUTotalCost(),
+		RAMCost:            alloc.RAMTotalCost(),
+		PVCost:             alloc.PVTotalCost(),
+		NetworkCost:        alloc.NetworkTotalCost(),
+		LoadBalancerCost:   alloc.LBTotalCost(),
+		SharedCost:         alloc.SharedTotalCost(),
+	}
+	charge.setPeriods(alloc.Window)
+	return charge
+}
+
+// setPeriods sets the charge period to the window, and the billing period to the calendar month in which it starts
+func (c *Charge) setPeriods(window opencost.Window) {
+	if window.Start() == nil || window.End() == nil {
+		return
+	}
+	c.ChargePeriodStart = window.Start().UTC()
+	c.ChargePeriodEnd = window.End().UTC()
+	c.BillingPeriodStart = time.Date(c.ChargePeriodStart.Year(), c.ChargePeriodStart.Month(), 1, 0, 0, 0, 0, time.UTC)
+	c.BillingPeriodEnd = c.BillingPeriodStart.AddDate(0, 1, 0)
+}
+
+// FOCUSServiceCategory maps a category to the FOCUS ServiceCategory. This is the inverse of SelectFOCUSCategory.
+func FOCUSServiceCategory(category string) string {
+	switch category {
+	case opencost.ComputeCategory:
+		return "Compute"
+	case opencost.StorageCategory:
+		return "Storage"
+	case opencost.NetworkCategory:
+		return "Networking"
+	case opencost.ManagementCategory:
+		return "Management and Governance"
+	default:
+		return "Other"
+	}
+}
+
+// formatFOCUSTime formats times as ISO 8601 in UTC, as required by FOCUS, or an empty string for zero times
+func formatFOCUSTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ChargeColumnDefs returns the columns of FOCUS exports. Cloud costs and allocations are written with the same
+// columns, so that both may be ingested as a single dataset.
+func ChargeColumnDefs() []tabular.ColumnDef[*Charge] {
+	return []tabular.ColumnDef[*Charge]{
+		tabular.FloatColumnDef(BilledCostColumnName, func(c *Charge) float64 { return c.BilledCost }),
+		tabular.StringColumnDef(BillingAccountIDColumnName, func(c *Charge) string { return c.BillingAccountID }),
+		tabular.StringColumnDef(BillingAccountNameColumnName, func(c *Charge) string { return c.BillingAccountName }),
+		tabular.StringColumnDef(BillingCurrencyColumnName, func(c *Charge) string { return c.BillingCurrency }),
+		tabular.StringColumnDef(BillingPeriodEndColumnName, func(c *Charge) string { return formatFOCUSTime(c.BillingPeriodEnd) }),
+		tabular.StringColumnDef(BillingPeriodStartColumnName, func(c *Charge) string { return formatFOCUSTime(c.BillingPeriodStart) }),
+		tabular.StringColumnDef(ChargeCategoryColumnName, func(c *Charge) string { return c.ChargeCategory }),
+		tabular.StringColumnDef(ChargeFrequencyColumnName, func(c *Charge) string { return c.ChargeFrequency }),
+		tabular.StringColumnDef(ChargePeriodEndColumnName, func(c *Charge) string { return formatFOCUSTime(c.ChargePeriodEnd) }),
+		tabular.StringColumnDef(ChargePeriodStartColumnName, func(c *Charge) string { return formatFOCUSTime(c.ChargePeriodStart) }),
+		tabular.FloatColumnDef(ContractedCostColumnName, func(c *Charge) float64 { return c.ContractedCost }),
+		tabular.FloatColumnDef(EffectiveCostColumnName, func(c *Charge) float64 { return c.EffectiveCost }),
+		tabular.StringColumnDef(InvoiceIssuerNameColumnName, func(c *Charge) string { return c.InvoiceIssuerName }),
+		tabular.FloatColumnDef(ListCostColumnName, func(c *Charge) float64 { return c.ListCost }),
+		tabular.StringColumnDef(ProviderNameColumnName, func(c *Charge) string { return c.ProviderName }),
+		tabular.StringColumnDef(PublisherNameColumnName, func(c *Charge) string { return c.PublisherName }),
+		tabular.StringColumnDef(ResourceIDColumnName, func(c *Charge) string { return c.ResourceID }),
+		tabular.StringColumnDef(ResourceNameColumnName, func(c *Charge) string { return c.ResourceName }),
+		tabular.StringColumnDef(ResourceTypeColumnName, func(c *Charge) string { return c.ResourceType }),
+		tabular.StringColumnDef(ServiceCategoryColumnName, func(c *Charge) string { return c.ServiceCategory }),
+		tabular.StringColumnDef(ServiceNameColumnName, func(c *Charge) string { return c.ServiceName }),
+		tabular.StringColumnDef(SubAccountIDColumnName, func(c *Charge) string { return c.SubAccountID }),
+		tabular.StringColumnDef(SubAccountNameColumnName, func(c *Charge) string { return c.SubAccountName }),
+		tabular.StringColumnDef(TagsColumnName, func(c *Charge) string { return tabular.FormatLabels(c.Tags) }),
+		tabular.FloatColumnDef(KubernetesPercentColumnName, func(c *Charge) float64 { return c.KubernetesPercent }),
+		tabular.StringColumnDef(ClusterColumnName, func(c *Charge) string { return c.Cluster }),
+		tabular.StringColumnDef(NodeColumnName, func(c *Charge) string { return c.Node }),
+		tabular.StringColumnDef(NamespaceColumnName, func(c *Charge) string { return c.Namespace }),
+		tabular.StringColumnDef(ControllerKindColumnName, func(c *Charge) string { return c.ControllerKind }),
+		tabular.StringColumnDef(ControllerColumnName, func(c *Charge) string { return c.Controller }),
+		tabular.StringColumnDef(PodColumnName, func(c *Charge) string { return c.Pod }),
+		tabular.StringColumnDef(ContainerColumnName, func(c *Charge) string { return c.Container }),
+		tabular.FloatColumnDef(CPUCostColumnName, func(c *Charge) float64 { return c.CPUCost }),
+		tabular.FloatColumnDef(GPUCostColumnName, func(c *Charge) float64 { return c.GPUCost }),
+		tabular.FloatColumnDef(RAMCostColumnName, func(c *Charge) float64 { return c.RAMCost }),
+		tabular.FloatColumnDef(PVCostColumnName, func(c *Charge) float64 { return c.PVCost }),
+		tabular.FloatColumnDef(NetworkCostColumnName, func(c *Charge) float64 { return c.NetworkCost }),
+		tabular.FloatColumnDef(LoadBalancerCostColumnName, func(c *Charge) float64 { return c.LoadBalancerCost }),
+		tabular.FloatColumnDef(SharedCostColumnName, func(c *Charge) float64 { return c.SharedCost }),
+	}
+}
+
+// WriteCloudCosts writes the CloudCosts of each set of the range as FOCUS charges in the given format, ordered by
+// set and then by key
+func WriteCloudCosts(format tabular.Format, w io.Writer, ccsr *opencost.CloudCostSetRange, currency string) error {
+	defs := ChargeColumnDefs()
+	tw, err := tabular.NewWriter(format, w, tabular.Columns(defs))
+	if err != nil {
+		return err
+	}
+
+	if ccsr != nil {
+		for _, ccs := range ccsr.CloudCostSets {
+			keys := make([]string, 0, len(ccs.CloudCosts))
+			for key := range ccs.CloudCosts {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				err = tabular.WriteRow(tw, defs, NewCloudCostCharge(ccs.CloudCosts[key], currency))
+				if err != nil {
+					return fmt.Errorf("failed to write cloud cost %s: %w", key, err)
+				}
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+// WriteAllocations writes the allocations of the set as FOCUS charges in the given format, ordered by name
+func WriteAllocations(format tabular.Format, w io.Writer, as *opencost.AllocationSet, currency string) error {
+	defs := ChargeColumnDefs()
+	tw, err := tabular.NewWriter(format, w, tabular.Columns(defs))
+	if err != nil {
+		return err
+	}
+
+	if as != nil {
+		names := make([]string, 0, len(as.Allocations))
+		for name := range as.Allocations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			err = tabular.WriteRow(tw, defs, NewAllocationCharge(as.Allocations[name], currency))
+			if err != nil {
+				return fmt.Errorf("failed to write allocation %s: %w", name, err)
+			}
+		}
+	}
+
+	return tw.Close()
+}
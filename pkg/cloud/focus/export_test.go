@@ -0,0 +1,250 @@
+package focus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+func testCloudCostSetRange(start time.Time) *opencost.CloudCostSetRange {
+	end := start.Add(timeutil.Day)
+	newCloudCost := func(providerID, category string, cost, k8sPct float64) *opencost.CloudCost {
+		return &opencost.CloudCost{
+			Properties: &opencost.CloudCostProperties{
+				ProviderID:      providerID,
+				Provider:        "Microsoft",
+				AccountID:       "sub-account-1",
+				InvoiceEntityID: "billing-account-1",
+				Service:         "Virtual Machines",
+				Category:        category,
+				Labels:          map[string]string{"aks-managed-cluster-name": "aks"},
+			},
+			Window:           opencost.NewWindow(&start, &end),
+			ListCost:         opencost.CostMetric{Cost: cost * 1.2, KubernetesPercent: k8sPct},
+			NetCost:          opencost.CostMetric{Cost: cost, KubernetesPercent: k8sPct},
+			AmortizedNetCost: opencost.CostMetric{Cost: cost * 0.8, KubernetesPercent: k8sPct},
+			InvoicedCost:     opencost.CostMetric{Cost: cost, KubernetesPercent: k8sPct},
+			AmortizedCost:    opencost.CostMetric{Cost: cost * 0.8, KubernetesPercent: k8sPct},
+		}
+	}
+
+	ccs := opencost.NewCloudCostSet(start, end,
+		newCloudCost("vmss-1", opencost.ComputeCategory, 10, 1),
+		newCloudCost("disk-1", opencost.StorageCategory, 2, 1),
+	)
+	return &opencost.CloudCostSetRange{
+		CloudCostSets: []*opencost.CloudCostSet{ccs},
+		Window:        ccs.Window,
+	}
+}
+
+func testAllocationSet(start time.Time) *opencost.AllocationSet {
+	return opencost.NewAllocationSet(start, start.Add(timeutil.Day),
+		opencost.NewMockUnitAllocation("cluster1/node1/namespace1/pod1/container1", start, timeutil.Day, nil),
+		opencost.NewMockUnitAllocation("cluster1/__idle__", start, timeutil.Day, &opencost.AllocationProperties{Cluster: "cluster1"}),
+	)
+}
+
+func TestWriteCloudCosts(t *testing.T) {
+	start := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	ccsr := testCloudCostSetRange(start)
+
+	buf := &bytes.Buffer{}
+	err := WriteCloudCosts(tabular.FormatCSV, buf, ccsr, "EUR")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	byProviderID := map[string]*opencost.CloudCost{}
+	for _, cc := range ccsr.CloudCostSets[0].CloudCosts {
+		byProviderID[cc.Properties.ProviderID] = cc
+	}
+
+	// Exported cloud costs can be read back as the cloud costs they were written from
+	rows := 0
+	err = parseFOCUSFile("cloudcost.csv", buf, func(row tabular.Row) error {
+		rows++
+		if row.String(BillingCurrencyColumnName) != "EUR" {
+			t.Errorf("expected billing currency EUR, got %s", row.String(BillingCurrencyColumnName))
+		}
+		if row.String(BillingPeriodStartColumnName) != "2024-05-01T00:00:00Z" || row.String(BillingPeriodEndColumnName) != "2024-06-01T00:00:00Z" {
+			t.Errorf("unexpected billing period %s to %s", row.String(BillingPeriodStartColumnName), row.String(BillingPeriodEndColumnName))
+		}
+
+		cc, err := FOCUSRowToCloudCost(row)
+		if err != nil {
+			return err
+		}
+		expected := byProviderID[cc.Properties.ProviderID]
+		if expected == nil {
+			t.Errorf("unexpected cloud cost %s", cc.Properties.ProviderID)
+			return nil
+		}
+		if !cc.Properties.Equal(expected.Properties) {
+			t.Errorf("expected properties %+v, got %+v", expected.Properties, cc.Properties)
+		}
+		if !cc.Window.Equal(expected.Window) {
+			t.Errorf("expected window %s, got %s", expected.Window, cc.Window)
+		}
+		for metric, values := range map[string][2]float64{
+			"list":          {expected.ListCost.Cost, cc.ListCost.Cost},
+			"invoiced":      {expected.InvoicedCost.Cost, cc.InvoicedCost.Cost},
+			"amortized net": {expected.AmortizedNetCost.Cost, cc.AmortizedNetCost.Cost},
+		} {
+			if math.Abs(values[0]-values[1]) > 1e-9 {
+				t.Errorf("%s: expected %s cost %f, got %f", cc.Properties.ProviderID, metric, values[0], values[1])
+			}
+		}
+		if contracted, _ := row.Float(ContractedCostColumnName); contracted != expected.NetCost.Cost {
+			t.Errorf("%s: expected contracted cost %f, got %f", cc.Properties.ProviderID, expected.NetCost.Cost, contracted)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read export: %s", err)
+	}
+	if rows != 2 {
+		t.Errorf("expected 2 rows, got %d", rows)
+	}
+}
+
+func TestWriteAllocations(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	as := testAllocationSet(start)
+
+	buf := &bytes.Buffer{}
+	err := WriteAllocations(tabular.FormatParquet, buf, as, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reader, err := tabular.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read export: %s", err)
+	}
+	defer reader.Close()
+
+	rows := map[string]tabular.Row{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read export: %s", err)
+		}
+		rows[row.String(ResourceIDColumnName)] = row
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+
+	for name, alloc := range as.Allocations {
+		row, ok := rows[name]
+		if !ok {
+			t.Errorf("missing allocation %s", name)
+			continue
+		}
+		for _, column := range []string{BilledCostColumnName, EffectiveCostColumnName, ListCostColumnName} {
+			if cost, _ := row.Float(column); cost != alloc.TotalCost() {
+				t.Errorf("%s: expected %s %f, got %f", name, column, alloc.TotalCost(), cost)
+			}
+		}
+		if row.String(BillingAccountIDColumnName) != "cluster1" || row.String(ProviderNameColumnName) != KubernetesProviderName {
+			t.Errorf("%s: unexpected billing account %s and provider %s", name, row.String(BillingAccountIDColumnName), row.String(ProviderNameColumnName))
+		}
+	}
+
+	container := rows["cluster1/node1/namespace1/pod1/container1"]
+	if container.String(ResourceTypeColumnName) != "Container" || container.String(SubAccountIDColumnName) != "namespace1" || container.String(PodColumnName) != "pod1" {
+		t.Errorf("unexpected container row %v", container)
+	}
+	if cost, _ := container.Float(PVCostColumnName); cost != 1 {
+		t.Errorf("expected PV cost 1, got %f", cost)
+	}
+	if idle := rows["cluster1/__idle__"]; idle.String(ResourceTypeColumnName) != "Idle" {
+		t.Errorf("expected idle resource type, got %s", idle.String(ResourceTypeColumnName))
+	}
+}
+
+type mockCloudCostSource struct {
+	ccsr *opencost.CloudCostSetRange
+}
+
+func (m *mockCloudCostSource) CloudCosts(ctx context.Context, window opencost.Window) (*opencost.CloudCostSetRange, error) {
+	return m.ccsr, nil
+}
+
+type mockAllocationSource struct {
+	as *opencost.AllocationSet
+}
+
+func (m *mockAllocationSource) Allocations(ctx context.Context, window opencost.Window) (*opencost.AllocationSet, error) {
+	return m.as, nil
+}
+
+func TestExporter_ExportDay(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store := storage.NewFileStorage(t.TempDir())
+
+	exporter := NewExporter(store, "exports/focus", tabular.FormatCSV, "USD",
+		&mockCloudCostSource{ccsr: testCloudCostSetRange(start)},
+		&mockAllocationSource{as: testAllocationSet(start)},
+	)
+	err := exporter.ExportDay(context.Background(), start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for dataset, expected := range map[string]string{
+		CloudCostDataset:  "exports/focus/cloudcost/2024-05/cloudcost_2024-05-01.csv",
+		AllocationDataset: "exports/focus/allocation/2024-05/allocation_2024-05-01.csv",
+	} {
+		filePath := exporter.FilePath(dataset, start)
+		if filePath != expected {
+			t.Errorf("expected path %s, got %s", expected, filePath)
+		}
+
+		r, err := store.ReadStream(filePath)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", filePath, err)
+		}
+		rows := 0
+		err = parseFOCUSFile(filePath, r, func(row tabular.Row) error {
+			rows++
+			return nil
+		})
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to parse %s: %s", filePath, err)
+		}
+		if rows != 2 {
+			t.Errorf("%s: expected 2 rows, got %d", filePath, rows)
+		}
+	}
+
+	// Exported files are read by the FOCUS integration
+	si := &StorageIntegration{
+		StorageConnection: StorageConnection{
+			StorageConfiguration: StorageConfiguration{
+				Name: "export",
+				Path: store.FullPath("exports/focus/cloudcost"),
+			},
+		},
+	}
+	ccsr, err := si.GetCloudCost(start, start.Add(timeutil.Day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ccsr.CloudCostSets) != 1 || len(ccsr.CloudCostSets[0].CloudCosts) != 2 {
+		t.Errorf("expected 2 cloud costs to be read from the export")
+	}
+}
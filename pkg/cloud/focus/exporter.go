@@ -0,0 +1,143 @@
+package focus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// Datasets of FOCUS exports, which name the directory and files of each
+const (
+	CloudCostDataset  = "cloudcost"
+	AllocationDataset = "allocation"
+)
+
+// CloudCostSource provides the cloud costs which are exported
+type CloudCostSource interface {
+	// CloudCosts returns the cloud costs of each item in the window
+	CloudCosts(ctx context.Context, window opencost.Window) (*opencost.CloudCostSetRange, error)
+}
+
+// AllocationSource provides the allocations of Kubernetes resources which are exported
+type AllocationSource interface {
+	// Allocations returns the allocations of each Kubernetes resource over the window
+	Allocations(ctx context.Context, window opencost.Window) (*opencost.AllocationSet, error)
+}
+
+// Exporter periodically writes the cloud costs and allocations of each day to FOCUS files in a storage.Storage. The
+// files of each dataset are written to <dir>/<dataset>/<YYYY-MM>/<dataset>_<YYYY-MM-DD>.<format>, and are rewritten
+// on each export of their day.
+type Exporter struct {
+	store       storage.Storage
+	dir         string
+	format      tabular.Format
+	currency    string
+	cloudCosts  CloudCostSource
+	allocations AllocationSource
+
+	job timeutil.PeriodicJob
+}
+
+// NewExporter creates an Exporter of the cloud costs and allocations of the sources, in the given format and
+// currency. Either source may be nil, in which case its dataset is not exported.
+func NewExporter(store storage.Storage, dir string, format tabular.Format, currency string, cloudCosts CloudCostSource, allocations AllocationSource) *Exporter {
+	return &Exporter{
+		store:       store,
+		dir:         dir,
+		format:      format,
+		currency:    currency,
+		cloudCosts:  cloudCosts,
+		allocations: allocations,
+	}
+}
+
+// Start exports each of the given number of most recent complete days at each interval, starting immediately, so
+// that exported files pick up billing data completed after the day
+func (e *Exporter) Start(interval time.Duration, days int) error {
+	return e.job.Start(interval, func() {
+		e.exportRecent(days)
+	})
+}
+
+// Stop stops a started Exporter
+func (e *Exporter) Stop() {
+	e.job.Stop()
+}
+
+func (e *Exporter) exportRecent(days int) {
+	today := time.Now().UTC().Truncate(timeutil.Day)
+	for i := days; i >= 1; i-- {
+		err := e.ExportDay(context.Background(), today.Add(-time.Duration(i)*timeutil.Day))
+		if err != nil {
+			log.Warnf("FOCUS: export: %s", err)
+		}
+	}
+}
+
+// ExportDay writes the FOCUS files of each dataset for the day starting at the given time. An error exporting one
+// dataset does not prevent the export of the other.
+func (e *Exporter) ExportDay(ctx context.Context, start time.Time) error {
+	start = start.UTC().Truncate(timeutil.Day)
+	window := opencost.NewClosedWindow(start, start.Add(timeutil.Day))
+
+	var errs []error
+	if e.cloudCosts != nil {
+		ccsr, err := e.cloudCosts.CloudCosts(ctx, window)
+		if err == nil {
+			err = e.write(CloudCostDataset, start, func(buf *bytes.Buffer) error {
+				return WriteCloudCosts(e.format, buf, ccsr, e.currency)
+			})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to export cloud costs for %s: %w", window, err))
+		}
+	}
+
+	if e.allocations != nil {
+		as, err := e.allocations.Allocations(ctx, window)
+		if err == nil {
+			err = e.write(AllocationDataset, start, func(buf *bytes.Buffer) error {
+				return WriteAllocations(e.format, buf, as, e.currency)
+			})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to export allocations for %s: %w", window, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// write writes the file of the dataset for the day, with the contents written by fn
+func (e *Exporter) write(dataset string, start time.Time, fn func(*bytes.Buffer) error) error {
+	buf := &bytes.Buffer{}
+	err := fn(buf)
+	if err != nil {
+		return err
+	}
+
+	filePath := e.FilePath(dataset, start)
+	err = e.store.Write(filePath, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", e.store.FullPath(filePath), err)
+	}
+
+	log.Debugf("FOCUS: exported %s", e.store.FullPath(filePath))
+	return nil
+}
+
+// FilePath returns the path in the storage of the file of the dataset for the day starting at the given time
+func (e *Exporter) FilePath(dataset string, start time.Time) string {
+	start = start.UTC()
+	name := fmt.Sprintf("%s_%s", dataset, start.Format("2006-01-02"))
+	return path.Join(e.dir, dataset, start.Format("2006-01"), e.format.FileName(name))
+}
@@ -0,0 +1,149 @@
+// Package focus provides a provider agnostic cloud cost integration for billing files in the FinOps Open Cost and
+// Usage Specification (FOCUS) format. See https://focus.finops.org
+package focus
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// FOCUSProvider is the provider of FOCUS configurations, as each row of a FOCUS file names its own provider
+const FOCUSProvider = "FOCUS"
+
+// StorageConfiguration is the location of FOCUS CSV or Parquet files. Files are read from Path, and the directories
+// below it, in the bucket storage of BucketConfig, which has the same format as the ETL bucket storage configuration.
+// If BucketConfig is empty, Path is a local directory.
+type StorageConfiguration struct {
+	Name         string         `json:"name"`
+	Path         string         `json:"path"`
+	BucketConfig map[string]any `json:"bucketConfig,omitempty"`
+}
+
+// Check ensures that all required fields are set, and throws an error if they are not
+func (sc *StorageConfiguration) Validate() error {
+	if sc.Name == "" {
+		return fmt.Errorf("StorageConfiguration: missing Name")
+	}
+
+	if sc.Path == "" && len(sc.BucketConfig) == 0 {
+		return fmt.Errorf("StorageConfiguration: missing Path")
+	}
+
+	if len(sc.BucketConfig) != 0 {
+		if _, ok := sc.BucketConfig["type"]; !ok {
+			return fmt.Errorf("StorageConfiguration: missing bucket type")
+		}
+	}
+
+	return nil
+}
+
+func (sc *StorageConfiguration) Equals(config cloud.Config) bool {
+	if config == nil {
+		return false
+	}
+	thatConfig, ok := config.(*StorageConfiguration)
+	if !ok {
+		return false
+	}
+
+	if sc.Name != thatConfig.Name {
+		return false
+	}
+
+	if sc.Path != thatConfig.Path {
+		return false
+	}
+
+	if len(sc.BucketConfig) != 0 || len(thatConfig.BucketConfig) != 0 {
+		if !reflect.DeepEqual(sc.BucketConfig, thatConfig.BucketConfig) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (sc *StorageConfiguration) Sanitize() cloud.Config {
+	return &StorageConfiguration{
+		Name:         sc.Name,
+		Path:         sc.Path,
+		BucketConfig: sanitizeBucketConfig(sc.BucketConfig),
+	}
+}
+
+// sanitizeBucketConfig redacts the credentials of a bucket configuration
+func sanitizeBucketConfig(config map[string]any) map[string]any {
+	if config == nil {
+		return nil
+	}
+
+	sanitized := make(map[string]any, len(config))
+	for key, value := range config {
+		lowerKey := strings.ToLower(key)
+		switch {
+		case strings.Contains(lowerKey, "secret"),
+			strings.Contains(lowerKey, "password"),
+			strings.Contains(lowerKey, "token"),
+			strings.Contains(lowerKey, "account_key"),
+			strings.Contains(lowerKey, "service_account"):
+			sanitized[key] = cloud.Redacted
+		default:
+			if nested, ok := value.(map[string]any); ok {
+				sanitized[key] = sanitizeBucketConfig(nested)
+			} else {
+				sanitized[key] = value
+			}
+		}
+	}
+	return sanitized
+}
+
+func (sc *StorageConfiguration) Key() string {
+	return sc.Name
+}
+
+func (sc *StorageConfiguration) Provider() string {
+	return FOCUSProvider
+}
+
+func (sc *StorageConfiguration) UnmarshalJSON(b []byte) error {
+	var f interface{}
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return err
+	}
+
+	fmap, ok := f.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("StorageConfiguration: UnmarshalJSON: could not cast interface as map")
+	}
+
+	name, err := cloud.GetInterfaceValue[string](fmap, "name")
+	if err != nil {
+		return fmt.Errorf("StorageConfiguration: UnmarshalJSON: %s", err.Error())
+	}
+	sc.Name = name
+
+	if _, ok := fmap["path"]; ok {
+		path, err := cloud.GetInterfaceValue[string](fmap, "path")
+		if err != nil {
+			return fmt.Errorf("StorageConfiguration: UnmarshalJSON: %s", err.Error())
+		}
+		sc.Path = path
+	}
+
+	if _, ok := fmap["bucketConfig"]; ok {
+		bucketConfig, err := cloud.GetInterfaceValue[map[string]any](fmap, "bucketConfig")
+		if err != nil {
+			return fmt.Errorf("StorageConfiguration: UnmarshalJSON: %s", err.Error())
+		}
+		sc.BucketConfig = bucketConfig
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package focus
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// fileExtensions are the extensions of the FOCUS files which are read
+var fileExtensions = []string{".csv", ".csv.gz", ".parquet"}
+
+// StorageConnection provides access to FOCUS files in a storage.Storage
+type StorageConnection struct {
+	StorageConfiguration
+	ConnectionStatus cloud.ConnectionStatus
+}
+
+func (sc *StorageConnection) GetStatus() cloud.ConnectionStatus {
+	// initialize status if it has not done so; this can happen if the integration is inactive
+	if sc.ConnectionStatus.String() == "" {
+		sc.ConnectionStatus = cloud.InitialStatus
+	}
+	return sc.ConnectionStatus
+}
+
+func (sc *StorageConnection) Equals(config cloud.Config) bool {
+	thatConfig, ok := config.(*StorageConnection)
+	if !ok {
+		return false
+	}
+
+	return sc.StorageConfiguration.Equals(&thatConfig.StorageConfiguration)
+}
+
+// GetStorage returns the storage of the FOCUS files and the path of the files in it
+func (sc *StorageConnection) GetStorage() (storage.Storage, string, error) {
+	if len(sc.BucketConfig) == 0 {
+		return storage.NewFileStorage(sc.Path), "", nil
+	}
+
+	// bucket configurations are YAML, of which JSON is a subset
+	config, err := json.Marshal(sc.BucketConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("StorageConnection: failed to marshal bucket config: %w", err)
+	}
+	store, err := storage.NewBucketStorage(config)
+	if err != nil {
+		return nil, "", fmt.Errorf("StorageConnection: failed to create bucket storage: %w", err)
+	}
+	return store, sc.Path, nil
+}
+
+// ListFiles returns the paths of the FOCUS files in dir, and the directories below it, which were modified since
+// the given time, as older files cannot contain charges after it
+func (sc *StorageConnection) ListFiles(store storage.Storage, dir string, since time.Time) ([]string, error) {
+	files, err := store.List(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ListFiles: %s: %w", store.FullPath(dir), err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		if !isFOCUSFile(file.Name) || file.ModTime.Before(since) {
+			continue
+		}
+		paths = append(paths, path.Join(dir, file.Name))
+	}
+
+	dirs, err := store.ListDirectories(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ListFiles: %s: %w", store.FullPath(dir), err)
+	}
+	for _, subDir := range dirs {
+		subPaths, err := sc.ListFiles(store, strings.TrimSuffix(subDir.Name, storage.DirDelim), since)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, subPaths...)
+	}
+
+	return paths, nil
+}
+
+func isFOCUSFile(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range fileExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,287 @@
+package focus
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/cloud/alibaba"
+	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// FOCUS columns which are mapped onto CloudCosts
+const (
+	BilledCostColumnName        = "BilledCost"
+	EffectiveCostColumnName     = "EffectiveCost"
+	ListCostColumnName          = "ListCost"
+	ChargeCategoryColumnName    = "ChargeCategory"
+	ChargePeriodStartColumnName = "ChargePeriodStart"
+	ChargePeriodEndColumnName   = "ChargePeriodEnd"
+	BillingAccountIDColumnName  = "BillingAccountId"
+	SubAccountIDColumnName      = "SubAccountId"
+	ProviderNameColumnName      = "ProviderName"
+	ResourceIDColumnName        = "ResourceId"
+	ServiceNameColumnName       = "ServiceName"
+	ServiceCategoryColumnName   = "ServiceCategory"
+	TagsColumnName              = "Tags"
+)
+
+// StorageIntegration reads the cloud costs of FOCUS files
+type StorageIntegration struct {
+	StorageConnection
+}
+
+// GetCloudCost reads the rows of the FOCUS files with charge periods in the window. Files which were last modified
+// before the window are skipped.
+//
+// FOCUS costs are mapped as follows:
+//   - ListCost is the ListCost
+//   - NetCost and InvoicedCost are the BilledCost, which is what is invoiced for the charge period
+//   - AmortizedNetCost and AmortizedCost are the EffectiveCost, which amortizes purchases and includes discounts
+func (si *StorageIntegration) GetCloudCost(start time.Time, end time.Time) (*opencost.CloudCostSetRange, error) {
+	log.Infof("StorageIntegration[%s]: GetCloudCost: %s", si.Key(), opencost.NewWindow(&start, &end).String())
+
+	ccsr, err := opencost.NewCloudCostSetRange(start, end, opencost.AccumulateOptionDay, si.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	err = si.Validate()
+	if err != nil {
+		si.ConnectionStatus = cloud.InvalidConfiguration
+		return nil, err
+	}
+
+	store, dir, err := si.GetStorage()
+	if err != nil {
+		si.ConnectionStatus = cloud.FailedConnection
+		return nil, err
+	}
+
+	files, err := si.ListFiles(store, dir, start)
+	if err != nil {
+		si.ConnectionStatus = cloud.FailedConnection
+		return nil, err
+	}
+
+	hasResults := false
+	for _, file := range files {
+		r, err := store.ReadStream(file)
+		if err != nil {
+			si.ConnectionStatus = cloud.FailedConnection
+			return nil, fmt.Errorf("StorageIntegration: failed to read %s: %w", store.FullPath(file), err)
+		}
+
+		err = parseFOCUSFile(file, r, func(row tabular.Row) error {
+			cc, err := FOCUSRowToCloudCost(row)
+			if err != nil {
+				return err
+			}
+			if cc == nil {
+				return nil
+			}
+			// skip charges outside the window
+			if !cc.Window.Start().Before(end) || !cc.Window.End().After(start) {
+				return nil
+			}
+			hasResults = true
+			ccsr.LoadCloudCost(cc)
+			return nil
+		})
+		r.Close()
+		if err != nil {
+			si.ConnectionStatus = cloud.ParseError
+			return nil, fmt.Errorf("StorageIntegration: failed to parse %s: %w", store.FullPath(file), err)
+		}
+	}
+
+	si.ConnectionStatus = cloud.ResultStatus(hasResults, si.ConnectionStatus)
+	return ccsr, nil
+}
+
+// parseFOCUSFile calls fn with each row of the CSV, gzipped CSV or Parquet file read from r. CSV files are
+// parsed as they are read, while Parquet files, which are read from their footer, are first copied to a
+// temporary file.
+func parseFOCUSFile(name string, r io.Reader, fn func(tabular.Row) error) error {
+	var reader tabular.Reader
+	var err error
+
+	lowerName := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lowerName, ".parquet"):
+		tmp, err := os.CreateTemp("", "focus-*.parquet")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		_, err = io.Copy(tmp, r)
+		if err != nil {
+			return err
+		}
+		reader, err = tabular.NewParquetReader(tmp)
+		if err != nil {
+			return err
+		}
+	case strings.HasSuffix(lowerName, ".gz"):
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+
+		reader, err = tabular.NewCSVReader(gzipReader)
+		if err != nil {
+			return err
+		}
+	default:
+		reader, err = tabular.NewCSVReader(r)
+		if err != nil {
+			return err
+		}
+	}
+	defer reader.Close()
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// FOCUSRowToCloudCost creates the CloudCost of a row of a FOCUS file, or nil if the row has no cost
+func FOCUSRowToCloudCost(row tabular.Row) (*opencost.CloudCost, error) {
+	billedCost, err := row.Float(BilledCostColumnName)
+	if err != nil {
+		return nil, err
+	}
+	effectiveCost, err := row.Float(EffectiveCostColumnName)
+	if err != nil {
+		return nil, err
+	}
+	listCost, err := row.Float(ListCostColumnName)
+	if err != nil {
+		return nil, err
+	}
+	if billedCost == 0 && effectiveCost == 0 && listCost == 0 {
+		return nil, nil
+	}
+
+	chargePeriodStart, err := row.Time(ChargePeriodStartColumnName)
+	if err != nil {
+		return nil, err
+	}
+	chargePeriodEnd, err := row.Time(ChargePeriodEndColumnName)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := row.Map(TagsColumnName)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sPct := 0.0
+	if FOCUSIsK8s(labels) {
+		k8sPct = 1.0
+	}
+
+	provider := row.String(ProviderNameColumnName)
+	if provider == "" {
+		provider = FOCUSProvider
+	}
+
+	accountID := row.String(SubAccountIDColumnName)
+	if accountID == "" {
+		accountID = row.String(BillingAccountIDColumnName)
+	}
+
+	return &opencost.CloudCost{
+		Properties: &opencost.CloudCostProperties{
+			ProviderID:      row.String(ResourceIDColumnName),
+			Provider:        provider,
+			AccountID:       accountID,
+			InvoiceEntityID: row.String(BillingAccountIDColumnName),
+			Service:         row.String(ServiceNameColumnName),
+			Category:        SelectFOCUSCategory(row.String(ChargeCategoryColumnName), row.String(ServiceCategoryColumnName)),
+			Labels:          labels,
+		},
+		Window: opencost.NewWindow(&chargePeriodStart, &chargePeriodEnd),
+		ListCost: opencost.CostMetric{
+			Cost:              listCost,
+			KubernetesPercent: k8sPct,
+		},
+		NetCost: opencost.CostMetric{
+			Cost:              billedCost,
+			KubernetesPercent: k8sPct,
+		},
+		InvoicedCost: opencost.CostMetric{
+			Cost:              billedCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedNetCost: opencost.CostMetric{
+			Cost:              effectiveCost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedCost: opencost.CostMetric{
+			Cost:              effectiveCost,
+			KubernetesPercent: k8sPct,
+		},
+	}, nil
+}
+
+// SelectFOCUSCategory maps the FOCUS ServiceCategory of a charge to a category. Purchases and taxes are not
+// attributable to the category of a service, so are in the Other category.
+func SelectFOCUSCategory(chargeCategory, serviceCategory string) string {
+	switch strings.ToLower(chargeCategory) {
+	case "purchase", "tax":
+		return opencost.OtherCategory
+	}
+
+	switch strings.ToLower(serviceCategory) {
+	case "compute":
+		return opencost.ComputeCategory
+	case "storage":
+		return opencost.StorageCategory
+	case "networking":
+		return opencost.NetworkCategory
+	case "management and governance":
+		return opencost.ManagementCategory
+	default:
+		return opencost.OtherCategory
+	}
+}
+
+// FOCUSIsK8s checks for the tags which the Kubernetes services of each provider add to resources
+func FOCUSIsK8s(labels map[string]string) bool {
+	if gcp.IsK8s(labels) || azure.AzureIsK8s(labels) || alibaba.BoaIsK8s(labels) || oracle.OCIIsK8s(labels) {
+		return true
+	}
+	for key := range labels {
+		if strings.HasPrefix(key, "eks:") || strings.HasPrefix(key, "aws:eks:") {
+			return true
+		}
+		if strings.HasPrefix(key, "kubernetes.io/cluster/") {
+			return true
+		}
+	}
+	return false
+}
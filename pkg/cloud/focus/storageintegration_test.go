@@ -0,0 +1,242 @@
+package focus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+const testFOCUSFile = "test/focus_2024-05.csv"
+
+// writeTestParquet converts the CSV test file to Parquet, with float cost columns
+func writeTestParquet(t *testing.T, path string) {
+	data, err := os.ReadFile(testFOCUSFile)
+	if err != nil {
+		t.Fatalf("failed to read test file: %s", err)
+	}
+	reader, err := tabular.NewCSVReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read test file: %s", err)
+	}
+
+	names := []string{BillingAccountIDColumnName, SubAccountIDColumnName, ProviderNameColumnName, ChargeCategoryColumnName, ChargePeriodStartColumnName, ChargePeriodEndColumnName, ResourceIDColumnName, ServiceNameColumnName, ServiceCategoryColumnName, BilledCostColumnName, EffectiveCostColumnName, ListCostColumnName, TagsColumnName}
+	columns := make([]tabular.Column, 0, len(names))
+	for _, name := range names {
+		column := tabular.Column{Name: name, Type: tabular.StringColumn}
+		switch name {
+		case BilledCostColumnName, EffectiveCostColumnName, ListCostColumnName:
+			column.Type = tabular.FloatColumn
+		}
+		columns = append(columns, column)
+	}
+
+	buf := &bytes.Buffer{}
+	writer, err := tabular.NewParquetWriter(buf, columns)
+	if err != nil {
+		t.Fatalf("failed to create parquet writer: %s", err)
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read test file: %s", err)
+		}
+		values := make([]any, 0, len(columns))
+		for _, column := range columns {
+			if column.Type == tabular.FloatColumn {
+				f, _ := row.Float(column.Name)
+				values = append(values, f)
+			} else {
+				values = append(values, row.String(column.Name))
+			}
+		}
+		err = writer.Write(values)
+		if err != nil {
+			t.Fatalf("failed to write parquet: %s", err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("failed to write parquet: %s", err)
+	}
+
+	err = os.WriteFile(path, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatalf("failed to write parquet: %s", err)
+	}
+}
+
+func TestStorageIntegration_GetCloudCost(t *testing.T) {
+	csvDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(csvDir, "exports", "202405"), 0755)
+	if err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	data, err := os.ReadFile(testFOCUSFile)
+	if err != nil {
+		t.Fatalf("failed to read test file: %s", err)
+	}
+	err = os.WriteFile(filepath.Join(csvDir, "exports", "202405", "focus.csv"), data, 0644)
+	if err != nil {
+		t.Fatalf("failed to write test file: %s", err)
+	}
+
+	gzipDir := t.TempDir()
+	gzipData := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(gzipData)
+	_, err = gzipWriter.Write(data)
+	if err != nil {
+		t.Fatalf("failed to compress test file: %s", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		t.Fatalf("failed to compress test file: %s", err)
+	}
+	err = os.WriteFile(filepath.Join(gzipDir, "focus.csv.gz"), gzipData.Bytes(), 0644)
+	if err != nil {
+		t.Fatalf("failed to write test file: %s", err)
+	}
+
+	parquetDir := t.TempDir()
+	writeTestParquet(t, filepath.Join(parquetDir, "focus.parquet"))
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	expected := map[string]struct {
+		category      string
+		billedCost    float64
+		effectiveCost float64
+		listCost      float64
+		k8sPct        float64
+	}{
+		"/subscriptions/sub-account-1/resourcegroups/mc_rg/providers/microsoft.compute/virtualmachinescalesets/aks-nodepool1-vmss": {opencost.ComputeCategory, 10, 8, 12, 1},
+		"/subscriptions/sub-account-1/resourcegroups/rg/providers/microsoft.compute/disks/disk1":                                   {opencost.StorageCategory, 2, 2, 2, 0},
+		"/providers/microsoft.capacity/reservationorders/reservation1":                                                             {opencost.OtherCategory, 1, 0, 1, 0},
+	}
+
+	for name, dir := range map[string]string{"csv": csvDir, "gzip": gzipDir, "parquet": parquetDir} {
+		t.Run(name, func(t *testing.T) {
+			si := &StorageIntegration{
+				StorageConnection: StorageConnection{
+					StorageConfiguration: StorageConfiguration{
+						Name: "focus-" + name,
+						Path: dir,
+					},
+				},
+			}
+
+			ccsr, err := si.GetCloudCost(start, end)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if si.GetStatus() != cloud.SuccessfulConnection {
+				t.Errorf("expected status %s, got %s", cloud.SuccessfulConnection, si.GetStatus())
+			}
+			if len(ccsr.CloudCostSets) != 1 {
+				t.Fatalf("expected 1 cloud cost set, got %d", len(ccsr.CloudCostSets))
+			}
+
+			ccs := ccsr.CloudCostSets[0]
+			if len(ccs.CloudCosts) != len(expected) {
+				t.Fatalf("expected %d cloud costs, got %d", len(expected), len(ccs.CloudCosts))
+			}
+			for _, cc := range ccs.CloudCosts {
+				exp, ok := expected[cc.Properties.ProviderID]
+				if !ok {
+					t.Errorf("unexpected provider ID %s", cc.Properties.ProviderID)
+					continue
+				}
+				if cc.Properties.Provider != "Microsoft" {
+					t.Errorf("%s: expected provider Microsoft, got %s", cc.Properties.ProviderID, cc.Properties.Provider)
+				}
+				if cc.Properties.AccountID != "sub-account-1" || cc.Properties.InvoiceEntityID != "billing-account-1" {
+					t.Errorf("%s: unexpected account %s and invoice entity %s", cc.Properties.ProviderID, cc.Properties.AccountID, cc.Properties.InvoiceEntityID)
+				}
+				if cc.Properties.Category != exp.category {
+					t.Errorf("%s: expected category %s, got %s", cc.Properties.ProviderID, exp.category, cc.Properties.Category)
+				}
+				for metric, values := range map[string][2]float64{
+					"list":          {exp.listCost, cc.ListCost.Cost},
+					"net":           {exp.billedCost, cc.NetCost.Cost},
+					"invoiced":      {exp.billedCost, cc.InvoicedCost.Cost},
+					"amortized net": {exp.effectiveCost, cc.AmortizedNetCost.Cost},
+					"amortized":     {exp.effectiveCost, cc.AmortizedCost.Cost},
+				} {
+					if math.Abs(values[0]-values[1]) > 1e-9 {
+						t.Errorf("%s: expected %s cost %f, got %f", cc.Properties.ProviderID, metric, values[0], values[1])
+					}
+				}
+				if cc.NetCost.KubernetesPercent != exp.k8sPct {
+					t.Errorf("%s: expected kubernetes percent %f, got %f", cc.Properties.ProviderID, exp.k8sPct, cc.NetCost.KubernetesPercent)
+				}
+			}
+		})
+	}
+
+	// Files which were last modified before the window are not read
+	si := &StorageIntegration{
+		StorageConnection: StorageConnection{
+			StorageConfiguration: StorageConfiguration{
+				Name: "focus",
+				Path: csvDir,
+			},
+		},
+	}
+	future := time.Now().UTC().Add(24 * time.Hour).Truncate(24 * time.Hour)
+	_, err = si.GetCloudCost(future, future.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if si.GetStatus() != cloud.MissingData {
+		t.Errorf("expected status %s, got %s", cloud.MissingData, si.GetStatus())
+	}
+}
+
+func TestStorageConfiguration_Sanitize(t *testing.T) {
+	config := &StorageConfiguration{
+		Name: "reseller",
+		Path: "exports/focus",
+		BucketConfig: map[string]any{
+			"type": "S3",
+			"config": map[string]any{
+				"bucket":     "billing",
+				"access_key": "AKIA",
+				"secret_key": "secret",
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sanitized := config.Sanitize().(*StorageConfiguration)
+	bucketConfig := sanitized.BucketConfig["config"].(map[string]any)
+	if bucketConfig["secret_key"] != cloud.Redacted {
+		t.Errorf("expected secret key to be redacted, got %v", bucketConfig["secret_key"])
+	}
+	if bucketConfig["bucket"] != "billing" {
+		t.Errorf("expected bucket to be kept, got %v", bucketConfig["bucket"])
+	}
+	if config.BucketConfig["config"].(map[string]any)["secret_key"] != "secret" {
+		t.Errorf("expected original config to be unchanged")
+	}
+	if sanitized.Equals(config) {
+		t.Errorf("expected sanitized config not to equal original")
+	}
+
+	if err := (&StorageConfiguration{Name: "missing path"}).Validate(); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+}
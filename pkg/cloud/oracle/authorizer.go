@@ -0,0 +1,118 @@
+package oracle
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/oracle/oci-go-sdk/v65/common"
+)
+
+const APIKeyAuthorizerType = "OCIAPIKey"
+
+// Authorizer signs requests to the OCI APIs
+type Authorizer interface {
+	cloud.Authorizer
+	SignRequest(req *http.Request) error
+}
+
+// SelectAuthorizerByType is an implementation of AuthorizerSelectorFn and acts as a register for Authorizer types
+func SelectAuthorizerByType(typeStr string) (Authorizer, error) {
+	switch typeStr {
+	case APIKeyAuthorizerType:
+		return &APIKey{}, nil
+	default:
+		return nil, fmt.Errorf("oracle: provider authorizer type '%s' is not valid", typeStr)
+	}
+}
+
+// APIKey holds the API signing key of an OCI user
+type APIKey struct {
+	TenancyID   string `json:"tenancyID"`
+	UserID      string `json:"userID"`
+	Fingerprint string `json:"fingerprint"`
+	PrivateKey  string `json:"privateKey"`
+}
+
+// MarshalJSON custom json marshalling functions, sets properties as tagged in struct and sets the authorizer type property
+func (ak *APIKey) MarshalJSON() ([]byte, error) {
+	fmap := make(map[string]any, 5)
+	fmap[cloud.AuthorizerTypeProperty] = APIKeyAuthorizerType
+	fmap["tenancyID"] = ak.TenancyID
+	fmap["userID"] = ak.UserID
+	fmap["fingerprint"] = ak.Fingerprint
+	fmap["privateKey"] = ak.PrivateKey
+	return json.Marshal(fmap)
+}
+
+func (ak *APIKey) Validate() error {
+	if ak.TenancyID == "" {
+		return fmt.Errorf("APIKey: missing tenancy ID")
+	}
+	if ak.UserID == "" {
+		return fmt.Errorf("APIKey: missing user ID")
+	}
+	if ak.Fingerprint == "" {
+		return fmt.Errorf("APIKey: missing fingerprint")
+	}
+	if ak.PrivateKey == "" {
+		return fmt.Errorf("APIKey: missing private key")
+	}
+	return nil
+}
+
+func (ak *APIKey) Equals(config cloud.Config) bool {
+	if config == nil {
+		return false
+	}
+	thatConfig, ok := config.(*APIKey)
+	if !ok {
+		return false
+	}
+
+	if ak.TenancyID != thatConfig.TenancyID {
+		return false
+	}
+	if ak.UserID != thatConfig.UserID {
+		return false
+	}
+	if ak.Fingerprint != thatConfig.Fingerprint {
+		return false
+	}
+	if ak.PrivateKey != thatConfig.PrivateKey {
+		return false
+	}
+	return true
+}
+
+func (ak *APIKey) Sanitize() cloud.Config {
+	return &APIKey{
+		TenancyID:   ak.TenancyID,
+		UserID:      ak.UserID,
+		Fingerprint: ak.Fingerprint,
+		PrivateKey:  cloud.Redacted,
+	}
+}
+
+// SignRequest signs a request with the HTTP signature which OCI uses to authenticate API keys, using the signer of the
+// OCI SDK. See https://docs.oracle.com/en-us/iaas/Content/API/Concepts/signingrequests.htm
+func (ak *APIKey) SignRequest(req *http.Request) error {
+	err := ak.Validate()
+	if err != nil {
+		return err
+	}
+
+	if req.Header.Get("date") == "" {
+		req.Header.Set("date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	// the region is not part of the signature
+	provider := common.NewRawConfigurationProvider(ak.TenancyID, ak.UserID, "", ak.Fingerprint, ak.PrivateKey, nil)
+	err = common.DefaultRequestSigner(provider).Sign(req)
+	if err != nil {
+		return fmt.Errorf("APIKey: failed to sign request: %w", err)
+	}
+	return nil
+}
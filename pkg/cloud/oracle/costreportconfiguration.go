@@ -0,0 +1,202 @@
+package oracle
+
+import (
+	"fmt"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/json"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+const (
+	// costReportNamespace is the Object Storage namespace in which OCI writes the cost reports of every tenancy
+	costReportNamespace = "bling"
+	costReportPrefix    = "reports/cost-csv/"
+)
+
+// CostReportConfiguration is the configuration for the Cost and Usage Reports which OCI writes to Object Storage.
+// By default, the reports are read from the bucket of the tenancy in the "bling" namespace.
+type CostReportConfiguration struct {
+	TenancyID  string     `json:"tenancyID"`
+	Region     string     `json:"region"`
+	Namespace  string     `json:"namespace,omitempty"`
+	Bucket     string     `json:"bucket,omitempty"`
+	Prefix     string     `json:"prefix,omitempty"`
+	Endpoint   string     `json:"endpoint,omitempty"`
+	Authorizer Authorizer `json:"authorizer"`
+}
+
+func (crc *CostReportConfiguration) Validate() error {
+	// Validate Authorizer
+	if crc.Authorizer == nil {
+		return fmt.Errorf("CostReportConfiguration: missing authorizer")
+	}
+
+	err := crc.Authorizer.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Validate base properties
+	if crc.TenancyID == "" {
+		return fmt.Errorf("CostReportConfiguration: missing tenancy ID")
+	}
+
+	if crc.Region == "" {
+		return fmt.Errorf("CostReportConfiguration: missing region")
+	}
+
+	_, err = cloud.ParseEndpoint(crc.Endpoint)
+	if err != nil {
+		return fmt.Errorf("CostReportConfiguration: %w", err)
+	}
+	return nil
+}
+
+func (crc *CostReportConfiguration) Equals(config cloud.Config) bool {
+	if config == nil {
+		return false
+	}
+	thatConfig, ok := config.(*CostReportConfiguration)
+	if !ok {
+		return false
+	}
+
+	if crc.Authorizer != nil {
+		if !crc.Authorizer.Equals(thatConfig.Authorizer) {
+			return false
+		}
+	} else {
+		if thatConfig.Authorizer != nil {
+			return false
+		}
+	}
+
+	if crc.TenancyID != thatConfig.TenancyID {
+		return false
+	}
+
+	if crc.Region != thatConfig.Region {
+		return false
+	}
+
+	if crc.Namespace != thatConfig.Namespace {
+		return false
+	}
+
+	if crc.Bucket != thatConfig.Bucket {
+		return false
+	}
+
+	if crc.Prefix != thatConfig.Prefix {
+		return false
+	}
+
+	if crc.Endpoint != thatConfig.Endpoint {
+		return false
+	}
+	return true
+}
+
+func (crc *CostReportConfiguration) Sanitize() cloud.Config {
+	return &CostReportConfiguration{
+		TenancyID:  crc.TenancyID,
+		Region:     crc.Region,
+		Namespace:  crc.Namespace,
+		Bucket:     crc.Bucket,
+		Prefix:     crc.Prefix,
+		Endpoint:   crc.Endpoint,
+		Authorizer: crc.Authorizer.Sanitize().(Authorizer),
+	}
+}
+
+func (crc *CostReportConfiguration) Key() string {
+	return fmt.Sprintf("%s/%s", crc.TenancyID, crc.GetBucket())
+}
+
+func (crc *CostReportConfiguration) Provider() string {
+	return opencost.OracleProvider
+}
+
+// GetNamespace returns the Object Storage namespace of the reports, which defaults to "bling"
+func (crc *CostReportConfiguration) GetNamespace() string {
+	if crc.Namespace == "" {
+		return costReportNamespace
+	}
+	return crc.Namespace
+}
+
+// GetBucket returns the bucket of the reports, which defaults to the tenancy ID
+func (crc *CostReportConfiguration) GetBucket() string {
+	if crc.Bucket == "" {
+		return crc.TenancyID
+	}
+	return crc.Bucket
+}
+
+// GetPrefix returns the object name prefix of the reports, which defaults to "reports/cost-csv/"
+func (crc *CostReportConfiguration) GetPrefix() string {
+	if crc.Prefix == "" {
+		return costReportPrefix
+	}
+	return crc.Prefix
+}
+
+// GetEndpoint returns the Object Storage endpoint, which defaults to the endpoint of the region
+func (crc *CostReportConfiguration) GetEndpoint() string {
+	if crc.Endpoint == "" {
+		return fmt.Sprintf(objectStorageEndpointFmt, crc.Region)
+	}
+	return crc.Endpoint
+}
+
+func (crc *CostReportConfiguration) UnmarshalJSON(b []byte) error {
+	var f interface{}
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return err
+	}
+
+	fmap := f.(map[string]interface{})
+
+	tenancyID, err := cloud.GetInterfaceValue[string](fmap, "tenancyID")
+	if err != nil {
+		return fmt.Errorf("CostReportConfiguration: UnmarshalJSON: %s", err.Error())
+	}
+	crc.TenancyID = tenancyID
+
+	region, err := cloud.GetInterfaceValue[string](fmap, "region")
+	if err != nil {
+		return fmt.Errorf("CostReportConfiguration: UnmarshalJSON: %s", err.Error())
+	}
+	crc.Region = region
+
+	// The location of the reports and the endpoint are optional
+	for property, value := range map[string]*string{
+		"namespace": &crc.Namespace,
+		"bucket":    &crc.Bucket,
+		"prefix":    &crc.Prefix,
+		"endpoint":  &crc.Endpoint,
+	} {
+		if _, ok := fmap[property]; !ok {
+			continue
+		}
+		str, err := cloud.GetInterfaceValue[string](fmap, property)
+		if err != nil {
+			return fmt.Errorf("CostReportConfiguration: UnmarshalJSON: %s", err.Error())
+		}
+		*value = str
+	}
+
+	authAny, ok := fmap["authorizer"]
+	if !ok {
+		return fmt.Errorf("CostReportConfiguration: UnmarshalJSON: missing authorizer")
+	}
+	authorizer, err := cloud.AuthorizerFromInterface(authAny, SelectAuthorizerByType)
+	if err != nil {
+		return fmt.Errorf("CostReportConfiguration: UnmarshalJSON: %s", err.Error())
+	}
+	crc.Authorizer = authorizer
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package oracle
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+// Columns of the OCI cost reports. Tags are in one column each, prefixed with "tags/".
+// See https://docs.oracle.com/en-us/iaas/Content/Billing/Concepts/costusagereportsoverview.htm
+const (
+	ReferenceNoColumnName        = "lineItem/referenceNo"
+	TenantIDColumnName           = "lineItem/tenantId"
+	IntervalUsageStartColumnName = "lineItem/intervalUsageStart"
+	IntervalUsageEndColumnName   = "lineItem/intervalUsageEnd"
+	IsCorrectionColumnName       = "lineItem/isCorrection"
+	BackReferenceNoColumnName    = "lineItem/backreferenceNo"
+	ServiceColumnName            = "product/service"
+	CompartmentIDColumnName      = "product/compartmentId"
+	ResourceIDColumnName         = "product/resourceId"
+	MyCostColumnName             = "cost/myCost"
+	TagColumnPrefix              = "tags/"
+)
+
+// costReportLag is how long after the end of the window reports may be created which contain its usage
+const costReportLag = 2 * timeutil.Day
+
+var costReportTimeLayouts = []string{
+	"2006-01-02T15:04Z",
+	time.RFC3339,
+}
+
+type CostReportIntegration struct {
+	ObjectStorageConnection
+}
+
+// GetCloudCost reads the cost reports which were created during the window, or shortly after it, and loads the line
+// items of usage in the window. Line items which were corrected by a later report are replaced by their correction.
+func (cri *CostReportIntegration) GetCloudCost(start time.Time, end time.Time) (*opencost.CloudCostSetRange, error) {
+	log.Infof("CostReportIntegration[%s]: GetCloudCost: %s", cri.Key(), opencost.NewWindow(&start, &end).String())
+
+	ccsr, err := opencost.NewCloudCostSetRange(start, end, opencost.AccumulateOptionDay, cri.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	err = cri.Validate()
+	if err != nil {
+		cri.ConnectionStatus = cloud.InvalidConfiguration
+		return nil, err
+	}
+
+	ctx := context.TODO()
+	objects, err := cri.ListObjects(ctx, cri.GetPrefix())
+	if err != nil {
+		cri.ConnectionStatus = cloud.FailedConnection
+		return nil, err
+	}
+
+	lineItems := map[string]*opencost.CloudCost{}
+	corrected := map[string]bool{}
+	for _, object := range objects {
+		if object.TimeCreated.Before(start) || !object.TimeCreated.Before(end.Add(costReportLag)) {
+			continue
+		}
+
+		err = cri.parseCostReport(ctx, object.Name, start, end, func(referenceNo, backReferenceNo string, cc *opencost.CloudCost) {
+			lineItems[referenceNo] = cc
+			if backReferenceNo != "" {
+				corrected[backReferenceNo] = true
+			}
+		})
+		if err != nil {
+			cri.ConnectionStatus = cloud.FailedConnection
+			return nil, err
+		}
+	}
+
+	for referenceNo, cc := range lineItems {
+		if corrected[referenceNo] {
+			continue
+		}
+		ccsr.LoadCloudCost(cc)
+	}
+
+	cri.ConnectionStatus = cloud.ResultStatus(len(lineItems) > 0, cri.ConnectionStatus)
+	return ccsr, nil
+}
+
+// parseCostReport calls fn with the CloudCost of each line item of the report with usage in the window
+func (cri *CostReportIntegration) parseCostReport(ctx context.Context, name string, start, end time.Time, fn func(string, string, *opencost.CloudCost)) error {
+	body, err := cri.GetObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var reader io.Reader = body
+	if strings.HasSuffix(name, ".gz") {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("parseCostReport: %s: %w", name, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	csvReader := csv.NewReader(reader)
+	header, err := csvReader.Read()
+	if err != nil {
+		return fmt.Errorf("parseCostReport: %s: failed to read header: %w", name, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[column] = i
+	}
+	for _, column := range []string{ReferenceNoColumnName, IntervalUsageStartColumnName, IntervalUsageEndColumnName, ServiceColumnName, ResourceIDColumnName, MyCostColumnName} {
+		if _, ok := columns[column]; !ok {
+			return fmt.Errorf("parseCostReport: %s: missing column %s", name, column)
+		}
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parseCostReport: %s: %w", name, err)
+		}
+
+		cc, err := costReportRecordToCloudCost(record, columns)
+		if err != nil {
+			log.Warnf("CostReportIntegration[%s]: skipping line item of %s: %s", cri.Key(), name, err)
+			continue
+		}
+		if cc == nil {
+			continue
+		}
+		if usageStart := *cc.Window.Start(); usageStart.Before(start) || !usageStart.Before(end) {
+			continue
+		}
+
+		backReferenceNo := ""
+		if strings.EqualFold(getColumn(record, columns, IsCorrectionColumnName), "true") {
+			backReferenceNo = getColumn(record, columns, BackReferenceNoColumnName)
+		}
+		fn(getColumn(record, columns, ReferenceNoColumnName), backReferenceNo, cc)
+	}
+}
+
+// costReportRecordToCloudCost creates the CloudCost of a line item, or nil if it has no cost
+func costReportRecordToCloudCost(record []string, columns map[string]int) (*opencost.CloudCost, error) {
+	costStr := getColumn(record, columns, MyCostColumnName)
+	if costStr == "" {
+		return nil, nil
+	}
+	cost, err := strconv.ParseFloat(costStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cost '%s': %w", costStr, err)
+	}
+	if cost == 0 {
+		return nil, nil
+	}
+
+	usageStart, err := parseCostReportTime(getColumn(record, columns, IntervalUsageStartColumnName))
+	if err != nil {
+		return nil, err
+	}
+	usageEnd, err := parseCostReportTime(getColumn(record, columns, IntervalUsageEndColumnName))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for column, i := range columns {
+		if strings.HasPrefix(column, TagColumnPrefix) && i < len(record) && record[i] != "" {
+			labels[strings.TrimPrefix(column, TagColumnPrefix)] = record[i]
+		}
+	}
+
+	k8sPct := 0.0
+	if OCIIsK8s(labels) {
+		k8sPct = 1.0
+	}
+
+	providerID := getColumn(record, columns, ResourceIDColumnName)
+	service := getColumn(record, columns, ServiceColumnName)
+
+	// The reports only have the cost at the rates of the tenancy, which is used for all costs
+	return &opencost.CloudCost{
+		Properties: &opencost.CloudCostProperties{
+			ProviderID:      providerID,
+			Provider:        opencost.OracleProvider,
+			AccountID:       getColumn(record, columns, CompartmentIDColumnName),
+			InvoiceEntityID: getColumn(record, columns, TenantIDColumnName),
+			Service:         service,
+			Category:        SelectOCICategory(providerID, service),
+			Labels:          labels,
+		},
+		Window: opencost.NewWindow(&usageStart, &usageEnd),
+		ListCost: opencost.CostMetric{
+			Cost:              cost,
+			KubernetesPercent: k8sPct,
+		},
+		NetCost: opencost.CostMetric{
+			Cost:              cost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedNetCost: opencost.CostMetric{
+			Cost:              cost,
+			KubernetesPercent: k8sPct,
+		},
+		InvoicedCost: opencost.CostMetric{
+			Cost:              cost,
+			KubernetesPercent: k8sPct,
+		},
+		AmortizedCost: opencost.CostMetric{
+			Cost:              cost,
+			KubernetesPercent: k8sPct,
+		},
+	}, nil
+}
+
+func getColumn(record []string, columns map[string]int, column string) string {
+	i, ok := columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseCostReportTime(str string) (time.Time, error) {
+	for _, layout := range costReportTimeLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid usage interval time '%s'", str)
+}
+
+// OCIIsK8s checks for the tags which OKE adds to the resources of a cluster
+func OCIIsK8s(labels map[string]string) bool {
+	for key, value := range labels {
+		if strings.HasPrefix(key, "orcl-containerengine") {
+			return true
+		}
+		if strings.HasPrefix(value, "ocid1.nodepool.") {
+			return true
+		}
+		if strings.HasPrefix(value, "ocid1.cluster.") {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectOCICategory processes the resource ID and service of a line item to the associated category
+func SelectOCICategory(providerID, service string) string {
+	switch {
+	case strings.HasPrefix(providerID, "ocid1.instance."):
+		return opencost.ComputeCategory
+	case strings.HasPrefix(providerID, "ocid1.volume."), strings.HasPrefix(providerID, "ocid1.bootvolume."):
+		return opencost.StorageCategory
+	case strings.HasPrefix(providerID, "ocid1.loadbalancer."), strings.HasPrefix(providerID, "ocid1.networkloadbalancer."):
+		return opencost.NetworkCategory
+	case strings.HasPrefix(providerID, "ocid1.cluster."):
+		return opencost.ManagementCategory
+	}
+
+	switch strings.ToUpper(service) {
+	case "COMPUTE":
+		return opencost.ComputeCategory
+	case "BLOCK_STORAGE", "OBJECT_STORAGE", "FILE_STORAGE":
+		return opencost.StorageCategory
+	case "NETWORK", "LOAD_BALANCER", "NETWORK_LOAD_BALANCER", "FASTCONNECT", "DNS":
+		return opencost.NetworkCategory
+	case "OKE", "CONTAINER_ENGINE":
+		return opencost.ManagementCategory
+	default:
+		return opencost.OtherCategory
+	}
+}
@@ -0,0 +1,281 @@
+package oracle
+
+import (
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+const testTenancyID = "ocid1.tenancy.oc1..aaaatenancy"
+
+var signatureRegex = regexp.MustCompile(`signature="([^"]+)"`)
+
+// newTestObjectStorageServer serves the recorded cost reports from the bling namespace, verifying the signature of
+// each request with the public key
+func newTestObjectStorageServer(t *testing.T, publicKey *rsa.PublicKey) *httptest.Server {
+	created := func(s string) time.Time {
+		tc, _ := time.Parse(time.RFC3339, s)
+		return tc
+	}
+	reports := map[string]struct {
+		file    string
+		created time.Time
+	}{
+		"reports/cost-csv/0001000000000000.csv.gz": {"", created("2024-04-20T06:00:00Z")},
+		"reports/cost-csv/0001000000000001.csv.gz": {"test/cost_report_1.csv", created("2024-05-01T06:00:00Z")},
+		"reports/cost-csv/0001000000000002.csv.gz": {"test/cost_report_2.csv", created("2024-05-01T12:00:00Z")},
+	}
+	names := []string{
+		"reports/cost-csv/0001000000000000.csv.gz",
+		"reports/cost-csv/0001000000000001.csv.gz",
+		"reports/cost-csv/0001000000000002.csv.gz",
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := signatureRegex.FindStringSubmatch(r.Header.Get("authorization"))
+		if match == nil {
+			http.Error(w, "missing signature", http.StatusUnauthorized)
+			return
+		}
+		signature, _ := base64.StdEncoding.DecodeString(match[1])
+		signingString := fmt.Sprintf("date: %s\n(request-target): get %s\nhost: %s", r.Header.Get("date"), r.URL.RequestURI(), r.Host)
+		hashed := sha256.Sum256([]byte(signingString))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature); err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		bucketPath := fmt.Sprintf("/n/bling/b/%s/o", testTenancyID)
+		switch {
+		case r.URL.Path == bucketPath:
+			if r.URL.Query().Get("prefix") != costReportPrefix {
+				t.Errorf("unexpected prefix %s", r.URL.Query().Get("prefix"))
+			}
+			// return the reports in pages of two
+			page := listObjectsResponse{}
+			startIndex := 0
+			for i, name := range names {
+				if name == r.URL.Query().Get("start") {
+					startIndex = i
+				}
+			}
+			for _, name := range names[startIndex:] {
+				if len(page.Objects) == 2 {
+					page.NextStartWith = name
+					break
+				}
+				page.Objects = append(page.Objects, ObjectSummary{Name: name, TimeCreated: reports[name].created})
+			}
+			json.NewEncoder(w).Encode(page)
+		case strings.HasPrefix(r.URL.Path, bucketPath+"/"):
+			name := strings.TrimPrefix(r.URL.Path, bucketPath+"/")
+			report, ok := reports[name]
+			if !ok || report.file == "" {
+				t.Errorf("unexpected report %s", name)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			data, err := os.ReadFile(report.file)
+			if err != nil {
+				t.Fatalf("failed to read report: %s", err)
+			}
+			gw := gzip.NewWriter(w)
+			gw.Write(data)
+			gw.Close()
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestAPIKey(t *testing.T) (*APIKey, *rsa.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+	return &APIKey{
+		TenancyID:   testTenancyID,
+		UserID:      "ocid1.user.oc1..aaaauser",
+		Fingerprint: "20:3b:97:13:55:1c:5b:0d:d3:37:d8:50:4e:c5:3a:34",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}, &key.PublicKey
+}
+
+func TestCostReportIntegration_GetCloudCost(t *testing.T) {
+	apiKey, publicKey := newTestAPIKey(t)
+	server := newTestObjectStorageServer(t, publicKey)
+	defer server.Close()
+
+	cri := &CostReportIntegration{
+		ObjectStorageConnection: ObjectStorageConnection{
+			CostReportConfiguration: CostReportConfiguration{
+				TenancyID:  testTenancyID,
+				Region:     "us-ashburn-1",
+				Endpoint:   server.URL,
+				Authorizer: apiKey,
+			},
+		},
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ccsr, err := cri.GetCloudCost(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cri.GetStatus() != cloud.SuccessfulConnection {
+		t.Errorf("expected status %s, got %s", cloud.SuccessfulConnection, cri.GetStatus())
+	}
+	if len(ccsr.CloudCostSets) != 1 {
+		t.Fatalf("expected 1 cloud cost set, got %d", len(ccsr.CloudCostSets))
+	}
+
+	expected := map[string]struct {
+		category string
+		cost     float64
+		k8sPct   float64
+		team     string
+	}{
+		"ocid1.instance.oc1.iad.aaaainstance":         {opencost.ComputeCategory, 1.5, 1, ""},
+		"ocid1.volume.oc1.iad.aaaavolume":             {opencost.StorageCategory, 0.25, 0, ""},
+		"ocid1.loadbalancer.oc1.iad.aaaaloadbalancer": {opencost.NetworkCategory, 0.4, 0, "platform"},
+	}
+	ccs := ccsr.CloudCostSets[0]
+	if len(ccs.CloudCosts) != len(expected) {
+		t.Fatalf("expected %d cloud costs, got %d", len(expected), len(ccs.CloudCosts))
+	}
+	for _, cc := range ccs.CloudCosts {
+		exp, ok := expected[cc.Properties.ProviderID]
+		if !ok {
+			t.Errorf("unexpected provider ID %s", cc.Properties.ProviderID)
+			continue
+		}
+		if cc.Properties.Provider != opencost.OracleProvider {
+			t.Errorf("%s: expected provider %s, got %s", cc.Properties.ProviderID, opencost.OracleProvider, cc.Properties.Provider)
+		}
+		if cc.Properties.AccountID != "ocid1.compartment.oc1..aaaacompartment" {
+			t.Errorf("%s: expected compartment as account, got %s", cc.Properties.ProviderID, cc.Properties.AccountID)
+		}
+		if cc.Properties.InvoiceEntityID != testTenancyID {
+			t.Errorf("%s: expected tenancy as invoice entity, got %s", cc.Properties.ProviderID, cc.Properties.InvoiceEntityID)
+		}
+		if cc.Properties.Category != exp.category {
+			t.Errorf("%s: expected category %s, got %s", cc.Properties.ProviderID, exp.category, cc.Properties.Category)
+		}
+		if cc.Properties.Labels["team"] != exp.team {
+			t.Errorf("%s: expected team label %s, got %s", cc.Properties.ProviderID, exp.team, cc.Properties.Labels["team"])
+		}
+		if math.Abs(cc.NetCost.Cost-exp.cost) > 1e-9 {
+			t.Errorf("%s: expected net cost %f, got %f", cc.Properties.ProviderID, exp.cost, cc.NetCost.Cost)
+		}
+		if cc.NetCost.KubernetesPercent != exp.k8sPct {
+			t.Errorf("%s: expected kubernetes percent %f, got %f", cc.Properties.ProviderID, exp.k8sPct, cc.NetCost.KubernetesPercent)
+		}
+	}
+
+	// Reports created before the window are not read
+	cri.ConnectionStatus = cloud.InitialStatus
+	_, err = cri.GetCloudCost(start.AddDate(0, 1, 0), start.AddDate(0, 1, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cri.GetStatus() != cloud.MissingData {
+		t.Errorf("expected status %s, got %s", cloud.MissingData, cri.GetStatus())
+	}
+}
+
+func TestCostReportConfiguration_UnmarshalJSON(t *testing.T) {
+	apiKey, _ := newTestAPIKey(t)
+	config := &CostReportConfiguration{
+		TenancyID:  testTenancyID,
+		Region:     "us-ashburn-1",
+		Prefix:     "reports/cost-csv/2024",
+		Endpoint:   "https://objectstorage.example.com",
+		Authorizer: apiKey,
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %s", err)
+	}
+	unmarshalled := &CostReportConfiguration{}
+	err = json.Unmarshal(data, unmarshalled)
+	if err != nil {
+		t.Fatalf("failed to unmarshal config: %s", err)
+	}
+	if !config.Equals(unmarshalled) {
+		t.Errorf("expected %+v, got %+v", config, unmarshalled)
+	}
+	if unmarshalled.GetNamespace() != costReportNamespace || unmarshalled.GetBucket() != testTenancyID {
+		t.Errorf("expected default location, got %s/%s", unmarshalled.GetNamespace(), unmarshalled.GetBucket())
+	}
+
+	sanitized := config.Sanitize().(*CostReportConfiguration)
+	if sanitized.Authorizer.(*APIKey).PrivateKey != cloud.Redacted {
+		t.Errorf("expected private key to be redacted")
+	}
+}
+
+func TestCostReportConfiguration_Validate(t *testing.T) {
+	apiKey, _ := newTestAPIKey(t)
+	testCases := map[string]struct {
+		endpoint string
+		expected string
+	}{
+		"default endpoint": {
+			endpoint: "",
+			expected: "nil",
+		},
+		"endpoint": {
+			endpoint: "https://objectstorage.example.com:8443",
+			expected: "nil",
+		},
+		"endpoint missing scheme": {
+			endpoint: "objectstorage.example.com",
+			expected: "CostReportConfiguration: invalid endpoint 'objectstorage.example.com': scheme must be http or https",
+		},
+		"endpoint with path": {
+			endpoint: "https://objectstorage.example.com/n/bling",
+			expected: "CostReportConfiguration: invalid endpoint 'https://objectstorage.example.com/n/bling': must only have a scheme, host and port",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := &CostReportConfiguration{
+				TenancyID:  testTenancyID,
+				Region:     "us-ashburn-1",
+				Endpoint:   testCase.endpoint,
+				Authorizer: apiKey,
+			}
+			actual := "nil"
+			if err := config.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != testCase.expected {
+				t.Errorf("expected error '%s', got '%s'", testCase.expected, actual)
+			}
+		})
+	}
+}
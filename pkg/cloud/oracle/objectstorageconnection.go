@@ -0,0 +1,137 @@
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/opencost/opencost/pkg/cloud"
+)
+
+const objectStorageEndpointFmt = "https://objectstorage.%s.oraclecloud.com"
+
+// objectStorageClient bounds each request to Object Storage, including reading the body of the response, so that a
+// stalled response cannot hang ingestion
+var objectStorageClient = &http.Client{
+	Timeout: 10 * time.Minute,
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = time.Minute
+		return transport
+	}(),
+}
+
+// ObjectStorageConnection provides access to the cost reports in OCI Object Storage
+type ObjectStorageConnection struct {
+	CostReportConfiguration
+	ConnectionStatus cloud.ConnectionStatus
+}
+
+func (osc *ObjectStorageConnection) GetStatus() cloud.ConnectionStatus {
+	// initialize status if it has not done so; this can happen if the integration is inactive
+	if osc.ConnectionStatus.String() == "" {
+		osc.ConnectionStatus = cloud.InitialStatus
+	}
+	return osc.ConnectionStatus
+}
+
+func (osc *ObjectStorageConnection) Equals(config cloud.Config) bool {
+	thatConfig, ok := config.(*ObjectStorageConnection)
+	if !ok {
+		return false
+	}
+
+	return osc.CostReportConfiguration.Equals(&thatConfig.CostReportConfiguration)
+}
+
+// ObjectSummary is the name and creation time of an object in a bucket
+type ObjectSummary struct {
+	Name        string    `json:"name"`
+	TimeCreated time.Time `json:"timeCreated"`
+}
+
+type listObjectsResponse struct {
+	Objects       []ObjectSummary `json:"objects"`
+	NextStartWith string          `json:"nextStartWith"`
+}
+
+// ListObjects lists all objects in the bucket with the given prefix, following pagination
+func (osc *ObjectStorageConnection) ListObjects(ctx context.Context, prefix string) ([]ObjectSummary, error) {
+	var objects []ObjectSummary
+	start := ""
+	for {
+		query := url.Values{}
+		query.Set("prefix", prefix)
+		query.Set("fields", "name,timeCreated")
+		if start != "" {
+			query.Set("start", start)
+		}
+
+		resp, err := osc.get(ctx, fmt.Sprintf("/n/%s/b/%s/o", url.PathEscape(osc.GetNamespace()), url.PathEscape(osc.GetBucket())), query)
+		if err != nil {
+			return nil, fmt.Errorf("ListObjects: %w", err)
+		}
+
+		var page listObjectsResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("ListObjects: failed to decode response: %w", err)
+		}
+
+		objects = append(objects, page.Objects...)
+		if page.NextStartWith == "" {
+			return objects, nil
+		}
+		start = page.NextStartWith
+	}
+}
+
+// GetObject returns the content of an object in the bucket, which must be closed by the caller
+func (osc *ObjectStorageConnection) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := osc.get(ctx, fmt.Sprintf("/n/%s/b/%s/o/%s", url.PathEscape(osc.GetNamespace()), url.PathEscape(osc.GetBucket()), url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetObject: %s: %w", name, err)
+	}
+	return resp.Body, nil
+}
+
+// get performs a signed GET request against the Object Storage API, returning the response if it was successful
+func (osc *ObjectStorageConnection) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	endpoint, err := url.Parse(osc.GetEndpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	// path is escaped as object names may contain slashes
+	u := *endpoint
+	u.RawPath = path
+	u.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	err = osc.Authorizer.SignRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := objectStorageClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, body)
+	}
+	return resp, nil
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/opencost/opencost/pkg/cloud/alibaba"
 	"github.com/opencost/opencost/pkg/cloud/aws"
 	"github.com/opencost/opencost/pkg/cloud/azure"
+	"github.com/opencost/opencost/pkg/cloud/focus"
 	"github.com/opencost/opencost/pkg/cloud/gcp"
+	"github.com/opencost/opencost/pkg/cloud/oracle"
 )
 
 // CloudCostIntegration is an interface for retrieving daily granularity CloudCost data for a given range
@@ -87,9 +89,61 @@ func GetIntegrationFromConfig(kc cloud.KeyedConfig) CloudCostIntegration {
 		}
 	case *aws.S3SelectIntegration:
 		return keyedConfig
+	// Data Export Integration
+	case *aws.DataExportConfiguration:
+		return &aws.DataExportIntegration{
+			DataExportQuerier: aws.DataExportQuerier{
+				S3Connection: aws.S3Connection{
+					S3Configuration: keyedConfig.S3Configuration,
+				},
+				Prefix: keyedConfig.Prefix,
+			},
+		}
+	case *aws.DataExportQuerier:
+		return &aws.DataExportIntegration{
+			DataExportQuerier: *keyedConfig,
+		}
+	case *aws.DataExportIntegration:
+		return keyedConfig
 	// Alibaba BOA Integration
 	case *alibaba.BOAConfiguration:
-		return nil
+		return &alibaba.BoaIntegration{
+			BoaQuerier: alibaba.BoaQuerier{
+				BOAConfiguration: *keyedConfig,
+			},
+		}
+	case *alibaba.BoaQuerier:
+		return &alibaba.BoaIntegration{
+			BoaQuerier: *keyedConfig,
+		}
+	case *alibaba.BoaIntegration:
+		return keyedConfig
+	// OCI Cost Report Integration
+	case *oracle.CostReportConfiguration:
+		return &oracle.CostReportIntegration{
+			ObjectStorageConnection: oracle.ObjectStorageConnection{
+				CostReportConfiguration: *keyedConfig,
+			},
+		}
+	case *oracle.ObjectStorageConnection:
+		return &oracle.CostReportIntegration{
+			ObjectStorageConnection: *keyedConfig,
+		}
+	case *oracle.CostReportIntegration:
+		return keyedConfig
+	// FOCUS Storage Integration
+	case *focus.StorageConfiguration:
+		return &focus.StorageIntegration{
+			StorageConnection: focus.StorageConnection{
+				StorageConfiguration: *keyedConfig,
+			},
+		}
+	case *focus.StorageConnection:
+		return &focus.StorageIntegration{
+			StorageConnection: *keyedConfig,
+		}
+	case *focus.StorageIntegration:
+		return keyedConfig
 	default:
 		return nil
 	}
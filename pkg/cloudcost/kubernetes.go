@@ -0,0 +1,310 @@
+package cloudcost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/filter/cloudcost"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+)
+
+// IdleShareName is the key of the share of a resource's cost which no
+// namespace uses
+const IdleShareName = opencost.IdleSuffix
+
+// KubernetesAttribution is the use by Kubernetes of a cloud resource, such as
+// a node, disk or load balancer, on a day
+type KubernetesAttribution struct {
+	// ProviderID identifies the cloud resource
+	ProviderID string `json:"providerID"`
+	// KubernetesPercent is the fraction of the resource's cost which is
+	// Kubernetes spend
+	KubernetesPercent float64 `json:"kubernetesPercent"`
+	// Shares are the fractions of the resource's cost used by each namespace,
+	// with any unused cost under IdleShareName
+	Shares map[string]float64 `json:"shares"`
+}
+
+// KubernetesAttributionSource provides the attributions of the cloud
+// resources used by Kubernetes
+type KubernetesAttributionSource interface {
+	// Attributions returns the attributions of each resource used over the
+	// window, by lower-cased provider ID
+	Attributions(ctx context.Context, window opencost.Window) (map[string]*KubernetesAttribution, error)
+}
+
+// KubernetesAttributor periodically attributes the cloud costs of a
+// Repository to Kubernetes, setting the KubernetesPercent of the cloud costs
+// of resources used by Kubernetes and persisting the breakdown of each
+// resource's cost by namespace.
+type KubernetesAttributor struct {
+	repo      Repository
+	source    KubernetesAttributionSource
+	store     KubernetesAttributionStore
+	retention time.Duration
+
+	job timeutil.PeriodicJob
+}
+
+// NewKubernetesAttributor creates a KubernetesAttributor of the cloud costs
+// of the repository, retaining attributions in the store for the given
+// duration
+func NewKubernetesAttributor(repo Repository, source KubernetesAttributionSource, store KubernetesAttributionStore, retention time.Duration) *KubernetesAttributor {
+	return &KubernetesAttributor{
+		repo:      repo,
+		source:    source,
+		store:     store,
+		retention: retention,
+	}
+}
+
+// Start attributes each of the given number of most recent days, including
+// the current day, at each interval, starting immediately. Re-ingested cloud
+// costs are attributed again on the next run.
+func (ka *KubernetesAttributor) Start(interval time.Duration, days int) error {
+	return ka.job.Start(interval, func() {
+		ka.attributeRecent(days)
+	})
+}
+
+// Stop stops a started KubernetesAttributor
+func (ka *KubernetesAttributor) Stop() {
+	ka.job.Stop()
+}
+
+func (ka *KubernetesAttributor) attributeRecent(days int) {
+	today := time.Now().UTC().Truncate(timeutil.Day)
+	for i := days - 1; i >= 0; i-- {
+		err := ka.AttributeDay(context.Background(), today.Add(-time.Duration(i)*timeutil.Day))
+		if err != nil {
+			log.Warnf("CloudCost: Kubernetes attribution: %s", err)
+		}
+	}
+
+	err := ka.store.Expire(today.Add(-ka.retention))
+	if err != nil {
+		log.Warnf("CloudCost: Kubernetes attribution: failed to expire attributions: %s", err)
+	}
+}
+
+// AttributeDay attributes the cloud costs of the day starting at the given
+// time. The cloud costs of each resource used by Kubernetes are entirely
+// Kubernetes spend. Those of other resources are left unchanged, as they may
+// be used by clusters which are not monitored. Cloud costs are updated
+// atomically by the Repository, so data ingested concurrently is not
+// overwritten.
+func (ka *KubernetesAttributor) AttributeDay(ctx context.Context, start time.Time) error {
+	start = start.UTC()
+	window := opencost.NewClosedWindow(start, start.Add(timeutil.Day))
+
+	attributions, err := ka.source.Attributions(ctx, window)
+	if err != nil {
+		return fmt.Errorf("failed to attribute %s: %w", window, err)
+	}
+
+	err = ka.store.Save(start, attributions)
+	if err != nil {
+		return fmt.Errorf("failed to save attributions for %s: %w", window, err)
+	}
+
+	keys, err := ka.repo.Keys()
+	if err != nil {
+		return fmt.Errorf("failed to get integration keys: %w", err)
+	}
+
+	var errs []error
+	for _, key := range keys {
+		err = ka.repo.Update(start, key, func(ccs *opencost.CloudCostSet) bool {
+			return setKubernetesPercents(ccs, attributions) > 0
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to attribute cloud costs of %s for %s: %w", key, window, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setKubernetesPercents sets the KubernetesPercent of each cost metric of
+// the cloud costs of attributed resources, returning the number changed
+func setKubernetesPercents(ccs *opencost.CloudCostSet, attributions map[string]*KubernetesAttribution) int {
+	changed := 0
+	for _, cc := range ccs.CloudCosts {
+		if cc.Properties == nil || cc.Properties.ProviderID == "" {
+			continue
+		}
+		attribution, ok := attributions[strings.ToLower(cc.Properties.ProviderID)]
+		if !ok {
+			continue
+		}
+
+		pct := attribution.KubernetesPercent
+		if cc.ListCost.KubernetesPercent == pct && cc.NetCost.KubernetesPercent == pct &&
+			cc.AmortizedNetCost.KubernetesPercent == pct && cc.InvoicedCost.KubernetesPercent == pct &&
+			cc.AmortizedCost.KubernetesPercent == pct {
+			continue
+		}
+
+		cc.ListCost.KubernetesPercent = pct
+		cc.NetCost.KubernetesPercent = pct
+		cc.AmortizedNetCost.KubernetesPercent = pct
+		cc.InvoicedCost.KubernetesPercent = pct
+		cc.AmortizedCost.KubernetesPercent = pct
+		changed++
+	}
+	return changed
+}
+
+// Attribution returns the attribution of the resource on the day starting at
+// the given time, if it was used by Kubernetes
+func (ka *KubernetesAttributor) Attribution(day time.Time, providerID string) (*KubernetesAttribution, bool, error) {
+	attributions, err := ka.store.Load(day)
+	if err != nil {
+		return nil, false, err
+	}
+
+	attribution, ok := attributions[strings.ToLower(providerID)]
+	return attribution, ok, nil
+}
+
+// KubernetesBreakdown is the cost of a cloud resource over a window, and the
+// cost of it used by Kubernetes, by namespace
+type KubernetesBreakdown struct {
+	ProviderID     string             `json:"providerID"`
+	Category       string             `json:"category"`
+	Service        string             `json:"service"`
+	Cost           float64            `json:"cost"`
+	KubernetesCost float64            `json:"kubernetesCost"`
+	Namespaces     map[string]float64 `json:"namespaces"`
+}
+
+// Breakdown returns the Kubernetes breakdown of the cloud costs of each
+// resource used by Kubernetes on the days of the range, by descending cost
+func (ka *KubernetesAttributor) Breakdown(ccsr *opencost.CloudCostSetRange, costMetric opencost.CostMetricName) ([]*KubernetesBreakdown, error) {
+	byKey := map[string]*KubernetesBreakdown{}
+	for _, ccs := range ccsr.CloudCostSets {
+		if ccs.Window.IsOpen() {
+			continue
+		}
+
+		attributions, err := ka.store.Load(*ccs.Window.Start())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load attributions for %s: %w", ccs.Window, err)
+		}
+
+		for _, cc := range ccs.CloudCosts {
+			if cc.Properties == nil || cc.Properties.ProviderID == "" {
+				continue
+			}
+			attribution, ok := attributions[strings.ToLower(cc.Properties.ProviderID)]
+			if !ok {
+				continue
+			}
+
+			cm, err := cc.GetCostMetric(costMetric)
+			if err != nil {
+				return nil, err
+			}
+
+			key := strings.Join([]string{cc.Properties.ProviderID, cc.Properties.Category, cc.Properties.Service}, "/")
+			kb, ok := byKey[key]
+			if !ok {
+				kb = &KubernetesBreakdown{
+					ProviderID: cc.Properties.ProviderID,
+					Category:   cc.Properties.Category,
+					Service:    cc.Properties.Service,
+					Namespaces: map[string]float64{},
+				}
+				byKey[key] = kb
+			}
+
+			kubernetesCost := cm.Cost * attribution.KubernetesPercent
+			kb.Cost += cm.Cost
+			kb.KubernetesCost += kubernetesCost
+			for namespace, share := range attribution.Shares {
+				kb.Namespaces[namespace] += kubernetesCost * share
+			}
+		}
+	}
+
+	breakdowns := make([]*KubernetesBreakdown, 0, len(byKey))
+	for _, kb := range byKey {
+		breakdowns = append(breakdowns, kb)
+	}
+	sort.Slice(breakdowns, func(i, j int) bool {
+		if breakdowns[i].Cost != breakdowns[j].Cost {
+			return breakdowns[i].Cost > breakdowns[j].Cost
+		}
+		return breakdowns[i].ProviderID < breakdowns[j].ProviderID
+	})
+	return breakdowns, nil
+}
+
+// GetKubernetesBreakdownHandler returns the Kubernetes breakdown of the cloud
+// costs over the 'window', defaulting to the last 7 days, optionally
+// restricted by a cloud cost 'filter' and of a 'costMetric', defaulting to
+// amortizedNetCost
+func (ka *KubernetesAttributor) GetKubernetesBreakdownHandler(querier Querier) func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// If the attributor is nil, always return 501
+		if ka == nil || querier == nil {
+			http.Error(w, "Kubernetes Attributor is nil", http.StatusNotImplemented)
+			return
+		}
+
+		qp := httputil.NewQueryParams(r.URL.Query())
+
+		window, err := opencost.ParseWindowUTC(qp.Get("window", "7d"))
+		if err != nil || window.IsOpen() || window.IsNegative() {
+			http.Error(w, fmt.Sprintf("invalid window parameter: %s", qp.Get("window", "")), http.StatusBadRequest)
+			return
+		}
+
+		costMetric, err := opencost.ParseCostMetricName(qp.Get("costMetric", string(opencost.CostMetricAmortizedNetCost)))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid costMetric parameter: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		request := QueryRequest{
+			Start:      opencost.RoundBack(*window.Start(), timeutil.Day),
+			End:        opencost.RoundForward(*window.End(), timeutil.Day),
+			Accumulate: opencost.AccumulateOptionNone,
+			AggregateBy: []string{
+				opencost.CloudCostProviderIDProp,
+				opencost.CloudCostCategoryProp,
+				opencost.CloudCostServiceProp,
+			},
+		}
+		if qp.Has("filter") {
+			request.Filter, err = cloudcost.NewCloudCostFilterParser().Parse(qp.Get("filter", ""))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid filter parameter: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ccsr, err := querier.Query(r.Context(), request)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		breakdowns, err := ka.Breakdown(ccsr, costMetric)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		protocol.WriteData(w, breakdowns)
+	}
+}
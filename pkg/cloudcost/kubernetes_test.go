@@ -0,0 +1,181 @@
+package cloudcost
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+type mockAttributionSource map[string]*KubernetesAttribution
+
+func (m mockAttributionSource) Attributions(ctx context.Context, window opencost.Window) (map[string]*KubernetesAttribution, error) {
+	return m, nil
+}
+
+func TestKubernetesAttributor(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(timeutil.Day)
+
+	repo := NewMemoryRepository()
+	if err := repo.Put(DefaultMockCloudCostSet(start, end, "aws", "key-1")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// id1 is used by Kubernetes, despite its labels, and id3 is not observed,
+	// so keeps its percent
+	source := mockAttributionSource{
+		"id1": {
+			ProviderID:        "id1",
+			KubernetesPercent: 1,
+			Shares:            map[string]float64{"web": 0.75, IdleShareName: 0.25},
+		},
+	}
+	ka := NewKubernetesAttributor(repo, source, NewMemoryKubernetesAttributionStore(), 7*timeutil.Day)
+
+	if err := ka.AttributeDay(context.Background(), start); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ccs, err := repo.Get(start, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, cc := range ccs.CloudCosts {
+		if cc.Properties.ProviderID == "id1" && (cc.ListCost.KubernetesPercent != 1 || cc.NetCost.KubernetesPercent != 1) {
+			t.Errorf("expected id1 to be attributed to Kubernetes, got %f", cc.ListCost.KubernetesPercent)
+		}
+		if cc.Properties.ProviderID == "id3" && cc.NetCost.KubernetesPercent != 1 {
+			t.Errorf("expected id3 to keep its percent, got %f", cc.NetCost.KubernetesPercent)
+		}
+	}
+
+	if _, ok, err := ka.Attribution(start, "ID1"); err != nil || !ok {
+		t.Errorf("expected attribution of id1 regardless of case")
+	}
+
+	ccsr, err := NewRepositoryQuerier(repo).Query(context.Background(), QueryRequest{
+		Start:       start,
+		End:         end,
+		AggregateBy: []string{opencost.CloudCostProviderIDProp, opencost.CloudCostCategoryProp, opencost.CloudCostServiceProp},
+		Accumulate:  opencost.AccumulateOptionNone,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	breakdowns, err := ka.Breakdown(ccsr, opencost.CostMetricNetCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(breakdowns) != 1 {
+		t.Fatalf("expected 1 breakdown, got %d", len(breakdowns))
+	}
+
+	kb := breakdowns[0]
+	if kb.ProviderID != "id1" || kb.Cost != 100 || kb.KubernetesCost != 100 {
+		t.Errorf("unexpected breakdown: %+v", kb)
+	}
+	if math.Abs(kb.Namespaces["web"]-75) > 1e-9 || math.Abs(kb.Namespaces[IdleShareName]-25) > 1e-9 {
+		t.Errorf("unexpected namespace costs: %v", kb.Namespaces)
+	}
+}
+
+func TestKubernetesAttributor_Restart(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(timeutil.Day)
+	store := storage.NewFileStorage(t.TempDir())
+
+	repo := NewStorageRepository(store)
+	if err := repo.Put(DefaultMockCloudCostSet(start, end, "aws", "key-1")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := mockAttributionSource{
+		"id1": {
+			ProviderID:        "id1",
+			KubernetesPercent: 1,
+			Shares:            map[string]float64{"web": 1},
+		},
+	}
+	ka := NewKubernetesAttributor(repo, source, NewStorageKubernetesAttributionStore(store), 7*timeutil.Day)
+	if err := ka.AttributeDay(context.Background(), start); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A new attributor over the same storage, which has not attributed any
+	// day, still breaks down the retained cloud costs
+	restarted := NewKubernetesAttributor(NewStorageRepository(store), mockAttributionSource{}, NewStorageKubernetesAttributionStore(store), 7*timeutil.Day)
+
+	ccsr, err := NewRepositoryQuerier(NewStorageRepository(store)).Query(context.Background(), QueryRequest{
+		Start:       start,
+		End:         end,
+		AggregateBy: []string{opencost.CloudCostProviderIDProp, opencost.CloudCostCategoryProp, opencost.CloudCostServiceProp},
+		Accumulate:  opencost.AccumulateOptionNone,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	breakdowns, err := restarted.Breakdown(ccsr, opencost.CostMetricNetCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(breakdowns) != 1 || breakdowns[0].Namespaces["web"] != 100 {
+		t.Fatalf("expected breakdown of id1 after restart, got %+v", breakdowns)
+	}
+
+	// Expiring past the day removes its attributions
+	if err := NewStorageKubernetesAttributionStore(store).Expire(end); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok, err := restarted.Attribution(start, "id1"); err != nil || ok {
+		t.Errorf("expected attribution to be expired, got %t, %v", ok, err)
+	}
+}
+
+func TestMemoryRepository_Update(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(timeutil.Day)
+
+	repo := NewMemoryRepository()
+	if err := repo.Put(DefaultMockCloudCostSet(start, end, "aws", "key-1")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Data ingested while the update is in progress is not overwritten, as
+	// the Put waits for the update to finish
+	newer := DefaultMockCloudCostSet(start, end, "aws", "key-1")
+	newer.CloudCosts = map[string]*opencost.CloudCost{}
+
+	done := make(chan struct{})
+	err := repo.Update(start, "key-1", func(ccs *opencost.CloudCostSet) bool {
+		go func() {
+			defer close(done)
+			if err := repo.Put(newer); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+		return setKubernetesPercents(ccs, map[string]*KubernetesAttribution{"id1": {KubernetesPercent: 0.5}}) > 0
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	<-done
+
+	ccs, err := repo.Get(start, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ccs.CloudCosts) != 0 {
+		t.Errorf("expected newer cloud costs to be kept, got %d cloud costs", len(ccs.CloudCosts))
+	}
+
+	if err := repo.Update(start, "missing", func(*opencost.CloudCostSet) bool { return true }); err != nil {
+		t.Errorf("expected no error updating a missing set, got %s", err)
+	}
+}
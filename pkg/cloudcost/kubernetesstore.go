@@ -0,0 +1,171 @@
+package cloudcost
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+const kubernetesAttributionStorageDir = "cloudcost-kubernetes"
+
+// KubernetesAttributionStore persists the Kubernetes attributions of the
+// cloud resources used on each day
+type KubernetesAttributionStore interface {
+	// Save replaces the attributions of the day starting at the given time
+	Save(day time.Time, attributions map[string]*KubernetesAttribution) error
+
+	// Load returns the attributions of the day starting at the given time, by
+	// lower-cased provider ID, which are empty if none have been saved
+	Load(day time.Time) (map[string]*KubernetesAttribution, error)
+
+	// Expire deletes the attributions of all days starting before the limit
+	Expire(limit time.Time) error
+}
+
+// MemoryKubernetesAttributionStore is a KubernetesAttributionStore which holds
+// attributions in memory
+type MemoryKubernetesAttributionStore struct {
+	lock sync.RWMutex
+	days map[time.Time]map[string]*KubernetesAttribution
+}
+
+// NewMemoryKubernetesAttributionStore creates an empty
+// MemoryKubernetesAttributionStore
+func NewMemoryKubernetesAttributionStore() *MemoryKubernetesAttributionStore {
+	return &MemoryKubernetesAttributionStore{
+		days: map[time.Time]map[string]*KubernetesAttribution{},
+	}
+}
+
+func (ms *MemoryKubernetesAttributionStore) Save(day time.Time, attributions map[string]*KubernetesAttribution) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.days[day.UTC()] = attributions
+	return nil
+}
+
+func (ms *MemoryKubernetesAttributionStore) Load(day time.Time) (map[string]*KubernetesAttribution, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	attributions, ok := ms.days[day.UTC()]
+	if !ok {
+		return map[string]*KubernetesAttribution{}, nil
+	}
+	return attributions, nil
+}
+
+func (ms *MemoryKubernetesAttributionStore) Expire(limit time.Time) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	for day := range ms.days {
+		if day.Before(limit) {
+			delete(ms.days, day)
+		}
+	}
+	return nil
+}
+
+// StorageKubernetesAttributionStore is a KubernetesAttributionStore which
+// persists attributions to a storage.Storage, with one file per day, so that
+// the breakdown of retained cloud costs survives restarts
+type StorageKubernetesAttributionStore struct {
+	lock  sync.Mutex
+	store storage.Storage
+}
+
+// NewStorageKubernetesAttributionStore creates a
+// StorageKubernetesAttributionStore which writes to the given storage
+func NewStorageKubernetesAttributionStore(store storage.Storage) *StorageKubernetesAttributionStore {
+	return &StorageKubernetesAttributionStore{
+		store: store,
+	}
+}
+
+const kubernetesAttributionFileSuffix = ".json"
+
+func (ss *StorageKubernetesAttributionStore) path(day time.Time) string {
+	return path.Join(kubernetesAttributionStorageDir, day.UTC().Format(time.DateOnly)+kubernetesAttributionFileSuffix)
+}
+
+func (ss *StorageKubernetesAttributionStore) Save(day time.Time, attributions map[string]*KubernetesAttribution) error {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	data, err := json.Marshal(attributions)
+	if err != nil {
+		return fmt.Errorf("StorageKubernetesAttributionStore: Save: failed to marshal attributions: %w", err)
+	}
+
+	err = ss.store.Write(ss.path(day), data)
+	if err != nil {
+		return fmt.Errorf("StorageKubernetesAttributionStore: Save: failed to write %s: %w", ss.path(day), err)
+	}
+	return nil
+}
+
+func (ss *StorageKubernetesAttributionStore) Load(day time.Time) (map[string]*KubernetesAttribution, error) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	p := ss.path(day)
+
+	exists, err := ss.store.Exists(p)
+	if err != nil {
+		return nil, fmt.Errorf("StorageKubernetesAttributionStore: Load: failed to check %s: %w", p, err)
+	}
+	if !exists {
+		return map[string]*KubernetesAttribution{}, nil
+	}
+
+	data, err := ss.store.Read(p)
+	if err != nil {
+		return nil, fmt.Errorf("StorageKubernetesAttributionStore: Load: failed to read %s: %w", p, err)
+	}
+
+	attributions := map[string]*KubernetesAttribution{}
+	err = json.Unmarshal(data, &attributions)
+	if err != nil {
+		return nil, fmt.Errorf("StorageKubernetesAttributionStore: Load: failed to unmarshal %s: %w", p, err)
+	}
+	return attributions, nil
+}
+
+func (ss *StorageKubernetesAttributionStore) Expire(limit time.Time) error {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	files, err := ss.store.List(kubernetesAttributionStorageDir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("StorageKubernetesAttributionStore: Expire: failed to list %s: %w", kubernetesAttributionStorageDir, err)
+	}
+
+	for _, file := range files {
+		day, err := time.Parse(time.DateOnly, strings.TrimSuffix(path.Base(file.Name), kubernetesAttributionFileSuffix))
+		if err != nil {
+			log.Debugf("StorageKubernetesAttributionStore: Expire: skipping unrecognized file %s", file.Name)
+			continue
+		}
+
+		if day.Before(limit) {
+			p := path.Join(kubernetesAttributionStorageDir, path.Base(file.Name))
+			err = ss.store.Remove(p)
+			if err != nil && !storage.IsNotExist(err) {
+				return fmt.Errorf("StorageKubernetesAttributionStore: Expire: failed to remove %s: %w", p, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -83,6 +83,22 @@ func (m *MemoryRepository) Put(ccs *opencost.CloudCostSet) error {
 	return nil
 }
 
+func (m *MemoryRepository) Update(startTime time.Time, billingIntegration string, update func(*opencost.CloudCostSet) bool) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+
+	ccs, ok := m.data[billingIntegration][startTime.UTC()]
+	if !ok {
+		return nil
+	}
+
+	ccs = ccs.Clone()
+	if update(ccs) {
+		m.data[billingIntegration][startTime.UTC()] = ccs
+	}
+	return nil
+}
+
 // Expire deletes all items in the map with a start time before the given limit
 func (m *MemoryRepository) Expire(limit time.Time) error {
 	m.rwLock.Lock()
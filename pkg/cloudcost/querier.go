@@ -8,6 +8,7 @@ import (
 
 	"github.com/opencost/opencost/core/pkg/filter"
 	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/currency"
 )
 
 // Querier allows for querying ranges of CloudCost data
@@ -21,6 +22,8 @@ type QueryRequest struct {
 	AggregateBy []string
 	Accumulate  opencost.AccumulateOption
 	Filter      filter.Filter
+	// Conversion, if set, converts costs from the currency of each integration
+	Conversion *currency.Conversion
 }
 
 // DefaultChartItemsLength the default max number of items for a ViewGraphDataSet
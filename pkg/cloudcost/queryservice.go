@@ -1,26 +1,37 @@
 package cloudcost
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
 	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/currency"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/forecast"
+	"github.com/opencost/opencost/pkg/tabular"
 	"go.opentelemetry.io/otel"
 )
 
 const tracerName = "github.com/opencost/ooencost/pkg/cloudcost"
 
 const (
-	csvFormat = "csv"
+	csvFormat   = "csv"
+	focusFormat = "focus"
 )
 
 // QueryService surfaces endpoints for accessing CloudCost data in raw form or for display in views
 type QueryService struct {
 	Querier     Querier
 	ViewQuerier ViewQuerier
+	// Converter converts costs to the currency requested, if enabled
+	Converter *currency.Converter
 }
 
 func NewQueryService(querier Querier, viewQuerier ViewQuerier) *QueryService {
@@ -55,6 +66,43 @@ func (s *QueryService) GetCloudCostHandler() func(w http.ResponseWriter, r *http
 			return
 		}
 
+		request.Conversion, err = s.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Format is an optional parameter which returns the cloud costs as a "csv" or "parquet" table rather than JSON,
+		// or as "focus" charges, which are written as a CSV or Parquet file according to the 'focusFormat' parameter
+		var tableFormat tabular.Format
+		isFOCUS := false
+		if format := qp.Get("format", ""); strings.EqualFold(format, focusFormat) {
+			isFOCUS = true
+			tableFormat, err = tabular.ParseFormat(qp.Get("focusFormat", string(tabular.FormatCSV)))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid 'focusFormat' parameter: %s", err), http.StatusBadRequest)
+				return
+			}
+		} else if format != "" && !strings.EqualFold(format, "json") {
+			tableFormat, err = tabular.ParseFormat(format)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid 'format' parameter: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Stream one cloud cost per line if requested, rather than marshalling
+		// the entire range into memory at once
+		if tableFormat == "" && protocol.AcceptsNDJSON(r) {
+			nw := protocol.NewNDJSONWriter(w)
+			err = s.streamCloudCosts(ctx, *request, nw)
+			if err != nil {
+				log.Errorf("CloudCost: error streaming response: %s", err)
+				nw.WriteError(protocol.InternalServerError(err.Error()))
+			}
+			return
+		}
+
 		resp, err := s.Querier.Query(ctx, *request)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
@@ -62,8 +110,177 @@ func (s *QueryService) GetCloudCostHandler() func(w http.ResponseWriter, r *http
 		}
 
 		_, spanResp := tracer.Start(ctx, "write response")
+		// Tables have no envelope to report the currency conversion in, so it is reported in a header
+		if tableFormat != "" && request.Conversion != nil {
+			err = protocol.SetCurrencyHeader(w, request.Conversion)
+			if err != nil {
+				log.Errorf("CloudCost: error writing currency header: %s", err)
+			}
+		}
+		if isFOCUS {
+			billingCurrency := env.GetFOCUSBillingCurrency()
+			if request.Conversion != nil {
+				billingCurrency = request.Conversion.To
+			}
+			writeCloudCostFOCUS(w, tableFormat, resp, billingCurrency)
+			spanResp.End()
+			return
+		}
+		if tableFormat != "" {
+			writeCloudCostTable(w, tableFormat, resp, qp.GetList("labels", ","), qp.GetBool("labelsAll", false))
+			spanResp.End()
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		writeData(w, resp, request.Conversion)
+		spanResp.End()
+	}
+}
+
+// parseConversion parses the optional 'currency' parameter, returning the Conversion of costs to it, or nil if costs are
+// not converted
+func (s *QueryService) parseConversion(qp httputil.QueryParams) (*currency.Conversion, error) {
+	if !qp.Has("currency") {
+		return nil, nil
+	}
+	if s.Converter == nil {
+		return nil, fmt.Errorf("invalid 'currency' parameter: currency conversion is not enabled")
+	}
+
+	conversion, err := s.Converter.NewConversion(qp.Get("currency", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'currency' parameter: %w", err)
+	}
+	return conversion, nil
+}
+
+// writeData writes the data with the currency conversion applied to its costs, if any
+func writeData(w http.ResponseWriter, data interface{}, conversion *currency.Conversion) {
+	if conversion == nil {
+		protocol.WriteData(w, data)
+		return
+	}
+	protocol.WriteDataWithCurrency(w, data, conversion)
+}
+
+// streamCloudCosts queries the request one day at a time, writing each CloudCost as a line of NDJSON as each day is
+// produced, so that memory use does not grow with the window. Accumulated requests require the full range, so are
+// queried at once.
+func (s *QueryService) streamCloudCosts(ctx context.Context, request QueryRequest, nw *proto.NDJSONWriter) error {
+	windows := []opencost.Window{opencost.NewClosedWindow(request.Start, request.End)}
+	if request.Accumulate == opencost.AccumulateOptionNone {
+		var err error
+		windows, err = windows[0].GetAccumulateWindows(opencost.AccumulateOptionDay)
+		if err != nil {
+			return fmt.Errorf("failed to split window into days: %w", err)
+		}
+	}
+
+	for _, window := range windows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dayRequest := request
+		dayRequest.Start = *window.Start()
+		dayRequest.End = *window.End()
+
+		ccsr, err := s.Querier.Query(ctx, dayRequest)
+		if err != nil {
+			return err
+		}
+
+		for _, ccs := range ccsr.CloudCostSets {
+			for _, cc := range ccs.CloudCosts {
+				err = nw.Write(cc)
+				if err != nil {
+					return err
+				}
+			}
+			nw.Flush()
+		}
+	}
+
+	// The conversion is reported last, as the rates applied are only known once every day has been converted
+	if request.Conversion != nil {
+		return nw.WriteCurrency(request.Conversion)
+	}
+	return nil
+}
+
+// GetCloudCostForecastHandler forecasts the daily cost of each cloud cost, fit to the whole days of the window. It
+// accepts the same window, aggregate and filter parameters as /cloudCost, and the 'costMetric' to forecast. The
+// 'horizon' parameter is the duration to forecast, defaulting to the end of the month, and 'confidence' the confidence
+// level of the prediction bands.
+func (s *QueryService) GetCloudCostForecastHandler() func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Return valid handler func
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		tracer := otel.Tracer(tracerName)
+		ctx, span := tracer.Start(r.Context(), "Service.GetCloudCostForecastHandler")
+		defer span.End()
+
+		// If Query Service is nil, always return 501
+		if s == nil {
+			http.Error(w, "Query Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		if s.Querier == nil {
+			http.Error(w, "CloudCost Query Service is nil", http.StatusNotImplemented)
+			return
+		}
+
+		qp := httputil.NewQueryParams(r.URL.Query())
+		request, err := ParseCloudCostRequest(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		request.Conversion, err = s.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		costMetricName, err := opencost.ParseCostMetricName(qp.Get("costMetric", string(opencost.CostMetricAmortizedNetCost)))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error parsing 'costMetric': %s", err), http.StatusBadRequest)
+			return
+		}
+
+		history, err := forecast.HistoryWindow(opencost.NewClosedWindow(request.Start, request.End), time.Now(), 0)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid window parameter: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		opts, err := forecast.ParseOptions(qp, *history.End(), 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The forecast is fit to the cost of each day of the history
+		request.Start = *history.Start()
+		request.End = *history.End()
+		request.Accumulate = opencost.AccumulateOptionNone
+
+		ccsr, err := s.Querier.Query(ctx, *request)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := forecast.ForecastCloudCosts(ccsr, costMetricName, opts)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		_, spanResp := tracer.Start(ctx, "write response")
+		w.Header().Set("Content-Type", "application/json")
+		writeData(w, resp, request.Conversion)
 		spanResp.End()
 	}
 }
@@ -93,6 +310,12 @@ func (s *QueryService) GetCloudCostViewGraphHandler() func(w http.ResponseWriter
 			return
 		}
 
+		request.Conversion, err = s.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		resp, err := s.ViewQuerier.QueryViewGraph(ctx, *request)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
@@ -101,7 +324,7 @@ func (s *QueryService) GetCloudCostViewGraphHandler() func(w http.ResponseWriter
 
 		_, spanResp := tracer.Start(ctx, "write response")
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		writeData(w, resp, request.Conversion)
 		spanResp.End()
 	}
 }
@@ -131,6 +354,12 @@ func (s *QueryService) GetCloudCostViewTotalsHandler() func(w http.ResponseWrite
 			return
 		}
 
+		request.Conversion, err = s.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		resp, err := s.ViewQuerier.QueryViewTotals(ctx, *request)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
@@ -139,7 +368,7 @@ func (s *QueryService) GetCloudCostViewTotalsHandler() func(w http.ResponseWrite
 
 		_, spanResp := tracer.Start(ctx, "write response")
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		writeData(w, resp, request.Conversion)
 		spanResp.End()
 	}
 }
@@ -169,6 +398,12 @@ func (s *QueryService) GetCloudCostViewTableHandler() func(w http.ResponseWriter
 			return
 		}
 
+		request.Conversion, err = s.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		format := qp.Get("format", "json")
 		if strings.HasPrefix(format, csvFormat) {
 			w.Header().Set("Content-Type", "text/csv")
@@ -192,6 +427,6 @@ func (s *QueryService) GetCloudCostViewTableHandler() func(w http.ResponseWriter
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		writeData(w, resp, request.Conversion)
 	}
 }
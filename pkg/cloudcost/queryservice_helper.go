@@ -4,11 +4,16 @@ import (
 	"encoding/csv"
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/opencost/opencost/core/pkg/filter"
 	"github.com/opencost/opencost/core/pkg/filter/cloudcost"
+	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/cloud/focus"
+	"github.com/opencost/opencost/pkg/tabular"
 )
 
 func ParseCloudCostRequest(qp httputil.QueryParams) (*QueryRequest, error) {
@@ -147,3 +152,120 @@ func writeCloudCostViewTableRowsAsCSV(w http.ResponseWriter, ctr ViewTableRows,
 		return
 	}
 }
+
+// cloudCostRow is a CloudCost and its key in a CloudCostSet, which names it in the tabular formats of /cloudCost
+type cloudCostRow struct {
+	key string
+	cc  *opencost.CloudCost
+}
+
+// cloudCostColumnDefs returns the columns of the tabular formats of /cloudCost. If labelsAll is true, all labels are
+// written to a "Labels" column in JSON format, and a column prefixed with "Label_" is written for each of the given
+// labels, as with allocations.
+func cloudCostColumnDefs(labels []string, labelsAll bool) []tabular.ColumnDef[cloudCostRow] {
+	properties := func(row cloudCostRow) *opencost.CloudCostProperties {
+		if row.cc.Properties == nil {
+			return &opencost.CloudCostProperties{}
+		}
+		return row.cc.Properties
+	}
+
+	defs := []tabular.ColumnDef[cloudCostRow]{
+		tabular.StringColumnDef("WindowStart", func(row cloudCostRow) string {
+			return row.cc.Window.Start().UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("WindowEnd", func(row cloudCostRow) string {
+			return row.cc.Window.End().UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("Name", func(row cloudCostRow) string {
+			return row.key
+		}),
+		tabular.StringColumnDef("InvoiceEntityID", func(row cloudCostRow) string {
+			return properties(row).InvoiceEntityID
+		}),
+		tabular.StringColumnDef("AccountID", func(row cloudCostRow) string {
+			return properties(row).AccountID
+		}),
+		tabular.StringColumnDef("Provider", func(row cloudCostRow) string {
+			return properties(row).Provider
+		}),
+		tabular.StringColumnDef("ProviderID", func(row cloudCostRow) string {
+			return properties(row).ProviderID
+		}),
+		tabular.StringColumnDef("Category", func(row cloudCostRow) string {
+			return properties(row).Category
+		}),
+		tabular.StringColumnDef("Service", func(row cloudCostRow) string {
+			return properties(row).Service
+		}),
+		tabular.FloatColumnDef("ListCost", func(row cloudCostRow) float64 {
+			return row.cc.ListCost.Cost
+		}),
+		tabular.FloatColumnDef("NetCost", func(row cloudCostRow) float64 {
+			return row.cc.NetCost.Cost
+		}),
+		tabular.FloatColumnDef("AmortizedNetCost", func(row cloudCostRow) float64 {
+			return row.cc.AmortizedNetCost.Cost
+		}),
+		tabular.FloatColumnDef("InvoicedCost", func(row cloudCostRow) float64 {
+			return row.cc.InvoicedCost.Cost
+		}),
+		tabular.FloatColumnDef("AmortizedCost", func(row cloudCostRow) float64 {
+			return row.cc.AmortizedCost.Cost
+		}),
+		tabular.FloatColumnDef("KubernetesPercent", func(row cloudCostRow) float64 {
+			return row.cc.NetCost.KubernetesPercent
+		}),
+	}
+
+	return append(defs, tabular.LabelColumnDefs(labels, labelsAll, func(row cloudCostRow) map[string]string {
+		return properties(row).Labels
+	})...)
+}
+
+// writeCloudCostTable writes the CloudCosts of each set in the range as a table in the given format
+func writeCloudCostTable(w http.ResponseWriter, format tabular.Format, ccsr *opencost.CloudCostSetRange, labels []string, labelsAll bool) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.FileName("cloudcost")))
+
+	defs := cloudCostColumnDefs(labels, labelsAll)
+	tw, err := tabular.NewWriter(format, w, tabular.Columns(defs))
+	if err != nil {
+		protocol.WriteError(w, protocol.InternalServerError(err.Error()))
+		return
+	}
+
+	for _, ccs := range ccsr.CloudCostSets {
+		keys := make([]string, 0, len(ccs.CloudCosts))
+		for key := range ccs.CloudCosts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			err = tabular.WriteRow(tw, defs, cloudCostRow{key: key, cc: ccs.CloudCosts[key]})
+			if err != nil {
+				// The status has already been sent, so errors can only be logged
+				log.Errorf("CloudCost: error writing %s: %s", format, err)
+				return
+			}
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		log.Errorf("CloudCost: error writing %s: %s", format, err)
+	}
+}
+
+// writeCloudCostFOCUS writes the CloudCosts of each set in the range as FOCUS charges in the given format
+func writeCloudCostFOCUS(w http.ResponseWriter, format tabular.Format, ccsr *opencost.CloudCostSetRange, currency string) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.FileName("cloudcost_focus")))
+
+	err := focus.WriteCloudCosts(format, w, ccsr, currency)
+	if err != nil {
+		// The status has already been sent, so errors can only be logged
+		log.Errorf("CloudCost: error writing FOCUS %s: %s", format, err)
+	}
+}
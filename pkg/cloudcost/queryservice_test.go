@@ -0,0 +1,319 @@
+package cloudcost
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/currency"
+)
+
+func TestQueryService_GetCloudCostHandler_NDJSON(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryRepository()
+	for i := 0; i < 3; i++ {
+		dayStart := start.Add(time.Duration(i) * timeutil.Day)
+		err := repo.Put(DefaultMockCloudCostSet(dayStart, dayStart.Add(timeutil.Day), "gcp", "integration"))
+		if err != nil {
+			t.Fatalf("Put() unexpected error: %s", err)
+		}
+	}
+
+	querier := NewRepositoryQuerier(repo)
+	qs := NewQueryService(querier, querier)
+
+	// Count the cloud costs returned by the equivalent non-streaming query
+	want := 0
+	ccsr, err := querier.Query(context.Background(), QueryRequest{
+		Start:       start,
+		End:         start.Add(3 * timeutil.Day),
+		AggregateBy: []string{opencost.CloudCostProviderIDProp},
+	})
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %s", err)
+	}
+	for _, ccs := range ccsr.CloudCostSets {
+		want += len(ccs.CloudCosts)
+	}
+	if want == 0 {
+		t.Fatalf("expected mock data to produce cloud costs")
+	}
+
+	window := start.Format(time.RFC3339) + "," + start.Add(3*timeutil.Day).Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/cloudCost?aggregate=providerID&window="+url.QueryEscape(window), nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+
+	qs.GetCloudCostHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON content type, got %s", ct)
+	}
+
+	got := 0
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		cc := map[string]any{}
+		err := json.Unmarshal(scanner.Bytes(), &cc)
+		if err != nil {
+			t.Fatalf("line %d is not valid JSON: %s", got+1, err)
+		}
+		if _, ok := cc["window"]; !ok {
+			t.Errorf("line %d is not a cloud cost: %s", got+1, scanner.Text())
+		}
+		got++
+	}
+
+	if got != want {
+		t.Errorf("expected %d lines, got %d", want, got)
+	}
+}
+
+func TestQueryService_GetCloudCostHandler_Currency(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryRepository()
+	err := repo.Put(DefaultMockCloudCostSet(start, start.Add(timeutil.Day), "gcp", "integration"))
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %s", err)
+	}
+	querier := NewRepositoryQuerier(repo)
+	qs := NewQueryService(querier, querier)
+	qs.Converter = currency.NewConverter(func() ([]byte, error) {
+		return []byte(`{"base": "USD", "rates": [{"currency": "EUR", "date": "2024-01-01", "rate": 0.9}]}`), nil
+	}, "", 0)
+
+	const expected = `{"to":"EUR","rates":[{"from":"USD","date":"2024-01-01","rate":0.9}]}`
+	window := start.Format(time.RFC3339) + "," + start.Add(timeutil.Day).Format(time.RFC3339)
+
+	// Streamed responses report the conversion on their last line
+	req := httptest.NewRequest(http.MethodGet, "/cloudCost?currency=EUR&window="+url.QueryEscape(window), nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	qs.GetCloudCostHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	last := struct {
+		Currency json.RawMessage `json:"currency"`
+	}{}
+	err = json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+	if err != nil || string(last.Currency) != expected {
+		t.Errorf("expected last line to report the conversion %s, got %s", expected, lines[len(lines)-1])
+	}
+
+	// Tables report it in a header
+	req = httptest.NewRequest(http.MethodGet, "/cloudCost?format=csv&currency=EUR&window="+url.QueryEscape(window), nil)
+	rec = httptest.NewRecorder()
+	qs.GetCloudCostHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if h := rec.Header().Get(proto.CurrencyHeader); h != expected {
+		t.Errorf("expected currency header %s, got %s", expected, h)
+	}
+}
+
+func TestQueryService_GetCloudCostHandler_CSV(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryRepository()
+	err := repo.Put(DefaultMockCloudCostSet(start, start.Add(timeutil.Day), "gcp", "integration"))
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %s", err)
+	}
+	querier := NewRepositoryQuerier(repo)
+	qs := NewQueryService(querier, querier)
+
+	window := start.Format(time.RFC3339) + "," + start.Add(timeutil.Day).Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/cloudCost?format=csv&labels=label1&labelsAll=true&window="+url.QueryEscape(window), nil)
+	rec := httptest.NewRecorder()
+
+	qs.GetCloudCostHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected CSV content type, got %s", ct)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %s", err)
+	}
+	if len(records) < 2 {
+		t.Fatalf("expected a header and rows, got %v", records)
+	}
+
+	header := records[0]
+	if header[0] != "WindowStart" || header[len(header)-2] != "Labels" || header[len(header)-1] != "Label_label1" {
+		t.Errorf("unexpected header: %v", header)
+	}
+	for _, record := range records[1:] {
+		if record[len(record)-1] != "value1" {
+			t.Errorf("expected Label_label1 to be value1, got %v", record)
+		}
+	}
+
+	// Unsupported formats are rejected
+	req = httptest.NewRequest(http.MethodGet, "/cloudCost?format=xlsx&window="+url.QueryEscape(window), nil)
+	rec = httptest.NewRecorder()
+	qs.GetCloudCostHandler()(rec, req, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unsupported format, got %d", rec.Code)
+	}
+}
+
+func TestQueryService_GetCloudCostHandler_FOCUS(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryRepository()
+	err := repo.Put(DefaultMockCloudCostSet(start, start.Add(timeutil.Day), "gcp", "integration"))
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %s", err)
+	}
+	querier := NewRepositoryQuerier(repo)
+	qs := NewQueryService(querier, querier)
+
+	window := start.Format(time.RFC3339) + "," + start.Add(timeutil.Day).Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/cloudCost?format=focus&window="+url.QueryEscape(window), nil)
+	rec := httptest.NewRecorder()
+
+	qs.GetCloudCostHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected CSV content type, got %s", ct)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %s", err)
+	}
+	if len(records) < 2 {
+		t.Fatalf("expected a header and rows, got %v", records)
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"BilledCost", "EffectiveCost", "BillingCurrency", "ChargePeriodStart", "ProviderName", "ServiceCategory"} {
+		if _, ok := columns[name]; !ok {
+			t.Fatalf("expected column %s in header %v", name, records[0])
+		}
+	}
+	for _, record := range records[1:] {
+		if record[columns["BillingCurrency"]] != "USD" {
+			t.Errorf("expected BillingCurrency USD, got %s", record[columns["BillingCurrency"]])
+		}
+		if record[columns["ChargePeriodStart"]] != start.Format(time.RFC3339) {
+			t.Errorf("expected ChargePeriodStart %s, got %s", start.Format(time.RFC3339), record[columns["ChargePeriodStart"]])
+		}
+	}
+
+	// Parquet FOCUS files are requested with the focusFormat parameter
+	req = httptest.NewRequest(http.MethodGet, "/cloudCost?format=focus&focusFormat=parquet&window="+url.QueryEscape(window), nil)
+	rec = httptest.NewRecorder()
+	qs.GetCloudCostHandler()(rec, req, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.apache.parquet" {
+		t.Errorf("expected Parquet content type, got %s", ct)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/cloudCost?format=focus&focusFormat=xlsx&window="+url.QueryEscape(window), nil)
+	rec = httptest.NewRecorder()
+	qs.GetCloudCostHandler()(rec, req, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unsupported FOCUS format, got %d", rec.Code)
+	}
+}
+
+func TestQueryService_GetCloudCostForecastHandler(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemoryRepository()
+	for i := 0; i < 14; i++ {
+		dayStart := start.Add(time.Duration(i) * timeutil.Day)
+		err := repo.Put(DefaultMockCloudCostSet(dayStart, dayStart.Add(timeutil.Day), "gcp", "integration"))
+		if err != nil {
+			t.Fatalf("Put() unexpected error: %s", err)
+		}
+	}
+
+	querier := NewRepositoryQuerier(repo)
+	qs := NewQueryService(querier, querier)
+
+	window := start.Format(time.RFC3339) + "," + start.Add(14*timeutil.Day).Format(time.RFC3339)
+
+	req := httptest.NewRequest(http.MethodGet, "/cloudCost/forecast?aggregate=service&horizon=7d&window="+url.QueryEscape(window), nil)
+	rec := httptest.NewRecorder()
+	qs.GetCloudCostForecastHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			Forecasts map[string]struct {
+				Actual   float64 `json:"actual"`
+				Forecast struct {
+					Points []struct {
+						Cost float64 `json:"cost"`
+					} `json:"points"`
+				} `json:"forecast"`
+			} `json:"forecasts"`
+		} `json:"data"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("failed to unmarshal response: %s", err)
+	}
+
+	if len(resp.Data.Forecasts) == 0 {
+		t.Fatalf("expected forecasts, got none")
+	}
+	for name, f := range resp.Data.Forecasts {
+		if len(f.Forecast.Points) != 7 {
+			t.Errorf("%s: expected 7 forecast days, got %d", name, len(f.Forecast.Points))
+		}
+		// The mock costs are the same every day
+		for _, p := range f.Forecast.Points {
+			if expected := f.Actual / 14; math.Abs(p.Cost-expected) > 1e-6 {
+				t.Errorf("%s: expected forecast cost %f, got %f", name, expected, p.Cost)
+			}
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/cloudCost/forecast?confidence=2&window="+url.QueryEscape(window), nil)
+	rec = httptest.NewRecorder()
+	qs.GetCloudCostForecastHandler()(rec, req, nil)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid confidence, got %d", rec.Code)
+	}
+}
@@ -13,4 +13,10 @@ type Repository interface {
 	Keys() ([]string, error)
 	Put(*opencost.CloudCostSet) error
 	Expire(time.Time) error
+
+	// Update applies the update func to the CloudCostSet of the billing
+	// integration starting at the given time, if there is one, and saves it if
+	// the func returns true. No Put can interleave with the update, so that it
+	// cannot overwrite newer data with a stale set.
+	Update(time.Time, string, func(*opencost.CloudCostSet) bool) error
 }
@@ -49,6 +49,16 @@ func (rq *RepositoryQuerier) Query(ctx context.Context, request QueryRequest) (*
 				continue
 			}
 
+			// Costs are converted before aggregation, as each integration
+			// may bill in a different currency
+			if request.Conversion != nil {
+				ccs.Integration = key
+				err = request.Conversion.ConvertCloudCostSet(ccs)
+				if err != nil {
+					return nil, fmt.Errorf("RepositoryQuerier: Query: failed to convert currency: %w", err)
+				}
+			}
+
 			for _, cc := range ccs.CloudCosts {
 				if matcher.Matches(cc) {
 					cloudCostSet.Insert(cc)
@@ -0,0 +1,137 @@
+package cloudcost
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/etl"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+const storageRepositoryDir = "cloudcost"
+
+// StorageRepository is an implementation of Repository that persists CloudCostSets to a storage.Storage, with one
+// directory per billing integration and one file per day, so that ingested data survives restarts.
+type StorageRepository struct {
+	lock  sync.Mutex
+	store storage.Storage
+	repos map[string]*etl.StorageRepository[*opencost.CloudCostSet]
+
+	// writeLock serializes Put and Update
+	writeLock sync.Mutex
+}
+
+// NewStorageRepository creates a StorageRepository which writes to the given storage
+func NewStorageRepository(store storage.Storage) *StorageRepository {
+	return &StorageRepository{
+		store: store,
+		repos: make(map[string]*etl.StorageRepository[*opencost.CloudCostSet]),
+	}
+}
+
+func (sr *StorageRepository) Has(startTime time.Time, billingIntegration string) (bool, error) {
+	return sr.repo(billingIntegration).Has(startTime)
+}
+
+func (sr *StorageRepository) Get(startTime time.Time, billingIntegration string) (*opencost.CloudCostSet, error) {
+	return sr.repo(billingIntegration).Get(startTime)
+}
+
+// Keys returns the billing integration keys which have a directory in storage
+func (sr *StorageRepository) Keys() ([]string, error) {
+	dirs, err := sr.store.ListDirectories(storageRepositoryDir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("StorageRepository: Keys: failed to list %s: %w", storageRepositoryDir, err)
+	}
+
+	keys := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		name := path.Base(strings.TrimSuffix(dir.Name, "/"))
+		key, err := storage.DecodeDirName(name)
+		if err != nil {
+			log.Debugf("StorageRepository: Keys: skipping unrecognized directory %s", dir.Name)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (sr *StorageRepository) Put(ccs *opencost.CloudCostSet) error {
+	if ccs == nil {
+		return fmt.Errorf("StorageRepository: Put: cannot save nil")
+	}
+
+	if ccs.Window.IsOpen() {
+		return fmt.Errorf("StorageRepository: Put: cloud cost set has invalid window %s", ccs.Window.String())
+	}
+
+	if ccs.Integration == "" {
+		return fmt.Errorf("StorageRepository: Put: cloud cost set does not have an integration value")
+	}
+
+	sr.writeLock.Lock()
+	defer sr.writeLock.Unlock()
+
+	return sr.repo(ccs.Integration).Put(ccs)
+}
+
+func (sr *StorageRepository) Update(startTime time.Time, billingIntegration string, update func(*opencost.CloudCostSet) bool) error {
+	sr.writeLock.Lock()
+	defer sr.writeLock.Unlock()
+
+	repo := sr.repo(billingIntegration)
+	ccs, err := repo.Get(startTime)
+	if err != nil {
+		return err
+	}
+	if ccs == nil || !update(ccs) {
+		return nil
+	}
+
+	return repo.Put(ccs)
+}
+
+// Expire deletes all CloudCostSets with a start time before the given limit
+func (sr *StorageRepository) Expire(limit time.Time) error {
+	keys, err := sr.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = sr.repo(key).Expire(limit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repo returns the daily etl.StorageRepository for the given billing integration, creating it if necessary
+func (sr *StorageRepository) repo(billingIntegration string) *etl.StorageRepository[*opencost.CloudCostSet] {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	if repo, ok := sr.repos[billingIntegration]; ok {
+		return repo
+	}
+
+	dir := path.Join(storageRepositoryDir, storage.EncodeDirName(billingIntegration))
+	repo := etl.NewStorageRepository(sr.store, dir, timeutil.Day, func() *opencost.CloudCostSet {
+		return &opencost.CloudCostSet{}
+	})
+	sr.repos[billingIntegration] = repo
+	return repo
+}
@@ -0,0 +1,179 @@
+package cloudcost
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func TestStorageRepository_PutGet(t *testing.T) {
+	defaultStart := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	defaultEnd := defaultStart.Add(timeutil.Day)
+
+	repo := NewStorageRepository(storage.NewFileStorage(t.TempDir()))
+
+	tests := map[string]struct {
+		input   *opencost.CloudCostSet
+		wantErr bool
+	}{
+		"nil set": {
+			input:   nil,
+			wantErr: true,
+		},
+		"invalid window": {
+			input: &opencost.CloudCostSet{
+				Integration: "key-1",
+				Window:      opencost.NewWindow(&defaultStart, nil),
+			},
+			wantErr: true,
+		},
+		"missing integration": {
+			input:   DefaultMockCloudCostSet(defaultStart, defaultEnd, "aws", ""),
+			wantErr: true,
+		},
+		"valid": {
+			input:   DefaultMockCloudCostSet(defaultStart, defaultEnd, "aws", "account/bucket"),
+			wantErr: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := repo.Put(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Put() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	has, err := repo.Has(defaultStart, "account/bucket")
+	if err != nil || !has {
+		t.Fatalf("Has() got = %t, %v, want true", has, err)
+	}
+
+	has, err = repo.Has(defaultEnd, "account/bucket")
+	if err != nil || has {
+		t.Fatalf("Has() got = %t, %v, want false", has, err)
+	}
+
+	got, err := repo.Get(defaultStart, "account/bucket")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %s", err)
+	}
+	want := DefaultMockCloudCostSet(defaultStart, defaultEnd, "aws", "account/bucket")
+	if got == nil || !got.Window.Equal(want.Window) || got.Integration != want.Integration || len(got.CloudCosts) != len(want.CloudCosts) {
+		t.Errorf("Get() got = %v, want %v", got, want)
+	}
+
+	got, err = repo.Get(defaultStart, "key-2")
+	if err != nil || got != nil {
+		t.Errorf("Get() got = %v, %v, want nil", got, err)
+	}
+}
+
+func TestStorageRepository_KeysExpire(t *testing.T) {
+	defaultStart := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewStorageRepository(storage.NewFileStorage(t.TempDir()))
+
+	keys, err := repo.Keys()
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("Keys() got = %v, %v, want empty", keys, err)
+	}
+
+	for _, key := range []string{"account/bucket", "project/dataset"} {
+		for i := 0; i < 3; i++ {
+			start := defaultStart.Add(time.Duration(i) * timeutil.Day)
+			err = repo.Put(DefaultMockCloudCostSet(start, start.Add(timeutil.Day), "aws", key))
+			if err != nil {
+				t.Fatalf("Put() unexpected error: %s", err)
+			}
+		}
+	}
+
+	keys, err = repo.Keys()
+	if err != nil {
+		t.Fatalf("Keys() unexpected error: %s", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "account/bucket" || keys[1] != "project/dataset" {
+		t.Errorf("Keys() got = %v", keys)
+	}
+
+	err = repo.Expire(defaultStart.Add(2 * timeutil.Day))
+	if err != nil {
+		t.Fatalf("Expire() unexpected error: %s", err)
+	}
+
+	for _, key := range keys {
+		for i, want := range []bool{false, false, true} {
+			start := defaultStart.Add(time.Duration(i) * timeutil.Day)
+			has, err := repo.Has(start, key)
+			if err != nil {
+				t.Fatalf("Has() unexpected error: %s", err)
+			}
+			if has != want {
+				t.Errorf("Has(%s, %s) got = %t, want %t", start, key, has, want)
+			}
+		}
+	}
+}
+
+type countingIntegration struct {
+	calls int
+}
+
+func (ci *countingIntegration) GetCloudCost(start, end time.Time) (*opencost.CloudCostSetRange, error) {
+	ci.calls++
+	ccsr, err := opencost.NewCloudCostSetRange(start, end, opencost.AccumulateOptionDay, "key-1")
+	if err != nil {
+		return nil, err
+	}
+	for _, ccs := range ccsr.CloudCostSets {
+		ccs.Insert(&opencost.CloudCost{
+			Properties: &opencost.CloudCostProperties{ProviderID: "id1"},
+			Window:     ccs.Window,
+		})
+	}
+	return ccsr, nil
+}
+
+func (ci *countingIntegration) GetStatus() cloud.ConnectionStatus {
+	return cloud.SuccessfulConnection
+}
+
+func TestIngestor_LoadWindow_ResumesFromStorage(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3 * timeutil.Day)
+	store := storage.NewFileStorage(t.TempDir())
+
+	newIngestor := func(integration CloudCostIntegration) *ingestor {
+		return &ingestor{
+			key:         "key-1",
+			integration: integration,
+			repo:        NewStorageRepository(store),
+			coverage:    opencost.NewClosedWindow(end, end),
+		}
+	}
+
+	first := &countingIntegration{}
+	newIngestor(first).LoadWindow(start, end)
+	if first.calls != 1 {
+		t.Fatalf("expected initial load to query the integration once, got %d", first.calls)
+	}
+
+	// A new ingestor, as after a restart, finds the data in storage
+	second := &countingIntegration{}
+	ing := newIngestor(second)
+	ing.LoadWindow(start, end)
+	if second.calls != 0 {
+		t.Errorf("expected stored windows not to be queried again, got %d", second.calls)
+	}
+	if !ing.coverage.Equal(opencost.NewClosedWindow(start, end)) {
+		t.Errorf("expected coverage %s, got %s", opencost.NewClosedWindow(start, end), ing.coverage)
+	}
+}
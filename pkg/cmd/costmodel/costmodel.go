@@ -11,6 +11,8 @@ import (
 	"github.com/opencost/opencost/core/pkg/util/json"
 	"github.com/opencost/opencost/pkg/cloud/models"
 	"github.com/opencost/opencost/pkg/cloud/provider"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/currency"
 	"github.com/opencost/opencost/pkg/customcost"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
@@ -52,28 +54,83 @@ func Execute(opts *CostModelOpts) error {
 		// Register OpenCost Specific Endpoints
 		router.GET("/allocation", a.ComputeAllocationHandler)
 		router.GET("/allocation/summary", a.ComputeAllocationHandlerSummary)
+		router.GET("/allocation/compare", a.ComputeAllocationCompareHandler)
+		router.GET("/allocation/forecast", a.ComputeAllocationForecastHandler)
+		router.GET("/allocation/status", a.AllocationStoreStatusHandler)
+		router.GET("/allocation/unitCost", a.ComputeUnitCostsHandler)
+		router.GET("/recommendations/requests", a.ComputeRequestRecommendationsHandler)
+		router.GET("/recommendations/nodePools", a.ComputeNodePoolRecommendationsHandler)
 		router.GET("/assets", a.ComputeAssetsHandler)
+		router.GET("/assets/status", a.AssetStoreStatusHandler)
 		if env.IsCarbonEstimatesEnabled() {
 			router.GET("/assets/carbon", a.ComputeAssetsCarbonHandler)
 		}
 
+		// Asynchronous allocation and asset queries
+		router.POST("/jobs", a.SubmitQueryJobHandler)
+		router.GET("/jobs/:id", a.QueryJobStatusHandler)
+		router.GET("/jobs/:id/result", a.QueryJobResultHandler)
+		router.POST("/jobs/:id/cancel", a.CancelQueryJobHandler)
+		router.DELETE("/jobs/:id", a.CancelQueryJobHandler)
+
 		// set cloud provider for cloud cost
 		cp = a.CloudProvider
 	}
 
+	log.Infof("Currency conversion enabled: %t", env.IsCurrencyConversionEnabled())
+	var currencyConverter *currency.Converter
+	if env.IsCurrencyConversionEnabled() {
+		currencyConverter = costmodel.InitializeCurrencyConversion(cp)
+		if a != nil {
+			a.CurrencyConverter = currencyConverter
+		}
+	}
+
 	log.Infof("Cloud Costs enabled: %t", env.IsCloudCostEnabled())
+	var cloudCostQuerier cloudcost.Querier
+	var cloudCostRepo cloudcost.Repository
 	if env.IsCloudCostEnabled() {
 		var providerConfig models.ProviderConfig
 		if cp != nil {
 			providerConfig = provider.ExtractConfigFromProviders(cp)
 		}
-		costmodel.InitializeCloudCost(router, providerConfig)
+		cloudCostQuerier, cloudCostRepo = costmodel.InitializeCloudCost(router, providerConfig, currencyConverter)
+	}
+
+	var model *costmodel.CostModel
+	if a != nil {
+		model = a.Model
+	}
+
+	log.Infof("Allocation reconciliation enabled: %t", env.IsAllocationReconciliationEnabled())
+	if env.IsAllocationReconciliationEnabled() {
+		costmodel.InitializeReconciliation(model, cloudCostQuerier)
+	}
+
+	log.Infof("Cloud Cost Kubernetes attribution enabled: %t", env.IsCloudCostKubernetesAttributionEnabled())
+	if env.IsCloudCostKubernetesAttributionEnabled() {
+		costmodel.InitializeKubernetesAttribution(router, model, cloudCostRepo, cloudCostQuerier)
+	}
+
+	log.Infof("Anomaly detection enabled: %t", env.IsAnomalyDetectionEnabled())
+	if env.IsAnomalyDetectionEnabled() {
+		costmodel.InitializeAnomalyDetection(router, model, cloudCostQuerier)
+	}
+
+	log.Infof("Budgets enabled: %t", env.IsBudgetsEnabled())
+	if env.IsBudgetsEnabled() {
+		costmodel.InitializeBudgets(router, model, cloudCostQuerier)
+	}
+
+	log.Infof("FOCUS export enabled: %t", env.IsFOCUSExportEnabled())
+	if env.IsFOCUSExportEnabled() {
+		costmodel.InitializeFOCUSExport(model, cloudCostQuerier)
 	}
 
 	log.Infof("Custom Costs enabled: %t", env.IsCustomCostEnabled())
 	var customCostPipelineService *customcost.PipelineService
 	if env.IsCustomCostEnabled() {
-		customCostPipelineService = costmodel.InitializeCustomCost(router)
+		customCostPipelineService = costmodel.InitializeCustomCost(router, currencyConverter)
 	}
 
 	// this endpoint is intentionally left out of the "if env.IsCustomCostEnabled()" conditional; in the handler, it is
@@ -1,6 +1,7 @@
 package costmodel
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -15,6 +16,10 @@ import (
 	"github.com/patrickmn/go-cache"
 	prometheusClient "github.com/prometheus/client_golang/api"
 
+	"github.com/opencost/opencost/core/pkg/filter"
+	allocationfilter "github.com/opencost/opencost/core/pkg/filter/allocation"
+	"github.com/opencost/opencost/core/pkg/filter/ast"
+	"github.com/opencost/opencost/core/pkg/filter/ops"
 	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/core/pkg/util"
@@ -23,6 +28,7 @@ import (
 	"github.com/opencost/opencost/core/pkg/util/promutil"
 	"github.com/opencost/opencost/core/pkg/util/timeutil"
 	"github.com/opencost/opencost/pkg/cloud/models"
+	"github.com/opencost/opencost/pkg/currency"
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/errors"
 	"github.com/opencost/opencost/pkg/prom"
@@ -2143,6 +2149,224 @@ func ParseAggregationProperties(aggregations []string) ([]string, error) {
 	return aggregateBy, nil
 }
 
+// ParseAllocationFilter parses the given v2 filter string using the
+// allocation filter parser. An empty string returns a nil filter, which
+// matches all allocations.
+func ParseAllocationFilter(filterString string) (filter.Filter, error) {
+	if filterString == "" {
+		return nil, nil
+	}
+
+	parser := allocationfilter.NewAllocationFilterParser()
+	tree, err := parser.Parse(filterString)
+	if err != nil {
+		return nil, fmt.Errorf("err parsing filter '%s': %w", filterString, err)
+	}
+
+	return tree, nil
+}
+
+// AllocationShareOptions describes how the costs of shared workloads, idle
+// resources, and fixed overhead are distributed across allocations.
+type AllocationShareOptions struct {
+	// ShareIdle, if true, distributes idle costs proportionally across the
+	// remaining allocations.
+	ShareIdle bool
+	// ShareNamespaces is a list of namespaces whose costs are shared.
+	ShareNamespaces []string
+	// ShareLabels maps label names to the label values whose costs are shared.
+	ShareLabels map[string][]string
+	// ShareSplit determines how shared costs are split: opencost.ShareWeighted
+	// (proportional to cost) or opencost.ShareEven.
+	ShareSplit string
+	// SharedHourlyCosts is a map of fixed overhead costs, per hour, to share.
+	SharedHourlyCosts map[string]float64
+}
+
+// ShareFilter returns a filter matching allocations in any of the shared
+// namespaces or with any of the shared labels, or nil if there is nothing
+// to share.
+func (s *AllocationShareOptions) ShareFilter() filter.Filter {
+	if s == nil {
+		return nil
+	}
+
+	var nodes []ast.FilterNode
+	for _, ns := range s.ShareNamespaces {
+		nodes = append(nodes, ops.Eq(allocationfilter.FieldNamespace, ns))
+	}
+
+	// sort label names to keep the resulting filter deterministic
+	labelNames := make([]string, 0, len(s.ShareLabels))
+	for name := range s.ShareLabels {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	for _, name := range labelNames {
+		for _, value := range s.ShareLabels[name] {
+			nodes = append(nodes, ops.Eq(ops.WithKey(allocationfilter.FieldLabel, name), value))
+		}
+	}
+
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	default:
+		return ops.Or(nodes[0], nodes[1], nodes[2:]...)
+	}
+}
+
+// apply sets the sharing fields of the given aggregation options.
+func (s *AllocationShareOptions) apply(opts *opencost.AllocationAggregationOptions) {
+	if s == nil {
+		return
+	}
+
+	if s.ShareIdle {
+		opts.ShareIdle = opencost.ShareWeighted
+	}
+	opts.Share = s.ShareFilter()
+	opts.SharedNamespaces = s.ShareNamespaces
+	opts.SharedLabels = s.ShareLabels
+	opts.ShareSplit = s.ShareSplit
+	opts.SharedHourlyCosts = s.SharedHourlyCosts
+}
+
+// ParseShareLabels parses a list of "name:value" pairs into a map of label
+// names to label values.
+func ParseShareLabels(pairs []string) (map[string][]string, error) {
+	labels := map[string][]string{}
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("illegal shared label '%s': expected format 'name:value'", pair)
+		}
+
+		labels[name] = append(labels[name], value)
+	}
+
+	return labels, nil
+}
+
+// ParseShareSplit converts the given split string to opencost.ShareEven or
+// opencost.ShareWeighted, defaulting to the latter.
+func ParseShareSplit(split string) (string, error) {
+	switch strings.ToLower(split) {
+	case "", "weighted":
+		return opencost.ShareWeighted, nil
+	case "even":
+		return opencost.ShareEven, nil
+	default:
+		return "", fmt.Errorf("illegal share split '%s': expected 'weighted' or 'even'", split)
+	}
+}
+
+// parseAllocationShareOptions parses the sharing query parameters. Only the
+// parameters the caller passed are applied unless "shareDefaults=true" is
+// set, in which case the shared namespaces, labels, and overhead configured
+// in the custom pricing settings fill in any that are missing.
+func (a *Accesses) parseAllocationShareOptions(qp httputil.QueryParams) (*AllocationShareOptions, error) {
+	var c *models.CustomPricing
+	if qp.GetBool("shareDefaults", false) {
+		var err error
+		c, err = a.CloudProvider.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("error getting config: %w", err)
+		}
+	}
+
+	return ParseAllocationShareOptions(qp, c)
+}
+
+// ParseAllocationShareOptions parses the sharing query parameters. If the
+// given custom pricing is non-nil, its shared namespaces, labels, and overhead
+// are used for any sharing parameters missing from the query.
+func ParseAllocationShareOptions(qp httputil.QueryParams, c *models.CustomPricing) (*AllocationShareOptions, error) {
+	var err error
+
+	// ShareNamespaces is an optional comma-separated list of namespaces whose
+	// costs are shared; e.g. "kube-system,monitoring"
+	shareNamespaces := qp.GetList("shareNamespaces", ",")
+	if !qp.Has("shareNamespaces") && c != nil {
+		shareNamespaces = strings.Split(c.SharedNamespaces, ",")
+	}
+
+	var namespaces []string
+	for _, ns := range shareNamespaces {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	// ShareLabels is an optional comma-separated list of "name:value" label
+	// pairs whose costs are shared; e.g. "team:platform,app:prometheus"
+	var labels map[string][]string
+	if qp.Has("shareLabels") {
+		labels, err = ParseShareLabels(qp.GetList("shareLabels", ","))
+		if err != nil {
+			return nil, fmt.Errorf("bad request - invalid 'shareLabels' parameter: %w", err)
+		}
+	} else if c != nil && c.SharedLabelNames != "" {
+		names := strings.Split(c.SharedLabelNames, ",")
+		values := strings.Split(c.SharedLabelValues, ",")
+		if len(names) != len(values) {
+			return nil, fmt.Errorf("configured shared label names and values must be the same length")
+		}
+
+		pairs := make([]string, len(names))
+		for i := range names {
+			pairs[i] = fmt.Sprintf("%s:%s", names[i], values[i])
+		}
+
+		labels, err = ParseShareLabels(pairs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configured shared labels: %w", err)
+		}
+	}
+
+	// ShareSplit determines whether shared costs are split by cost
+	// ("weighted", the default) or evenly ("even")
+	shareSplit, err := ParseShareSplit(qp.Get("shareSplit", ""))
+	if err != nil {
+		return nil, fmt.Errorf("bad request - invalid 'shareSplit' parameter: %w", err)
+	}
+
+	// ShareCost is an optional fixed monthly cost to share, defaulting to the
+	// configured shared overhead when share defaults are requested
+	defaultShareCost := 0.0
+	if c != nil {
+		defaultShareCost = c.GetSharedOverheadCostPerMonth()
+	}
+	shareCost := qp.GetFloat64("shareCost", defaultShareCost)
+	if shareCost < 0 {
+		return nil, fmt.Errorf("bad request - invalid 'shareCost' parameter: must be non-negative")
+	}
+
+	var sharedHourlyCosts map[string]float64
+	if shareCost > 0 {
+		sharedHourlyCosts = map[string]float64{
+			"total": shareCost / timeutil.HoursPerMonth,
+		}
+	}
+
+	return &AllocationShareOptions{
+		ShareIdle:         qp.GetBool("shareIdle", false),
+		ShareNamespaces:   namespaces,
+		ShareLabels:       labels,
+		ShareSplit:        shareSplit,
+		SharedHourlyCosts: sharedHourlyCosts,
+	}, nil
+}
+
 func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -2178,6 +2402,49 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 	// sums each Set in the Range, producing one Set.
 	accumulate := qp.GetBool("accumulate", false)
 
+	// Filter is an optional v2 filter string which restricts the allocations
+	// returned; e.g. namespace:"kubecost"+label[app]:"cost-analyzer"
+	allocFilter, err := ParseAllocationFilter(qp.Get("filter", ""))
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("Invalid 'filter' parameter: %s", err)))
+		return
+	}
+
+	// Share options describe which namespaces, labels, and overhead costs
+	// are shared, and how. Idle is not computed, so it cannot be shared.
+	shareOpts, err := a.parseAllocationShareOptions(qp)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+	shareOpts.ShareIdle = false
+
+	// Currency is an optional currency to which costs are converted, if
+	// currency conversion is enabled; e.g. "EUR"
+	conversion, err := a.parseCurrencyConversion(qp)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	// Format is an optional parameter which returns the summary as a "csv"
+	// or "parquet" table rather than JSON
+	tableOpts, err := parseTableOptions(qp)
+	if err != nil {
+		WriteError(w, BadRequest(err.Error()))
+		return
+	}
+
 	// Query for AllocationSets in increments of the given step duration,
 	// appending each to the AllocationSetRange.
 	asr := opencost.NewAllocationSetRange()
@@ -2186,7 +2453,7 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 		stepEnd := stepStart.Add(step)
 		stepWindow := opencost.NewWindow(&stepStart, &stepEnd)
 
-		as, err := a.Model.ComputeAllocation(*stepWindow.Start(), *stepWindow.End(), resolution)
+		as, err := a.Model.loadOrComputeAllocation(r.Context(), *stepWindow.Start(), *stepWindow.End(), resolution)
 		if err != nil {
 			WriteError(w, InternalServerError(err.Error()))
 			return
@@ -2196,15 +2463,27 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 		stepStart = stepEnd
 	}
 
-	// Aggregate, if requested
-	if len(aggregateBy) > 0 {
-		err = asr.AggregateBy(aggregateBy, nil)
+	// Aggregate, filter, and share, if requested
+	aggOpts := &opencost.AllocationAggregationOptions{
+		Filter: allocFilter,
+	}
+	shareOpts.apply(aggOpts)
+
+	if len(aggregateBy) > 0 || aggOpts.Filter != nil || aggOpts.Share != nil || len(aggOpts.SharedHourlyCosts) > 0 {
+		err = asr.AggregateBy(aggregateBy, aggOpts)
 		if err != nil {
 			WriteError(w, InternalServerError(err.Error()))
 			return
 		}
 	}
 
+	// Convert each step at the rate of its start, before accumulating
+	err = conversion.ConvertAllocationSetRange(asr, "")
+	if err != nil {
+		WriteError(w, InternalServerError(fmt.Sprintf("error converting currency: %s", err)))
+		return
+	}
+
 	// Accumulate, if requested
 	if accumulate {
 		asr, err = asr.Accumulate(opencost.AccumulateOptionAll)
@@ -2221,7 +2500,22 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 	}
 	sasr := opencost.NewSummaryAllocationSetRange(sasl...)
 
-	w.Write(WrapData(sasr, nil))
+	if tableOpts != nil {
+		writeTable(w, tableOpts, conversion, "allocation-summary", summaryAllocationTableColumnDefs(tableOpts.labels, tableOpts.labelsAll), func(write func(*opencost.SummaryAllocation) error) error {
+			for _, sas := range sasr.SummaryAllocationSets {
+				for _, name := range sortedKeys(sas.SummaryAllocations) {
+					err := write(sas.SummaryAllocations[name])
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		return
+	}
+
+	w.Write(WrapDataWithCurrency(sasr, conversion))
 }
 
 // ComputeAllocationHandler computes an AllocationSetRange from the CostModel.
@@ -2230,11 +2524,101 @@ func (a *Accesses) ComputeAllocationHandler(w http.ResponseWriter, r *http.Reque
 
 	qp := httputil.NewQueryParams(r.URL.Query())
 
+	query, err := a.parseAllocationQuery(qp)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	// Format is an optional parameter which returns the allocations as a
+	// "csv" or "parquet" table rather than JSON
+	tableOpts, err := parseTableOptions(qp)
+	if err != nil {
+		WriteError(w, BadRequest(err.Error()))
+		return
+	}
+
+	if tableOpts != nil {
+		asr, err := a.queryAllocation(r.Context(), query)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+				WriteError(w, BadRequest(err.Error()))
+			} else {
+				WriteError(w, InternalServerError(err.Error()))
+			}
+
+			return
+		}
+
+		writeTable(w, tableOpts, query.conversion, "allocation", allocationTableColumnDefs(tableOpts.labels, tableOpts.labelsAll), func(write func(*opencost.Allocation) error) error {
+			for _, as := range asr.Allocations {
+				for _, name := range sortedKeys(as.Allocations) {
+					err := write(as.Allocations[name])
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		return
+	}
+
+	// Stream one allocation per line if requested, rather than marshalling
+	// the entire range into memory at once
+	if nw := streamResponse(w, r); nw != nil {
+		err = a.streamAllocation(r.Context(), query, nw)
+		if err != nil {
+			writeStreamError(nw, err)
+		}
+		return
+	}
+
+	asr, err := a.queryAllocation(r.Context(), query)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	w.Write(WrapDataWithCurrency(asr, query.conversion))
+}
+
+// allocationQuery holds the parsed parameters of an allocation request
+type allocationQuery struct {
+	window                                opencost.Window
+	resolution                            time.Duration
+	step                                  time.Duration
+	aggregateBy                           []string
+	filter                                filter.Filter
+	shareOpts                             *AllocationShareOptions
+	includeIdle                           bool
+	idleByNode                            bool
+	includeProportionalAssetResourceCosts bool
+	includeAggregatedMetadata             bool
+	sharedLoadBalancer                    bool
+	reconcile                             bool
+	accumulateBy                          opencost.AccumulateOption
+	conversion                            *currency.Conversion
+}
+
+// parseAllocationQuery parses the parameters of an allocation request.
+// Errors caused by invalid parameters are prefixed with "bad request".
+func (a *Accesses) parseAllocationQuery(qp httputil.QueryParams) (*allocationQuery, error) {
 	// Window is a required field describing the window of time over which to
 	// compute allocation data.
 	window, err := opencost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return nil, fmt.Errorf("bad request - invalid 'window' parameter: %s", err)
 	}
 
 	// Resolution is an optional parameter, defaulting to the configured ETL
@@ -2253,7 +2637,7 @@ func (a *Accesses) ComputeAllocationHandler(w http.ResponseWriter, r *http.Reque
 	aggregations := qp.GetList("aggregate", ",")
 	aggregateBy, err := ParseAggregationProperties(aggregations)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid 'aggregate' parameter: %s", err), http.StatusBadRequest)
+		return nil, fmt.Errorf("bad request - invalid 'aggregate' parameter: %s", err)
 	}
 
 	// IncludeIdle, if true, uses Asset data to incorporate Idle Allocation
@@ -2278,24 +2662,80 @@ func (a *Accesses) ComputeAllocationHandler(w http.ResponseWriter, r *http.Reque
 	idleByNode := qp.GetBool("idleByNode", false)
 	sharedLoadBalancer := qp.GetBool("sharelb", false)
 
+	// Reconcile, if true, adjusts allocation costs to the costs billed by the
+	// cloud provider, if reconciliation is enabled
+	reconcile := qp.GetBool("reconcile", true)
+
 	// IncludeProportionalAssetResourceCosts, if true,
 	includeProportionalAssetResourceCosts := qp.GetBool("includeProportionalAssetResourceCosts", false)
 
 	// include aggregated labels/annotations if true
 	includeAggregatedMetadata := qp.GetBool("includeAggregatedMetadata", false)
 
-	asr, err := a.Model.QueryAllocation(window, resolution, step, aggregateBy, includeIdle, idleByNode, includeProportionalAssetResourceCosts, includeAggregatedMetadata, sharedLoadBalancer, accumulateBy)
+	// Filter is an optional v2 filter string which restricts the allocations
+	// returned; e.g. namespace:"kubecost"+label[app]:"cost-analyzer"
+	allocFilter, err := ParseAllocationFilter(qp.Get("filter", ""))
 	if err != nil {
-		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
-			WriteError(w, BadRequest(err.Error()))
-		} else {
-			WriteError(w, InternalServerError(err.Error()))
-		}
+		return nil, fmt.Errorf("bad request - invalid 'filter' parameter: %s", err)
+	}
 
-		return
+	// Share options describe which namespaces, labels, idle, and overhead
+	// costs are shared, and how; e.g. shareNamespaces=kube-system&shareIdle=true
+	shareOpts, err := a.parseAllocationShareOptions(qp)
+	if err != nil {
+		return nil, err
 	}
 
-	w.Write(WrapData(asr, nil))
+	// Currency is an optional currency to which costs are converted, if
+	// currency conversion is enabled; e.g. "EUR"
+	conversion, err := a.parseCurrencyConversion(qp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &allocationQuery{
+		window:                                window,
+		resolution:                            resolution,
+		step:                                  step,
+		aggregateBy:                           aggregateBy,
+		filter:                                allocFilter,
+		shareOpts:                             shareOpts,
+		includeIdle:                           includeIdle,
+		idleByNode:                            idleByNode,
+		includeProportionalAssetResourceCosts: includeProportionalAssetResourceCosts,
+		includeAggregatedMetadata:             includeAggregatedMetadata,
+		sharedLoadBalancer:                    sharedLoadBalancer,
+		reconcile:                             reconcile,
+		accumulateBy:                          accumulateBy,
+		conversion:                            conversion,
+	}, nil
+}
+
+// queryAllocation runs the given allocation query against the CostModel. If
+// the query converts currency, each step is converted at the rate of its
+// start before the range is accumulated.
+func (a *Accesses) queryAllocation(ctx context.Context, q *allocationQuery) (*opencost.AllocationSetRange, error) {
+	if q.conversion == nil {
+		return a.Model.QueryAllocation(ctx, q.window, q.resolution, q.step, q.aggregateBy, q.filter, q.shareOpts, q.includeIdle, q.idleByNode, q.includeProportionalAssetResourceCosts, q.includeAggregatedMetadata, q.sharedLoadBalancer, q.reconcile, q.accumulateBy)
+	}
+
+	asr, err := a.Model.QueryAllocation(ctx, q.window, q.resolution, q.step, q.aggregateBy, q.filter, q.shareOpts, q.includeIdle, q.idleByNode, q.includeProportionalAssetResourceCosts, q.includeAggregatedMetadata, q.sharedLoadBalancer, q.reconcile, opencost.AccumulateOptionNone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.conversion.ConvertAllocationSetRange(asr, "")
+	if err != nil {
+		return nil, fmt.Errorf("error converting currency: %w", err)
+	}
+
+	if q.accumulateBy != opencost.AccumulateOptionNone {
+		asr, err = asr.Accumulate(q.accumulateBy)
+		if err != nil {
+			return nil, fmt.Errorf("error accumulating by %v: %w", q.accumulateBy, err)
+		}
+	}
+	return asr, nil
 }
 
 // The below was transferred from a different package in order to maintain
@@ -1,10 +1,20 @@
 package costmodel
 
 import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/core/pkg/util"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/cloud/models"
 )
 
 func TestScaleHourlyCostData(t *testing.T) {
@@ -231,3 +241,313 @@ func TestParseAggregationProperties_All(t *testing.T) {
 		t.Fatalf("TestParseAggregationPropertiesDefault: expected length of 0, got: %d", len(got))
 	}
 }
+
+func TestParseAllocationFilter(t *testing.T) {
+	got, err := ParseAllocationFilter("")
+	if err != nil {
+		t.Fatalf("TestParseAllocationFilter: unexpected error: %s", err)
+	}
+	if got != nil {
+		t.Fatalf("TestParseAllocationFilter: expected nil filter for empty string, got: %v", got)
+	}
+
+	got, err = ParseAllocationFilter(`namespace:"payments"+label[team]:"core"`)
+	if err != nil {
+		t.Fatalf("TestParseAllocationFilter: unexpected error: %s", err)
+	}
+
+	compiler := opencost.NewAllocationMatchCompiler(nil)
+	matcher, err := compiler.Compile(got)
+	if err != nil {
+		t.Fatalf("TestParseAllocationFilter: unexpected error compiling filter: %s", err)
+	}
+
+	match := &opencost.Allocation{
+		Properties: &opencost.AllocationProperties{
+			Namespace: "payments",
+			Labels:    map[string]string{"team": "core"},
+		},
+	}
+	if !matcher.Matches(match) {
+		t.Fatalf("TestParseAllocationFilter: expected filter to match %s", match.Properties)
+	}
+
+	noMatch := &opencost.Allocation{
+		Properties: &opencost.AllocationProperties{
+			Namespace: "payments",
+			Labels:    map[string]string{"team": "platform"},
+		},
+	}
+	if matcher.Matches(noMatch) {
+		t.Fatalf("TestParseAllocationFilter: expected filter not to match %s", noMatch.Properties)
+	}
+
+	_, err = ParseAllocationFilter(`namespace:"payments`)
+	if err == nil {
+		t.Fatalf("TestParseAllocationFilter: expected error for malformed filter")
+	}
+
+	_, err = ParseAllocationFilter(`notafield:"payments"`)
+	if err == nil {
+		t.Fatalf("TestParseAllocationFilter: expected error for unknown field")
+	}
+}
+
+func TestParseShareLabels(t *testing.T) {
+	got, err := ParseShareLabels([]string{"team:platform", " app:prometheus ", "team:infra", ""})
+	if err != nil {
+		t.Fatalf("TestParseShareLabels: unexpected error: %s", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("TestParseShareLabels: expected 2 label names, got: %d", len(got))
+	}
+	if len(got["team"]) != 2 || got["team"][0] != "platform" || got["team"][1] != "infra" {
+		t.Fatalf("TestParseShareLabels: unexpected values for label team: %v", got["team"])
+	}
+	if len(got["app"]) != 1 || got["app"][0] != "prometheus" {
+		t.Fatalf("TestParseShareLabels: unexpected values for label app: %v", got["app"])
+	}
+
+	for _, illegal := range []string{"team", "team:", ":platform"} {
+		if _, err := ParseShareLabels([]string{illegal}); err == nil {
+			t.Fatalf("TestParseShareLabels: expected error for '%s'", illegal)
+		}
+	}
+}
+
+func TestParseShareSplit(t *testing.T) {
+	cases := map[string]string{
+		"":         opencost.ShareWeighted,
+		"weighted": opencost.ShareWeighted,
+		"even":     opencost.ShareEven,
+		"EVEN":     opencost.ShareEven,
+	}
+
+	for split, expected := range cases {
+		got, err := ParseShareSplit(split)
+		if err != nil {
+			t.Fatalf("TestParseShareSplit: unexpected error for '%s': %s", split, err)
+		}
+		if got != expected {
+			t.Fatalf("TestParseShareSplit: expected '%s' for '%s', got: '%s'", expected, split, got)
+		}
+	}
+
+	if _, err := ParseShareSplit("random"); err == nil {
+		t.Fatalf("TestParseShareSplit: expected error for illegal split")
+	}
+}
+
+func TestAllocationShareOptions_ShareFilter(t *testing.T) {
+	var nilOpts *AllocationShareOptions
+	if nilOpts.ShareFilter() != nil {
+		t.Fatalf("TestAllocationShareOptions_ShareFilter: expected nil filter for nil options")
+	}
+
+	if (&AllocationShareOptions{}).ShareFilter() != nil {
+		t.Fatalf("TestAllocationShareOptions_ShareFilter: expected nil filter for empty options")
+	}
+
+	shareOpts := &AllocationShareOptions{
+		ShareNamespaces: []string{"kube-system", "monitoring"},
+		ShareLabels:     map[string][]string{"team": {"platform"}},
+	}
+
+	compiler := opencost.NewAllocationMatchCompiler(nil)
+	matcher, err := compiler.Compile(shareOpts.ShareFilter())
+	if err != nil {
+		t.Fatalf("TestAllocationShareOptions_ShareFilter: unexpected error compiling filter: %s", err)
+	}
+
+	cases := []struct {
+		props    *opencost.AllocationProperties
+		expected bool
+	}{
+		{&opencost.AllocationProperties{Namespace: "kube-system"}, true},
+		{&opencost.AllocationProperties{Namespace: "monitoring"}, true},
+		{&opencost.AllocationProperties{Namespace: "payments", Labels: map[string]string{"team": "platform"}}, true},
+		{&opencost.AllocationProperties{Namespace: "payments", Labels: map[string]string{"team": "core"}}, false},
+		{&opencost.AllocationProperties{Namespace: "payments"}, false},
+	}
+
+	for _, c := range cases {
+		if got := matcher.Matches(&opencost.Allocation{Properties: c.props}); got != c.expected {
+			t.Fatalf("TestAllocationShareOptions_ShareFilter: expected %t for %s, got: %t", c.expected, c.props, got)
+		}
+	}
+}
+
+func TestAllocationShareOptions_AggregateBy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newSet := func() *opencost.AllocationSet {
+		as := opencost.NewAllocationSet(start, start.Add(24*time.Hour))
+		for _, ns := range []string{"payments", "payments", "search", "kube-system"} {
+			name := fmt.Sprintf("cluster1/%s/pod-%d", ns, as.Length())
+			as.Insert(opencost.NewMockUnitAllocation(name, start, 24*time.Hour, &opencost.AllocationProperties{
+				Cluster:   "cluster1",
+				Namespace: ns,
+				Pod:       fmt.Sprintf("pod-%d", as.Length()),
+			}))
+		}
+		return as
+	}
+
+	totalCost := newSet().TotalCost()
+
+	cases := map[string]struct {
+		split    string
+		expected map[string]float64
+	}{
+		"weighted": {
+			split: opencost.ShareWeighted,
+			expected: map[string]float64{
+				"payments": totalCost * 2.0 / 3.0,
+				"search":   totalCost * 1.0 / 3.0,
+			},
+		},
+		"even": {
+			split: opencost.ShareEven,
+			expected: map[string]float64{
+				"payments": totalCost/4.0*2.0 + totalCost/8.0,
+				"search":   totalCost/4.0 + totalCost/8.0,
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			as := newSet()
+
+			aggOpts := &opencost.AllocationAggregationOptions{}
+			(&AllocationShareOptions{
+				ShareNamespaces: []string{"kube-system"},
+				ShareSplit:      c.split,
+			}).apply(aggOpts)
+
+			err := as.AggregateBy([]string{opencost.AllocationNamespaceProp}, aggOpts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if as.Length() != len(c.expected) {
+				t.Fatalf("expected %d allocations, got: %d", len(c.expected), as.Length())
+			}
+
+			for key, expected := range c.expected {
+				alloc := as.Get(key)
+				if alloc == nil {
+					t.Fatalf("missing allocation %s", key)
+				}
+				if math.Abs(alloc.TotalCost()-expected) > 0.0001 {
+					t.Fatalf("expected %s total cost %f, got: %f", key, expected, alloc.TotalCost())
+				}
+			}
+
+			if math.Abs(as.TotalCost()-totalCost) > 0.0001 {
+				t.Fatalf("expected total cost %f to be conserved, got: %f", totalCost, as.TotalCost())
+			}
+		})
+	}
+}
+
+func TestParseAllocationShareOptions(t *testing.T) {
+	c := &models.CustomPricing{
+		SharedNamespaces:  "kube-system,monitoring",
+		SharedLabelNames:  "team",
+		SharedLabelValues: "platform",
+		SharedOverhead:    "730",
+	}
+
+	// a request without share parameters must leave aggregation unchanged,
+	// regardless of the configured share settings; the config is only
+	// consulted when share defaults are requested, so no provider is needed
+	shareOpts, err := (&Accesses{}).parseAllocationShareOptions(httputil.NewQueryParams(url.Values{}))
+	if err != nil {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected error: %s", err)
+	}
+	if shareOpts.ShareFilter() != nil || shareOpts.SharedHourlyCosts != nil || shareOpts.ShareIdle {
+		t.Fatalf("TestParseAllocationShareOptions: expected no sharing without share parameters, got: %+v", shareOpts)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSet := func() *opencost.AllocationSet {
+		as := opencost.NewAllocationSet(start, start.Add(24*time.Hour))
+		for i, ns := range []string{"payments", "kube-system"} {
+			as.Insert(opencost.NewMockUnitAllocation(fmt.Sprintf("cluster1/%s/pod-%d", ns, i), start, 24*time.Hour, &opencost.AllocationProperties{
+				Cluster:   "cluster1",
+				Namespace: ns,
+				Pod:       fmt.Sprintf("pod-%d", i),
+			}))
+		}
+		return as
+	}
+
+	expected := newSet()
+	if err := expected.AggregateBy([]string{opencost.AllocationNamespaceProp}, &opencost.AllocationAggregationOptions{}); err != nil {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected error: %s", err)
+	}
+
+	actual := newSet()
+	aggOpts := &opencost.AllocationAggregationOptions{}
+	shareOpts.apply(aggOpts)
+	if err := actual.AggregateBy([]string{opencost.AllocationNamespaceProp}, aggOpts); err != nil {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected error: %s", err)
+	}
+
+	if actual.Length() != expected.Length() {
+		t.Fatalf("TestParseAllocationShareOptions: expected %d allocations, got: %d", expected.Length(), actual.Length())
+	}
+	for key, alloc := range expected.Allocations {
+		if got := actual.Get(key); got == nil || math.Abs(got.TotalCost()-alloc.TotalCost()) > 0.0001 {
+			t.Fatalf("TestParseAllocationShareOptions: expected allocation %s to be unchanged, got: %v", key, got)
+		}
+	}
+
+	// share defaults fill in the configured values
+	shareOpts, err = ParseAllocationShareOptions(httputil.NewQueryParams(url.Values{}), c)
+	if err != nil {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(shareOpts.ShareNamespaces, []string{"kube-system", "monitoring"}) {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected default namespaces: %v", shareOpts.ShareNamespaces)
+	}
+	if !reflect.DeepEqual(shareOpts.ShareLabels, map[string][]string{"team": {"platform"}}) {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected default labels: %v", shareOpts.ShareLabels)
+	}
+	if math.Abs(shareOpts.SharedHourlyCosts["total"]-1.0) > 0.0001 {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected default shared hourly costs: %v", shareOpts.SharedHourlyCosts)
+	}
+
+	// explicit parameters take precedence over share defaults
+	shareOpts, err = ParseAllocationShareOptions(httputil.NewQueryParams(url.Values{
+		"shareNamespaces": []string{"logging"},
+		"shareCost":       []string{"0"},
+	}), c)
+	if err != nil {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(shareOpts.ShareNamespaces, []string{"logging"}) {
+		t.Fatalf("TestParseAllocationShareOptions: unexpected namespaces: %v", shareOpts.ShareNamespaces)
+	}
+	if shareOpts.SharedHourlyCosts != nil {
+		t.Fatalf("TestParseAllocationShareOptions: expected no shared hourly costs, got: %v", shareOpts.SharedHourlyCosts)
+	}
+}
+
+func TestComputeAllocationHandlerSummary_Currency(t *testing.T) {
+	a := &Accesses{}
+
+	// Costs are not silently left unconverted when conversion is disabled
+	r := httptest.NewRequest(http.MethodGet, "/allocation/summary?window=1d&currency=EUR", nil)
+	w := httptest.NewRecorder()
+	a.ComputeAllocationHandlerSummary(w, r, nil)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "currency") {
+		t.Errorf("expected a currency error, got %s", w.Body.String())
+	}
+}
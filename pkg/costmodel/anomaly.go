@@ -0,0 +1,134 @@
+package costmodel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/anomaly"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// allocationAnomalySource is an anomaly.Source of daily allocation costs,
+// broken down by resource type
+type allocationAnomalySource struct {
+	model       *CostModel
+	aggregateBy []string
+}
+
+func (s *allocationAnomalySource) Name() string {
+	return "allocation"
+}
+
+func (s *allocationAnomalySource) AggregateBy() []string {
+	return s.aggregateBy
+}
+
+func (s *allocationAnomalySource) Costs(ctx context.Context, window opencost.Window) ([]*anomaly.DailyCosts, error) {
+	asr, err := s.model.QueryAllocation(ctx, window, env.GetETLResolution(), 24*time.Hour, s.aggregateBy, nil, nil, false, false, false, false, false, true, opencost.AccumulateOptionNone)
+	if err != nil {
+		return nil, err
+	}
+
+	return allocationDailyCosts(asr), nil
+}
+
+// allocationDailyCosts returns the total cost of each allocation of each set,
+// broken down by resource type
+func allocationDailyCosts(asr *opencost.AllocationSetRange) []*anomaly.DailyCosts {
+	result := make([]*anomaly.DailyCosts, 0, len(asr.Allocations))
+	for _, as := range asr.Allocations {
+		dc := &anomaly.DailyCosts{
+			Window: as.Window.Clone(),
+			Costs:  make(map[string]*anomaly.Cost, len(as.Allocations)),
+		}
+
+		for name, alloc := range as.Allocations {
+			costs := NewAllocationCosts(alloc)
+			dc.Costs[name] = &anomaly.Cost{
+				Total: costs.TotalCost,
+				Breakdown: map[string]float64{
+					"cpu":          costs.CPUCost,
+					"gpu":          costs.GPUCost,
+					"ram":          costs.RAMCost,
+					"pv":           costs.PVCost,
+					"network":      costs.NetworkCost,
+					"loadBalancer": costs.LoadBalancerCost,
+					"shared":       costs.SharedCost,
+					"external":     costs.ExternalCost,
+				},
+			}
+		}
+
+		result = append(result, dc)
+	}
+	return result
+}
+
+// InitializeAnomalyDetection starts detecting anomalies in the daily
+// allocation costs of the model and the cloud costs of the querier, for each
+// of the configured aggregations, and registers the /anomalies endpoint.
+// Either the model or querier may be nil if its costs are not available.
+func InitializeAnomalyDetection(router *httprouter.Router, model *CostModel, cloudCostQuerier cloudcost.Querier) *anomaly.Detector {
+	var store anomaly.Store = anomaly.NewMemoryStore()
+	if env.IsETLStoreEnabled() {
+		etlStore, err := NewETLStorage()
+		if err != nil {
+			log.Errorf("Init: failed to create ETL storage for anomalies, falling back to memory: %s", err)
+		} else {
+			log.Infof("Init: persisting anomalies using %s storage", etlStore.StorageType())
+			store = anomaly.NewStorageStore(etlStore)
+		}
+	}
+
+	var sources []anomaly.Source
+	if model != nil {
+		for _, aggregation := range env.GetAnomalyAllocationAggregations() {
+			aggregateBy, err := ParseAggregationProperties(strings.Split(aggregation, ","))
+			if err != nil {
+				log.Errorf("Init: invalid allocation anomaly aggregation '%s': %s", aggregation, err)
+				continue
+			}
+			sources = append(sources, &allocationAnomalySource{model: model, aggregateBy: aggregateBy})
+		}
+	}
+	if cloudCostQuerier != nil {
+		for _, aggregation := range env.GetAnomalyCloudCostAggregations() {
+			var aggregateBy []string
+			for _, agg := range strings.Split(aggregation, ",") {
+				prop, err := opencost.ParseCloudCostProperty(agg)
+				if err != nil {
+					log.Errorf("Init: invalid cloud cost anomaly aggregation '%s': %s", aggregation, err)
+					aggregateBy = nil
+					break
+				}
+				aggregateBy = append(aggregateBy, string(prop))
+			}
+			if aggregateBy != nil {
+				sources = append(sources, anomaly.NewCloudCostSource(cloudCostQuerier, aggregateBy, opencost.CostMetricAmortizedNetCost))
+			}
+		}
+	}
+
+	config := anomaly.DefaultConfig()
+	config.BaselineDays = env.GetAnomalyBaselineDays()
+	config.Threshold = env.GetAnomalyThreshold()
+	config.MinDelta = env.GetAnomalyMinCostDelta()
+	config.MinBaselineDays = min(config.MinBaselineDays, config.BaselineDays)
+
+	detector := anomaly.NewDetector(store, config, sources...)
+	err := detector.Start(env.GetAnomalyDetectionInterval(), env.GetAnomalyDetectionDays())
+	if err != nil {
+		log.Errorf("Init: failed to start anomaly detection: %s", err)
+		return nil
+	}
+
+	queryService := anomaly.NewQueryService(store)
+	router.GET("/anomalies", queryService.GetAnomaliesHandler())
+
+	return detector
+}
@@ -1,13 +1,63 @@
 package costmodel
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/opencost/opencost/core/pkg/filter"
 	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/env"
 )
 
+// QueryAssets returns an AssetSetRange covering the given window, with one
+// AssetSet per step. Each set is filtered and aggregated by the given
+// properties, and the range is then accumulated as requested.
+func (cm *CostModel) QueryAssets(ctx context.Context, window opencost.Window, step time.Duration, aggregateBy []string, assetFilter filter.Filter, accumulateBy opencost.AccumulateOption) (*opencost.AssetSetRange, error) {
+	// Validate window is legal
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("bad request - illegal window: %s", window)
+	}
+
+	if step <= 0 {
+		return nil, fmt.Errorf("bad request - illegal step: %s", step)
+	}
+
+	// Compute the AssetSet for each step concurrently, bounded by the
+	// maximum query concurrency.
+	assetSets, err := computeQuerySteps(ctx, getQuerySteps(window, step), env.GetMaxQueryConcurrency(), func(ctx context.Context, stepStart, stepEnd time.Time) (*opencost.AssetSet, error) {
+		assetSet, err := cm.loadOrComputeAssets(ctx, stepStart, stepEnd)
+		if err != nil {
+			return nil, fmt.Errorf("error computing assets for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
+		}
+		return assetSet, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	asr := opencost.NewAssetSetRange(assetSets...)
+
+	// Aggregate, which also applies the filter
+	if len(aggregateBy) > 0 || assetFilter != nil {
+		err = asr.AggregateBy(aggregateBy, &opencost.AssetAggregationOptions{Filter: assetFilter})
+		if err != nil {
+			return nil, fmt.Errorf("error aggregating for %s: %w", window, err)
+		}
+	}
+
+	// Accumulate, if requested
+	if accumulateBy != opencost.AccumulateOptionNone {
+		asr, err = asr.Accumulate(accumulateBy)
+		if err != nil {
+			return nil, fmt.Errorf("error accumulating by %v: %w", accumulateBy, err)
+		}
+	}
+
+	return asr, nil
+}
+
 func (cm *CostModel) ComputeAssets(start, end time.Time) (*opencost.AssetSet, error) {
 	assetSet := opencost.NewAssetSet(start, end)
 
@@ -0,0 +1,77 @@
+package costmodel
+
+import (
+	"context"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/budget"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// allocationBudgetQuerier is a budget.Querier of the spend of budgets over
+// allocations
+type allocationBudgetQuerier struct {
+	model *CostModel
+}
+
+func (q *allocationBudgetQuerier) DailyCosts(ctx context.Context, b *budget.Budget, window opencost.Window) ([]float64, error) {
+	f, err := b.ParseFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Aggregate by cluster to reduce the allocations to sum, as only the total
+	// of each day is needed
+	asr, err := q.model.QueryAllocation(ctx, window, env.GetETLResolution(), 24*time.Hour, []string{opencost.AllocationClusterProp}, f, nil, false, false, false, false, false, true, opencost.AccumulateOptionNone)
+	if err != nil {
+		return nil, err
+	}
+
+	costs := make([]float64, 0, len(asr.Allocations))
+	for _, as := range asr.Allocations {
+		costs = append(costs, as.TotalCost())
+	}
+	return costs, nil
+}
+
+// InitializeBudgets registers the budget endpoints, and starts evaluating the
+// budgets against the allocation costs of the model and the cloud costs of the
+// querier. Either the model or querier may be nil if its costs are not
+// available, in which case budgets over those costs are not evaluated.
+func InitializeBudgets(router *httprouter.Router, model *CostModel, cloudCostQuerier cloudcost.Querier) *budget.Evaluator {
+	store, err := NewETLStorage()
+	if err != nil {
+		log.Errorf("Init: failed to create storage for budgets: %s", err)
+		return nil
+	}
+	log.Infof("Init: persisting budgets using %s storage", store.StorageType())
+	budgetStore := budget.NewStore(store)
+
+	queriers := map[budget.Scope]budget.Querier{}
+	if model != nil {
+		queriers[budget.ScopeAllocation] = &allocationBudgetQuerier{model: model}
+	}
+	if cloudCostQuerier != nil {
+		queriers[budget.ScopeCloudCost] = budget.NewCloudCostQuerier(cloudCostQuerier)
+	}
+
+	evaluator := budget.NewEvaluator(budgetStore, budget.NewWebhookNotifier(env.GetBudgetWebhookTimeout()), queriers)
+	err = evaluator.Start(env.GetBudgetEvaluationInterval())
+	if err != nil {
+		log.Errorf("Init: failed to start budget evaluation: %s", err)
+		return nil
+	}
+
+	service := budget.NewService(budgetStore)
+	router.GET("/budgets", service.GetBudgetsHandler())
+	router.POST("/budgets", service.CreateBudgetHandler())
+	router.GET("/budgets/:id", service.GetBudgetHandler())
+	router.PUT("/budgets/:id", service.UpdateBudgetHandler())
+	router.DELETE("/budgets/:id", service.DeleteBudgetHandler())
+
+	return evaluator
+}
@@ -0,0 +1,284 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// AllocationCosts is the breakdown of an allocation's total cost by resource
+type AllocationCosts struct {
+	CPUCost          float64 `json:"cpuCost"`
+	GPUCost          float64 `json:"gpuCost"`
+	RAMCost          float64 `json:"ramCost"`
+	PVCost           float64 `json:"pvCost"`
+	NetworkCost      float64 `json:"networkCost"`
+	LoadBalancerCost float64 `json:"loadBalancerCost"`
+	SharedCost       float64 `json:"sharedCost"`
+	ExternalCost     float64 `json:"externalCost"`
+	TotalCost        float64 `json:"totalCost"`
+}
+
+// NewAllocationCosts returns the costs of the allocation, including
+// adjustments. A nil allocation has zero costs.
+func NewAllocationCosts(alloc *opencost.Allocation) AllocationCosts {
+	if alloc == nil {
+		return AllocationCosts{}
+	}
+
+	return AllocationCosts{
+		CPUCost:          alloc.CPUTotalCost(),
+		GPUCost:          alloc.GPUTotalCost(),
+		RAMCost:          alloc.RAMTotalCost(),
+		PVCost:           alloc.PVTotalCost(),
+		NetworkCost:      alloc.NetworkTotalCost(),
+		LoadBalancerCost: alloc.LoadBalancerTotalCost(),
+		SharedCost:       alloc.SharedTotalCost(),
+		ExternalCost:     alloc.ExternalCost,
+		TotalCost:        alloc.TotalCost(),
+	}
+}
+
+func (ac AllocationCosts) add(that AllocationCosts) AllocationCosts {
+	return AllocationCosts{
+		CPUCost:          ac.CPUCost + that.CPUCost,
+		GPUCost:          ac.GPUCost + that.GPUCost,
+		RAMCost:          ac.RAMCost + that.RAMCost,
+		PVCost:           ac.PVCost + that.PVCost,
+		NetworkCost:      ac.NetworkCost + that.NetworkCost,
+		LoadBalancerCost: ac.LoadBalancerCost + that.LoadBalancerCost,
+		SharedCost:       ac.SharedCost + that.SharedCost,
+		ExternalCost:     ac.ExternalCost + that.ExternalCost,
+		TotalCost:        ac.TotalCost + that.TotalCost,
+	}
+}
+
+func (ac AllocationCosts) sub(that AllocationCosts) AllocationCosts {
+	return AllocationCosts{
+		CPUCost:          ac.CPUCost - that.CPUCost,
+		GPUCost:          ac.GPUCost - that.GPUCost,
+		RAMCost:          ac.RAMCost - that.RAMCost,
+		PVCost:           ac.PVCost - that.PVCost,
+		NetworkCost:      ac.NetworkCost - that.NetworkCost,
+		LoadBalancerCost: ac.LoadBalancerCost - that.LoadBalancerCost,
+		SharedCost:       ac.SharedCost - that.SharedCost,
+		ExternalCost:     ac.ExternalCost - that.ExternalCost,
+		TotalCost:        ac.TotalCost - that.TotalCost,
+	}
+}
+
+// AllocationCostPercents is the percent change of each cost of an
+// allocation. A percent is nil if the cost it is relative to is zero.
+type AllocationCostPercents struct {
+	CPUCost          *float64 `json:"cpuCost"`
+	GPUCost          *float64 `json:"gpuCost"`
+	RAMCost          *float64 `json:"ramCost"`
+	PVCost           *float64 `json:"pvCost"`
+	NetworkCost      *float64 `json:"networkCost"`
+	LoadBalancerCost *float64 `json:"loadBalancerCost"`
+	SharedCost       *float64 `json:"sharedCost"`
+	ExternalCost     *float64 `json:"externalCost"`
+	TotalCost        *float64 `json:"totalCost"`
+}
+
+func newAllocationCostPercents(delta, base AllocationCosts) AllocationCostPercents {
+	percent := func(delta, base float64) *float64 {
+		if base == 0 {
+			return nil
+		}
+		p := delta / base * 100
+		return &p
+	}
+
+	return AllocationCostPercents{
+		CPUCost:          percent(delta.CPUCost, base.CPUCost),
+		GPUCost:          percent(delta.GPUCost, base.GPUCost),
+		RAMCost:          percent(delta.RAMCost, base.RAMCost),
+		PVCost:           percent(delta.PVCost, base.PVCost),
+		NetworkCost:      percent(delta.NetworkCost, base.NetworkCost),
+		LoadBalancerCost: percent(delta.LoadBalancerCost, base.LoadBalancerCost),
+		SharedCost:       percent(delta.SharedCost, base.SharedCost),
+		ExternalCost:     percent(delta.ExternalCost, base.ExternalCost),
+		TotalCost:        percent(delta.TotalCost, base.TotalCost),
+	}
+}
+
+// AllocationCostComparison compares the costs of an aggregation key in two
+// windows. Delta is the change from the compared window to the window, and
+// PercentDelta is that change relative to the compared window's costs.
+type AllocationCostComparison struct {
+	Name         string                 `json:"name"`
+	Costs        AllocationCosts        `json:"costs"`
+	CompareCosts AllocationCosts        `json:"compareCosts"`
+	Delta        AllocationCosts        `json:"delta"`
+	PercentDelta AllocationCostPercents `json:"percentDelta"`
+}
+
+func newAllocationCostComparison(name string, costs, compareCosts AllocationCosts) *AllocationCostComparison {
+	delta := costs.sub(compareCosts)
+	return &AllocationCostComparison{
+		Name:         name,
+		Costs:        costs,
+		CompareCosts: compareCosts,
+		Delta:        delta,
+		PercentDelta: newAllocationCostPercents(delta, compareCosts),
+	}
+}
+
+// AllocationComparison compares the allocations of one window to those of
+// another, per aggregation key. Appeared lists the keys which only have costs
+// in the window, and Disappeared those which only have costs in the compared
+// window.
+type AllocationComparison struct {
+	Window        opencost.Window                      `json:"window"`
+	CompareWindow opencost.Window                      `json:"compareWindow"`
+	Total         *AllocationCostComparison            `json:"total"`
+	Allocations   map[string]*AllocationCostComparison `json:"allocations"`
+	Appeared      []string                             `json:"appeared"`
+	Disappeared   []string                             `json:"disappeared"`
+}
+
+// CompareAllocationSets compares the allocations of the set to those of the
+// compared set by name. Either set may be nil, in which case it is treated as
+// having no allocations.
+func CompareAllocationSets(as, compareAS *opencost.AllocationSet) *AllocationComparison {
+	comparison := &AllocationComparison{
+		Allocations: map[string]*AllocationCostComparison{},
+		Appeared:    []string{},
+		Disappeared: []string{},
+	}
+
+	allocs := map[string]*opencost.Allocation{}
+	if as != nil {
+		comparison.Window = as.Window.Clone()
+		allocs = as.Allocations
+	}
+	compareAllocs := map[string]*opencost.Allocation{}
+	if compareAS != nil {
+		comparison.CompareWindow = compareAS.Window.Clone()
+		compareAllocs = compareAS.Allocations
+	}
+
+	var total, compareTotal AllocationCosts
+
+	for name, alloc := range allocs {
+		costs := NewAllocationCosts(alloc)
+		compareCosts := NewAllocationCosts(compareAllocs[name])
+		if _, ok := compareAllocs[name]; !ok {
+			comparison.Appeared = append(comparison.Appeared, name)
+		}
+
+		comparison.Allocations[name] = newAllocationCostComparison(name, costs, compareCosts)
+		total = total.add(costs)
+		compareTotal = compareTotal.add(compareCosts)
+	}
+
+	for name, compareAlloc := range compareAllocs {
+		if _, ok := allocs[name]; ok {
+			continue
+		}
+
+		compareCosts := NewAllocationCosts(compareAlloc)
+		comparison.Disappeared = append(comparison.Disappeared, name)
+		comparison.Allocations[name] = newAllocationCostComparison(name, AllocationCosts{}, compareCosts)
+		compareTotal = compareTotal.add(compareCosts)
+	}
+
+	sort.Strings(comparison.Appeared)
+	sort.Strings(comparison.Disappeared)
+	comparison.Total = newAllocationCostComparison("", total, compareTotal)
+
+	return comparison
+}
+
+// ComputeAllocationCompareHandler compares the allocations of two windows.
+// It accepts the same aggregate, filter, share and idle parameters as
+// /allocation, which are applied to both windows. The 'compareWindow'
+// parameter is the window to compare to, defaulting to the period of the
+// same duration immediately before 'window'.
+func (a *Accesses) ComputeAllocationCompareHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	query, err := a.parseAllocationQuery(qp)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+	if query.window.IsOpen() || query.window.IsNegative() {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", query.window)))
+		return
+	}
+
+	compareWindow := opencost.NewClosedWindow(query.window.Start().Add(-query.window.Duration()), *query.window.Start())
+	if qp.Has("compareWindow") {
+		compareWindow, err = opencost.ParseWindowWithOffset(qp.Get("compareWindow", ""), env.GetParsedUTCOffset())
+		if err != nil || compareWindow.IsOpen() || compareWindow.IsNegative() {
+			WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'compareWindow' parameter: %s", qp.Get("compareWindow", ""))))
+			return
+		}
+	}
+
+	as, err := a.queryAccumulatedAllocation(r.Context(), query, query.window, qp.Has("step"))
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	compareAS, err := a.queryAccumulatedAllocation(r.Context(), query, compareWindow, qp.Has("step"))
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	comparison := CompareAllocationSets(as, compareAS)
+	comparison.Window = query.window.Clone()
+	comparison.CompareWindow = compareWindow.Clone()
+
+	w.Write(WrapDataWithCurrency(comparison, query.conversion))
+}
+
+// queryAccumulatedAllocation runs the allocation query for the given window,
+// accumulated into a single set. Unless a step was requested, the window is
+// computed as a single step.
+func (a *Accesses) queryAccumulatedAllocation(ctx context.Context, q *allocationQuery, window opencost.Window, hasStep bool) (*opencost.AllocationSet, error) {
+	wq := *q
+	wq.window = window
+	wq.accumulateBy = opencost.AccumulateOptionAll
+	if !hasStep {
+		wq.step = window.Duration()
+	}
+
+	asr, err := a.queryAllocation(ctx, &wq)
+	if err != nil {
+		return nil, fmt.Errorf("error querying allocations for %s: %w", window, err)
+	}
+
+	if asr == nil || len(asr.Allocations) == 0 {
+		return nil, nil
+	}
+	return asr.Allocations[0], nil
+}
@@ -0,0 +1,84 @@
+package costmodel
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/json"
+)
+
+func TestCompareAllocationSets(t *testing.T) {
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	newAlloc := func(name string, start time.Time, cpuCost, ramCost float64) *opencost.Allocation {
+		return &opencost.Allocation{
+			Name:       name,
+			Properties: &opencost.AllocationProperties{Namespace: name},
+			Window:     opencost.NewClosedWindow(start, start.Add(week)),
+			Start:      start,
+			End:        start.Add(week),
+			CPUCost:    cpuCost,
+			RAMCost:    ramCost,
+		}
+	}
+
+	prevStart := start.Add(-week)
+	current := opencost.NewAllocationSet(start, start.Add(week),
+		newAlloc("app", start, 14, 6),
+		newAlloc("new", start, 5, 0),
+	)
+	previous := opencost.NewAllocationSet(prevStart, start,
+		newAlloc("app", prevStart, 10, 4),
+		newAlloc("old", prevStart, 0, 3),
+	)
+
+	comparison := CompareAllocationSets(current, previous)
+
+	if len(comparison.Appeared) != 1 || comparison.Appeared[0] != "new" {
+		t.Errorf("expected 'new' to have appeared, got %v", comparison.Appeared)
+	}
+	if len(comparison.Disappeared) != 1 || comparison.Disappeared[0] != "old" {
+		t.Errorf("expected 'old' to have disappeared, got %v", comparison.Disappeared)
+	}
+	if len(comparison.Allocations) != 3 {
+		t.Fatalf("expected 3 allocations, got %d", len(comparison.Allocations))
+	}
+
+	app := comparison.Allocations["app"]
+	if app.Costs.TotalCost != 20 || app.CompareCosts.TotalCost != 14 || app.Delta.CPUCost != 4 || app.Delta.RAMCost != 2 || app.Delta.TotalCost != 6 {
+		t.Errorf("unexpected comparison for 'app': %+v", app)
+	}
+	if app.PercentDelta.CPUCost == nil || *app.PercentDelta.CPUCost != 40 || app.PercentDelta.RAMCost == nil || *app.PercentDelta.RAMCost != 50 {
+		t.Errorf("unexpected percent delta for 'app': %+v", app.PercentDelta)
+	}
+	if app.PercentDelta.GPUCost != nil {
+		t.Errorf("expected no percent delta for a cost which was zero, got %f", *app.PercentDelta.GPUCost)
+	}
+
+	old := comparison.Allocations["old"]
+	if old.Costs.TotalCost != 0 || old.Delta.TotalCost != -3 || *old.PercentDelta.TotalCost != -100 {
+		t.Errorf("unexpected comparison for 'old': %+v", old)
+	}
+	if comparison.Allocations["new"].PercentDelta.TotalCost != nil {
+		t.Errorf("expected no percent delta for a new allocation")
+	}
+
+	if comparison.Total.Costs.TotalCost != 25 || comparison.Total.CompareCosts.TotalCost != 17 || math.Abs(*comparison.Total.PercentDelta.TotalCost-800.0/17) > 1e-9 {
+		t.Errorf("unexpected total: %+v", comparison.Total)
+	}
+
+	// Percents which are undefined must still marshal
+	_, err := json.Marshal(comparison)
+	if err != nil {
+		t.Errorf("failed to marshal comparison: %s", err)
+	}
+
+	// A missing set is treated as empty
+	comparison = CompareAllocationSets(current, nil)
+	if len(comparison.Appeared) != 2 || len(comparison.Disappeared) != 0 || comparison.Total.CompareCosts.TotalCost != 0 {
+		t.Errorf("unexpected comparison with nil set: %+v", comparison)
+	}
+}
@@ -1,6 +1,7 @@
 package costmodel
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/opencost/opencost/core/pkg/clusters"
+	"github.com/opencost/opencost/core/pkg/filter"
 	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/core/pkg/util"
@@ -17,6 +19,7 @@ import (
 	costAnalyzerCloud "github.com/opencost/opencost/pkg/cloud/models"
 	"github.com/opencost/opencost/pkg/clustercache"
 	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/etl"
 	"github.com/opencost/opencost/pkg/prom"
 	prometheus "github.com/prometheus/client_golang/api"
 	prometheusClient "github.com/prometheus/client_golang/api"
@@ -62,6 +65,14 @@ type CostModel struct {
 	PrometheusClient           prometheus.Client
 	Provider                   costAnalyzerCloud.Provider
 	pricingMetadata            *costAnalyzerCloud.PricingMatchMetadata
+
+	// AllocationStore and AssetStore, if set, persist sets built in the
+	// background so that they can be queried without Prometheus
+	AllocationStore *etl.Store[*opencost.AllocationSet]
+	AssetStore      *etl.Store[*opencost.AssetSet]
+
+	// Reconciler, if set, adjusts queried allocations to billed costs
+	Reconciler *Reconciler
 }
 
 func NewCostModel(client prometheus.Client, provider costAnalyzerCloud.Provider, cache clustercache.ClusterCache, clusterMap clusters.ClusterMap, scrapeInterval time.Duration) *CostModel {
@@ -2408,12 +2419,17 @@ func measureTimeAsync(start time.Time, threshold time.Duration, name string, ch
 	}
 }
 
-func (cm *CostModel) QueryAllocation(window opencost.Window, resolution, step time.Duration, aggregate []string, includeIdle, idleByNode, includeProportionalAssetResourceCosts, includeAggregatedMetadata, sharedLoadBalancer bool, accumulateBy opencost.AccumulateOption) (*opencost.AllocationSetRange, error) {
+func (cm *CostModel) QueryAllocation(ctx context.Context, window opencost.Window, resolution, step time.Duration, aggregate []string, allocFilter filter.Filter, shareOpts *AllocationShareOptions, includeIdle, idleByNode, includeProportionalAssetResourceCosts, includeAggregatedMetadata, sharedLoadBalancer, reconcile bool, accumulateBy opencost.AccumulateOption) (*opencost.AllocationSetRange, error) {
 	// Validate window is legal
 	if window.IsOpen() || window.IsNegative() {
 		return nil, fmt.Errorf("illegal window: %s", window)
 	}
 
+	// Idle is required to be computed in order to be shared
+	if shareOpts != nil && shareOpts.ShareIdle {
+		includeIdle = true
+	}
+
 	var totalsStore opencost.TotalsStore
 	// Idle is required for proportional asset costs
 	if includeProportionalAssetResourceCosts {
@@ -2423,70 +2439,99 @@ func (cm *CostModel) QueryAllocation(window opencost.Window, resolution, step ti
 		totalsStore = opencost.NewMemoryTotalsStore()
 	}
 
+	// Reconciliation requires the billed costs of cloud costs
+	reconcile = reconcile && cm.Reconciler != nil
+
 	// Begin with empty response
 	asr := opencost.NewAllocationSetRange()
 
-	// Query for AllocationSets in increments of the given step duration,
-	// appending each to the response.
-	stepStart := *window.Start()
-	stepEnd := stepStart.Add(step)
-	var isAKS bool
-	for window.End().After(stepStart) {
-		allocSet, err := cm.ComputeAllocation(stepStart, stepEnd, resolution)
+	// Compute the AllocationSet, and the AssetSet if idle is included or
+	// allocations are reconciled, for each step concurrently, bounded by the
+	// maximum query concurrency.
+	type allocationStep struct {
+		allocSet *opencost.AllocationSet
+		assetSet *opencost.AssetSet
+	}
+
+	results, err := computeQuerySteps(ctx, getQuerySteps(window, step), env.GetMaxQueryConcurrency(), func(ctx context.Context, stepStart, stepEnd time.Time) (allocationStep, error) {
+		allocSet, err := cm.loadOrComputeAllocation(ctx, stepStart, stepEnd, resolution)
 		if err != nil {
-			return nil, fmt.Errorf("error computing allocations for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
+			return allocationStep{}, fmt.Errorf("error computing allocations for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
 		}
 
-		if includeIdle {
-			assetSet, err := cm.ComputeAssets(stepStart, stepEnd)
+		if !includeIdle && !reconcile {
+			return allocationStep{allocSet: allocSet}, nil
+		}
+
+		assetSet, err := cm.loadOrComputeAssets(ctx, stepStart, stepEnd)
+		if err != nil {
+			return allocationStep{}, fmt.Errorf("error computing assets for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
+		}
+
+		if reconcile {
+			// Each step loads or computes its own sets, and Reconcile only
+			// fails before adjusting them, so they are reconciled in place
+			err := cm.Reconciler.Reconcile(ctx, allocSet, assetSet)
 			if err != nil {
-				return nil, fmt.Errorf("error computing assets for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
+				log.Warnf("error reconciling allocations for %s: %s", opencost.NewClosedWindow(stepStart, stepEnd), err)
 			}
+		}
+
+		if !includeIdle {
+			return allocationStep{allocSet: allocSet}, nil
+		}
 
-			if includeProportionalAssetResourceCosts {
+		idleSet, err := computeIdleAllocations(allocSet, assetSet, true)
+		if err != nil {
+			return allocationStep{}, fmt.Errorf("error computing idle allocations for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
+		}
 
-				// AKS is a special case - there can be a maximum of 2
-				// load balancers (1 public and 1 private) in an AKS cluster
-				// therefore, when calculating PARCs for load balancers,
-				// we must know if this is an AKS cluster
-				for _, node := range assetSet.Nodes {
-					if _, found := node.Labels["label_kubernetes_azure_com_cluster"]; found {
-						isAKS = true
-						break
-					}
-				}
+		for _, idleAlloc := range idleSet.Allocations {
+			allocSet.Insert(idleAlloc)
+		}
 
-				_, err := opencost.UpdateAssetTotalsStore(totalsStore, assetSet)
-				if err != nil {
-					log.Errorf("ETL: error updating asset resource totals for %s: %s", assetSet.Window, err)
+		return allocationStep{allocSet: allocSet, assetSet: assetSet}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Append each step to the response in order.
+	var isAKS bool
+	for _, result := range results {
+		if includeProportionalAssetResourceCosts && result.assetSet != nil {
+
+			// AKS is a special case - there can be a maximum of 2
+			// load balancers (1 public and 1 private) in an AKS cluster
+			// therefore, when calculating PARCs for load balancers,
+			// we must know if this is an AKS cluster
+			for _, node := range result.assetSet.Nodes {
+				if _, found := node.Labels["label_kubernetes_azure_com_cluster"]; found {
+					isAKS = true
+					break
 				}
 			}
 
-			idleSet, err := computeIdleAllocations(allocSet, assetSet, true)
+			_, err := opencost.UpdateAssetTotalsStore(totalsStore, result.assetSet)
 			if err != nil {
-				return nil, fmt.Errorf("error computing idle allocations for %s: %w", opencost.NewClosedWindow(stepStart, stepEnd), err)
-			}
-
-			for _, idleAlloc := range idleSet.Allocations {
-				allocSet.Insert(idleAlloc)
+				log.Errorf("ETL: error updating asset resource totals for %s: %s", result.assetSet.Window, err)
 			}
 		}
 
-		asr.Append(allocSet)
-
-		stepStart = stepEnd
-		stepEnd = stepStart.Add(step)
+		asr.Append(result.allocSet)
 	}
 
 	// Set aggregation options and aggregate
 	opts := &opencost.AllocationAggregationOptions{
+		Filter:                                allocFilter,
 		IncludeProportionalAssetResourceCosts: includeProportionalAssetResourceCosts,
 		IdleByNode:                            idleByNode,
 		IncludeAggregatedMetadata:             includeAggregatedMetadata,
 	}
+	shareOpts.apply(opts)
 
 	// Aggregate
-	err := asr.AggregateBy(aggregate, opts)
+	err = asr.AggregateBy(aggregate, opts)
 	if err != nil {
 		return nil, fmt.Errorf("error aggregating for %s: %w", window, err)
 	}
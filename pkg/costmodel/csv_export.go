@@ -3,18 +3,17 @@ package costmodel
 import (
 	"context"
 	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sort"
-	"strconv"
 	"time"
 
 	"github.com/opencost/opencost/core/pkg/log"
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/pkg/filemanager"
+	"github.com/opencost/opencost/pkg/tabular"
 )
 
 type AllocationModel interface {
@@ -155,172 +154,31 @@ func (e *csvExporter) availableAllocationDates() (map[time.Time]struct{}, error)
 }
 
 func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates []time.Time) error {
-	fmtFloat := func(f float64) string {
-		return strconv.FormatFloat(f, 'f', -1, 64)
-	}
-
 	type rowData struct {
 		date  time.Time
 		alloc *opencost.Allocation
 	}
 
-	type columnDef struct {
-		column string
-		value  func(data rowData) string
-	}
-
-	csvDef := []columnDef{
-		{
-			column: "Date",
-			value: func(data rowData) string {
-				return data.date.Format("2006-01-02")
-			},
-		},
-		{
-			column: "Namespace",
-			value: func(data rowData) string {
-				return data.alloc.Properties.Namespace
-			},
-		},
-		{
-			column: "ControllerKind",
-			value: func(data rowData) string {
-				return data.alloc.Properties.ControllerKind
-			},
-		},
-		{
-			column: "ControllerName",
-			value: func(data rowData) string {
-				return data.alloc.Properties.Controller
-			},
-		},
-		{
-			column: "Pod",
-			value: func(data rowData) string {
-				return data.alloc.Properties.Pod
-			},
-		},
-		{
-			column: "Container",
-			value: func(data rowData) string {
-				return data.alloc.Properties.Container
-			},
-		},
-		{
-			column: "CPUCoreUsageAverage",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.CPUCoreUsageAverage)
-			},
-		},
-		{
-			column: "CPUCoreRequestAverage",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.CPUCoreRequestAverage)
-			},
-		},
-		{
-			column: "RAMBytesUsageAverage",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.RAMBytesUsageAverage)
-			},
-		},
-		{
-			column: "RAMBytesRequestAverage",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.RAMBytesRequestAverage)
-			},
-		},
-		{
-			column: "NetworkReceiveBytes",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.NetworkReceiveBytes)
-			},
-		},
-		{
-			column: "NetworkTransferBytes",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.NetworkTransferBytes)
-			},
-		},
-		{
-			column: "GPUs",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.GPUs())
-			},
-		},
-		{
-			column: "PVBytes",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.PVBytes())
-			},
-		},
-		{
-			column: "CPUCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.CPUTotalCost())
-			},
-		},
-		{
-			column: "RAMCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.RAMTotalCost())
-			},
-		},
-		{
-			column: "NetworkCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.NetworkTotalCost())
-			},
-		},
-		{
-			column: "PVCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.PVTotalCost())
-			},
-		},
-		{
-			column: "GPUCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.GPUTotalCost())
-			},
-		},
-		{
-			column: "TotalCost",
-			value: func(data rowData) string {
-				return fmtFloat(data.alloc.TotalCost())
-			},
-		},
-	}
-	if e.LabelsAll {
-		csvDef = append(csvDef, columnDef{
-			column: "Labels",
-			value: func(data rowData) string {
-				return fmtLabelsCSV(data.alloc.Properties.Labels)
-			},
-		})
-	}
-	for i := range e.Labels {
-		label := e.Labels[i] // it's important to copy the label name, otherwise all closures will reference the same label
-		csvDef = append(csvDef, columnDef{
-			column: "Label_" + label,
-			value: func(data rowData) string {
-				value, _ := data.alloc.Properties.Labels[label]
-				return value
-			},
-		})
-	}
-
-	header := make([]string, 0, len(csvDef))
-	for _, def := range csvDef {
-		header = append(header, def.column)
-	}
-
-	csvWriter := csv.NewWriter(w)
-	lines := 0
-	err := csvWriter.Write(header)
+	csvDef := []tabular.ColumnDef[rowData]{
+		tabular.StringColumnDef("Date", func(data rowData) string {
+			return data.date.Format("2006-01-02")
+		}),
+	}
+	csvDef = append(csvDef, tabular.MapColumnDefs(allocationColumnDefs(e.Labels, e.LabelsAll), func(data rowData) *opencost.Allocation {
+		return data.alloc
+	})...)
+
+	columns := tabular.Columns(csvDef)
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+
+	csvWriter, err := tabular.NewCSVWriter(w, columns)
 	if err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+		return err
 	}
+	lines := 0
 
 	log.Infof("writing CSV with header: %v", header)
 
@@ -336,13 +194,9 @@ func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates [
 			if err := ctx.Err(); err != nil {
 				return err
 			}
-			row := make([]string, 0, len(csvDef))
-			for _, def := range csvDef {
-				row = append(row, def.value(rowData{date: date, alloc: alloc}))
-			}
-			err := csvWriter.Write(row)
+			err := tabular.WriteRow(csvWriter, csvDef, rowData{date: date, alloc: alloc})
 			if err != nil {
-				return fmt.Errorf("failed to write csv row: %w", err)
+				return err
 			}
 
 			lines++
@@ -353,27 +207,13 @@ func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates [
 		return errNoData
 	}
 
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
+	if err := csvWriter.Close(); err != nil {
 		return err
 	}
 	log.Infof("exported %d lines", lines)
 	return nil
 }
 
-func fmtLabelsCSV(labels map[string]string) string {
-	if len(labels) == 0 {
-		return ""
-	}
-
-	data, err := json.Marshal(labels)
-	if err != nil {
-		log.Errorf("failed to marshal labels: %s", err)
-		return ""
-	}
-	return string(data)
-}
-
 // loadDate scans through CSV export file and extract all dates from "Date" column
 func (e *csvExporter) loadDates(csvFile *os.File) (map[time.Time]struct{}, error) {
 	_, err := csvFile.Seek(0, io.SeekStart)
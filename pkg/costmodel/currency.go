@@ -0,0 +1,65 @@
+package costmodel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/cloud/models"
+	"github.com/opencost/opencost/pkg/currency"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// InitializeCurrencyConversion creates the currency.Converter of the
+// exchange-rate table, which is read from the ETL storage if
+// CURRENCY_RATES_STORAGE_PATH is set, and otherwise from CURRENCY_RATES_PATH.
+// Costs are assumed to be in the currency of the provider's pricing, if any.
+func InitializeCurrencyConversion(cp models.Provider) *currency.Converter {
+	base := ""
+	if cp != nil {
+		if pricing, err := cp.GetConfig(); err != nil {
+			log.Warnf("Init: failed to get currency of pricing, using the base currency of the rate table: %s", err)
+		} else if pricing != nil {
+			base = pricing.CurrencyCode
+		}
+	}
+
+	source := currency.FileSource(env.GetCurrencyRatesPath())
+	if storagePath := env.GetCurrencyRatesStoragePath(); storagePath != "" {
+		store, err := NewETLStorage()
+		if err != nil {
+			log.Errorf("Init: failed to create storage for exchange rates, reading %s: %s", env.GetCurrencyRatesPath(), err)
+		} else {
+			log.Infof("Init: reading exchange rates from %s", store.FullPath(storagePath))
+			source = currency.StorageSource(store, storagePath)
+		}
+	}
+
+	converter := currency.NewConverter(source, base, env.GetCurrencyRatesRefreshInterval())
+	if _, err := converter.RateTable(); err != nil {
+		log.Errorf("Init: failed to load exchange rates: %s", err)
+	}
+	return converter
+}
+
+// parseCurrencyConversion parses the optional 'currency' parameter, returning
+// the Conversion of costs to it, or nil if costs are not converted. Errors
+// caused by invalid parameters are prefixed with "bad request".
+func (a *Accesses) parseCurrencyConversion(qp httputil.QueryParams) (*currency.Conversion, error) {
+	if !qp.Has("currency") {
+		return nil, nil
+	}
+	if a.CurrencyConverter == nil {
+		return nil, fmt.Errorf("bad request - invalid 'currency' parameter: currency conversion is not enabled")
+	}
+
+	conversion, err := a.CurrencyConverter.NewConversion(qp.Get("currency", ""))
+	if errors.Is(err, currency.ErrUnknownCurrency) {
+		return nil, fmt.Errorf("bad request - invalid 'currency' parameter: %s", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error converting currency: %w", err)
+	}
+	return conversion, nil
+}
@@ -0,0 +1,186 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/etl"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+const (
+	allocationStoreDir = "allocation"
+	assetStoreDir      = "assets"
+)
+
+// NewETLStorage creates the storage.Storage used to persist ETL data. A bucket
+// storage is used if ETL_BUCKET_CONFIG is set, otherwise data is written to
+// the local file system at ETL_FILE_STORE_PATH.
+func NewETLStorage() (storage.Storage, error) {
+	bucketConfigPath := env.GetETLBucketConfig()
+	if bucketConfigPath == "" {
+		return storage.NewFileStorage(env.GetETLFileStorePath()), nil
+	}
+
+	bucketConfig, err := os.ReadFile(bucketConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ETL bucket config %s: %w", bucketConfigPath, err)
+	}
+
+	store, err := storage.NewBucketStorage(bucketConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ETL bucket storage: %w", err)
+	}
+
+	return store, nil
+}
+
+// InitializeAllocationStore creates the persistent AllocationSet store, which
+// builds daily and hourly AllocationSets at the ETL resolution and writes them
+// to the given storage. The store must be started separately.
+func (cm *CostModel) InitializeAllocationStore(store storage.Storage) error {
+	resolution := env.GetETLResolution()
+
+	allocStore, err := etl.NewStorageStore(
+		"Allocation",
+		store,
+		allocationStoreDir,
+		etl.DefaultPipelineConfigs(),
+		func() *opencost.AllocationSet { return &opencost.AllocationSet{} },
+		func(start, end time.Time) (*opencost.AllocationSet, error) {
+			return cm.ComputeAllocation(start, end, resolution)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create allocation store: %w", err)
+	}
+
+	cm.AllocationStore = allocStore
+	return nil
+}
+
+// InitializeAssetStore creates the persistent AssetSet store, which builds
+// daily and hourly AssetSets and writes them to the given storage. The store
+// must be started separately.
+func (cm *CostModel) InitializeAssetStore(store storage.Storage) error {
+	assetStore, err := etl.NewStorageStore(
+		"Asset",
+		store,
+		assetStoreDir,
+		etl.DefaultPipelineConfigs(),
+		func() *opencost.AssetSet { return &opencost.AssetSet{} },
+		cm.ComputeAssets,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create asset store: %w", err)
+	}
+
+	cm.AssetStore = assetStore
+	return nil
+}
+
+// loadOrComputeAllocation returns the AllocationSet for the given window from
+// the persistent store, if the store is enabled and holds the window at the
+// requested resolution. Otherwise, the AllocationSet is computed directly,
+// unless the context has been cancelled.
+func (cm *CostModel) loadOrComputeAllocation(ctx context.Context, start, end time.Time, resolution time.Duration) (*opencost.AllocationSet, error) {
+	if cm.AllocationStore != nil && resolution == env.GetETLResolution() {
+		sets, ok, err := cm.AllocationStore.Query(start, end)
+		if err != nil {
+			log.Warnf("ETL: failed to load allocations for %s from store: %s", opencost.NewClosedWindow(start, end), err)
+		} else if ok {
+			return accumulateAllocationSets(sets)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cm.ComputeAllocation(start, end, resolution)
+}
+
+// loadOrComputeAssets returns the AssetSet for the given window from the
+// persistent store, if the store is enabled and holds the window. Otherwise,
+// the AssetSet is computed directly, unless the context has been cancelled.
+func (cm *CostModel) loadOrComputeAssets(ctx context.Context, start, end time.Time) (*opencost.AssetSet, error) {
+	if cm.AssetStore != nil {
+		sets, ok, err := cm.AssetStore.Query(start, end)
+		if err != nil {
+			log.Warnf("ETL: failed to load assets for %s from store: %s", opencost.NewClosedWindow(start, end), err)
+		} else if ok {
+			return accumulateAssetSets(sets)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cm.ComputeAssets(start, end)
+}
+
+// accumulateAllocationSets combines contiguous AllocationSets into a single
+// AllocationSet covering all of their windows.
+func accumulateAllocationSets(sets []*opencost.AllocationSet) (*opencost.AllocationSet, error) {
+	if len(sets) == 1 {
+		return sets[0], nil
+	}
+
+	asr, err := opencost.NewAllocationSetRange(sets...).Accumulate(opencost.AccumulateOptionAll)
+	if err != nil {
+		return nil, fmt.Errorf("error accumulating stored allocations: %w", err)
+	}
+
+	if len(asr.Allocations) != 1 {
+		return nil, fmt.Errorf("error accumulating stored allocations: expected 1 set, found %d", len(asr.Allocations))
+	}
+
+	return asr.Allocations[0], nil
+}
+
+// accumulateAssetSets combines contiguous AssetSets into a single AssetSet
+// covering all of their windows.
+func accumulateAssetSets(sets []*opencost.AssetSet) (*opencost.AssetSet, error) {
+	if len(sets) == 1 {
+		return sets[0], nil
+	}
+
+	as, err := opencost.NewAssetSetRange(sets...).AccumulateToAssetSet()
+	if err != nil {
+		return nil, fmt.Errorf("error accumulating stored assets: %w", err)
+	}
+
+	return as, nil
+}
+
+// AllocationStoreStatusHandler returns the status of the persistent
+// allocation store pipelines.
+func (a *Accesses) AllocationStoreStatusHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if a.Model == nil || a.Model.AllocationStore == nil {
+		http.Error(w, "Allocation store is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	w.Write(WrapData(a.Model.AllocationStore.Status(), nil))
+}
+
+// AssetStoreStatusHandler returns the status of the persistent asset store
+// pipelines.
+func (a *Accesses) AssetStoreStatusHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if a.Model == nil || a.Model.AssetStore == nil {
+		http.Error(w, "Asset store is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	w.Write(WrapData(a.Model.AssetStore.Status(), nil))
+}
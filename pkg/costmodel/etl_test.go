@@ -0,0 +1,162 @@
+package costmodel
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/filter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/etl"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func TestCostModel_loadOrComputeAllocation(t *testing.T) {
+	build := func(start, end time.Time) (*opencost.AllocationSet, error) {
+		return opencost.NewAllocationSet(start, end, opencost.NewMockUnitAllocation("cluster1/namespace1/pod1/container1", start, end.Sub(start), nil)), nil
+	}
+
+	repo := etl.NewStorageRepository(storage.NewFileStorage(t.TempDir()), allocationStoreDir, time.Hour, func() *opencost.AllocationSet {
+		return &opencost.AllocationSet{}
+	})
+	p, err := etl.NewPipeline[*opencost.AllocationSet]("Allocation", etl.PipelineConfig{Resolution: time.Hour, Duration: 2 * time.Hour}, repo, build)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.Run()
+
+	cm := &CostModel{AllocationStore: etl.NewStore(p)}
+
+	end := opencost.RoundBack(time.Now().UTC(), time.Hour)
+	start := end.Add(-2 * time.Hour)
+
+	as, err := cm.loadOrComputeAllocation(context.Background(), start, end, env.GetETLResolution())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !as.Window.Equal(opencost.NewClosedWindow(start, end)) {
+		t.Errorf("expected window %s, got %s", opencost.NewClosedWindow(start, end), as.Window)
+	}
+
+	hourly, _ := build(start, start.Add(time.Hour))
+	if math.Abs(as.TotalCost()-2*hourly.TotalCost()) > 0.0001 {
+		t.Errorf("expected total cost %f, got %f", 2*hourly.TotalCost(), as.TotalCost())
+	}
+
+	// Windows which are not in the store are not computed once cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cm.loadOrComputeAllocation(ctx, end, end.Add(time.Hour), env.GetETLResolution())
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestCostModel_QueryAssets(t *testing.T) {
+	build := func(start, end time.Time) (*opencost.AssetSet, error) {
+		return opencost.GenerateMockAssetSet(start, end.Sub(start)), nil
+	}
+
+	repo := etl.NewStorageRepository(storage.NewFileStorage(t.TempDir()), assetStoreDir, time.Hour, func() *opencost.AssetSet {
+		return &opencost.AssetSet{}
+	})
+	p, err := etl.NewPipeline[*opencost.AssetSet]("Asset", etl.PipelineConfig{Resolution: time.Hour, Duration: 2 * time.Hour}, repo, build)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.Run()
+
+	cm := &CostModel{AssetStore: etl.NewStore(p)}
+
+	end := opencost.RoundBack(time.Now().UTC(), time.Hour)
+	start := end.Add(-2 * time.Hour)
+	window := opencost.NewClosedWindow(start, end)
+
+	hourly, _ := build(start, start.Add(time.Hour))
+	hourlyCost := hourly.TotalCost()
+
+	clusterOne, err := ParseAssetFilter(`cluster:"cluster1"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := map[string]struct {
+		step         time.Duration
+		aggregateBy  []string
+		filter       filter.Filter
+		accumulateBy opencost.AccumulateOption
+		wantSets     int
+		wantKeys     int
+		wantCost     float64
+	}{
+		"hourly by cluster": {
+			step:        time.Hour,
+			aggregateBy: []string{"cluster"},
+			wantSets:    2,
+			wantKeys:    3,
+			wantCost:    2 * hourlyCost,
+		},
+		"accumulated by cluster": {
+			step:         time.Hour,
+			aggregateBy:  []string{"cluster"},
+			accumulateBy: opencost.AccumulateOptionAll,
+			wantSets:     1,
+			wantKeys:     3,
+			wantCost:     2 * hourlyCost,
+		},
+		"single step filtered by cluster": {
+			step:        2 * time.Hour,
+			aggregateBy: []string{"cluster"},
+			filter:      clusterOne,
+			wantSets:    1,
+			wantKeys:    1,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			asr, err := cm.QueryAssets(context.Background(), window, tt.step, tt.aggregateBy, tt.filter, tt.accumulateBy)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if asr.Length() != tt.wantSets {
+				t.Fatalf("expected %d sets, got %d", tt.wantSets, asr.Length())
+			}
+			for _, as := range asr.Assets {
+				if len(as.Assets) != tt.wantKeys {
+					t.Errorf("expected %d assets in %s, got %d", tt.wantKeys, as.Window, len(as.Assets))
+				}
+			}
+			if tt.wantCost > 0 && math.Abs(asr.TotalCost()-tt.wantCost) > 0.0001 {
+				t.Errorf("expected total cost %f, got %f", tt.wantCost, asr.TotalCost())
+			}
+		})
+	}
+
+	_, err = cm.QueryAssets(context.Background(), window, 0, nil, nil, opencost.AccumulateOptionNone)
+	if err == nil {
+		t.Errorf("expected error for zero step")
+	}
+}
+
+func TestParseAssetAggregationProperties(t *testing.T) {
+	got, err := ParseAssetAggregationProperties([]string{"Cluster", "providerid", "label:app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"cluster", "providerID", "label:app"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+
+	_, err = ParseAssetAggregationProperties([]string{"namespace"})
+	if err == nil {
+		t.Errorf("expected error for invalid asset property")
+	}
+}
@@ -0,0 +1,109 @@
+package costmodel
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloud/focus"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// focusAllocationAggregation identifies the Kubernetes resources whose
+// allocations are exported
+var focusAllocationAggregation = []string{
+	opencost.AllocationClusterProp,
+	opencost.AllocationNodeProp,
+	opencost.AllocationNamespaceProp,
+	opencost.AllocationControllerKindProp,
+	opencost.AllocationControllerProp,
+	opencost.AllocationPodProp,
+	opencost.AllocationContainerProp,
+}
+
+// focusCloudCostAggregation identifies the cloud costs which are exported,
+// which are the items of /cloudCost
+var focusCloudCostAggregation = []string{
+	opencost.CloudCostInvoiceEntityIDProp,
+	opencost.CloudCostAccountIDProp,
+	opencost.CloudCostProviderProp,
+	opencost.CloudCostProviderIDProp,
+	opencost.CloudCostCategoryProp,
+	opencost.CloudCostServiceProp,
+}
+
+// focusAllocationSource is a focus.AllocationSource of the allocations of the
+// model, including idle
+type focusAllocationSource struct {
+	model *CostModel
+}
+
+func (s *focusAllocationSource) Allocations(ctx context.Context, window opencost.Window) (*opencost.AllocationSet, error) {
+	asr, err := s.model.QueryAllocation(ctx, window, env.GetETLResolution(), 24*time.Hour, focusAllocationAggregation, nil, nil, true, false, false, false, false, true, opencost.AccumulateOptionNone)
+	if err != nil {
+		return nil, err
+	}
+	if len(asr.Allocations) == 0 {
+		return opencost.NewAllocationSet(*window.Start(), *window.End()), nil
+	}
+	return asr.Allocations[0], nil
+}
+
+// focusCloudCostSource is a focus.CloudCostSource of the cloud costs of a
+// querier
+type focusCloudCostSource struct {
+	querier cloudcost.Querier
+}
+
+func (s *focusCloudCostSource) CloudCosts(ctx context.Context, window opencost.Window) (*opencost.CloudCostSetRange, error) {
+	return s.querier.Query(ctx, cloudcost.QueryRequest{
+		Start:       *window.Start(),
+		End:         *window.End(),
+		AggregateBy: focusCloudCostAggregation,
+		Accumulate:  opencost.AccumulateOptionNone,
+	})
+}
+
+// InitializeFOCUSExport starts exporting the allocations of the model and the
+// cloud costs of the querier to FOCUS files in the ETL storage. Either the
+// model or querier may be nil if its costs are not available, in which case
+// they are not exported.
+func InitializeFOCUSExport(model *CostModel, cloudCostQuerier cloudcost.Querier) *focus.Exporter {
+	if model == nil && cloudCostQuerier == nil {
+		log.Warnf("Init: FOCUS export requires allocations or cloud costs")
+		return nil
+	}
+
+	format, err := tabular.ParseFormat(env.GetFOCUSExportFormat())
+	if err != nil {
+		log.Errorf("Init: invalid FOCUS export format: %s", err)
+		return nil
+	}
+
+	store, err := NewETLStorage()
+	if err != nil {
+		log.Errorf("Init: failed to create storage for FOCUS export: %s", err)
+		return nil
+	}
+	log.Infof("Init: exporting FOCUS %s files to %s storage", format, store.StorageType())
+
+	var allocations focus.AllocationSource
+	if model != nil {
+		allocations = &focusAllocationSource{model: model}
+	}
+	var cloudCosts focus.CloudCostSource
+	if cloudCostQuerier != nil {
+		cloudCosts = &focusCloudCostSource{querier: cloudCostQuerier}
+	}
+
+	exporter := focus.NewExporter(store, env.GetFOCUSExportPath(), format, env.GetFOCUSBillingCurrency(), cloudCosts, allocations)
+	err = exporter.Start(env.GetFOCUSExportInterval(), env.GetFOCUSExportDays())
+	if err != nil {
+		log.Errorf("Init: failed to start FOCUS export: %s", err)
+		return nil
+	}
+	return exporter
+}
@@ -0,0 +1,71 @@
+package costmodel
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/forecast"
+)
+
+// ComputeAllocationForecastHandler forecasts the daily total cost of each
+// allocation, fit to the whole days of 'window'. It accepts the same
+// aggregate, filter, share and idle parameters as /allocation. The 'horizon'
+// parameter is the duration to forecast, defaulting to the end of the month,
+// and 'confidence' the confidence level of the prediction bands.
+func (a *Accesses) ComputeAllocationForecastHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	query, err := a.parseAllocationQuery(qp)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	history, err := forecast.HistoryWindow(query.window, time.Now(), env.GetParsedUTCOffset())
+	if err != nil {
+		WriteError(w, BadRequest("bad request - invalid 'window' parameter: "+err.Error()))
+		return
+	}
+
+	opts, err := forecast.ParseOptions(qp, *history.End(), env.GetParsedUTCOffset())
+	if err != nil {
+		WriteError(w, BadRequest(err.Error()))
+		return
+	}
+
+	// The forecast is fit to the cost of each day of the history
+	query.window = history
+	query.step = 24 * time.Hour
+	query.accumulateBy = opencost.AccumulateOptionNone
+
+	asr, err := a.queryAllocation(r.Context(), query)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	result, err := forecast.ForecastAllocations(asr, opts)
+	if err != nil {
+		WriteError(w, InternalServerError(err.Error()))
+		return
+	}
+
+	w.Write(WrapDataWithCurrency(result, query.conversion))
+}
@@ -0,0 +1,335 @@
+package costmodel
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/currency"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+// allocationColumnDefs returns the columns written for each allocation by the
+// CSV exporter and the tabular formats of the allocation endpoints. If
+// labelsAll is true, all labels are written to a "Labels" column in JSON
+// format, and a column prefixed with "Label_" is written for each of the
+// given labels.
+func allocationColumnDefs(labels []string, labelsAll bool) []tabular.ColumnDef[*opencost.Allocation] {
+	properties := func(alloc *opencost.Allocation) *opencost.AllocationProperties {
+		if alloc.Properties == nil {
+			return &opencost.AllocationProperties{}
+		}
+		return alloc.Properties
+	}
+
+	defs := []tabular.ColumnDef[*opencost.Allocation]{
+		tabular.StringColumnDef("Namespace", func(alloc *opencost.Allocation) string {
+			return properties(alloc).Namespace
+		}),
+		tabular.StringColumnDef("ControllerKind", func(alloc *opencost.Allocation) string {
+			return properties(alloc).ControllerKind
+		}),
+		tabular.StringColumnDef("ControllerName", func(alloc *opencost.Allocation) string {
+			return properties(alloc).Controller
+		}),
+		tabular.StringColumnDef("Pod", func(alloc *opencost.Allocation) string {
+			return properties(alloc).Pod
+		}),
+		tabular.StringColumnDef("Container", func(alloc *opencost.Allocation) string {
+			return properties(alloc).Container
+		}),
+		tabular.FloatColumnDef("CPUCoreUsageAverage", func(alloc *opencost.Allocation) float64 {
+			return alloc.CPUCoreUsageAverage
+		}),
+		tabular.FloatColumnDef("CPUCoreRequestAverage", func(alloc *opencost.Allocation) float64 {
+			return alloc.CPUCoreRequestAverage
+		}),
+		tabular.FloatColumnDef("RAMBytesUsageAverage", func(alloc *opencost.Allocation) float64 {
+			return alloc.RAMBytesUsageAverage
+		}),
+		tabular.FloatColumnDef("RAMBytesRequestAverage", func(alloc *opencost.Allocation) float64 {
+			return alloc.RAMBytesRequestAverage
+		}),
+		tabular.FloatColumnDef("NetworkReceiveBytes", func(alloc *opencost.Allocation) float64 {
+			return alloc.NetworkReceiveBytes
+		}),
+		tabular.FloatColumnDef("NetworkTransferBytes", func(alloc *opencost.Allocation) float64 {
+			return alloc.NetworkTransferBytes
+		}),
+		tabular.FloatColumnDef("GPUs", func(alloc *opencost.Allocation) float64 {
+			return alloc.GPUs()
+		}),
+		tabular.FloatColumnDef("PVBytes", func(alloc *opencost.Allocation) float64 {
+			return alloc.PVBytes()
+		}),
+		tabular.FloatColumnDef("CPUCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.CPUTotalCost()
+		}),
+		tabular.FloatColumnDef("RAMCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.RAMTotalCost()
+		}),
+		tabular.FloatColumnDef("NetworkCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.NetworkTotalCost()
+		}),
+		tabular.FloatColumnDef("PVCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.PVTotalCost()
+		}),
+		tabular.FloatColumnDef("GPUCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.GPUTotalCost()
+		}),
+		tabular.FloatColumnDef("TotalCost", func(alloc *opencost.Allocation) float64 {
+			return alloc.TotalCost()
+		}),
+	}
+
+	return append(defs, tabular.LabelColumnDefs(labels, labelsAll, func(alloc *opencost.Allocation) map[string]string {
+		return properties(alloc).Labels
+	})...)
+}
+
+// allocationTableColumnDefs returns the columns of the tabular formats of
+// /allocation, which identify each allocation by its window and name
+func allocationTableColumnDefs(labels []string, labelsAll bool) []tabular.ColumnDef[*opencost.Allocation] {
+	defs := []tabular.ColumnDef[*opencost.Allocation]{
+		tabular.StringColumnDef("WindowStart", func(alloc *opencost.Allocation) string {
+			return alloc.Start.UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("WindowEnd", func(alloc *opencost.Allocation) string {
+			return alloc.End.UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("Name", func(alloc *opencost.Allocation) string {
+			return alloc.Name
+		}),
+	}
+
+	return append(defs, allocationColumnDefs(labels, labelsAll)...)
+}
+
+// summaryAllocationTableColumnDefs returns the columns of the tabular formats
+// of /allocation/summary
+func summaryAllocationTableColumnDefs(labels []string, labelsAll bool) []tabular.ColumnDef[*opencost.SummaryAllocation] {
+	properties := func(sa *opencost.SummaryAllocation) *opencost.AllocationProperties {
+		if sa.Properties == nil {
+			return &opencost.AllocationProperties{}
+		}
+		return sa.Properties
+	}
+
+	defs := []tabular.ColumnDef[*opencost.SummaryAllocation]{
+		tabular.StringColumnDef("WindowStart", func(sa *opencost.SummaryAllocation) string {
+			return sa.Start.UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("WindowEnd", func(sa *opencost.SummaryAllocation) string {
+			return sa.End.UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("Name", func(sa *opencost.SummaryAllocation) string {
+			return sa.Name
+		}),
+		tabular.StringColumnDef("Namespace", func(sa *opencost.SummaryAllocation) string {
+			return properties(sa).Namespace
+		}),
+		tabular.StringColumnDef("ControllerKind", func(sa *opencost.SummaryAllocation) string {
+			return properties(sa).ControllerKind
+		}),
+		tabular.StringColumnDef("ControllerName", func(sa *opencost.SummaryAllocation) string {
+			return properties(sa).Controller
+		}),
+		tabular.StringColumnDef("Pod", func(sa *opencost.SummaryAllocation) string {
+			return properties(sa).Pod
+		}),
+		tabular.StringColumnDef("Container", func(sa *opencost.SummaryAllocation) string {
+			return properties(sa).Container
+		}),
+		tabular.FloatColumnDef("CPUCoreUsageAverage", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.CPUCoreUsageAverage
+		}),
+		tabular.FloatColumnDef("CPUCoreRequestAverage", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.CPUCoreRequestAverage
+		}),
+		tabular.FloatColumnDef("RAMBytesUsageAverage", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.RAMBytesUsageAverage
+		}),
+		tabular.FloatColumnDef("RAMBytesRequestAverage", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.RAMBytesRequestAverage
+		}),
+		tabular.FloatColumnDef("CPUCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.CPUCost
+		}),
+		tabular.FloatColumnDef("RAMCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.RAMCost
+		}),
+		tabular.FloatColumnDef("NetworkCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.NetworkCost
+		}),
+		tabular.FloatColumnDef("PVCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.PVCost
+		}),
+		tabular.FloatColumnDef("GPUCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.GPUCost
+		}),
+		tabular.FloatColumnDef("LoadBalancerCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.LoadBalancerCost
+		}),
+		tabular.FloatColumnDef("SharedCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.SharedCost
+		}),
+		tabular.FloatColumnDef("ExternalCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.ExternalCost
+		}),
+		tabular.FloatColumnDef("TotalCost", func(sa *opencost.SummaryAllocation) float64 {
+			return sa.TotalCost()
+		}),
+	}
+
+	return append(defs, tabular.LabelColumnDefs(labels, labelsAll, func(sa *opencost.SummaryAllocation) map[string]string {
+		return properties(sa).Labels
+	})...)
+}
+
+// assetRow is an asset and its key in an AssetSet, which names it in the
+// tabular formats of /assets, since aggregated assets have no name property
+type assetRow struct {
+	key   string
+	asset opencost.Asset
+}
+
+// assetTableColumnDefs returns the columns of the tabular formats of /assets
+func assetTableColumnDefs(labels []string, labelsAll bool) []tabular.ColumnDef[assetRow] {
+	properties := func(asset opencost.Asset) *opencost.AssetProperties {
+		if asset.GetProperties() == nil {
+			return &opencost.AssetProperties{}
+		}
+		return asset.GetProperties()
+	}
+
+	defs := []tabular.ColumnDef[assetRow]{
+		tabular.StringColumnDef("WindowStart", func(row assetRow) string {
+			return row.asset.GetStart().UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("WindowEnd", func(row assetRow) string {
+			return row.asset.GetEnd().UTC().Format(time.RFC3339)
+		}),
+		tabular.StringColumnDef("Name", func(row assetRow) string {
+			return row.key
+		}),
+		tabular.StringColumnDef("Type", func(row assetRow) string {
+			return row.asset.Type().String()
+		}),
+		tabular.StringColumnDef("Category", func(row assetRow) string {
+			return properties(row.asset).Category
+		}),
+		tabular.StringColumnDef("Provider", func(row assetRow) string {
+			return properties(row.asset).Provider
+		}),
+		tabular.StringColumnDef("ProviderID", func(row assetRow) string {
+			return properties(row.asset).ProviderID
+		}),
+		tabular.StringColumnDef("Account", func(row assetRow) string {
+			return properties(row.asset).Account
+		}),
+		tabular.StringColumnDef("Project", func(row assetRow) string {
+			return properties(row.asset).Project
+		}),
+		tabular.StringColumnDef("Service", func(row assetRow) string {
+			return properties(row.asset).Service
+		}),
+		tabular.StringColumnDef("Cluster", func(row assetRow) string {
+			return properties(row.asset).Cluster
+		}),
+		tabular.FloatColumnDef("Adjustment", func(row assetRow) float64 {
+			return row.asset.GetAdjustment()
+		}),
+		tabular.FloatColumnDef("TotalCost", func(row assetRow) float64 {
+			return row.asset.TotalCost()
+		}),
+	}
+
+	return append(defs, tabular.LabelColumnDefs(labels, labelsAll, func(row assetRow) map[string]string {
+		return row.asset.GetLabels()
+	})...)
+}
+
+// tableOptions holds the parameters of a request for a tabular format
+type tableOptions struct {
+	format    tabular.Format
+	labels    []string
+	labelsAll bool
+}
+
+// parseTableOptions parses the 'format' parameter, returning nil if a
+// tabular format was not requested. The 'labels' and 'labelsAll' parameters
+// select the label columns, defaulting to those of the CSV exporter.
+func parseTableOptions(qp httputil.QueryParams) (*tableOptions, error) {
+	formatParam := qp.Get("format", "")
+	if formatParam == "" || strings.EqualFold(formatParam, "json") {
+		return nil, nil
+	}
+
+	format, err := tabular.ParseFormat(formatParam)
+	if err != nil {
+		return nil, fmt.Errorf("bad request - invalid 'format' parameter: %s", err)
+	}
+
+	labels := env.GetExportCSVLabelsList()
+	if qp.Has("labels") {
+		labels = qp.GetList("labels", ",")
+	}
+
+	return &tableOptions{
+		format:    format,
+		labels:    labels,
+		labelsAll: qp.GetBool("labelsAll", env.GetExportCSVLabelsAll()),
+	}, nil
+}
+
+// writeTable writes one row per value produced by each as a download in the
+// requested format, reporting the currency conversion of its costs, if any
+func writeTable[T any](w http.ResponseWriter, opts *tableOptions, conversion *currency.Conversion, name string, defs []tabular.ColumnDef[T], each func(write func(T) error) error) {
+	w.Header().Set("Content-Type", opts.format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", opts.format.FileName(name)))
+
+	// Tables have no envelope to report the currency conversion in, so it is
+	// reported in a header, as the costs have been converted already
+	if conversion != nil {
+		err := protocol.SetCurrencyHeader(w, conversion)
+		if err != nil {
+			log.Errorf("Error writing currency of %s %s: %s", name, opts.format, err)
+		}
+	}
+
+	tw, err := tabular.NewWriter(opts.format, w, tabular.Columns(defs))
+	if err != nil {
+		log.Errorf("Error writing %s %s: %s", name, opts.format, err)
+		WriteError(w, InternalServerError(err.Error()))
+		return
+	}
+
+	err = each(func(row T) error {
+		return tabular.WriteRow(tw, defs, row)
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+
+	// The status has already been sent, so errors can only be logged
+	if err != nil {
+		log.Errorf("Error writing %s %s: %s", name, opts.format, err)
+	}
+}
+
+// sortedKeys returns the keys of the map in order, so that tables are written
+// in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
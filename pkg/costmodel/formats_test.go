@@ -0,0 +1,132 @@
+package costmodel
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/tabular"
+)
+
+func TestParseTableOptions(t *testing.T) {
+	tests := map[string]struct {
+		query   string
+		want    *tableOptions
+		wantErr bool
+	}{
+		"default": {
+			query: "",
+			want:  nil,
+		},
+		"json": {
+			query: "format=json",
+			want:  nil,
+		},
+		"csv": {
+			query: "format=csv&labels=app,team&labelsAll=true",
+			want:  &tableOptions{format: tabular.FormatCSV, labels: []string{"app", "team"}, labelsAll: true},
+		},
+		"parquet": {
+			query: "format=parquet&labels=app",
+			want:  &tableOptions{format: tabular.FormatParquet, labels: []string{"app"}},
+		},
+		"invalid": {
+			query:   "format=xlsx",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, err := parseTableOptions(httputil.NewQueryParams(values))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTableOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == nil || got == nil {
+				if tt.want != got {
+					t.Errorf("parseTableOptions() = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+			if got.format != tt.want.format || got.labelsAll != tt.want.labelsAll || len(got.labels) != len(tt.want.labels) {
+				t.Errorf("parseTableOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTable_Allocation(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	allocs := []*opencost.Allocation{
+		{
+			Name:  "kube-system",
+			Start: start,
+			End:   start.Add(24 * time.Hour),
+			Properties: &opencost.AllocationProperties{
+				Namespace: "kube-system",
+				Labels:    map[string]string{"app": "dns"},
+			},
+			CPUCost: 1.25,
+		},
+		{
+			// Allocations without properties, e.g. some aggregated
+			// allocations, are written with empty property columns
+			Name:    "__unallocated__",
+			Start:   start,
+			End:     start.Add(24 * time.Hour),
+			RAMCost: 2,
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	opts := &tableOptions{format: tabular.FormatCSV, labels: []string{"app"}, labelsAll: true}
+	writeTable(rec, opts, nil, "allocation", allocationTableColumnDefs(opts.labels, opts.labelsAll), func(write func(*opencost.Allocation) error) error {
+		for _, alloc := range allocs {
+			err := write(alloc)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="allocation.csv"` {
+		t.Errorf("unexpected Content-Disposition: %s", cd)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %s", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d records", len(records))
+	}
+
+	columns := map[string]int{}
+	for i, column := range records[0] {
+		columns[column] = i
+	}
+	for _, column := range []string{"WindowStart", "WindowEnd", "Name", "Namespace", "CPUCost", "TotalCost", "Labels", "Label_app"} {
+		if _, ok := columns[column]; !ok {
+			t.Errorf("missing column %s in %v", column, records[0])
+		}
+	}
+
+	if records[1][columns["WindowStart"]] != "2024-01-01T00:00:00Z" || records[1][columns["Namespace"]] != "kube-system" {
+		t.Errorf("unexpected row: %v", records[1])
+	}
+	if records[1][columns["CPUCost"]] != "1.25" || records[1][columns["Label_app"]] != "dns" || records[1][columns["Labels"]] != `{"app":"dns"}` {
+		t.Errorf("unexpected row: %v", records[1])
+	}
+	if records[2][columns["Namespace"]] != "" || records[2][columns["TotalCost"]] != "2" {
+		t.Errorf("unexpected row: %v", records[2])
+	}
+}
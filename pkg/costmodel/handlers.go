@@ -1,16 +1,21 @@
 package costmodel
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/filter"
 	assetfilter "github.com/opencost/opencost/core/pkg/filter/asset"
 	"github.com/opencost/opencost/core/pkg/filter/ast"
 	"github.com/opencost/opencost/core/pkg/filter/matcher"
 	"github.com/opencost/opencost/core/pkg/opencost"
 	"github.com/opencost/opencost/core/pkg/util/httputil"
 	"github.com/opencost/opencost/pkg/carbon"
+	"github.com/opencost/opencost/pkg/currency"
 	"github.com/opencost/opencost/pkg/env"
 )
 
@@ -30,13 +35,234 @@ func (a *Accesses) ComputeAssetsHandler(w http.ResponseWriter, r *http.Request,
 
 	filterString := qp.Get("filter", "")
 
-	assetSet, err := a.computeAssetsFromCostmodel(window, filterString)
+	// Currency is an optional currency to which costs are converted
+	conversion, err := a.parseCurrencyConversion(qp)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error getting assets: %s", err), http.StatusInternalServerError)
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	// Format is an optional parameter which returns the assets as a "csv" or
+	// "parquet" table rather than JSON
+	tableOpts, err := parseTableOptions(qp)
+	if err != nil {
+		WriteError(w, BadRequest(err.Error()))
+		return
+	}
+
+	// If none of the range parameters are provided, return a single AssetSet
+	// for the entire window, as before they were supported.
+	if !qp.Has("step") && !qp.Has("aggregate") && !qp.Has("accumulate") && !qp.Has("accumulateBy") {
+		assetSet, err := a.computeAssetsFromCostmodel(r.Context(), window, filterString)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error getting assets: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		err = conversion.ConvertAssetSet(assetSet, "")
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error converting currency: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		if tableOpts != nil {
+			writeAssetsTable(w, tableOpts, conversion, opencost.NewAssetSetRange(assetSet))
+			return
+		}
+
+		// Otherwise, stream one asset per line if requested, rather than
+		// marshalling the entire response into memory at once
+		if nw := streamResponse(w, r); nw != nil {
+			err = writeAssetSet(nw, assetSet)
+			if err == nil {
+				err = writeStreamCurrency(nw, conversion)
+			}
+			if err != nil {
+				writeStreamError(nw, err)
+			}
+			return
+		}
+
+		w.Write(WrapDataWithCurrency(assetSet, conversion))
 		return
 	}
 
-	w.Write(WrapData(assetSet, nil))
+	query, err := parseAssetsQuery(qp)
+	if err != nil {
+		WriteError(w, BadRequest(err.Error()))
+		return
+	}
+	query.conversion = conversion
+
+	// Only switch the response to NDJSON once every parameter has been
+	// parsed, so that bad requests are answered with a JSON error
+	if tableOpts == nil {
+		if nw := streamResponse(w, r); nw != nil {
+			err = a.streamAssets(r.Context(), query, nw)
+			if err != nil {
+				writeStreamError(nw, err)
+			}
+			return
+		}
+	}
+
+	asr, err := a.queryAssets(r.Context(), query)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+
+		return
+	}
+
+	if tableOpts != nil {
+		writeAssetsTable(w, tableOpts, query.conversion, asr)
+		return
+	}
+
+	w.Write(WrapDataWithCurrency(asr, query.conversion))
+}
+
+// writeAssetsTable writes the assets of each set in the range as a table
+func writeAssetsTable(w http.ResponseWriter, opts *tableOptions, conversion *currency.Conversion, asr *opencost.AssetSetRange) {
+	writeTable(w, opts, conversion, "assets", assetTableColumnDefs(opts.labels, opts.labelsAll), func(write func(assetRow) error) error {
+		for _, as := range asr.Assets {
+			for _, key := range sortedKeys(as.Assets) {
+				err := write(assetRow{key: key, asset: as.Assets[key]})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// assetsQuery holds the parsed parameters of a multi-step assets request
+type assetsQuery struct {
+	window       opencost.Window
+	step         time.Duration
+	aggregateBy  []string
+	filter       filter.Filter
+	accumulateBy opencost.AccumulateOption
+	conversion   *currency.Conversion
+}
+
+// parseAssetsQuery parses the parameters of a multi-step assets request.
+// Errors caused by invalid parameters are prefixed with "bad request".
+func parseAssetsQuery(qp httputil.QueryParams) (*assetsQuery, error) {
+	// Window is a required field describing the window of time over which to
+	// compute asset data.
+	window, err := opencost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		return nil, fmt.Errorf("bad request - invalid 'window' parameter: %s", err)
+	}
+
+	// Step is an optional parameter that defines the duration per-set, i.e.
+	// the window for an AssetSet, of the AssetSetRange to be computed.
+	// Defaults to the window size, making one set.
+	step := qp.GetDuration("step", window.Duration())
+
+	// Aggregation is an optional comma-separated list of fields by which to
+	// aggregate results. Labels are distinguished with a colon; e.g.
+	// "type,label:app"
+	aggregateBy, err := ParseAssetAggregationProperties(qp.GetList("aggregate", ","))
+	if err != nil {
+		return nil, fmt.Errorf("bad request - invalid 'aggregate' parameter: %s", err)
+	}
+
+	// Accumulate is an optional parameter, defaulting to false, which if true
+	// sums each Set in the Range, producing one Set. AccumulateBy accumulates
+	// the Range by the given duration instead, e.g. "day".
+	accumulateBy := opencost.ParseAccumulate(qp.Get("accumulateBy", ""))
+	if accumulateBy == opencost.AccumulateOptionNone && qp.GetBool("accumulate", false) {
+		accumulateBy = opencost.AccumulateOptionAll
+	}
+
+	// Filter is an optional v2 filter string which restricts the assets
+	// returned; e.g. assetType:"node"
+	assetFilter, err := ParseAssetFilter(qp.Get("filter", ""))
+	if err != nil {
+		return nil, fmt.Errorf("bad request - invalid 'filter' parameter: %s", err)
+	}
+
+	return &assetsQuery{
+		window:       window,
+		step:         step,
+		aggregateBy:  aggregateBy,
+		filter:       assetFilter,
+		accumulateBy: accumulateBy,
+	}, nil
+}
+
+// queryAssets runs the given assets query against the CostModel. If the query
+// converts currency, each step is converted at the rate of its start before
+// the range is accumulated.
+func (a *Accesses) queryAssets(ctx context.Context, q *assetsQuery) (*opencost.AssetSetRange, error) {
+	if q.conversion == nil {
+		return a.Model.QueryAssets(ctx, q.window, q.step, q.aggregateBy, q.filter, q.accumulateBy)
+	}
+
+	asr, err := a.Model.QueryAssets(ctx, q.window, q.step, q.aggregateBy, q.filter, opencost.AccumulateOptionNone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.conversion.ConvertAssetSetRange(asr, "")
+	if err != nil {
+		return nil, fmt.Errorf("error converting currency: %w", err)
+	}
+
+	if q.accumulateBy != opencost.AccumulateOptionNone {
+		asr, err = asr.Accumulate(q.accumulateBy)
+		if err != nil {
+			return nil, fmt.Errorf("error accumulating by %v: %w", q.accumulateBy, err)
+		}
+	}
+	return asr, nil
+}
+
+// ParseAssetAggregationProperties validates the given asset aggregation
+// properties, returning them in their canonical form; e.g. "ProviderID"
+// becomes "providerID".
+func ParseAssetAggregationProperties(aggregations []string) ([]string, error) {
+	if len(aggregations) == 0 {
+		return nil, nil
+	}
+
+	props, err := opencost.ParseAssetProperties(aggregations)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregateBy := make([]string, 0, len(props))
+	for _, prop := range props {
+		aggregateBy = append(aggregateBy, string(prop))
+	}
+
+	return aggregateBy, nil
+}
+
+// ParseAssetFilter parses a v2 asset filter string. An empty string results
+// in a nil filter, which matches all assets.
+func ParseAssetFilter(filterString string) (filter.Filter, error) {
+	if filterString == "" {
+		return nil, nil
+	}
+
+	parser := assetfilter.NewAssetFilterParser()
+	tree, err := parser.Parse(filterString)
+	if err != nil {
+		return nil, fmt.Errorf("err parsing filter '%s': %w", filterString, err)
+	}
+
+	return tree, nil
 }
 
 // ComputeAllocationHandler returns the assets from the CostModel.
@@ -55,7 +281,7 @@ func (a *Accesses) ComputeAssetsCarbonHandler(w http.ResponseWriter, r *http.Req
 
 	filterString := qp.Get("filter", "")
 
-	assetSet, err := a.computeAssetsFromCostmodel(window, filterString)
+	assetSet, err := a.computeAssetsFromCostmodel(r.Context(), window, filterString)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error getting assets: %s", err), http.StatusInternalServerError)
 		return
@@ -66,9 +292,9 @@ func (a *Accesses) ComputeAssetsCarbonHandler(w http.ResponseWriter, r *http.Req
 	w.Write(WrapData(carbonEstimates, nil))
 }
 
-func (a *Accesses) computeAssetsFromCostmodel(window opencost.Window, filterString string) (*opencost.AssetSet, error) {
+func (a *Accesses) computeAssetsFromCostmodel(ctx context.Context, window opencost.Window, filterString string) (*opencost.AssetSet, error) {
 
-	assetSet, err := a.Model.ComputeAssets(*window.Start(), *window.End())
+	assetSet, err := a.Model.loadOrComputeAssets(ctx, *window.Start(), *window.End())
 	if err != nil {
 		return nil, fmt.Errorf("error computing asset set: %s", err)
 	}
@@ -0,0 +1,143 @@
+package costmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/jobs"
+)
+
+const (
+	allocationJobType = "allocation"
+	assetsJobType     = "assets"
+)
+
+// SubmitQueryJobHandler starts an asynchronous allocation or asset query. The
+// query accepts the same parameters as /allocation or /assets, with the
+// additional 'type' parameter selecting which to run. The response is the
+// status of the new job, whose ID can be used to poll for its progress and
+// fetch its result.
+func (a *Accesses) SubmitQueryJobHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := r.ParseForm()
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("invalid query: %s", err)))
+		return
+	}
+
+	qp := httputil.NewQueryParams(r.Form)
+
+	var fn jobs.Func
+	jobType := qp.Get("type", allocationJobType)
+	switch jobType {
+	case allocationJobType:
+		query, err := a.parseAllocationQuery(qp)
+		if err != nil {
+			WriteError(w, BadRequest(err.Error()))
+			return
+		}
+		fn = func(ctx context.Context) (any, error) {
+			return a.queryAllocation(ctx, query)
+		}
+	case assetsJobType:
+		query, err := parseAssetsQuery(qp)
+		if err != nil {
+			WriteError(w, BadRequest(err.Error()))
+			return
+		}
+		query.conversion, err = a.parseCurrencyConversion(qp)
+		if err != nil {
+			WriteError(w, BadRequest(err.Error()))
+			return
+		}
+		fn = func(ctx context.Context) (any, error) {
+			return a.queryAssets(ctx, query)
+		}
+	default:
+		WriteError(w, BadRequest(fmt.Sprintf("invalid 'type' parameter: %s, must be one of '%s' or '%s'", jobType, allocationJobType, assetsJobType)))
+		return
+	}
+
+	status, err := a.JobManager.Submit(jobType, r.Form.Encode(), fn)
+	if errors.Is(err, jobs.ErrTooManyPending) {
+		WriteError(w, Error{StatusCode: http.StatusTooManyRequests, Body: err.Error()})
+		return
+	}
+	if err != nil {
+		WriteError(w, InternalServerError(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(WrapData(status, nil))
+}
+
+// QueryJobStatusHandler returns the status and progress of a query job
+func (a *Accesses) QueryJobStatusHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status, err := a.JobManager.Status(ps.ByName("id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Write(WrapData(status, nil))
+}
+
+// QueryJobResultHandler returns the result of a completed query job. If the
+// job has not yet finished, its status is returned with 202 Accepted so that
+// clients can continue polling.
+func (a *Accesses) QueryJobResultHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	result, status, err := a.JobManager.Result(ps.ByName("id"))
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotDone) {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write(WrapData(status, nil))
+			return
+		}
+
+		writeJobError(w, err)
+		return
+	}
+
+	w.Write(WrapData(result, nil))
+}
+
+// CancelQueryJobHandler cancels a query job which has not yet finished
+func (a *Accesses) CancelQueryJobHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status, err := a.JobManager.Cancel(ps.ByName("id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Write(WrapData(status, nil))
+}
+
+// writeJobError writes the response for an error returned by the job manager,
+// including the error of a failed or cancelled job.
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		WriteError(w, Error{StatusCode: http.StatusNotFound, Body: err.Error()})
+	case errors.Is(err, context.Canceled):
+		WriteError(w, Error{StatusCode: http.StatusConflict, Body: "job was cancelled"})
+	case errors.Is(err, jobs.ErrPendingExpired):
+		WriteError(w, Error{StatusCode: http.StatusServiceUnavailable, Body: err.Error()})
+	case strings.Contains(strings.ToLower(err.Error()), "bad request"):
+		WriteError(w, BadRequest(err.Error()))
+	default:
+		WriteError(w, InternalServerError(err.Error()))
+	}
+}
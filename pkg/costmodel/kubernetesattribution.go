@@ -0,0 +1,201 @@
+package costmodel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// attributedAsset is the cost of the assets of a provider ID and the cost of
+// them used by each namespace
+type attributedAsset struct {
+	cost float64
+	used map[string]float64
+}
+
+// KubernetesAttributions returns the attributions of the cloud resources of
+// the nodes, disks and load balancers of the asset set, by lower-cased
+// provider ID. Each resource's cost is shared between the namespaces of the
+// allocations which use it in proportion to their cost of it, with any
+// remaining cost idle.
+func KubernetesAttributions(allocSet *opencost.AllocationSet, assetSet *opencost.AssetSet) map[string]*cloudcost.KubernetesAttribution {
+	byProviderID := map[string]*attributedAsset{}
+	add := func(props *opencost.AssetProperties, cost float64) *attributedAsset {
+		if props == nil || props.ProviderID == "" {
+			return nil
+		}
+		providerID := strings.ToLower(props.ProviderID)
+		aa, ok := byProviderID[providerID]
+		if !ok {
+			aa = &attributedAsset{used: map[string]float64{}}
+			byProviderID[providerID] = aa
+		}
+		aa.cost += cost
+		return aa
+	}
+
+	nodes := map[string]*attributedAsset{}
+	for _, node := range assetSet.Nodes {
+		if aa := add(node.Properties, node.TotalCost()-node.GetAdjustment()); aa != nil {
+			nodes[node.Properties.Cluster+"/"+node.Properties.Name] = aa
+		}
+	}
+
+	disks := map[opencost.PVKey]*attributedAsset{}
+	for _, disk := range assetSet.Disks {
+		if aa := add(disk.Properties, disk.TotalCost()-disk.GetAdjustment()); aa != nil {
+			disks[opencost.PVKey{Cluster: disk.Properties.Cluster, Name: disk.Properties.Name}] = aa
+		}
+	}
+
+	lbs := map[string]*attributedAsset{}
+	for _, lb := range assetSet.LoadBalancers {
+		if aa := add(lb.Properties, lb.TotalCost()-lb.GetAdjustment()); aa != nil {
+			lbs[lb.Properties.Cluster+"/"+lb.Properties.Name] = aa
+		}
+	}
+
+	// Allocations and their volumes are matched by provider ID where known,
+	// and otherwise by name
+	lookup := func(providerID string, byName *attributedAsset) *attributedAsset {
+		if providerID != "" {
+			if aa, ok := byProviderID[strings.ToLower(providerID)]; ok {
+				return aa
+			}
+		}
+		return byName
+	}
+
+	if allocSet != nil {
+		for _, alloc := range allocSet.Allocations {
+			if alloc.Properties == nil || alloc.IsIdle() {
+				continue
+			}
+			namespace := alloc.Properties.Namespace
+
+			if aa := lookup(alloc.Properties.ProviderID, nodes[alloc.Properties.Cluster+"/"+alloc.Properties.Node]); aa != nil {
+				aa.used[namespace] += alloc.CPUCost + alloc.RAMCost + alloc.GPUCost
+			}
+
+			for key, pv := range alloc.PVs {
+				if pv == nil {
+					continue
+				}
+				if aa := lookup(pv.ProviderID, disks[key]); aa != nil {
+					aa.used[namespace] += pv.Cost
+				}
+			}
+
+			for _, lb := range alloc.LoadBalancers {
+				if lb == nil {
+					continue
+				}
+				if aa := lbs[alloc.Properties.Cluster+"/"+lb.Service]; aa != nil {
+					aa.used[namespace] += lb.Cost
+				}
+			}
+		}
+	}
+
+	attributions := make(map[string]*cloudcost.KubernetesAttribution, len(byProviderID))
+	for providerID, aa := range byProviderID {
+		var used float64
+		for _, cost := range aa.used {
+			used += cost
+		}
+
+		// Shares of resources whose allocations exceed their cost, such as
+		// those without cost, are normalized
+		total := aa.cost
+		if used > total {
+			total = used
+		}
+
+		shares := map[string]float64{}
+		if total > 0 {
+			for namespace, cost := range aa.used {
+				if cost > 0 {
+					shares[namespace] = cost / total
+				}
+			}
+		}
+		switch {
+		case total == 0:
+			shares[cloudcost.IdleShareName] = 1
+		case used < total:
+			shares[cloudcost.IdleShareName] = (total - used) / total
+		}
+
+		attributions[providerID] = &cloudcost.KubernetesAttribution{
+			ProviderID:        providerID,
+			KubernetesPercent: 1,
+			Shares:            shares,
+		}
+	}
+	return attributions
+}
+
+// kubernetesAttributionSource is a cloudcost.KubernetesAttributionSource of
+// the allocations and assets of a CostModel
+type kubernetesAttributionSource struct {
+	model *CostModel
+}
+
+func (s *kubernetesAttributionSource) Attributions(ctx context.Context, window opencost.Window) (map[string]*cloudcost.KubernetesAttribution, error) {
+	start, end := *window.Start(), *window.End()
+	if now := time.Now().UTC(); end.After(now) {
+		end = now
+	}
+
+	allocSet, err := s.model.loadOrComputeAllocation(ctx, start, end, env.GetETLResolution())
+	if err != nil {
+		return nil, err
+	}
+
+	assetSet, err := s.model.loadOrComputeAssets(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return KubernetesAttributions(allocSet, assetSet), nil
+}
+
+// InitializeKubernetesAttribution starts attributing the cloud costs of the
+// repository to the allocations of the model, and registers the
+// /cloudCost/kubernetes endpoint
+func InitializeKubernetesAttribution(router *httprouter.Router, model *CostModel, repo cloudcost.Repository, querier cloudcost.Querier) *cloudcost.KubernetesAttributor {
+	if model == nil || repo == nil {
+		log.Warnf("Init: Kubernetes attribution of cloud costs requires allocations and cloud costs")
+		return nil
+	}
+
+	var store cloudcost.KubernetesAttributionStore = cloudcost.NewMemoryKubernetesAttributionStore()
+	if env.IsETLStoreEnabled() {
+		etlStore, err := NewETLStorage()
+		if err != nil {
+			log.Errorf("Init: failed to create ETL storage for Kubernetes attributions, falling back to memory: %s", err)
+		} else {
+			log.Infof("Init: persisting Kubernetes attributions using %s storage", etlStore.StorageType())
+			store = cloudcost.NewStorageKubernetesAttributionStore(etlStore)
+		}
+	}
+
+	retention := timeutil.Day * time.Duration(env.GetDataRetentionDailyResolutionDays())
+	attributor := cloudcost.NewKubernetesAttributor(repo, &kubernetesAttributionSource{model: model}, store, retention)
+	err := attributor.Start(env.GetCloudCostKubernetesAttributionInterval(), env.GetCloudCostKubernetesAttributionDays())
+	if err != nil {
+		log.Errorf("Init: failed to start Kubernetes attribution of cloud costs: %s", err)
+		return nil
+	}
+
+	router.GET("/cloudCost/kubernetes", attributor.GetKubernetesBreakdownHandler(querier))
+
+	return attributor
+}
@@ -0,0 +1,83 @@
+package costmodel
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+)
+
+func TestKubernetesAttributions(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	window := opencost.NewClosedWindow(start, end)
+
+	node := opencost.NewNode("node-1", "cluster-1", "i-123", start, end, window)
+	node.CPUCost = 6
+	node.RAMCost = 4
+	// Adjustments, such as from reconciliation, do not change shares
+	node.SetAdjustment(-2)
+
+	disk := opencost.NewDisk("pv-1", "cluster-1", "vol-1", start, end, window)
+	disk.Cost = 5
+
+	lb := opencost.NewLoadBalancer("default/web", "cluster-1", "lb-1", start, end, window, false, "")
+	lb.Cost = 2
+
+	newAlloc := func(name, namespace, providerID string) *opencost.Allocation {
+		alloc := opencost.NewMockUnitAllocation(name, start, 24*time.Hour, &opencost.AllocationProperties{
+			Cluster:    "cluster-1",
+			Node:       "node-1",
+			Namespace:  namespace,
+			ProviderID: providerID,
+		})
+		alloc.CPUCost = 2
+		alloc.RAMCost = 1
+		alloc.GPUCost = 0
+		alloc.PVs = nil
+		alloc.LoadBalancers = nil
+		return alloc
+	}
+
+	web := newAlloc("web", "default", "I-123")
+	web.PVs = opencost.PVAllocations{{Cluster: "cluster-1", Name: "pv-1"}: {Cost: 5}}
+	web.LoadBalancers = opencost.LbAllocations{"default/web": {Service: "default/web", Cost: 2}}
+
+	monitoring := newAlloc("prometheus", "monitoring", "")
+
+	idle := newAlloc("cluster-1/"+opencost.IdleSuffix, opencost.IdleSuffix, "")
+	idle.CPUCost = 10
+
+	assetSet := opencost.NewAssetSet(start, end, node, disk, lb)
+	allocSet := opencost.NewAllocationSet(start, end, web, monitoring, idle)
+
+	attributions := KubernetesAttributions(allocSet, assetSet)
+	if len(attributions) != 3 {
+		t.Fatalf("expected 3 attributions, got %d", len(attributions))
+	}
+
+	expected := map[string]map[string]float64{
+		"i-123": {"default": 0.3, "monitoring": 0.3, cloudcost.IdleShareName: 0.4},
+		"vol-1": {"default": 1},
+		"lb-1":  {"default": 1},
+	}
+	for providerID, shares := range expected {
+		attribution, ok := attributions[providerID]
+		if !ok {
+			t.Fatalf("missing attribution of %s", providerID)
+		}
+		if attribution.KubernetesPercent != 1 {
+			t.Errorf("expected %s to be entirely Kubernetes, got %f", providerID, attribution.KubernetesPercent)
+		}
+		if len(attribution.Shares) != len(shares) {
+			t.Errorf("expected %s shares %v, got %v", providerID, shares, attribution.Shares)
+		}
+		for namespace, share := range shares {
+			if math.Abs(attribution.Shares[namespace]-share) > 1e-9 {
+				t.Errorf("expected %s shares %v, got %v", providerID, shares, attribution.Shares)
+			}
+		}
+	}
+}
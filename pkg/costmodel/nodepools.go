@@ -0,0 +1,513 @@
+package costmodel
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/binpack"
+	"github.com/opencost/opencost/pkg/cloud/models"
+	"github.com/opencost/opencost/pkg/env"
+	v1 "k8s.io/api/core/v1"
+)
+
+// nodePoolLabels are the labels which name the node pool of a node, in order
+// of precedence. Nodes without any of them are pooled by instance type.
+var nodePoolLabels = []string{
+	"karpenter.sh/nodepool",
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"alpha.eksctl.io/nodegroup-name",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"doks.digitalocean.com/node-pool",
+}
+
+// ramQuantity matches amounts of RAM as reported by providers' pricing, such
+// as "16 GiB", "1,952 GiB" or "16"
+var ramQuantity = regexp.MustCompile(`^([0-9][0-9,]*(?:\.[0-9]+)?)\s*([KMGT]i?B?)?$`)
+
+// NodePoolCost is the current cost of the nodes of a node pool. HourlyCost is
+// the average hourly cost of each node's asset over the window, or its list
+// price if it has no asset.
+type NodePoolCost struct {
+	Nodes       int                `json:"nodes"`
+	Counts      map[string]int     `json:"counts"`
+	Requested   binpack.Resources  `json:"requested"`
+	Capacity    binpack.Resources  `json:"capacity"`
+	HourlyCost  float64            `json:"hourlyCost"`
+	MonthlyCost float64            `json:"monthlyCost"`
+	NodeCosts   map[string]float64 `json:"nodeCosts"`
+}
+
+// NodePoolRecommendation is the cheapest mix of node types onto which the
+// pods of a node pool can be repacked. Recommended is nil if the pods fit no
+// node type, in which case Error describes why.
+type NodePoolRecommendation struct {
+	Pool                   string             `json:"pool"`
+	Taints                 []v1.Taint         `json:"taints"`
+	Pods                   int                `json:"pods"`
+	DaemonSetPods          int                `json:"daemonSetPods"`
+	DaemonSetOverhead      binpack.Resources  `json:"daemonSetOverhead"`
+	NodeTypes              []binpack.NodeType `json:"nodeTypes"`
+	Current                *NodePoolCost      `json:"current"`
+	Recommended            *binpack.Plan      `json:"recommended"`
+	RecommendedMonthlyCost float64            `json:"recommendedMonthlyCost"`
+	MonthlySavings         float64            `json:"monthlySavings"`
+	Warnings               []string           `json:"warnings,omitempty"`
+	Error                  string             `json:"error,omitempty"`
+}
+
+// NodePoolRecommendations are the recommendations of every node pool of the
+// cluster. Costs are monthly, and the current costs are of the node assets
+// over the window.
+type NodePoolRecommendations struct {
+	Window                 opencost.Window           `json:"window"`
+	Headroom               float64                   `json:"headroom"`
+	MaxPods                int                       `json:"maxPods"`
+	CurrentMonthlyCost     float64                   `json:"currentMonthlyCost"`
+	RecommendedMonthlyCost float64                   `json:"recommendedMonthlyCost"`
+	MonthlySavings         float64                   `json:"monthlySavings"`
+	Pools                  []*NodePoolRecommendation `json:"pools"`
+}
+
+// nodePool is a set of nodes with the same pool and taints, and the pods
+// scheduled on them
+type nodePool struct {
+	name   string
+	taints []v1.Taint
+	nodes  []*v1.Node
+	pods   []binpack.Resources
+	// daemonSets are the largest requests of a pod of each DaemonSet, or of
+	// each static pod, which run on every node of the pool
+	daemonSets map[string]binpack.Resources
+}
+
+// daemonSetOverhead returns the requests of the pods of DaemonSets on each
+// node, and the number of those pods
+func (np *nodePool) daemonSetOverhead() (binpack.Resources, int) {
+	var overhead binpack.Resources
+	for _, requests := range np.daemonSets {
+		overhead = overhead.Add(requests)
+	}
+	return overhead, len(np.daemonSets)
+}
+
+// schedulingTaints returns the taints of the node which prevent pods from
+// being scheduled on it, sorted by key
+func schedulingTaints(node *v1.Node) []v1.Taint {
+	taints := []v1.Taint{}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			taints = append(taints, v1.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+		}
+	}
+	sort.Slice(taints, func(i, j int) bool {
+		return taints[i].ToString() < taints[j].ToString()
+	})
+	return taints
+}
+
+// nodePoolName returns the name of the node pool of the node, including its
+// taints so that pods are only repacked onto nodes which they tolerate
+func nodePoolName(node *v1.Node, taints []v1.Taint) string {
+	name := ""
+	for _, label := range nodePoolLabels {
+		if value, ok := node.Labels[label]; ok && value != "" {
+			name = value
+			break
+		}
+	}
+	if name == "" {
+		name, _ = util.GetInstanceType(node.Labels)
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	for _, taint := range taints {
+		name += "," + taint.ToString()
+	}
+	return name
+}
+
+// podRequests returns the resources requested by the pod, which are the
+// larger of the sum of its containers' requests and the largest request of an
+// init container, plus its overhead
+func podRequests(pod *v1.Pod) binpack.Resources {
+	var requests binpack.Resources
+	for _, c := range pod.Spec.Containers {
+		requests = requests.Add(binpack.Resources{
+			CPU: c.Resources.Requests.Cpu().AsApproximateFloat64(),
+			RAM: c.Resources.Requests.Memory().AsApproximateFloat64(),
+		})
+	}
+	for _, c := range pod.Spec.InitContainers {
+		requests = requests.Max(binpack.Resources{
+			CPU: c.Resources.Requests.Cpu().AsApproximateFloat64(),
+			RAM: c.Resources.Requests.Memory().AsApproximateFloat64(),
+		})
+	}
+	if pod.Spec.Overhead != nil {
+		requests = requests.Add(binpack.Resources{
+			CPU: pod.Spec.Overhead.Cpu().AsApproximateFloat64(),
+			RAM: pod.Spec.Overhead.Memory().AsApproximateFloat64(),
+		})
+	}
+	return requests
+}
+
+// daemonKey returns a key which is the same for the pods of a DaemonSet, or
+// the static pods of the same manifest, on every node. Other pods return
+// false.
+func daemonKey(pod *v1.Pod) (string, bool) {
+	for _, ref := range pod.OwnerReferences {
+		switch ref.Kind {
+		case "DaemonSet":
+			return pod.Namespace + "/" + ref.Name, true
+		case "Node":
+			// Static pods are named for the node they run on
+			return pod.Namespace + "/" + strings.TrimSuffix(pod.Name, "-"+pod.Spec.NodeName), true
+		}
+	}
+	return "", false
+}
+
+// buildNodePools groups the nodes into pools, and the pods scheduled on them
+// into their nodes' pools. Pods which are not scheduled, or have completed,
+// are ignored.
+func buildNodePools(nodes []*v1.Node, pods []*v1.Pod) []*nodePool {
+	pools := map[string]*nodePool{}
+	poolByNode := map[string]*nodePool{}
+
+	for _, node := range nodes {
+		taints := schedulingTaints(node)
+		name := nodePoolName(node, taints)
+
+		pool, ok := pools[name]
+		if !ok {
+			pool = &nodePool{
+				name:       name,
+				taints:     taints,
+				daemonSets: map[string]binpack.Resources{},
+			}
+			pools[name] = pool
+		}
+		pool.nodes = append(pool.nodes, node)
+		poolByNode[node.Name] = pool
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		pool, ok := poolByNode[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+
+		requests := podRequests(pod)
+		if key, ok := daemonKey(pod); ok {
+			pool.daemonSets[key] = pool.daemonSets[key].Max(requests)
+			continue
+		}
+		pool.pods = append(pool.pods, requests)
+	}
+
+	result := make([]*nodePool, 0, len(pools))
+	for _, pool := range pools {
+		sort.Slice(pool.nodes, func(i, j int) bool {
+			return pool.nodes[i].Name < pool.nodes[j].Name
+		})
+		result = append(result, pool)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// parseRAMBytes parses an amount of RAM as reported by providers' pricing,
+// which is in GiB unless it has a unit
+func parseRAMBytes(s string) (float64, error) {
+	match := ramQuantity.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid RAM: '%s'", s)
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid RAM: '%s'", s)
+	}
+
+	unit := 1024.0 * 1024 * 1024
+	switch strings.ToUpper(match[2][:min(1, len(match[2]))]) {
+	case "K":
+		unit = 1024
+	case "M":
+		unit = 1024 * 1024
+	case "T":
+		unit = 1024 * 1024 * 1024 * 1024
+	}
+	return value * unit, nil
+}
+
+// nodeTypeFromPricing returns the node type of the instance type with the
+// given pricing. The capacity of the type is taken from its pricing, or
+// otherwise from an existing node of the type, which may be nil.
+func nodeTypeFromPricing(instanceType string, pricing *models.Node, existing *v1.Node) (binpack.NodeType, error) {
+	nt := binpack.NodeType{Name: instanceType}
+
+	if cpu, err := strconv.ParseFloat(pricing.VCPU, 64); err == nil && cpu > 0 {
+		nt.Capacity.CPU = cpu
+	} else if existing != nil {
+		nt.Capacity.CPU = existing.Status.Capacity.Cpu().AsApproximateFloat64()
+	}
+	if ram, err := parseRAMBytes(pricing.RAM); err == nil && ram > 0 {
+		nt.Capacity.RAM = ram
+	} else if existing != nil {
+		nt.Capacity.RAM = existing.Status.Capacity.Memory().AsApproximateFloat64()
+	}
+	if nt.Capacity.CPU <= 0 || nt.Capacity.RAM <= 0 {
+		return nt, fmt.Errorf("capacity of instance type %s is unknown", instanceType)
+	}
+
+	// Prefer the total hourly cost, otherwise price the resources
+	if cost, err := strconv.ParseFloat(pricing.Cost, 64); err == nil && cost > 0 {
+		nt.HourlyCost = cost
+	} else {
+		cpuCost, cpuErr := strconv.ParseFloat(pricing.VCPUCost, 64)
+		ramCost, ramErr := strconv.ParseFloat(pricing.RAMCost, 64)
+		if cpuErr != nil || ramErr != nil {
+			return nt, fmt.Errorf("price of instance type %s is unknown", instanceType)
+		}
+		nt.HourlyCost = cpuCost*nt.Capacity.CPU + ramCost*nt.Capacity.RAM/(1024*1024*1024)
+	}
+	if nt.HourlyCost <= 0 {
+		return nt, fmt.Errorf("price of instance type %s is unknown", instanceType)
+	}
+
+	return nt, nil
+}
+
+// priceNodeTypes prices the current instance types of the pool and the given
+// alternative instance types in the pool's region, returning a warning for
+// each which could not be priced
+func priceNodeTypes(cp models.Provider, pool *nodePool, instanceTypes []string) ([]binpack.NodeType, []string) {
+	existing := map[string]*v1.Node{}
+	types := []string{}
+	for _, node := range pool.nodes {
+		it, _ := util.GetInstanceType(node.Labels)
+		if _, ok := existing[it]; !ok {
+			existing[it] = node
+			types = append(types, it)
+		}
+	}
+	for _, it := range instanceTypes {
+		if _, ok := existing[it]; !ok {
+			existing[it] = nil
+			types = append(types, it)
+		}
+	}
+
+	nodeTypes := []binpack.NodeType{}
+	warnings := []string{}
+	for _, it := range types {
+		if it == "" {
+			warnings = append(warnings, "nodes without an instance type cannot be priced")
+			continue
+		}
+
+		// Price the instance type as a node of the pool with that type. The
+		// provider ID is only kept for existing nodes of the type.
+		node := existing[it]
+		if node == nil {
+			node = pool.nodes[0].DeepCopy()
+			node.Spec.ProviderID = ""
+		} else {
+			node = node.DeepCopy()
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		node.Labels[v1.LabelInstanceTypeStable] = it
+		node.Labels[v1.LabelInstanceType] = it
+		labels := node.Labels
+		labels["providerID"] = node.Spec.ProviderID
+
+		pricing, _, err := cp.NodePricing(cp.GetKey(labels, node))
+		if err != nil || pricing == nil {
+			warnings = append(warnings, fmt.Sprintf("failed to price instance type %s: %v", it, err))
+			continue
+		}
+
+		nt, err := nodeTypeFromPricing(it, pricing, existing[it])
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		nodeTypes = append(nodeTypes, nt)
+	}
+
+	return nodeTypes, warnings
+}
+
+// recommendNodePool repacks the pods of the pool onto the cheapest mix of the
+// node types, comparing its cost to the current cost of the pool's nodes. The
+// current hourly cost of each node is looked up by name, falling back to the
+// price of its node type.
+func recommendNodePool(pool *nodePool, nodeTypes []binpack.NodeType, nodeCosts map[string]float64, opts binpack.Options) *NodePoolRecommendation {
+	overhead, daemonSetPods := pool.daemonSetOverhead()
+	opts.DaemonSetOverhead = overhead
+	opts.DaemonSetPods = daemonSetPods
+
+	rec := &NodePoolRecommendation{
+		Pool:              pool.name,
+		Taints:            pool.taints,
+		Pods:              len(pool.pods),
+		DaemonSetPods:     daemonSetPods,
+		DaemonSetOverhead: overhead,
+		NodeTypes:         nodeTypes,
+		Current: &NodePoolCost{
+			Counts:    map[string]int{},
+			NodeCosts: map[string]float64{},
+		},
+	}
+
+	prices := map[string]float64{}
+	for _, nt := range nodeTypes {
+		prices[nt.Name] = nt.HourlyCost
+	}
+
+	for _, node := range pool.nodes {
+		it, _ := util.GetInstanceType(node.Labels)
+		cost, ok := nodeCosts[node.Name]
+		if !ok {
+			cost = prices[it]
+		}
+
+		rec.Current.Nodes++
+		rec.Current.Counts[it]++
+		rec.Current.Capacity = rec.Current.Capacity.Add(binpack.Resources{
+			CPU: node.Status.Capacity.Cpu().AsApproximateFloat64(),
+			RAM: node.Status.Capacity.Memory().AsApproximateFloat64(),
+		})
+		rec.Current.NodeCosts[node.Name] = cost
+		rec.Current.HourlyCost += cost
+	}
+	for _, requests := range pool.pods {
+		rec.Current.Requested = rec.Current.Requested.Add(requests)
+	}
+	rec.Current.Requested = rec.Current.Requested.Add(overhead.Scale(float64(rec.Current.Nodes)))
+	rec.Current.MonthlyCost = rec.Current.HourlyCost * timeutil.HoursPerMonth
+
+	plan, err := binpack.Pack(pool.pods, nodeTypes, opts)
+	if err != nil {
+		rec.Error = err.Error()
+		return rec
+	}
+
+	rec.Recommended = plan
+	rec.RecommendedMonthlyCost = plan.HourlyCost * timeutil.HoursPerMonth
+	rec.MonthlySavings = rec.Current.MonthlyCost - rec.RecommendedMonthlyCost
+	return rec
+}
+
+// nodeAssetCosts returns the average hourly cost of the asset of each node of
+// the cluster over the window, by node name
+func (a *Accesses) nodeAssetCosts(r *http.Request, window opencost.Window) (map[string]float64, error) {
+	asr, err := a.Model.QueryAssets(r.Context(), window, window.Duration(), nil, nil, opencost.AccumulateOptionAll)
+	if err != nil {
+		return nil, err
+	}
+
+	costs := map[string]float64{}
+	if asr == nil || len(asr.Assets) == 0 {
+		return costs, nil
+	}
+
+	for _, asset := range asr.Assets[0].Assets {
+		node, ok := asset.(*opencost.Node)
+		if !ok || node.Properties == nil || node.Properties.Cluster != env.GetClusterID() {
+			continue
+		}
+		if hours := node.Minutes() / 60; hours > 0 {
+			costs[node.Properties.Name] = node.TotalCost() / hours
+		}
+	}
+	return costs, nil
+}
+
+// ComputeNodePoolRecommendationsHandler simulates repacking the pods of each
+// node pool of the cluster onto the pool's current instance types and the
+// alternative 'instanceTypes', returning the cheapest mix of instance types
+// for each pool and its savings versus the costs of the current node assets
+// over 'window' (default 7d). Pods are only repacked within their pool, so
+// that taints are respected, and every node is assumed to run the pool's
+// DaemonSets. 'headroom' (default 0.1) is the fraction of each node's capacity
+// left unrequested, and 'maxPods' (default 110) the pods which fit on a node.
+func (a *Accesses) ComputeNodePoolRecommendationsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	window, err := opencost.ParseWindowWithOffset(qp.Get("window", "7d"), env.GetParsedUTCOffset())
+	if err != nil || window.IsOpen() || window.IsNegative() || window.IsEmpty() {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", qp.Get("window", "7d"))))
+		return
+	}
+
+	opts := binpack.DefaultOptions()
+	opts.Headroom = qp.GetFloat64("headroom", binpack.DefaultHeadroom)
+	if opts.Headroom < 0 || opts.Headroom >= 1 {
+		WriteError(w, BadRequest("bad request - 'headroom' must be in [0, 1)"))
+		return
+	}
+	opts.MaxPods = qp.GetInt("maxPods", binpack.DefaultMaxPods)
+	if opts.MaxPods <= 0 {
+		WriteError(w, BadRequest("bad request - 'maxPods' must be positive"))
+		return
+	}
+	instanceTypes := qp.GetList("instanceTypes", ",")
+
+	nodeCosts, err := a.nodeAssetCosts(r, window)
+	if err != nil {
+		log.Warnf("ComputeNodePoolRecommendationsHandler: failed to query node costs, using list prices: %s", err)
+		nodeCosts = map[string]float64{}
+	}
+
+	result := &NodePoolRecommendations{
+		Window:   window,
+		Headroom: opts.Headroom,
+		MaxPods:  opts.MaxPods,
+		Pools:    []*NodePoolRecommendation{},
+	}
+
+	for _, pool := range buildNodePools(a.ClusterCache.GetAllNodes(), a.ClusterCache.GetAllPods()) {
+		nodeTypes, warnings := priceNodeTypes(a.CloudProvider, pool, instanceTypes)
+
+		rec := recommendNodePool(pool, nodeTypes, nodeCosts, opts)
+		rec.Warnings = warnings
+
+		result.CurrentMonthlyCost += rec.Current.MonthlyCost
+		if rec.Recommended != nil {
+			result.RecommendedMonthlyCost += rec.RecommendedMonthlyCost
+			result.MonthlySavings += rec.MonthlySavings
+		} else {
+			// Pools which cannot be repacked are assumed to stay as they are
+			result.RecommendedMonthlyCost += rec.Current.MonthlyCost
+		}
+		result.Pools = append(result.Pools, rec)
+	}
+
+	w.Write(WrapData(result, nil))
+}
@@ -0,0 +1,166 @@
+package costmodel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/binpack"
+	"github.com/opencost/opencost/pkg/cloud/models"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodePools(t *testing.T) {
+	newNode := func(name, pool, instanceType string, taints ...v1.Taint) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"eks.amazonaws.com/nodegroup": pool,
+					v1.LabelInstanceTypeStable:    instanceType,
+				},
+			},
+			Spec: v1.NodeSpec{Taints: taints},
+			Status: v1.NodeStatus{
+				Capacity: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("4"),
+					v1.ResourceMemory: resource.MustParse("16Gi"),
+				},
+			},
+		}
+	}
+	newPod := func(name, node, cpu, memory, ownerKind, owner string) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName: node,
+				Containers: []v1.Container{{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse(cpu),
+							v1.ResourceMemory: resource.MustParse(memory),
+						},
+					},
+				}},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: owner}}
+		}
+		return pod
+	}
+
+	gpuTaint := v1.Taint{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}
+	nodes := []*v1.Node{
+		newNode("node-1", "general", "m5.xlarge"),
+		newNode("node-2", "general", "m5.xlarge"),
+		newNode("node-3", "general", "m5.xlarge"),
+		newNode("gpu-1", "gpu", "g4dn.xlarge", gpuTaint, v1.Taint{Key: "soft", Effect: v1.TaintEffectPreferNoSchedule}),
+	}
+
+	completed := newPod("done", "node-1", "2", "1Gi", "", "")
+	completed.Status.Phase = v1.PodSucceeded
+
+	pods := []*v1.Pod{
+		newPod("web-1", "node-1", "1", "2Gi", "ReplicaSet", "web"),
+		newPod("web-2", "node-2", "1", "2Gi", "ReplicaSet", "web"),
+		newPod("web-3", "node-3", "1", "2Gi", "ReplicaSet", "web"),
+		newPod("logs-1", "node-1", "100m", "128Mi", "DaemonSet", "logs"),
+		newPod("logs-2", "node-2", "200m", "128Mi", "DaemonSet", "logs"),
+		newPod("proxy-node-1", "node-1", "100m", "64Mi", "Node", "node-1"),
+		newPod("proxy-node-2", "node-2", "100m", "64Mi", "Node", "node-2"),
+		newPod("train", "gpu-1", "2", "8Gi", "Job", "train"),
+		newPod("pending", "", "1", "1Gi", "", ""),
+		completed,
+	}
+
+	pools := buildNodePools(nodes, pods)
+	if len(pools) != 2 {
+		t.Fatalf("expected 2 pools, got %d", len(pools))
+	}
+
+	general, gpu := pools[0], pools[1]
+	if general.name != "general" || len(general.nodes) != 3 || len(general.pods) != 3 {
+		t.Errorf("unexpected general pool: %+v", general)
+	}
+	// Only taints which prevent scheduling separate pools
+	if gpu.name != "gpu,gpu=true:NoSchedule" || len(gpu.taints) != 1 || len(gpu.pods) != 1 {
+		t.Errorf("unexpected gpu pool: %+v", gpu)
+	}
+
+	// The largest pod of each DaemonSet and static pod runs on every node
+	overhead, daemonSetPods := general.daemonSetOverhead()
+	if daemonSetPods != 2 || math.Abs(overhead.CPU-0.3) > 1e-9 || overhead.RAM != 192*1024*1024 {
+		t.Errorf("unexpected DaemonSet overhead: %d pods, %+v", daemonSetPods, overhead)
+	}
+
+	nodeTypes := []binpack.NodeType{
+		{Name: "m5.xlarge", Capacity: binpack.Resources{CPU: 4, RAM: 16 * gibBytes}, HourlyCost: 0.192},
+		{Name: "m5.large", Capacity: binpack.Resources{CPU: 2, RAM: 8 * gibBytes}, HourlyCost: 0.096},
+	}
+
+	// node-3 has no asset, so is priced at list price
+	nodeCosts := map[string]float64{"node-1": 0.2, "node-2": 0.2}
+
+	rec := recommendNodePool(general, nodeTypes, nodeCosts, binpack.DefaultOptions())
+	if rec.Error != "" {
+		t.Fatalf("unexpected error: %s", rec.Error)
+	}
+	if rec.Current.Nodes != 3 || math.Abs(rec.Current.HourlyCost-0.592) > 1e-9 {
+		t.Errorf("unexpected current cost: %+v", rec.Current)
+	}
+
+	// 3 cores of pods and 0.3 cores of DaemonSets per node fit one m5.xlarge
+	// with 10% headroom
+	if rec.Recommended.Counts["m5.xlarge"] != 1 || len(rec.Recommended.Nodes) != 1 {
+		t.Errorf("unexpected recommendation: %+v", rec.Recommended.Counts)
+	}
+	expectedSavings := (0.592 - 0.192) * 730
+	if math.Abs(rec.MonthlySavings-expectedSavings) > 1e-9 {
+		t.Errorf("expected monthly savings %f, got %f", expectedSavings, rec.MonthlySavings)
+	}
+
+	rec = recommendNodePool(gpu, nodeTypes[1:], nodeCosts, binpack.DefaultOptions())
+	if rec.Recommended != nil || rec.Error == "" {
+		t.Errorf("expected pods which fit no node type to have no recommendation, got %+v", rec.Recommended)
+	}
+}
+
+func TestNodeTypeFromPricing(t *testing.T) {
+	existing := &v1.Node{
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("15Gi"),
+			},
+		},
+	}
+
+	nt, err := nodeTypeFromPricing("m5.xlarge", &models.Node{Cost: "0.192", VCPU: "4", RAM: "16 GiB"}, nil)
+	if err != nil || nt.Capacity.CPU != 4 || nt.Capacity.RAM != 16*gibBytes || nt.HourlyCost != 0.192 {
+		t.Errorf("unexpected node type: %+v, %v", nt, err)
+	}
+
+	// Capacity from the existing node, and cost from the resource prices
+	nt, err = nodeTypeFromPricing("custom", &models.Node{VCPUCost: "0.03", RAMCost: "0.004"}, existing)
+	if err != nil || nt.Capacity.RAM != 15*gibBytes || math.Abs(nt.HourlyCost-(0.12+0.06)) > 1e-9 {
+		t.Errorf("unexpected node type: %+v, %v", nt, err)
+	}
+
+	_, err = nodeTypeFromPricing("unknown", &models.Node{Cost: "0.1"}, nil)
+	if err == nil {
+		t.Errorf("expected error for node type with unknown capacity")
+	}
+
+	for s, expected := range map[string]float64{"16 GiB": 16 * gibBytes, "1,952 GiB": 1952 * gibBytes, "8": 8 * gibBytes, "512MiB": 512 * 1024 * 1024} {
+		ram, err := parseRAMBytes(s)
+		if err != nil || ram != expected {
+			t.Errorf("expected %s to parse to %f, got %f, %v", s, expected, ram, err)
+		}
+	}
+	if _, err := parseRAMBytes("lots"); err == nil {
+		t.Errorf("expected error parsing invalid RAM")
+	}
+}
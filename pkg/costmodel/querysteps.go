@@ -0,0 +1,125 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/worker"
+	"github.com/opencost/opencost/pkg/jobs"
+)
+
+// queryStep is the window of a single set in a range query
+type queryStep struct {
+	start time.Time
+	end   time.Time
+}
+
+// queryStepResult pairs the output of a step with the error, if any, that
+// occurred computing it
+type queryStepResult[U any] struct {
+	value U
+	err   error
+}
+
+// getQuerySteps splits the given window into consecutive steps of the given
+// duration. The final step may extend beyond the end of the window.
+func getQuerySteps(window opencost.Window, step time.Duration) []queryStep {
+	steps := []queryStep{}
+	if window.IsOpen() || step <= 0 {
+		return steps
+	}
+
+	stepStart := *window.Start()
+	for window.End().After(stepStart) {
+		stepEnd := stepStart.Add(step)
+		steps = append(steps, queryStep{start: stepStart, end: stepEnd})
+		stepStart = stepEnd
+	}
+
+	return steps
+}
+
+// computeQuerySteps calls compute for each of the given steps using at most
+// the given number of concurrent workers, and returns the results in the
+// order of the steps. Compute is passed a context which is cancelled once any
+// step fails or the given context is cancelled, so that it can stop early. The state of each step is reported to the query job, if
+// any, running with the given context. Once any step fails, or the context is cancelled (e.g.
+// because the client disconnected), steps which have not yet started are
+// skipped and an error is returned.
+func computeQuerySteps[U any](ctx context.Context, steps []queryStep, concurrency int, compute func(ctx context.Context, start, end time.Time) (U, error)) ([]U, error) {
+	if len(steps) == 0 {
+		return []U{}, nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(steps) {
+		concurrency = len(steps)
+	}
+
+	// Report the state of each step, if running as an asynchronous query job
+	progress := jobs.ProgressFromContext(ctx)
+	progressSteps := make([]*jobs.Step, len(steps))
+	for i, step := range steps {
+		progressSteps[i] = progress.AddStep(opencost.NewClosedWindow(step.start, step.end).String())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errOnce sync.Once
+	var firstErr error
+
+	work := func(i int) queryStepResult[U] {
+		select {
+		case <-ctx.Done():
+			return queryStepResult[U]{err: ctx.Err()}
+		default:
+		}
+
+		progressSteps[i].Start()
+		value, err := compute(ctx, steps[i].start, steps[i].end)
+		if err != nil {
+			progressSteps[i].Fail(err)
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		} else {
+			progressSteps[i].Done()
+		}
+
+		return queryStepResult[U]{value: value, err: err}
+	}
+
+	pool := worker.NewWorkerPool(concurrency, work)
+	defer pool.Shutdown()
+
+	group := worker.NewOrderedGroup(pool, len(steps))
+	for i, step := range steps {
+		err := group.Push(i)
+		if err != nil {
+			return nil, fmt.Errorf("error scheduling step %s: %w", opencost.NewClosedWindow(step.start, step.end), err)
+		}
+	}
+
+	results := group.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	values := make([]U, 0, len(results))
+	for i, result := range results {
+		if result.err != nil {
+			return nil, fmt.Errorf("query cancelled at %s: %w", opencost.NewClosedWindow(steps[i].start, steps[i].end), result.err)
+		}
+		values = append(values, result.value)
+	}
+
+	return values, nil
+}
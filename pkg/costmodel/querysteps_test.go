@@ -0,0 +1,156 @@
+package costmodel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/jobs"
+)
+
+func TestGetQuerySteps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := getQuerySteps(opencost.NewClosedWindow(start, start.Add(36*time.Hour)), 24*time.Hour)
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(steps))
+	}
+	if !steps[0].start.Equal(start) || !steps[1].start.Equal(start.Add(24*time.Hour)) || !steps[1].end.Equal(start.Add(48*time.Hour)) {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+
+	if len(getQuerySteps(opencost.NewClosedWindow(start, start.Add(time.Hour)), 0)) != 0 {
+		t.Errorf("expected no steps for a zero step duration")
+	}
+}
+
+func TestComputeQuerySteps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := getQuerySteps(opencost.NewClosedWindow(start, start.Add(30*24*time.Hour)), 24*time.Hour)
+
+	var running, maxRunning atomic.Int32
+	results, err := computeQuerySteps(context.Background(), steps, 4, func(ctx context.Context, s, e time.Time) (time.Time, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		// Finish later steps first to ensure ordering doesn't depend on
+		// completion order
+		time.Sleep(time.Duration(len(steps)-s.Day()) * time.Millisecond)
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(results) != len(steps) {
+		t.Fatalf("expected %d results, got %d", len(steps), len(results))
+	}
+	for i, result := range results {
+		if !result.Equal(steps[i].start) {
+			t.Errorf("result %d: expected %s, got %s", i, steps[i].start, result)
+		}
+	}
+	if maxRunning.Load() > 4 {
+		t.Errorf("expected at most 4 concurrent steps, got %d", maxRunning.Load())
+	}
+}
+
+func TestComputeQuerySteps_Error(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := getQuerySteps(opencost.NewClosedWindow(start, start.Add(30*24*time.Hour)), 24*time.Hour)
+
+	errBoom := errors.New("boom")
+	var calls atomic.Int32
+	_, err := computeQuerySteps(context.Background(), steps, 1, func(ctx context.Context, s, e time.Time) (int, error) {
+		calls.Add(1)
+		if s.Equal(steps[2].start) {
+			return 0, errBoom
+		}
+		return 1, nil
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected error %v, got %v", errBoom, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected remaining steps to be skipped after error, got %d calls", calls.Load())
+	}
+}
+
+func TestComputeQuerySteps_Cancel(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := getQuerySteps(opencost.NewClosedWindow(start, start.Add(30*24*time.Hour)), 24*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var once sync.Once
+	var calls atomic.Int32
+	_, err := computeQuerySteps(ctx, steps, 2, func(ctx context.Context, s, e time.Time) (int, error) {
+		calls.Add(1)
+		// Simulate the client disconnecting during the first steps
+		once.Do(cancel)
+		if ctx.Err() == nil {
+			t.Errorf("expected the step context to be cancelled")
+		}
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if calls.Load() > 2 {
+		t.Errorf("expected remaining steps to be skipped after cancellation, got %d calls", calls.Load())
+	}
+}
+
+func TestComputeQuerySteps_Progress(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := getQuerySteps(opencost.NewClosedWindow(start, start.Add(4*24*time.Hour)), 24*time.Hour)
+
+	m := jobs.NewManager(1, 1, time.Hour, time.Hour)
+	defer m.Shutdown()
+
+	errBoom := errors.New("boom")
+	status, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		return computeQuerySteps(ctx, steps, 1, func(ctx context.Context, s, e time.Time) (int, error) {
+			if s.Equal(steps[2].start) {
+				return 0, errBoom
+			}
+			return 1, nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !status.Status.IsDone() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		status, _ = m.Status(status.ID)
+	}
+	if status.Status != jobs.StatusFailed {
+		t.Fatalf("expected failed job, got %+v", status)
+	}
+
+	// Steps after the failure are skipped, so remain pending
+	expected := []jobs.StepState{jobs.StepDone, jobs.StepDone, jobs.StepFailed, jobs.StepPending}
+	if len(status.Progress.Steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %+v", len(expected), status.Progress.Steps)
+	}
+	for i, step := range status.Progress.Steps {
+		if step.Name != opencost.NewClosedWindow(steps[i].start, steps[i].end).String() || step.State != expected[i] {
+			t.Errorf("step %d: expected %s, got %+v", i, expected[i], step)
+		}
+	}
+	if status.Progress.Steps[2].Error != "boom" {
+		t.Errorf("expected failed step error boom, got %s", status.Progress.Steps[2].Error)
+	}
+}
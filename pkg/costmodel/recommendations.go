@@ -0,0 +1,469 @@
+package costmodel
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/filter"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+const (
+	queryFmtCPUUsagePercentile = `max(quantile_over_time(%f, irate(container_cpu_usage_seconds_total{container!="POD", container!="", %s}[%s])[%s:%s])) by (container, pod, namespace, %s)`
+	queryFmtRAMUsagePercentile = `max(quantile_over_time(%f, container_memory_working_set_bytes{container!="POD", container!="", %s}[%s])) by (container, pod, namespace, %s)`
+)
+
+const (
+	// DefaultRecommendationPercentile is the percentile of usage which
+	// requests are recommended to fit, unless otherwise specified
+	DefaultRecommendationPercentile = 95.0
+
+	// DefaultTargetUtilization is the fraction of a recommended request which
+	// the percentile of usage is targeted to utilize, unless otherwise
+	// specified
+	DefaultTargetUtilization = 0.8
+
+	// minRecommendedCPUCores and minRecommendedRAMBytes are the smallest
+	// requests which are recommended, however little is used
+	minRecommendedCPUCores = 0.01
+	minRecommendedRAMBytes = 10 * 1024 * 1024
+
+	gibBytes = 1024 * 1024 * 1024
+)
+
+// RequestRecommendationOptions configure how requests are recommended
+type RequestRecommendationOptions struct {
+	// Percentile is the percentile of usage, in (0, 100], which requests are
+	// recommended to fit
+	Percentile float64
+	// TargetCPUUtilization and TargetRAMUtilization are the fractions, in
+	// (0, 1], of the recommended requests which the percentile of usage is
+	// targeted to utilize
+	TargetCPUUtilization float64
+	TargetRAMUtilization float64
+	// Filter optionally restricts the allocations which are recommended for
+	Filter filter.Filter
+}
+
+// ResourceRecommendation is the recommended request of one resource of a
+// container. CPU is measured in cores and priced per core-hour, and RAM in
+// bytes and priced per GiB-hour. Request and usages are averaged over the
+// container's replicas, except UsageMax and UsagePercentile, which are the
+// largest of any replica. UsagePercentile is nil if it is not available, in
+// which case the recommendation is based on UsageMax.
+type ResourceRecommendation struct {
+	Request         float64  `json:"request"`
+	Recommended     float64  `json:"recommended"`
+	UsageAverage    float64  `json:"usageAverage"`
+	UsageMax        float64  `json:"usageMax"`
+	UsagePercentile *float64 `json:"usagePercentile"`
+	HourlyRate      float64  `json:"hourlyRate"`
+	MonthlySavings  float64  `json:"monthlySavings"`
+}
+
+// RequestRecommendation recommends the CPU and RAM requests of a container
+// of a controller. Replicas is the average number of the controller's pods
+// running the container over the window. MonthlySavings is negative if the
+// recommended requests cost more than the current requests.
+type RequestRecommendation struct {
+	Cluster        string                  `json:"cluster"`
+	Namespace      string                  `json:"namespace"`
+	ControllerKind string                  `json:"controllerKind"`
+	Controller     string                  `json:"controller"`
+	Container      string                  `json:"container"`
+	Replicas       float64                 `json:"replicas"`
+	CPU            *ResourceRecommendation `json:"cpu"`
+	RAM            *ResourceRecommendation `json:"ram"`
+	MonthlySavings float64                 `json:"monthlySavings"`
+}
+
+// RequestRecommendations are the request recommendations of every container
+// over a window, ordered by monthly savings, largest first
+type RequestRecommendations struct {
+	Window               opencost.Window          `json:"window"`
+	Percentile           float64                  `json:"percentile"`
+	TargetCPUUtilization float64                  `json:"targetCPUUtilization"`
+	TargetRAMUtilization float64                  `json:"targetRAMUtilization"`
+	MonthlySavings       float64                  `json:"monthlySavings"`
+	Recommendations      []*RequestRecommendation `json:"recommendations"`
+}
+
+// nodeRates accumulates the costs and resource hours of the allocations of a
+// node, from which the node's hourly rates are derived
+type nodeRates struct {
+	cpuCost      float64
+	cpuCoreHours float64
+	ramCost      float64
+	ramGiBHours  float64
+}
+
+func (nr *nodeRates) cpuRate() float64 {
+	if nr == nil || nr.cpuCoreHours == 0 {
+		return 0
+	}
+	return nr.cpuCost / nr.cpuCoreHours
+}
+
+func (nr *nodeRates) ramRate() float64 {
+	if nr == nil || nr.ramGiBHours == 0 {
+		return 0
+	}
+	return nr.ramCost / nr.ramGiBHours
+}
+
+// resourceUsage accumulates the requests and usage of one resource of the
+// allocations of a container
+type resourceUsage struct {
+	requestHours float64
+	usageHours   float64
+	usageMax     float64
+	hasMax       bool
+	percentile   *float64
+}
+
+func (ru *resourceUsage) addPercentile(value float64, ok bool) {
+	if !ok {
+		return
+	}
+	if ru.percentile == nil || value > *ru.percentile {
+		ru.percentile = &value
+	}
+}
+
+// recommend returns the recommended request for the usage, or false if there
+// is no usage on which to base a recommendation
+func (ru *resourceUsage) recommend(target, minimum, unit float64) (float64, bool) {
+	var usage float64
+	switch {
+	case ru.percentile != nil:
+		usage = *ru.percentile
+	case ru.hasMax:
+		usage = ru.usageMax
+	default:
+		return 0, false
+	}
+
+	recommended := math.Max(usage/target, minimum)
+	return math.Ceil(recommended/unit) * unit, true
+}
+
+// containerGroup accumulates the allocations of a container of a controller
+type containerGroup struct {
+	recommendation *RequestRecommendation
+	allocs         []*opencost.Allocation
+	hours          float64
+	cpu            resourceUsage
+	ram            resourceUsage
+}
+
+// RecommendRequests recommends the CPU and RAM requests of each container of
+// each controller in the unaggregated AllocationSet. Requests are recommended
+// such that the given percentile of usage, which is looked up by container in
+// the given maps, utilizes the target fraction of the request. The savings of
+// each recommendation are priced with the hourly rates of the nodes which the
+// container's pods ran on, derived from the costs of every allocation of
+// those nodes.
+func RecommendRequests(as *opencost.AllocationSet, cpuPercentiles, ramPercentiles map[containerKey]float64, opts RequestRecommendationOptions) (*RequestRecommendations, error) {
+	result := &RequestRecommendations{
+		Percentile:           opts.Percentile,
+		TargetCPUUtilization: opts.TargetCPUUtilization,
+		TargetRAMUtilization: opts.TargetRAMUtilization,
+		Recommendations:      []*RequestRecommendation{},
+	}
+	if as == nil {
+		return result, nil
+	}
+	result.Window = as.Window.Clone()
+
+	if opts.TargetCPUUtilization <= 0 || opts.TargetRAMUtilization <= 0 {
+		return nil, fmt.Errorf("target utilization must be positive")
+	}
+
+	var matcher opencost.AllocationMatcher
+	if opts.Filter != nil {
+		var err error
+		matcher, err = opencost.NewAllocationMatchCompiler(nil).Compile(opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("compiling filter: %w", err)
+		}
+	}
+
+	rates := map[nodeKey]*nodeRates{}
+	groups := map[string]*containerGroup{}
+
+	for _, alloc := range as.Allocations {
+		if alloc.IsIdle() || alloc.IsUnmounted() || alloc.IsUnallocated() || alloc.Properties == nil {
+			continue
+		}
+		props := alloc.Properties
+
+		nk := newNodeKey(props.Cluster, props.Node)
+		if _, ok := rates[nk]; !ok {
+			rates[nk] = &nodeRates{}
+		}
+		rates[nk].cpuCost += alloc.CPUTotalCost()
+		rates[nk].cpuCoreHours += alloc.CPUCoreHours
+		rates[nk].ramCost += alloc.RAMTotalCost()
+		rates[nk].ramGiBHours += alloc.RAMByteHours / gibBytes
+
+		if props.Container == "" || (matcher != nil && !matcher.Matches(alloc)) {
+			continue
+		}
+
+		// Pods without a controller are recommended for individually
+		controllerKind, controller := props.ControllerKind, props.Controller
+		if controller == "" {
+			controllerKind, controller = "pod", props.Pod
+		}
+
+		key := strings.Join([]string{props.Cluster, props.Namespace, controllerKind, controller, props.Container}, "/")
+		group, ok := groups[key]
+		if !ok {
+			group = &containerGroup{
+				recommendation: &RequestRecommendation{
+					Cluster:        props.Cluster,
+					Namespace:      props.Namespace,
+					ControllerKind: controllerKind,
+					Controller:     controller,
+					Container:      props.Container,
+				},
+			}
+			groups[key] = group
+		}
+
+		hours := alloc.Minutes() / 60
+		group.allocs = append(group.allocs, alloc)
+		group.hours += hours
+		group.cpu.requestHours += alloc.CPUCoreRequestAverage * hours
+		group.cpu.usageHours += alloc.CPUCoreUsageAverage * hours
+		group.ram.requestHours += alloc.RAMBytesRequestAverage * hours
+		group.ram.usageHours += alloc.RAMBytesUsageAverage * hours
+		if alloc.RawAllocationOnly != nil {
+			group.cpu.usageMax = math.Max(group.cpu.usageMax, alloc.RawAllocationOnly.CPUCoreUsageMax)
+			group.cpu.hasMax = true
+			group.ram.usageMax = math.Max(group.ram.usageMax, alloc.RawAllocationOnly.RAMBytesUsageMax)
+			group.ram.hasMax = true
+		}
+
+		ck := newContainerKey(props.Cluster, props.Namespace, props.Pod, props.Container)
+		cpuPercentile, ok := cpuPercentiles[ck]
+		group.cpu.addPercentile(cpuPercentile, ok)
+		ramPercentile, ok := ramPercentiles[ck]
+		group.ram.addPercentile(ramPercentile, ok)
+	}
+
+	// Savings over the window are scaled to a month
+	windowHours := as.Window.Duration().Hours()
+	if windowHours <= 0 {
+		return result, nil
+	}
+	monthScale := timeutil.HoursPerMonth / windowHours
+
+	for _, group := range groups {
+		if group.hours == 0 {
+			continue
+		}
+
+		rec := group.recommendation
+		rec.Replicas = group.hours / windowHours
+
+		cpuRecommended, cpuOK := group.cpu.recommend(opts.TargetCPUUtilization, minRecommendedCPUCores, 0.001)
+		ramRecommended, ramOK := group.ram.recommend(opts.TargetRAMUtilization, minRecommendedRAMBytes, 1024*1024)
+		if !cpuOK && !ramOK {
+			continue
+		}
+
+		if cpuOK {
+			rec.CPU = &ResourceRecommendation{
+				Request:         group.cpu.requestHours / group.hours,
+				Recommended:     cpuRecommended,
+				UsageAverage:    group.cpu.usageHours / group.hours,
+				UsageMax:        group.cpu.usageMax,
+				UsagePercentile: group.cpu.percentile,
+			}
+		}
+		if ramOK {
+			rec.RAM = &ResourceRecommendation{
+				Request:         group.ram.requestHours / group.hours,
+				Recommended:     ramRecommended,
+				UsageAverage:    group.ram.usageHours / group.hours,
+				UsageMax:        group.ram.usageMax,
+				UsagePercentile: group.ram.percentile,
+			}
+		}
+
+		// Each allocation is priced with the rates of the node it ran on
+		var cpuCostHours, ramCostHours float64
+		for _, alloc := range group.allocs {
+			hours := alloc.Minutes() / 60
+			nr := rates[newNodeKey(alloc.Properties.Cluster, alloc.Properties.Node)]
+
+			if rec.CPU != nil {
+				cpuCostHours += nr.cpuRate() * hours
+				rec.CPU.MonthlySavings += (alloc.CPUCoreRequestAverage - rec.CPU.Recommended) * hours * nr.cpuRate() * monthScale
+			}
+			if rec.RAM != nil {
+				ramCostHours += nr.ramRate() * hours
+				rec.RAM.MonthlySavings += (alloc.RAMBytesRequestAverage - rec.RAM.Recommended) / gibBytes * hours * nr.ramRate() * monthScale
+			}
+		}
+
+		if rec.CPU != nil {
+			rec.CPU.HourlyRate = cpuCostHours / group.hours
+			rec.MonthlySavings += rec.CPU.MonthlySavings
+		}
+		if rec.RAM != nil {
+			rec.RAM.HourlyRate = ramCostHours / group.hours
+			rec.MonthlySavings += rec.RAM.MonthlySavings
+		}
+
+		result.MonthlySavings += rec.MonthlySavings
+		result.Recommendations = append(result.Recommendations, rec)
+	}
+
+	sort.Slice(result.Recommendations, func(i, j int) bool {
+		ri, rj := result.Recommendations[i], result.Recommendations[j]
+		if ri.MonthlySavings != rj.MonthlySavings {
+			return ri.MonthlySavings > rj.MonthlySavings
+		}
+		ki := strings.Join([]string{ri.Cluster, ri.Namespace, ri.ControllerKind, ri.Controller, ri.Container}, "/")
+		kj := strings.Join([]string{rj.Cluster, rj.Namespace, rj.ControllerKind, rj.Controller, rj.Container}, "/")
+		return ki < kj
+	})
+
+	return result, nil
+}
+
+// queryUsagePercentiles queries the given percentile, in (0, 100], of the CPU
+// and RAM usage of each container over the window
+func (cm *CostModel) queryUsagePercentiles(window opencost.Window, resolution time.Duration, percentile float64) (map[containerKey]float64, map[containerKey]float64, error) {
+	durStr := timeutil.DurationString(window.Duration())
+	resStr := timeutil.DurationString(resolution)
+	// The irate range should be 2x the resolution, to make sure the irate
+	// always has two points to query
+	doubleResStr := timeutil.DurationString(2 * resolution)
+	quantile := percentile / 100
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName)
+
+	queryCPU := fmt.Sprintf(queryFmtCPUUsagePercentile, quantile, env.GetPromClusterFilter(), doubleResStr, durStr, resStr, env.GetPromClusterLabel())
+	resChCPU := ctx.QueryAtTime(queryCPU, *window.End())
+
+	queryRAM := fmt.Sprintf(queryFmtRAMUsagePercentile, quantile, env.GetPromClusterFilter(), durStr, env.GetPromClusterLabel())
+	resChRAM := ctx.QueryAtTime(queryRAM, *window.End())
+
+	resCPU, _ := resChCPU.Await()
+	resRAM, _ := resChRAM.Await()
+	if ctx.HasErrors() {
+		for _, err := range ctx.Errors() {
+			log.Errorf("CostModel.queryUsagePercentiles: query context error %s", err)
+		}
+		return nil, nil, ctx.ErrorCollection()
+	}
+
+	toMap := func(results []*prom.QueryResult) map[containerKey]float64 {
+		m := map[containerKey]float64{}
+		for _, res := range results {
+			key, err := resultContainerKey(res, env.GetPromClusterLabel(), "namespace", "pod", "container")
+			if err != nil || len(res.Values) == 0 {
+				log.DedupedWarningf(10, "CostModel.queryUsagePercentiles: usage percentile result missing field: %v", err)
+				continue
+			}
+			m[key] = res.Values[0].Value
+		}
+		return m
+	}
+
+	return toMap(resCPU), toMap(resRAM), nil
+}
+
+// parsePercentile parses a percentile such as "95", "99.9" or "p99"
+func parsePercentile(s string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToLower(s), "p"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("percentile must be in (0, 100]: %s", s)
+	}
+	return p, nil
+}
+
+// ComputeRequestRecommendationsHandler recommends the CPU and RAM requests of
+// the containers of each controller over the required 'window'. Requests are
+// recommended such that the 'percentile' of usage (default 95, e.g. "p99")
+// utilizes 'targetCPUUtilization' and 'targetRAMUtilization' (default 0.8)
+// of the request. The optional 'filter' restricts the containers which are
+// recommended for.
+func (a *Accesses) ComputeRequestRecommendationsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	window, err := opencost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", err)))
+		return
+	}
+	if window.IsOpen() || window.IsNegative() || window.IsEmpty() {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", window)))
+		return
+	}
+
+	resolution := qp.GetDuration("resolution", env.GetETLResolution())
+
+	opts := RequestRecommendationOptions{
+		Percentile:           DefaultRecommendationPercentile,
+		TargetCPUUtilization: qp.GetFloat64("targetCPUUtilization", DefaultTargetUtilization),
+		TargetRAMUtilization: qp.GetFloat64("targetRAMUtilization", DefaultTargetUtilization),
+	}
+	if qp.Has("percentile") {
+		opts.Percentile, err = parsePercentile(qp.Get("percentile", ""))
+		if err != nil {
+			WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'percentile' parameter: %s", err)))
+			return
+		}
+	}
+	if opts.TargetCPUUtilization <= 0 || opts.TargetCPUUtilization > 1 {
+		WriteError(w, BadRequest("bad request - 'targetCPUUtilization' must be in (0, 1]"))
+		return
+	}
+	if opts.TargetRAMUtilization <= 0 || opts.TargetRAMUtilization > 1 {
+		WriteError(w, BadRequest("bad request - 'targetRAMUtilization' must be in (0, 1]"))
+		return
+	}
+
+	opts.Filter, err = ParseAllocationFilter(qp.Get("filter", ""))
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'filter' parameter: %s", err)))
+		return
+	}
+
+	as, err := a.Model.ComputeAllocation(*window.Start(), *window.End(), resolution)
+	if err != nil {
+		WriteError(w, InternalServerError(fmt.Sprintf("error computing allocations for %s: %s", window, err)))
+		return
+	}
+
+	cpuPercentiles, ramPercentiles, err := a.Model.queryUsagePercentiles(window, resolution, opts.Percentile)
+	if err != nil {
+		WriteError(w, InternalServerError(fmt.Sprintf("error querying usage percentiles for %s: %s", window, err)))
+		return
+	}
+
+	recommendations, err := RecommendRequests(as, cpuPercentiles, ramPercentiles, opts)
+	if err != nil {
+		WriteError(w, InternalServerError(err.Error()))
+		return
+	}
+
+	w.Write(WrapData(recommendations, nil))
+}
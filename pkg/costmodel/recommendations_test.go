@@ -0,0 +1,142 @@
+package costmodel
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+func TestRecommendRequests(t *testing.T) {
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	newAlloc := func(name, node, controller, pod string, cpuRequest, ramRequest, cpuRate, ramRate float64) *opencost.Allocation {
+		kind := ""
+		if controller != "" {
+			kind = "deployment"
+		}
+		return &opencost.Allocation{
+			Name: name,
+			Properties: &opencost.AllocationProperties{
+				Cluster:        "cluster",
+				Node:           node,
+				Namespace:      "default",
+				ControllerKind: kind,
+				Controller:     controller,
+				Pod:            pod,
+				Container:      "app",
+			},
+			Window:                 opencost.NewClosedWindow(start, end),
+			Start:                  start,
+			End:                    end,
+			CPUCoreRequestAverage:  cpuRequest,
+			CPUCoreHours:           cpuRequest * 24,
+			CPUCost:                cpuRequest * 24 * cpuRate,
+			RAMBytesRequestAverage: ramRequest * gibBytes,
+			RAMByteHours:           ramRequest * gibBytes * 24,
+			RAMCost:                ramRequest * 24 * ramRate,
+			RawAllocationOnly: &opencost.RawAllocationOnlyData{
+				CPUCoreUsageMax:  0.9,
+				RAMBytesUsageMax: 0.8 * gibBytes,
+			},
+		}
+	}
+
+	// Two replicas of a deployment on nodes with different rates, and a pod
+	// without a controller or requests
+	as := opencost.NewAllocationSet(start, end,
+		newAlloc("web-1", "node-a", "web", "web-1", 1, 2, 0.04, 0.005),
+		newAlloc("web-2", "node-b", "web", "web-2", 1, 2, 0.08, 0.01),
+		newAlloc("standalone", "node-a", "", "standalone", 0, 0, 0.04, 0.005),
+		&opencost.Allocation{Name: opencost.IdleSuffix, Window: opencost.NewClosedWindow(start, end), Start: start, End: end, CPUCost: 100},
+	)
+
+	cpuPercentiles := map[containerKey]float64{
+		newContainerKey("cluster", "default", "web-1", "app"):      0.2,
+		newContainerKey("cluster", "default", "web-2", "app"):      0.4,
+		newContainerKey("cluster", "default", "standalone", "app"): 0.08,
+	}
+
+	opts := RequestRecommendationOptions{
+		Percentile:           95,
+		TargetCPUUtilization: 0.8,
+		TargetRAMUtilization: 0.8,
+	}
+	result, err := RecommendRequests(as, cpuPercentiles, map[containerKey]float64{}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(result.Recommendations) != 2 {
+		t.Fatalf("expected 2 recommendations, got %d", len(result.Recommendations))
+	}
+
+	web := result.Recommendations[0]
+	if web.Controller != "web" || web.ControllerKind != "deployment" || web.Replicas != 2 {
+		t.Fatalf("expected first recommendation for deployment web with 2 replicas, got %+v", web)
+	}
+
+	// The largest percentile of the replicas, at the target utilization
+	if web.CPU.Request != 1 || web.CPU.Recommended != 0.5 || web.CPU.UsagePercentile == nil || *web.CPU.UsagePercentile != 0.4 {
+		t.Errorf("unexpected CPU recommendation: %+v", web.CPU)
+	}
+	// Without a percentile, RAM is recommended from the max usage
+	if web.RAM.Request != 2*gibBytes || web.RAM.Recommended != gibBytes || web.RAM.UsagePercentile != nil {
+		t.Errorf("unexpected RAM recommendation: %+v", web.RAM)
+	}
+
+	// Half a core less on each node, and 1 GiB less on each node, for a month
+	expectedCPUSavings := 0.5 * (0.04 + 0.08) * 730
+	expectedRAMSavings := 1 * (0.005 + 0.01) * 730
+	if math.Abs(web.CPU.MonthlySavings-expectedCPUSavings) > 1e-9 {
+		t.Errorf("expected CPU savings %f, got %f", expectedCPUSavings, web.CPU.MonthlySavings)
+	}
+	if math.Abs(web.RAM.MonthlySavings-expectedRAMSavings) > 1e-9 {
+		t.Errorf("expected RAM savings %f, got %f", expectedRAMSavings, web.RAM.MonthlySavings)
+	}
+	if math.Abs(web.CPU.HourlyRate-0.06) > 1e-9 || math.Abs(web.RAM.HourlyRate-0.0075) > 1e-9 {
+		t.Errorf("expected the average rates of the nodes, got %f and %f", web.CPU.HourlyRate, web.RAM.HourlyRate)
+	}
+
+	// Adding requests where there were none costs more
+	standalone := result.Recommendations[1]
+	if standalone.ControllerKind != "pod" || standalone.Controller != "standalone" {
+		t.Errorf("expected a pod recommendation for the standalone pod, got %+v", standalone)
+	}
+	if standalone.CPU.Recommended != 0.1 || standalone.MonthlySavings >= 0 {
+		t.Errorf("expected a negative saving for the standalone pod, got %+v", standalone)
+	}
+
+	if math.Abs(result.MonthlySavings-(web.MonthlySavings+standalone.MonthlySavings)) > 1e-9 {
+		t.Errorf("expected total savings to be the sum of the recommendations, got %f", result.MonthlySavings)
+	}
+
+	// Filtered allocations are not recommended for, but still price the nodes
+	opts.Filter, err = ParseAllocationFilter(`controllerName:"web"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result, err = RecommendRequests(as, cpuPercentiles, nil, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Recommendations) != 1 || math.Abs(result.Recommendations[0].CPU.MonthlySavings-expectedCPUSavings) > 1e-9 {
+		t.Errorf("expected only the web recommendation, got %+v", result.Recommendations)
+	}
+}
+
+func TestParsePercentile(t *testing.T) {
+	for s, expected := range map[string]float64{"95": 95, "p99": 99, "P99.9": 99.9, "100": 100} {
+		p, err := parsePercentile(s)
+		if err != nil || p != expected {
+			t.Errorf("expected %s to parse to %f, got %f, %v", s, expected, p, err)
+		}
+	}
+	for _, s := range []string{"", "0", "101", "p", "high"} {
+		if _, err := parsePercentile(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+// BilledCosts are the costs billed by a cloud provider over a window, by
+// lower-cased provider ID and then by category
+type BilledCosts map[string]map[string]float64
+
+// Add adds the cost of the provider ID and category
+func (bc BilledCosts) Add(providerID, category string, cost float64) {
+	providerID = strings.ToLower(providerID)
+	if _, ok := bc[providerID]; !ok {
+		bc[providerID] = map[string]float64{}
+	}
+	bc[providerID][category] += cost
+}
+
+// Cost returns the cost billed for the provider ID. Costs billed in the given
+// category are preferred, but if there are none, all costs billed for the
+// provider ID are used.
+func (bc BilledCosts) Cost(providerID, category string) (float64, bool) {
+	costs, ok := bc[strings.ToLower(providerID)]
+	if !ok {
+		return 0, false
+	}
+	if cost, ok := costs[category]; ok {
+		return cost, true
+	}
+
+	var total float64
+	for _, cost := range costs {
+		total += cost
+	}
+	return total, true
+}
+
+// Reconciler adjusts the costs of assets to the costs billed by the cloud
+// provider, which include discounts such as reserved instances, savings
+// plans and credits, and distributes the adjustments to the allocations
+// which use those assets.
+type Reconciler struct {
+	querier    cloudcost.Querier
+	costMetric opencost.CostMetricName
+}
+
+// NewReconciler creates a Reconciler of the billed costs of the given cost
+// metric of the cloud cost querier
+func NewReconciler(querier cloudcost.Querier, costMetric opencost.CostMetricName) *Reconciler {
+	return &Reconciler{
+		querier:    querier,
+		costMetric: costMetric,
+	}
+}
+
+// BilledCosts returns the costs billed over the window. Cloud costs are
+// billed daily, so the costs of each day are prorated by the fraction of the
+// day which falls within the window.
+func (r *Reconciler) BilledCosts(ctx context.Context, window opencost.Window) (BilledCosts, error) {
+	if window.IsOpen() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+
+	start := opencost.RoundBack(*window.Start(), 24*time.Hour)
+	end := opencost.RoundForward(*window.End(), 24*time.Hour)
+
+	ccsr, err := r.querier.Query(ctx, cloudcost.QueryRequest{
+		Start:       start,
+		End:         end,
+		AggregateBy: []string{opencost.CloudCostProviderIDProp, opencost.CloudCostCategoryProp},
+		Accumulate:  opencost.AccumulateOptionNone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying cloud costs for %s: %w", window, err)
+	}
+
+	billed := BilledCosts{}
+	for _, ccs := range ccsr.CloudCostSets {
+		if ccs.Window.IsOpen() || ccs.Window.IsEmpty() {
+			continue
+		}
+		overlapStart, overlapEnd := *ccs.Window.Start(), *ccs.Window.End()
+		if window.Start().After(overlapStart) {
+			overlapStart = *window.Start()
+		}
+		if window.End().Before(overlapEnd) {
+			overlapEnd = *window.End()
+		}
+		if !overlapEnd.After(overlapStart) {
+			continue
+		}
+		fraction := float64(overlapEnd.Sub(overlapStart)) / float64(ccs.Window.Duration())
+
+		for _, cc := range ccs.CloudCosts {
+			if cc.Properties == nil || cc.Properties.ProviderID == "" {
+				continue
+			}
+
+			costMetric, err := cc.GetCostMetric(r.costMetric)
+			if err != nil {
+				return nil, fmt.Errorf("getting cost metric: %w", err)
+			}
+			billed.Add(cc.Properties.ProviderID, cc.Properties.Category, costMetric.Cost*fraction)
+		}
+	}
+
+	return billed, nil
+}
+
+// Reconcile adjusts the costs of the assets, and the allocations which use
+// them, to the costs billed over the sets' window
+func (r *Reconciler) Reconcile(ctx context.Context, allocSet *opencost.AllocationSet, assetSet *opencost.AssetSet) error {
+	billed, err := r.BilledCosts(ctx, assetSet.Window)
+	if err != nil {
+		return err
+	}
+
+	ReconcileAssets(assetSet, billed)
+	ReconcileAllocations(allocSet, assetSet)
+	return nil
+}
+
+// ReconcileAssets sets the adjustment of each node, disk and load balancer
+// in the set which has billed costs, such that its total cost is the billed
+// cost. It returns the number of assets which were reconciled.
+func ReconcileAssets(assetSet *opencost.AssetSet, billed BilledCosts) int {
+	reconciled := 0
+
+	for _, byCategory := range assetSet.ReconciliationMatchMap() {
+		for _, asset := range byCategory {
+			var category string
+			switch asset.(type) {
+			case *opencost.Node:
+				category = opencost.CloudCostVirtualMachineCategory
+			case *opencost.Disk:
+				category = opencost.CloudCostDiskCategory
+			case *opencost.LoadBalancer:
+				category = opencost.CloudCostLoadBalancerCategory
+			default:
+				continue
+			}
+
+			cost, ok := billed.Cost(asset.GetProperties().ProviderID, category)
+			if !ok {
+				continue
+			}
+
+			unadjusted := asset.TotalCost() - asset.GetAdjustment()
+			asset.SetAdjustment(cost - unadjusted)
+			reconciled++
+		}
+	}
+
+	return reconciled
+}
+
+// adjustmentRate returns the ratio of the asset's adjusted cost to its
+// unadjusted cost, which is 1 if it has no adjustment or no unadjusted cost
+func adjustmentRate(asset opencost.Asset) float64 {
+	unadjusted := asset.TotalCost() - asset.GetAdjustment()
+	if asset.GetAdjustment() == 0 || unadjusted == 0 {
+		return 1
+	}
+	return asset.TotalCost() / unadjusted
+}
+
+// ReconcileAllocations distributes the adjustments of the assets to the
+// allocations which use them, in proportion to each allocation's cost of the
+// asset. The costs of the allocations of an asset, including idle, then sum
+// to the asset's adjusted cost. Existing adjustments are replaced.
+func ReconcileAllocations(allocSet *opencost.AllocationSet, assetSet *opencost.AssetSet) {
+	nodeRates := map[string]float64{}
+	for _, node := range assetSet.Nodes {
+		if node.Properties == nil {
+			continue
+		}
+		nodeRates[node.Properties.Cluster+"/"+node.Properties.Name] = adjustmentRate(node)
+	}
+
+	diskRates := map[opencost.PVKey]float64{}
+	for _, disk := range assetSet.Disks {
+		if disk.Properties == nil {
+			continue
+		}
+		diskRates[opencost.PVKey{Cluster: disk.Properties.Cluster, Name: disk.Properties.Name}] = adjustmentRate(disk)
+	}
+
+	lbRates := map[string]float64{}
+	for _, lb := range assetSet.LoadBalancers {
+		if lb.Properties == nil {
+			continue
+		}
+		lbRates[lb.Properties.Cluster+"/"+lb.Properties.Name] = adjustmentRate(lb)
+	}
+
+	for _, alloc := range allocSet.Allocations {
+		if alloc.Properties == nil {
+			continue
+		}
+
+		if rate, ok := nodeRates[alloc.Properties.Cluster+"/"+alloc.Properties.Node]; ok {
+			alloc.CPUCostAdjustment = alloc.CPUCost * (rate - 1)
+			alloc.RAMCostAdjustment = alloc.RAMCost * (rate - 1)
+			alloc.GPUCostAdjustment = alloc.GPUCost * (rate - 1)
+		}
+
+		var pvAdjustment float64
+		for key, pv := range alloc.PVs {
+			if pv == nil {
+				continue
+			}
+			rate, ok := diskRates[key]
+			if !ok {
+				continue
+			}
+			pv.Adjustment = pv.Cost * (rate - 1)
+			pvAdjustment += pv.Adjustment
+		}
+		alloc.PVCostAdjustment = pvAdjustment
+
+		var lbAdjustment float64
+		for _, lb := range alloc.LoadBalancers {
+			if lb == nil {
+				continue
+			}
+			rate, ok := lbRates[alloc.Properties.Cluster+"/"+lb.Service]
+			if !ok {
+				continue
+			}
+			lb.Adjustment = lb.Cost * (rate - 1)
+			lbAdjustment += lb.Adjustment
+		}
+		alloc.LoadBalancerCostAdjustment = lbAdjustment
+	}
+}
+
+// InitializeReconciliation enables the reconciliation of allocation queries
+// of the model against the billed costs of the cloud cost querier
+func InitializeReconciliation(model *CostModel, cloudCostQuerier cloudcost.Querier) {
+	if model == nil || cloudCostQuerier == nil {
+		log.Warnf("Init: allocation reconciliation requires allocations and cloud costs")
+		return
+	}
+
+	costMetric, err := opencost.ParseCostMetricName(env.GetReconciliationCostMetric())
+	if err != nil {
+		log.Errorf("Init: invalid reconciliation cost metric: %s", err)
+		return
+	}
+
+	log.Infof("Init: reconciling allocations against billed %s", costMetric)
+	model.Reconciler = NewReconciler(cloudCostQuerier, costMetric)
+}
@@ -0,0 +1,147 @@
+package costmodel
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/cloudcost"
+)
+
+func TestReconcile(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	window := opencost.NewClosedWindow(start, end)
+
+	node := opencost.NewNode("node-1", "cluster-1", "i-123", start, end, window)
+	node.CPUCost = 6
+	node.RAMCost = 4
+
+	unbilled := opencost.NewNode("node-2", "cluster-1", "i-456", start, end, window)
+	unbilled.CPUCost = 10
+
+	disk := opencost.NewDisk("pv-1", "cluster-1", "vol-1", start, end, window)
+	disk.Cost = 5
+
+	lb := opencost.NewLoadBalancer("default/web", "cluster-1", "lb-1", start, end, window, false, "")
+	lb.Cost = 2
+
+	alloc := opencost.NewMockUnitAllocation("cluster-1/node-1/default/web-1/web", start, 24*time.Hour, &opencost.AllocationProperties{
+		Cluster: "cluster-1",
+		Node:    "node-1",
+	})
+	alloc.CPUCost = 3
+	alloc.RAMCost = 2
+	alloc.GPUCost = 0
+	pvKey := opencost.PVKey{Cluster: "cluster-1", Name: "pv-1"}
+	alloc.PVs = opencost.PVAllocations{pvKey: {ByteHours: 1, Cost: 2.5}}
+	alloc.LoadBalancers = opencost.LbAllocations{"default/web": {Service: "default/web", Cost: 2}}
+
+	assetSet := opencost.NewAssetSet(start, end, node, unbilled, disk, lb)
+	allocSet := opencost.NewAllocationSet(start, end, alloc)
+
+	// Billed at the cloud cost category of each asset, with the node's
+	// provider ID differing in case and a fee in another category
+	billed := BilledCosts{}
+	billed.Add("I-123", opencost.CloudCostVirtualMachineCategory, 8)
+	billed.Add("i-123", opencost.CloudCostOtherCategory, 1)
+	billed.Add("vol-1", opencost.CloudCostDiskCategory, 6)
+	billed.Add("lb-1", opencost.CloudCostNetworkCategory, 1)
+
+	if reconciled := ReconcileAssets(assetSet, billed); reconciled != 3 {
+		t.Errorf("expected 3 assets to be reconciled, got %d", reconciled)
+	}
+
+	// The load balancer is billed in another category, so its total is used
+	for name, expected := range map[string]float64{"node-1": -2, "node-2": 0, "pv-1": 1, "default/web": -1} {
+		var asset opencost.Asset
+		for _, a := range assetSet.Assets {
+			if a.GetProperties().Name == name {
+				asset = a
+			}
+		}
+		if asset == nil {
+			t.Fatalf("missing asset %s", name)
+		}
+		if math.Abs(asset.GetAdjustment()-expected) > 1e-9 {
+			t.Errorf("expected %s adjustment %f, got %f", name, expected, asset.GetAdjustment())
+		}
+	}
+
+	// Reconciling again replaces, rather than adds to, the adjustments
+	ReconcileAssets(assetSet, billed)
+	if math.Abs(node.TotalCost()-8) > 1e-9 {
+		t.Errorf("expected reconciled node cost 8, got %f", node.TotalCost())
+	}
+
+	ReconcileAllocations(allocSet, assetSet)
+
+	expected := map[string]float64{
+		"cpu": -0.6,
+		"ram": -0.4,
+		"pv":  0.5,
+		"lb":  -1,
+	}
+	actual := map[string]float64{
+		"cpu": alloc.CPUCostAdjustment,
+		"ram": alloc.RAMCostAdjustment,
+		"pv":  alloc.PVCostAdjustment,
+		"lb":  alloc.LoadBalancerCostAdjustment,
+	}
+	for key, exp := range expected {
+		if math.Abs(actual[key]-exp) > 1e-9 {
+			t.Errorf("expected %s adjustment %f, got %f", key, exp, actual[key])
+		}
+	}
+	if alloc.PVs[pvKey].Adjustment != alloc.PVCostAdjustment {
+		t.Errorf("expected PV adjustment %f, got %f", alloc.PVCostAdjustment, alloc.PVs[pvKey].Adjustment)
+	}
+}
+
+func TestReconciler_BilledCosts(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := cloudcost.NewMemoryRepository()
+	for i, cost := range []float64{24, 48} {
+		start := day.Add(time.Duration(i) * 24 * time.Hour)
+		end := start.Add(24 * time.Hour)
+
+		props := &opencost.CloudCostProperties{
+			ProviderID:      "i-123",
+			Category:        opencost.CloudCostVirtualMachineCategory,
+			Service:         "AmazonEC2",
+			AccountID:       "account",
+			InvoiceEntityID: "account",
+		}
+		ccs := opencost.NewCloudCostSet(start, end, opencost.NewCloudCost(start, end, props, 1, cost*2, cost*2, cost, cost*2, cost))
+		ccs.Integration = "integration"
+		if err := repo.Put(ccs); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	r := NewReconciler(cloudcost.NewRepositoryQuerier(repo), opencost.CostMetricAmortizedNetCost)
+
+	// Half of the first day and a quarter of the second
+	window := opencost.NewClosedWindow(day.Add(12*time.Hour), day.Add(30*time.Hour))
+	billed, err := r.BilledCosts(context.Background(), window)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cost, ok := billed.Cost("i-123", opencost.CloudCostVirtualMachineCategory)
+	if !ok || math.Abs(cost-24) > 1e-9 {
+		t.Errorf("expected billed cost 24, got %f", cost)
+	}
+
+	if _, ok := billed.Cost("i-456", opencost.CloudCostVirtualMachineCategory); ok {
+		t.Errorf("expected no billed cost for unknown provider ID")
+	}
+
+	_, err = r.BilledCosts(context.Background(), opencost.NewWindow(&day, nil))
+	if err == nil {
+		t.Errorf("expected error for open window")
+	}
+}
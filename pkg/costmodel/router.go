@@ -28,7 +28,9 @@ import (
 	"github.com/opencost/opencost/pkg/cloudcost"
 	"github.com/opencost/opencost/pkg/config"
 	clustermap "github.com/opencost/opencost/pkg/costmodel/clusters"
+	"github.com/opencost/opencost/pkg/currency"
 	"github.com/opencost/opencost/pkg/customcost"
+	"github.com/opencost/opencost/pkg/jobs"
 	"github.com/opencost/opencost/pkg/kubeconfig"
 	"github.com/opencost/opencost/pkg/metrics"
 	"github.com/opencost/opencost/pkg/services"
@@ -102,6 +104,10 @@ type Accesses struct {
 	ClusterCostsCache   *cache.Cache
 	CacheExpiration     map[time.Duration]time.Duration
 	AggAPI              Aggregator
+	// JobManager runs asynchronous allocation and asset queries
+	JobManager *jobs.Manager
+	// CurrencyConverter converts costs to the currency requested, if enabled
+	CurrencyConverter *currency.Converter
 	// SettingsCache stores current state of app settings
 	SettingsCache *cache.Cache
 	// settingsSubscribers tracks channels through which changes to different
@@ -174,6 +180,8 @@ type Response struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message,omitempty"`
 	Warning string      `json:"warning,omitempty"`
+	// Currency is the conversion applied to the costs of the data, if any
+	Currency *currency.Conversion `json:"currency,omitempty"`
 }
 
 // FilterFunc is a filter that returns true iff the given CostData should be filtered out, and the environment that was used as the filter criteria, if it was an aggregate
@@ -299,6 +307,22 @@ func WrapData(data interface{}, err error) []byte {
 	return resp
 }
 
+// WrapDataWithCurrency wraps the data with the currency conversion applied to
+// its costs, which is omitted if nil
+func WrapDataWithCurrency(data interface{}, conversion *currency.Conversion) []byte {
+	resp, err := json.Marshal(&Response{
+		Code:     http.StatusOK,
+		Status:   "success",
+		Data:     data,
+		Currency: conversion,
+	})
+	if err != nil {
+		log.Errorf("error marshaling response json: %s", err.Error())
+	}
+
+	return resp
+}
+
 func WrapDataWithMessage(data interface{}, err error, message string) []byte {
 	var resp []byte
 
@@ -1683,6 +1707,9 @@ func Initialize(router *httprouter.Router, additionalConfigWatchers ...*watcher.
 		pc = promCli
 	}
 	costModel := NewCostModel(pc, cloudProvider, k8sCache, clusterMap, scrapeInterval)
+	if env.IsETLStoreEnabled() {
+		initializeETLStores(costModel)
+	}
 	metricsEmitter := NewCostModelMetricsEmitter(promCli, k8sCache, cloudProvider, clusterInfoProvider, costModel)
 
 	a := &Accesses{
@@ -1703,6 +1730,7 @@ func Initialize(router *httprouter.Router, additionalConfigWatchers ...*watcher.
 		OutOfClusterCache:   outOfClusterCache,
 		SettingsCache:       settingsCache,
 		CacheExpiration:     cacheExpiration,
+		JobManager:          jobs.NewManager(env.GetQueryJobWorkers(), env.GetQueryJobMaxPending(), env.GetQueryJobPendingTTL(), env.GetQueryJobResultTTL()),
 	}
 
 	// Use the Accesses instance, itself, as the CostModelAggregator. This is
@@ -1785,15 +1813,58 @@ func Initialize(router *httprouter.Router, additionalConfigWatchers ...*watcher.
 	return a
 }
 
-// InitializeCloudCost Initializes Cloud Cost pipeline and querier and registers endpoints
-func InitializeCloudCost(router *httprouter.Router, providerConfig models.ProviderConfig) {
+// initializeETLStores creates the persistent allocation and asset stores for
+// the CostModel and, unless running in read-only mode, starts building them.
+func initializeETLStores(costModel *CostModel) {
+	store, err := NewETLStorage()
+	if err != nil {
+		log.Errorf("Init: failed to create ETL storage: %s", err)
+		return
+	}
+
+	err = costModel.InitializeAllocationStore(store)
+	if err != nil {
+		log.Errorf("Init: %s", err)
+		return
+	}
+
+	err = costModel.InitializeAssetStore(store)
+	if err != nil {
+		log.Errorf("Init: %s", err)
+		return
+	}
+
+	if env.IsETLReadOnlyMode() {
+		log.Infof("Init: ETL stores are read-only, skipping build")
+		return
+	}
+
+	log.Infof("Init: starting ETL stores using %s storage", store.StorageType())
+	costModel.AllocationStore.Start()
+	costModel.AssetStore.Start()
+}
+
+// InitializeCloudCost Initializes Cloud Cost pipeline and querier and registers endpoints, returning the querier and
+// the repository of ingested Cloud Costs
+func InitializeCloudCost(router *httprouter.Router, providerConfig models.ProviderConfig, converter *currency.Converter) (cloudcost.Querier, cloudcost.Repository) {
 	log.Debugf("Cloud Cost config path: %s", env.GetCloudCostConfigPath())
 	cloudConfigController := cloudconfig.NewMemoryController(providerConfig)
 
-	repo := cloudcost.NewMemoryRepository()
+	var repo cloudcost.Repository = cloudcost.NewMemoryRepository()
+	if env.IsETLStoreEnabled() {
+		store, err := NewETLStorage()
+		if err != nil {
+			log.Errorf("Init: failed to create ETL storage for Cloud Costs, falling back to memory: %s", err)
+		} else {
+			log.Infof("Init: persisting Cloud Costs using %s storage", store.StorageType())
+			repo = cloudcost.NewStorageRepository(store)
+		}
+	}
+
 	cloudCostPipelineService := cloudcost.NewPipelineService(repo, cloudConfigController, cloudcost.DefaultIngestorConfiguration())
 	repoQuerier := cloudcost.NewRepositoryQuerier(repo)
 	cloudCostQueryService := cloudcost.NewQueryService(repoQuerier, repoQuerier)
+	cloudCostQueryService.Converter = converter
 
 	router.GET("/cloud/config/export", cloudConfigController.GetExportConfigHandler())
 	router.GET("/cloud/config/enable", cloudConfigController.GetEnableConfigHandler())
@@ -1801,6 +1872,7 @@ func InitializeCloudCost(router *httprouter.Router, providerConfig models.Provid
 	router.GET("/cloud/config/delete", cloudConfigController.GetDeleteConfigHandler())
 
 	router.GET("/cloudCost", cloudCostQueryService.GetCloudCostHandler())
+	router.GET("/cloudCost/forecast", cloudCostQueryService.GetCloudCostForecastHandler())
 	router.GET("/cloudCost/view/graph", cloudCostQueryService.GetCloudCostViewGraphHandler())
 	router.GET("/cloudCost/view/totals", cloudCostQueryService.GetCloudCostViewTotalsHandler())
 	router.GET("/cloudCost/view/table", cloudCostQueryService.GetCloudCostViewTableHandler())
@@ -1808,11 +1880,23 @@ func InitializeCloudCost(router *httprouter.Router, providerConfig models.Provid
 	router.GET("/cloudCost/status", cloudCostPipelineService.GetCloudCostStatusHandler())
 	router.GET("/cloudCost/rebuild", cloudCostPipelineService.GetCloudCostRebuildHandler())
 	router.GET("/cloudCost/repair", cloudCostPipelineService.GetCloudCostRepairHandler())
+
+	return repoQuerier, repo
 }
 
-func InitializeCustomCost(router *httprouter.Router) *customcost.PipelineService {
-	hourlyRepo := customcost.NewMemoryRepository()
-	dailyRepo := customcost.NewMemoryRepository()
+func InitializeCustomCost(router *httprouter.Router, converter *currency.Converter) *customcost.PipelineService {
+	var hourlyRepo, dailyRepo customcost.Repository = customcost.NewMemoryRepository(), customcost.NewMemoryRepository()
+	if env.IsETLStoreEnabled() {
+		store, err := NewETLStorage()
+		if err != nil {
+			log.Errorf("Init: failed to create ETL storage for Custom Costs, falling back to memory: %s", err)
+		} else {
+			log.Infof("Init: persisting Custom Costs using %s storage", store.StorageType())
+			hourlyRepo = customcost.NewStorageRepository(store, time.Hour)
+			dailyRepo = customcost.NewStorageRepository(store, timeutil.Day)
+		}
+	}
+
 	ingConfig := customcost.DefaultIngestorConfiguration()
 	var err error
 	customCostPipelineService, err := customcost.NewPipelineService(hourlyRepo, dailyRepo, ingConfig)
@@ -1823,6 +1907,7 @@ func InitializeCustomCost(router *httprouter.Router) *customcost.PipelineService
 
 	customCostQuerier := customcost.NewRepositoryQuerier(hourlyRepo, dailyRepo, ingConfig.HourlyDuration, ingConfig.DailyDuration)
 	customCostQueryService := customcost.NewQueryService(customCostQuerier)
+	customCostQueryService.Converter = converter
 
 	router.GET("/customCost/total", customCostQueryService.GetCustomCostTotalHandler())
 	router.GET("/customCost/timeseries", customCostQueryService.GetCustomCostTimeseriesHandler())
@@ -0,0 +1,149 @@
+package costmodel
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
+	"github.com/opencost/opencost/pkg/currency"
+	"github.com/opencost/opencost/pkg/env"
+)
+
+var protocol = proto.HTTP()
+
+// getStreamBatches splits the window into consecutive batches of the given
+// number of steps, so that streaming queries can compute and write out one
+// batch at a time. Batches start on step boundaries so that each batch
+// produces the same sets as the full query would have.
+func getStreamBatches(window opencost.Window, step time.Duration, stepsPerBatch int) []opencost.Window {
+	batches := []opencost.Window{}
+	if window.IsOpen() || step <= 0 {
+		return batches
+	}
+
+	if stepsPerBatch < 1 {
+		stepsPerBatch = 1
+	}
+	batch := step * time.Duration(stepsPerBatch)
+
+	start := *window.Start()
+	for window.End().After(start) {
+		end := start.Add(batch)
+		if end.After(*window.End()) {
+			end = *window.End()
+		}
+		batches = append(batches, opencost.NewClosedWindow(start, end))
+		start = end
+	}
+
+	return batches
+}
+
+// streamAllocation runs the given allocation query, writing each allocation
+// as a line of NDJSON. Unless the query is accumulated, which requires the
+// full range, steps are computed one batch at a time and written as soon as
+// each batch is produced, so memory use does not grow with the window.
+func (a *Accesses) streamAllocation(ctx context.Context, q *allocationQuery, nw *proto.NDJSONWriter) error {
+	batches := []opencost.Window{q.window}
+	if q.accumulateBy == opencost.AccumulateOptionNone {
+		batches = getStreamBatches(q.window, q.step, env.GetMaxQueryConcurrency())
+	}
+
+	for _, batch := range batches {
+		bq := *q
+		bq.window = batch
+
+		asr, err := a.queryAllocation(ctx, &bq)
+		if err != nil {
+			return err
+		}
+
+		for _, as := range asr.Allocations {
+			for _, alloc := range as.Allocations {
+				err = nw.Write(alloc)
+				if err != nil {
+					return err
+				}
+			}
+			nw.Flush()
+		}
+	}
+
+	return writeStreamCurrency(nw, q.conversion)
+}
+
+// streamAssets runs the given assets query, writing each asset as a line of
+// NDJSON, one batch of steps at a time as with streamAllocation.
+func (a *Accesses) streamAssets(ctx context.Context, q *assetsQuery, nw *proto.NDJSONWriter) error {
+	batches := []opencost.Window{q.window}
+	if q.accumulateBy == opencost.AccumulateOptionNone {
+		batches = getStreamBatches(q.window, q.step, env.GetMaxQueryConcurrency())
+	}
+
+	for _, batch := range batches {
+		bq := *q
+		bq.window = batch
+
+		asr, err := a.queryAssets(ctx, &bq)
+		if err != nil {
+			return err
+		}
+
+		for _, as := range asr.Assets {
+			err = writeAssetSet(nw, as)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeStreamCurrency(nw, q.conversion)
+}
+
+// writeAssetSet writes each asset in the set as a line of NDJSON
+func writeAssetSet(nw *proto.NDJSONWriter, as *opencost.AssetSet) error {
+	for _, asset := range as.Assets {
+		err := nw.Write(asset)
+		if err != nil {
+			return err
+		}
+	}
+	nw.Flush()
+	return nil
+}
+
+// writeStreamCurrency writes the currency conversion applied to the streamed
+// costs, if any, as the final line of an NDJSON response, in the same
+// "currency" property as JSON responses
+func writeStreamCurrency(nw *proto.NDJSONWriter, conversion *currency.Conversion) error {
+	if conversion == nil {
+		return nil
+	}
+	return nw.WriteCurrency(conversion)
+}
+
+// writeStreamError writes the error as the final line of an NDJSON response,
+// with the status of the response set accordingly if nothing has been
+// written yet.
+func writeStreamError(nw *proto.NDJSONWriter, err error) {
+	log.Errorf("Error streaming response: %s", err)
+
+	if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+		nw.WriteError(protocol.BadRequest(err.Error()))
+	} else {
+		nw.WriteError(protocol.InternalServerError(err.Error()))
+	}
+}
+
+// streamResponse returns an NDJSONWriter for the response if the request
+// accepts NDJSON, or nil otherwise
+func streamResponse(w http.ResponseWriter, r *http.Request) *proto.NDJSONWriter {
+	if !protocol.AcceptsNDJSON(r) {
+		return nil
+	}
+	return protocol.NewNDJSONWriter(w)
+}
@@ -0,0 +1,49 @@
+package costmodel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	proto "github.com/opencost/opencost/core/pkg/protocol"
+)
+
+func TestGetStreamBatches(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := opencost.NewClosedWindow(start, start.Add(10*24*time.Hour))
+
+	batches := getStreamBatches(window, 24*time.Hour, 4)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	if !batches[0].Start().Equal(start) || !batches[1].Start().Equal(start.Add(4*24*time.Hour)) || !batches[2].End().Equal(*window.End()) {
+		t.Errorf("unexpected batches: %v", batches)
+	}
+
+	// Each batch produces the same steps as the full window
+	steps := []queryStep{}
+	for _, batch := range batches {
+		steps = append(steps, getQuerySteps(batch, 24*time.Hour)...)
+	}
+	if len(steps) != len(getQuerySteps(window, 24*time.Hour)) {
+		t.Errorf("expected batches to cover %d steps, got %d", len(getQuerySteps(window, 24*time.Hour)), len(steps))
+	}
+}
+
+func TestComputeAssetsHandler_StreamBadRequest(t *testing.T) {
+	a := &Accesses{}
+
+	r := httptest.NewRequest(http.MethodGet, "/assets?window=1d&aggregate=notaproperty", nil)
+	r.Header.Set("Accept", proto.NDJSONContentType)
+	w := httptest.NewRecorder()
+	a.ComputeAssetsHandler(w, r, nil)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected a JSON error, got content type %s", contentType)
+	}
+}
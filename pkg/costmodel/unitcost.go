@@ -0,0 +1,259 @@
+package costmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+// unitMetricStepPlaceholder is replaced in the query of a UnitMetric by the
+// duration of each step, e.g. sum(increase(http_requests_total[$step]))
+const unitMetricStepPlaceholder = "$step"
+
+// UnitMetric is a business metric, such as requests served or GB processed,
+// by which the cost of the allocations matching its filter is divided. The
+// query is PromQL which returns the number of units in the step preceding
+// each evaluation; multiple series are summed. Costs are per Per units,
+// defaulting to 1, e.g. 1000 for the cost per 1k API calls.
+type UnitMetric struct {
+	Name   string  `json:"name"`
+	Query  string  `json:"query"`
+	Filter string  `json:"filter,omitempty"`
+	Unit   string  `json:"unit,omitempty"`
+	Per    float64 `json:"per,omitempty"`
+}
+
+// Validate returns an error if the UnitMetric is invalid
+func (um *UnitMetric) Validate() error {
+	if strings.TrimSpace(um.Query) == "" {
+		return errors.New("query is required")
+	}
+	if um.Per < 0 {
+		return fmt.Errorf("per must not be negative: %f", um.Per)
+	}
+	if _, err := ParseAllocationFilter(um.Filter); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	return nil
+}
+
+// per returns the number of units the cost is per
+func (um *UnitMetric) per() float64 {
+	if um.Per == 0 {
+		return 1
+	}
+	return um.Per
+}
+
+// LoadUnitMetrics loads the UnitMetrics, by name, from a JSON array at the
+// path. A missing file defines no UnitMetrics.
+func LoadUnitMetrics(path string) (map[string]*UnitMetric, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]*UnitMetric{}, nil
+		}
+		return nil, fmt.Errorf("failed to read unit metrics: %w", err)
+	}
+
+	var metrics []*UnitMetric
+	err = json.Unmarshal(data, &metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal unit metrics: %w", err)
+	}
+
+	byName := make(map[string]*UnitMetric, len(metrics))
+	for _, um := range metrics {
+		if um.Name == "" {
+			return nil, errors.New("unit metric name is required")
+		}
+		if err := um.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid unit metric %s: %w", um.Name, err)
+		}
+		if _, ok := byName[um.Name]; ok {
+			return nil, fmt.Errorf("duplicate unit metric: %s", um.Name)
+		}
+		byName[um.Name] = um
+	}
+	return byName, nil
+}
+
+// UnitCost is the cost of a window, the number of units in it, and the cost
+// per unit, which is nil if there are no units
+type UnitCost struct {
+	Window      opencost.Window `json:"window"`
+	Cost        float64         `json:"cost"`
+	Units       float64         `json:"units"`
+	CostPerUnit *float64        `json:"costPerUnit"`
+}
+
+func (uc *UnitCost) computeCostPerUnit(per float64) {
+	uc.CostPerUnit = nil
+	if uc.Units > 0 {
+		costPerUnit := uc.Cost / uc.Units * per
+		uc.CostPerUnit = &costPerUnit
+	}
+}
+
+// UnitCosts are the unit costs of each step of a window, and in total
+type UnitCosts struct {
+	Name  string      `json:"name,omitempty"`
+	Unit  string      `json:"unit,omitempty"`
+	Per   float64     `json:"per"`
+	Steps []*UnitCost `json:"steps"`
+	Total *UnitCost   `json:"total"`
+}
+
+// ComputeUnitCosts returns the unit costs of each set of the range, given the
+// results of the metric's query over the range. Each sample is counted in the
+// step which ends at or after it.
+func ComputeUnitCosts(um *UnitMetric, asr *opencost.AllocationSetRange, units []*prom.QueryResult) *UnitCosts {
+	ucs := &UnitCosts{
+		Name:  um.Name,
+		Unit:  um.Unit,
+		Per:   um.per(),
+		Steps: make([]*UnitCost, 0, len(asr.Allocations)),
+		Total: &UnitCost{},
+	}
+
+	for _, as := range asr.Allocations {
+		uc := &UnitCost{Window: as.Window.Clone()}
+		for _, alloc := range as.Allocations {
+			uc.Cost += alloc.TotalCost()
+		}
+		ucs.Steps = append(ucs.Steps, uc)
+	}
+
+	for _, res := range units {
+		for _, v := range res.Values {
+			t := time.Unix(int64(v.Timestamp), 0).UTC()
+			for _, uc := range ucs.Steps {
+				if uc.Window.Start().Before(t) && !uc.Window.End().Before(t) {
+					uc.Units += v.Value
+					break
+				}
+			}
+		}
+	}
+
+	var start, end *time.Time
+	for _, uc := range ucs.Steps {
+		uc.computeCostPerUnit(ucs.Per)
+		ucs.Total.Cost += uc.Cost
+		ucs.Total.Units += uc.Units
+		if start == nil || uc.Window.Start().Before(*start) {
+			start = uc.Window.Start()
+		}
+		if end == nil || uc.Window.End().After(*end) {
+			end = uc.Window.End()
+		}
+	}
+	ucs.Total.Window = opencost.NewWindow(start, end)
+	ucs.Total.computeCostPerUnit(ucs.Per)
+
+	return ucs
+}
+
+// queryUnits queries the metric's units in each step of the window
+func (cm *CostModel) queryUnits(um *UnitMetric, window opencost.Window, step time.Duration) ([]*prom.QueryResult, error) {
+	query := strings.ReplaceAll(um.Query, unitMetricStepPlaceholder, timeutil.DurationString(step))
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName)
+	res, _ := ctx.QueryRange(query, window.Start().Add(step), *window.End(), step).Await()
+	if ctx.HasErrors() {
+		return nil, ctx.ErrorCollection()
+	}
+	return res, nil
+}
+
+// ComputeUnitCostsHandler computes the cost per unit of a business metric in
+// each 'step' (default 1d) of the required 'window'. The metric is either the
+// 'metric' of the given name, as defined in the unit metrics config, or
+// defined by the 'query', optional 'filter', 'unit' and 'per' parameters.
+// 'includeIdle' and the share parameters of /allocation apply to the cost.
+func (a *Accesses) ComputeUnitCostsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	window, err := opencost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", err)))
+		return
+	}
+	if window.IsOpen() || window.IsNegative() || window.IsEmpty() {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'window' parameter: %s", window)))
+		return
+	}
+
+	step := qp.GetDuration("step", timeutil.Day)
+	if step < time.Minute || step > window.Duration() {
+		WriteError(w, BadRequest("bad request - 'step' must be at least 1m and at most the window"))
+		return
+	}
+
+	var um *UnitMetric
+	if qp.Has("metric") {
+		metrics, err := LoadUnitMetrics(env.GetUnitMetricsConfigPath())
+		if err != nil {
+			WriteError(w, InternalServerError(err.Error()))
+			return
+		}
+		var ok bool
+		um, ok = metrics[qp.Get("metric", "")]
+		if !ok {
+			WriteError(w, BadRequest(fmt.Sprintf("bad request - unknown 'metric': %s", qp.Get("metric", ""))))
+			return
+		}
+	} else {
+		um = &UnitMetric{
+			Query:  qp.Get("query", ""),
+			Filter: qp.Get("filter", ""),
+			Unit:   qp.Get("unit", ""),
+			Per:    qp.GetFloat64("per", 1),
+		}
+		if err := um.Validate(); err != nil {
+			WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid unit metric: %s", err)))
+			return
+		}
+	}
+
+	allocFilter, err := ParseAllocationFilter(um.Filter)
+	if err != nil {
+		WriteError(w, BadRequest(fmt.Sprintf("bad request - invalid 'filter' parameter: %s", err)))
+		return
+	}
+
+	var asr *opencost.AllocationSetRange
+	shareOpts, err := a.parseAllocationShareOptions(qp)
+	if err == nil {
+		asr, err = a.Model.QueryAllocation(r.Context(), window, env.GetETLResolution(), step, []string{opencost.AllocationClusterProp}, allocFilter, shareOpts, qp.GetBool("includeIdle", false), false, false, false, false, true, opencost.AccumulateOptionNone)
+	}
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+			WriteError(w, BadRequest(err.Error()))
+		} else {
+			WriteError(w, InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	units, err := a.Model.queryUnits(um, window, step)
+	if err != nil {
+		WriteError(w, InternalServerError(fmt.Sprintf("error querying units for %s: %s", window, err)))
+		return
+	}
+
+	w.Write(WrapData(ComputeUnitCosts(um, asr, units), nil))
+}
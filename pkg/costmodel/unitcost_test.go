@@ -0,0 +1,123 @@
+package costmodel
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util"
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+func TestComputeUnitCosts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	newSet := func(start time.Time, costs ...float64) *opencost.AllocationSet {
+		as := opencost.NewAllocationSet(start, start.Add(day))
+		for i, cost := range costs {
+			alloc := opencost.NewMockUnitAllocation(string(rune('a'+i)), start, day, nil)
+			alloc.CPUCost = cost
+			alloc.RAMCost = 0
+			alloc.GPUCost = 0
+			alloc.PVs = nil
+			alloc.NetworkCost = 0
+			alloc.LoadBalancerCost = 0
+			alloc.SharedCost = 0
+			alloc.ExternalCost = 0
+			as.Set(alloc)
+		}
+		return as
+	}
+
+	asr := opencost.NewAllocationSetRange(
+		newSet(start, 6, 4),
+		newSet(start.Add(day), 5),
+		newSet(start.Add(2*day), 3),
+	)
+
+	sample := func(t time.Time, value float64) *util.Vector {
+		return &util.Vector{Timestamp: float64(t.Unix()), Value: value}
+	}
+	// Samples are evaluated at the end of each step, and series are summed.
+	// There are no units on the last day.
+	units := []*prom.QueryResult{
+		{Values: []*util.Vector{sample(start.Add(day), 4000), sample(start.Add(2*day), 1000)}},
+		{Values: []*util.Vector{sample(start.Add(day), 1000)}},
+	}
+
+	ucs := ComputeUnitCosts(&UnitMetric{Name: "api", Unit: "calls", Per: 1000}, asr, units)
+
+	if len(ucs.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(ucs.Steps))
+	}
+
+	expected := []struct {
+		cost, units float64
+		costPerUnit *float64
+	}{
+		{10, 5000, ptr(2.0)},
+		{5, 1000, ptr(5.0)},
+		{3, 0, nil},
+	}
+	for i, exp := range expected {
+		uc := ucs.Steps[i]
+		if uc.Cost != exp.cost || uc.Units != exp.units {
+			t.Errorf("step %d: expected cost %f and units %f, got %f and %f", i, exp.cost, exp.units, uc.Cost, uc.Units)
+		}
+		if (uc.CostPerUnit == nil) != (exp.costPerUnit == nil) || (uc.CostPerUnit != nil && math.Abs(*uc.CostPerUnit-*exp.costPerUnit) > 1e-9) {
+			t.Errorf("step %d: expected cost per unit %v, got %v", i, exp.costPerUnit, uc.CostPerUnit)
+		}
+	}
+
+	if ucs.Total.Cost != 18 || ucs.Total.Units != 6000 || math.Abs(*ucs.Total.CostPerUnit-3) > 1e-9 {
+		t.Errorf("unexpected total: %+v", ucs.Total)
+	}
+	if !ucs.Total.Window.Start().Equal(start) || !ucs.Total.Window.End().Equal(start.Add(3*day)) {
+		t.Errorf("unexpected total window: %s", ucs.Total.Window)
+	}
+}
+
+func TestLoadUnitMetrics(t *testing.T) {
+	dir := t.TempDir()
+
+	metrics, err := LoadUnitMetrics(filepath.Join(dir, "missing.json"))
+	if err != nil || len(metrics) != 0 {
+		t.Errorf("expected no unit metrics from missing file, got %v, %v", metrics, err)
+	}
+
+	path := filepath.Join(dir, "unit-metrics.json")
+	err = os.WriteFile(path, []byte(`[{"name": "api", "query": "sum(increase(http_requests_total[$step]))", "filter": "namespace:\"web\"", "per": 1000}]`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	metrics, err = LoadUnitMetrics(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if um, ok := metrics["api"]; !ok || um.per() != 1000 || um.Filter != `namespace:"web"` {
+		t.Errorf("unexpected unit metrics: %v", metrics)
+	}
+
+	for name, data := range map[string]string{
+		"missing name":   `[{"query": "up"}]`,
+		"missing query":  `[{"name": "api"}]`,
+		"invalid filter": `[{"name": "api", "query": "up", "filter": "namespace:"}]`,
+		"duplicate":      `[{"name": "api", "query": "up"}, {"name": "api", "query": "up"}]`,
+	} {
+		err = os.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := LoadUnitMetrics(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,202 @@
+package currency
+
+import (
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+// ConvertAllocationSetRange converts the costs of the allocations of each set
+// of the range from the given currency, at the rate of the start of the set
+func (c *Conversion) ConvertAllocationSetRange(asr *opencost.AllocationSetRange, from string) error {
+	if c == nil || asr == nil {
+		return nil
+	}
+	for _, as := range asr.Allocations {
+		if err := c.ConvertAllocationSet(as, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertAllocationSet converts the costs of the allocations of the set from
+// the given currency, at the rate of the start of the set
+func (c *Conversion) ConvertAllocationSet(as *opencost.AllocationSet, from string) error {
+	if c == nil || as == nil {
+		return nil
+	}
+	rate, err := c.Rate(from, windowStart(as.Window))
+	if err != nil {
+		return err
+	}
+	if rate == 1 {
+		return nil
+	}
+	for _, alloc := range as.Allocations {
+		scaleAllocation(alloc, rate)
+	}
+	return nil
+}
+
+func scaleAllocation(alloc *opencost.Allocation, rate float64) {
+	alloc.CPUCost *= rate
+	alloc.CPUCostAdjustment *= rate
+	alloc.GPUCost *= rate
+	alloc.GPUCostAdjustment *= rate
+	alloc.RAMCost *= rate
+	alloc.RAMCostAdjustment *= rate
+	alloc.NetworkCost *= rate
+	alloc.NetworkCrossZoneCost *= rate
+	alloc.NetworkCrossRegionCost *= rate
+	alloc.NetworkInternetCost *= rate
+	alloc.NetworkCostAdjustment *= rate
+	alloc.LoadBalancerCost *= rate
+	alloc.LoadBalancerCostAdjustment *= rate
+	alloc.PVCostAdjustment *= rate
+	alloc.SharedCost *= rate
+	alloc.ExternalCost *= rate
+	alloc.UnmountedPVCost *= rate
+
+	for _, pv := range alloc.PVs {
+		if pv != nil {
+			pv.Cost *= rate
+			pv.Adjustment *= rate
+		}
+	}
+	for _, lb := range alloc.LoadBalancers {
+		if lb != nil {
+			lb.Cost *= rate
+			lb.Adjustment *= rate
+		}
+	}
+	for key, scb := range alloc.SharedCostBreakdown {
+		scb.TotalCost *= rate
+		scb.CPUCost *= rate
+		scb.GPUCost *= rate
+		scb.RAMCost *= rate
+		scb.PVCost *= rate
+		scb.NetworkCost *= rate
+		scb.LBCost *= rate
+		scb.ExternalCost *= rate
+		alloc.SharedCostBreakdown[key] = scb
+	}
+	for key, parc := range alloc.ProportionalAssetResourceCosts {
+		parc.CPUTotalCost *= rate
+		parc.CPUProportionalCost *= rate
+		parc.GPUTotalCost *= rate
+		parc.GPUProportionalCost *= rate
+		parc.RAMTotalCost *= rate
+		parc.RAMProportionalCost *= rate
+		parc.LoadBalancerTotalCost *= rate
+		parc.LoadBalancerProportionalCost *= rate
+		parc.PVTotalCost *= rate
+		parc.PVProportionalCost *= rate
+		alloc.ProportionalAssetResourceCosts[key] = parc
+	}
+}
+
+// ConvertAssetSetRange converts the costs of the assets of each set of the
+// range from the given currency, at the rate of the start of the set
+func (c *Conversion) ConvertAssetSetRange(asr *opencost.AssetSetRange, from string) error {
+	if c == nil || asr == nil {
+		return nil
+	}
+	for _, as := range asr.Assets {
+		if err := c.ConvertAssetSet(as, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertAssetSet converts the costs of the assets of the set from the given
+// currency, at the rate of the start of the set
+func (c *Conversion) ConvertAssetSet(as *opencost.AssetSet, from string) error {
+	if c == nil || as == nil {
+		return nil
+	}
+	rate, err := c.Rate(from, windowStart(as.Window))
+	if err != nil {
+		return err
+	}
+	if rate == 1 {
+		return nil
+	}
+	// The typed maps of the set hold the same assets
+	for _, asset := range as.Assets {
+		scaleAsset(asset, rate)
+	}
+	return nil
+}
+
+func scaleAsset(asset opencost.Asset, rate float64) {
+	switch a := asset.(type) {
+	case *opencost.Node:
+		a.CPUCost *= rate
+		a.GPUCost *= rate
+		a.RAMCost *= rate
+	case *opencost.Disk:
+		a.Cost *= rate
+	case *opencost.LoadBalancer:
+		a.Cost *= rate
+	case *opencost.Network:
+		a.Cost *= rate
+	case *opencost.ClusterManagement:
+		a.Cost *= rate
+		a.Adjustment *= rate
+	case *opencost.Cloud:
+		a.Cost *= rate
+		a.Credit *= rate
+	case *opencost.Any:
+		a.Cost *= rate
+	case *opencost.SharedAsset:
+		a.Cost *= rate
+	}
+	// SetAdjustment is a no-op for ClusterManagement and SharedAsset
+	asset.SetAdjustment(asset.GetAdjustment() * rate)
+}
+
+// ConvertCloudCostSetRange converts the costs of each set of the range from
+// the currency of its integration, at the rate of the start of the set
+func (c *Conversion) ConvertCloudCostSetRange(ccsr *opencost.CloudCostSetRange) error {
+	if c == nil || ccsr == nil {
+		return nil
+	}
+	for _, ccs := range ccsr.CloudCostSets {
+		if err := c.ConvertCloudCostSet(ccs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertCloudCostSet converts the costs of the set from the currency of its
+// integration, at the rate of the start of the set
+func (c *Conversion) ConvertCloudCostSet(ccs *opencost.CloudCostSet) error {
+	if c == nil || ccs == nil {
+		return nil
+	}
+	rate, err := c.Rate(c.CloudCostCurrency(ccs.Integration), windowStart(ccs.Window))
+	if err != nil {
+		return err
+	}
+	if rate == 1 {
+		return nil
+	}
+	for _, cc := range ccs.CloudCosts {
+		cc.ListCost.Cost *= rate
+		cc.NetCost.Cost *= rate
+		cc.AmortizedNetCost.Cost *= rate
+		cc.InvoicedCost.Cost *= rate
+		cc.AmortizedCost.Cost *= rate
+	}
+	return nil
+}
+
+func windowStart(window opencost.Window) time.Time {
+	if window.Start() == nil {
+		return time.Now().UTC()
+	}
+	return *window.Start()
+}
@@ -0,0 +1,228 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// ErrUnknownCurrency is returned when converting to a currency which is not in
+// the RateTable
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Source returns the JSON of a RateTable, or an error satisfying
+// errors.Is(err, os.ErrNotExist) if there is none
+type Source func() ([]byte, error)
+
+// FileSource is a Source which reads the file at the path
+func FileSource(path string) Source {
+	return func() ([]byte, error) {
+		return os.ReadFile(path)
+	}
+}
+
+// StorageSource is a Source which reads the file at the path of the storage
+func StorageSource(store storage.Storage, path string) Source {
+	return func() ([]byte, error) {
+		exists, err := store.Exists(path)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%s: %w", store.FullPath(path), os.ErrNotExist)
+		}
+		return store.Read(path)
+	}
+}
+
+// Converter creates Conversions using the RateTable of its source, which is
+// reloaded once older than the refresh interval
+type Converter struct {
+	lock     sync.Mutex
+	source   Source
+	base     string
+	refresh  time.Duration
+	table    *RateTable
+	loadedAt time.Time
+}
+
+// NewConverter creates a Converter of the rate table of the source. Costs are
+// assumed to be in the base currency, which is the base currency of the
+// table if empty.
+func NewConverter(source Source, base string, refresh time.Duration) *Converter {
+	return &Converter{
+		source:  source,
+		base:    normalize(base),
+		refresh: refresh,
+	}
+}
+
+// RateTable returns the current RateTable of the source. If the source has no
+// table, the table is empty. If it fails to reload, the previous table is
+// kept.
+func (c *Converter) RateTable() (*RateTable, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.table != nil && time.Since(c.loadedAt) < c.refresh {
+		return c.table, nil
+	}
+
+	table, err := c.load()
+	if err != nil {
+		if c.table == nil {
+			return nil, err
+		}
+		log.Warnf("Converter: failed to reload rate table, using previous: %s", err)
+		table = c.table
+	}
+
+	c.table = table
+	c.loadedAt = time.Now()
+	return c.table, nil
+}
+
+func (c *Converter) load() (*RateTable, error) {
+	data, err := c.source()
+	if errors.Is(err, os.ErrNotExist) {
+		return NewRateTable(c.base), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate table: %w", err)
+	}
+	return ParseRateTable(data)
+}
+
+// NewConversion creates a Conversion of costs to the given currency, which
+// must be in the current RateTable
+func (c *Converter) NewConversion(to string) (*Conversion, error) {
+	rt, err := c.RateTable()
+	if err != nil {
+		return nil, err
+	}
+
+	base := c.base
+	if base == "" {
+		base = rt.Base
+	}
+
+	to = normalize(to)
+	if to != base && !rt.Has(to) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+
+	return &Conversion{
+		To:      to,
+		base:    base,
+		table:   rt,
+		applied: map[appliedRateKey]*AppliedRate{},
+	}, nil
+}
+
+// AppliedRate is a rate by which costs of a currency on a date were multiplied
+type AppliedRate struct {
+	From string  `json:"from"`
+	Date string  `json:"date"`
+	Rate float64 `json:"rate"`
+}
+
+type appliedRateKey struct {
+	from string
+	date string
+}
+
+// Conversion converts costs to a currency, recording the rates it applies.
+// A nil Conversion leaves costs unchanged.
+type Conversion struct {
+	To      string
+	lock    sync.Mutex
+	base    string
+	table   *RateTable
+	applied map[appliedRateKey]*AppliedRate
+}
+
+// Rate returns the rate by which costs of the given currency at the given
+// time are multiplied. An empty currency is the base currency.
+func (c *Conversion) Rate(from string, at time.Time) (float64, error) {
+	if c == nil {
+		return 1, nil
+	}
+
+	from = normalize(from)
+	if from == "" {
+		from = c.base
+	}
+	if from == c.To {
+		return 1, nil
+	}
+
+	rate, err := c.table.Rate(from, c.To, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert %s to %s: %w", from, c.To, err)
+	}
+
+	key := appliedRateKey{from: from, date: at.UTC().Format(rateDateLayout)}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.applied[key]; !ok {
+		c.applied[key] = &AppliedRate{From: key.from, Date: key.date, Rate: rate}
+	}
+	return rate, nil
+}
+
+// CloudCostCurrency returns the currency of the billing data of the cloud cost
+// integration with the given key
+func (c *Conversion) CloudCostCurrency(integration string) string {
+	if c == nil {
+		return ""
+	}
+	if currency, ok := c.table.CloudCostCurrencies[integration]; ok {
+		return currency
+	}
+	return c.base
+}
+
+// Applied returns the rates applied by the Conversion, by currency and date
+func (c *Conversion) Applied() []*AppliedRate {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	applied := make([]*AppliedRate, 0, len(c.applied))
+	for _, ar := range c.applied {
+		applied = append(applied, ar)
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		if applied[i].From != applied[j].From {
+			return applied[i].From < applied[j].From
+		}
+		return applied[i].Date < applied[j].Date
+	})
+	return applied
+}
+
+// MarshalJSON reports the currency converted to and the rates applied
+func (c *Conversion) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(struct {
+		To    string         `json:"to"`
+		Rates []*AppliedRate `json:"rates"`
+	}{
+		To:    c.To,
+		Rates: c.Applied(),
+	})
+}
@@ -0,0 +1,106 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+)
+
+func TestConverter_NewConversion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "currency-rates.json")
+	converter := NewConverter(FileSource(path), "", 0)
+
+	// Without a rate table, costs are only in the default currency
+	if _, err := converter.NewConversion("usd"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := converter.NewConversion("EUR"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected unknown currency, got %v", err)
+	}
+
+	err := os.WriteFile(path, []byte(testRateTable), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The table is reloaded as the refresh interval has passed
+	conversion, err := converter.NewConversion("eur")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if conversion.To != "EUR" {
+		t.Errorf("expected conversion to EUR, got %s", conversion.To)
+	}
+
+	// A table which fails to reload is kept
+	err = os.WriteFile(path, []byte("{"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := converter.NewConversion("GBP"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestConversion(t *testing.T) {
+	rt, err := ParseRateTable([]byte(testRateTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversion := &Conversion{To: "EUR", base: "USD", table: rt, applied: map[appliedRateKey]*AppliedRate{}}
+
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	newAllocSet := func(start time.Time) *opencost.AllocationSet {
+		alloc := opencost.NewMockUnitAllocation("a", start, day, nil)
+		return opencost.NewAllocationSet(start, start.Add(day), alloc)
+	}
+	asr := opencost.NewAllocationSetRange(newAllocSet(jan), newAllocSet(feb))
+	totals := []float64{asr.Allocations[0].Allocations["a"].TotalCost(), asr.Allocations[1].Allocations["a"].TotalCost()}
+
+	err = conversion.ConvertAllocationSetRange(asr, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, rate := range []float64{0.80, 0.90} {
+		if cost := asr.Allocations[i].Allocations["a"].TotalCost(); math.Abs(cost-totals[i]*rate) > 1e-9 {
+			t.Errorf("set %d: expected total cost %f, got %f", i, totals[i]*rate, cost)
+		}
+	}
+
+	// Cloud costs are converted from the currency of their integration
+	ccs := opencost.NewCloudCostSet(jan, jan.Add(day), opencost.NewCloudCost(jan, jan.Add(day), &opencost.CloudCostProperties{ProviderID: "id1"}, 1, 10, 10, 10, 10, 10))
+	ccs.Integration = "azure-billing"
+	err = conversion.ConvertCloudCostSet(ccs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, cc := range ccs.CloudCosts {
+		if cc.NetCost.Cost != 10 {
+			t.Errorf("expected cloud cost already in EUR to be unchanged, got %f", cc.NetCost.Cost)
+		}
+	}
+
+	data, err := json.Marshal(conversion)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `{"to":"EUR","rates":[{"from":"USD","date":"2024-01-15","rate":0.8},{"from":"USD","date":"2024-02-15","rate":0.9}]}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// A nil conversion leaves costs unchanged
+	var none *Conversion
+	if rate, err := none.Rate("EUR", jan); err != nil || rate != 1 {
+		t.Errorf("expected nil conversion rate of 1, got %f, %v", rate, err)
+	}
+}
@@ -0,0 +1,164 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency costs are assumed to be in when none is
+// configured
+const DefaultCurrency = "USD"
+
+const rateDateLayout = "2006-01-02"
+
+// Rate is the number of units of a currency equal to one unit of the base
+// currency of a RateTable, effective from its date until the date of the
+// currency's next rate
+type Rate struct {
+	Currency string  `json:"currency"`
+	Date     string  `json:"date"`
+	Rate     float64 `json:"rate"`
+	date     time.Time
+}
+
+// RateTable holds dated exchange rates against a base currency, from which
+// the rate between any two of its currencies is derived. CloudCostCurrencies
+// maps the key of a cloud cost integration to the currency of its billing
+// data, which otherwise is assumed to be the base currency.
+type RateTable struct {
+	Base                string            `json:"base"`
+	Rates               []*Rate           `json:"rates"`
+	CloudCostCurrencies map[string]string `json:"cloudCostCurrencies,omitempty"`
+	byCurrency          map[string][]*Rate
+}
+
+// NewRateTable creates an empty RateTable of the given base currency
+func NewRateTable(base string) *RateTable {
+	rt := &RateTable{Base: base}
+	_ = rt.init()
+	return rt
+}
+
+// ParseRateTable parses and validates a RateTable from JSON
+func ParseRateTable(data []byte) (*RateTable, error) {
+	rt := &RateTable{}
+	err := json.Unmarshal(data, rt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rate table: %w", err)
+	}
+
+	err = rt.init()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate table: %w", err)
+	}
+	return rt, nil
+}
+
+// init normalizes the currency codes of the table, parses the dates of its
+// rates and indexes them by currency in date order
+func (rt *RateTable) init() error {
+	rt.Base = normalize(rt.Base)
+	if rt.Base == "" {
+		rt.Base = DefaultCurrency
+	}
+
+	rt.byCurrency = map[string][]*Rate{}
+	for _, r := range rt.Rates {
+		r.Currency = normalize(r.Currency)
+		if r.Currency == "" {
+			return fmt.Errorf("rate on %s has no currency", r.Date)
+		}
+		if r.Currency == rt.Base {
+			return fmt.Errorf("rate of base currency %s", rt.Base)
+		}
+		if r.Rate <= 0 {
+			return fmt.Errorf("rate of %s on %s must be positive: %f", r.Currency, r.Date, r.Rate)
+		}
+
+		date, err := time.Parse(rateDateLayout, r.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date of %s rate: %w", r.Currency, err)
+		}
+		r.date = date
+
+		rt.byCurrency[r.Currency] = append(rt.byCurrency[r.Currency], r)
+	}
+
+	for currency, rates := range rt.byCurrency {
+		sort.SliceStable(rates, func(i, j int) bool {
+			return rates[i].date.Before(rates[j].date)
+		})
+		for i := 1; i < len(rates); i++ {
+			if rates[i].date.Equal(rates[i-1].date) {
+				return fmt.Errorf("duplicate rate of %s on %s", currency, rates[i].Date)
+			}
+		}
+	}
+
+	for key, currency := range rt.CloudCostCurrencies {
+		rt.CloudCostCurrencies[key] = normalize(currency)
+		if !rt.Has(rt.CloudCostCurrencies[key]) {
+			return fmt.Errorf("cloud cost integration %s has unknown currency: %s", key, currency)
+		}
+	}
+
+	return nil
+}
+
+// Has returns true if the currency is the base currency or has rates
+func (rt *RateTable) Has(currency string) bool {
+	currency = normalize(currency)
+	if currency == rt.Base {
+		return true
+	}
+	_, ok := rt.byCurrency[currency]
+	return ok
+}
+
+// Rate returns the number of units of the 'to' currency equal to one unit of
+// the 'from' currency at the given time
+func (rt *RateTable) Rate(from, to string, at time.Time) (float64, error) {
+	from, to = normalize(from), normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, err := rt.baseRate(from, at)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := rt.baseRate(to, at)
+	if err != nil {
+		return 0, err
+	}
+	return toRate / fromRate, nil
+}
+
+// baseRate returns the rate of the currency against the base currency at the
+// given time, which is its latest rate effective by then, or its earliest
+// rate if none is
+func (rt *RateTable) baseRate(currency string, at time.Time) (float64, error) {
+	if currency == rt.Base {
+		return 1, nil
+	}
+
+	rates, ok := rt.byCurrency[currency]
+	if !ok {
+		return 0, fmt.Errorf("no rates of %s", currency)
+	}
+
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].date.After(at)
+	})
+	if i == 0 {
+		return rates[0].Rate, nil
+	}
+	return rates[i-1].Rate, nil
+}
+
+func normalize(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const testRateTable = `{
+	"base": "usd",
+	"rates": [
+		{"currency": "EUR", "date": "2024-02-01", "rate": 0.90},
+		{"currency": "EUR", "date": "2024-01-01", "rate": 0.80},
+		{"currency": "gbp", "date": "2024-01-01", "rate": 0.75}
+	],
+	"cloudCostCurrencies": {"azure-billing": "eur"}
+}`
+
+func TestParseRateTable(t *testing.T) {
+	rt, err := ParseRateTable([]byte(testRateTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rt.Base != "USD" {
+		t.Errorf("expected base USD, got %s", rt.Base)
+	}
+	for _, currency := range []string{"USD", "eur", "GBP"} {
+		if !rt.Has(currency) {
+			t.Errorf("expected table to have %s", currency)
+		}
+	}
+	if rt.Has("JPY") {
+		t.Errorf("expected table not to have JPY")
+	}
+	if rt.CloudCostCurrencies["azure-billing"] != "EUR" {
+		t.Errorf("expected azure-billing in EUR, got %s", rt.CloudCostCurrencies["azure-billing"])
+	}
+
+	for name, data := range map[string]string{
+		"missing currency":   `{"rates": [{"date": "2024-01-01", "rate": 1}]}`,
+		"base currency rate": `{"base": "EUR", "rates": [{"currency": "EUR", "date": "2024-01-01", "rate": 1}]}`,
+		"zero rate":          `{"rates": [{"currency": "EUR", "date": "2024-01-01", "rate": 0}]}`,
+		"invalid date":       `{"rates": [{"currency": "EUR", "date": "01/01/2024", "rate": 1}]}`,
+		"duplicate date":     `{"rates": [{"currency": "EUR", "date": "2024-01-01", "rate": 1}, {"currency": "EUR", "date": "2024-01-01", "rate": 2}]}`,
+		"unknown cloud cost": `{"cloudCostCurrencies": {"key": "EUR"}}`,
+	} {
+		if _, err := ParseRateTable([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRateTable_Rate(t *testing.T) {
+	rt, err := ParseRateTable([]byte(testRateTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	testCases := map[string]struct {
+		from, to string
+		at       time.Time
+		expected float64
+	}{
+		"same currency":          {"EUR", "eur", date(1, 15), 1},
+		"before the first rate":  {"USD", "EUR", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 0.80},
+		"first rate":             {"USD", "EUR", date(1, 15), 0.80},
+		"second rate":            {"USD", "EUR", date(2, 15), 0.90},
+		"from the start of date": {"USD", "EUR", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 0.90},
+		"to base":                {"EUR", "USD", date(2, 15), 1 / 0.90},
+		"cross rate":             {"EUR", "GBP", date(2, 15), 0.75 / 0.90},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rate, err := rt.Rate(tc.from, tc.to, tc.at)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if math.Abs(rate-tc.expected) > 1e-9 {
+				t.Errorf("expected rate %f, got %f", tc.expected, rate)
+			}
+		})
+	}
+
+	if _, err := rt.Rate("USD", "JPY", date(1, 15)); err == nil {
+		t.Errorf("expected error converting to a currency without rates")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opencost/opencost/core/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/currency"
 	"go.opentelemetry.io/otel"
 )
 
@@ -13,6 +14,8 @@ const tracerName = "github.com/opencost/opencost/pkg/customcost"
 
 type QueryService struct {
 	Querier Querier
+	// Converter converts costs to the currency requested, if enabled
+	Converter *currency.Converter
 }
 
 func NewQueryService(querier Querier) *QueryService {
@@ -45,6 +48,12 @@ func (qs *QueryService) GetCustomCostTotalHandler() func(w http.ResponseWriter,
 			return
 		}
 
+		request.Conversion, err = qs.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		resp, err := qs.Querier.QueryTotal(ctx, *request)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
@@ -53,7 +62,11 @@ func (qs *QueryService) GetCustomCostTotalHandler() func(w http.ResponseWriter,
 
 		_, spanResp := tracer.Start(ctx, "write response")
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		if request.Conversion != nil {
+			protocol.WriteDataWithCurrency(w, resp, request.Conversion)
+		} else {
+			protocol.WriteData(w, resp)
+		}
 		spanResp.End()
 	}
 }
@@ -82,6 +95,12 @@ func (qs *QueryService) GetCustomCostTimeseriesHandler() func(w http.ResponseWri
 			return
 		}
 
+		request.Conversion, err = qs.parseConversion(qp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		resp, err := qs.Querier.QueryTimeseries(ctx, *request)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Internal server error: %s", err), http.StatusInternalServerError)
@@ -90,7 +109,28 @@ func (qs *QueryService) GetCustomCostTimeseriesHandler() func(w http.ResponseWri
 
 		_, spanResp := tracer.Start(ctx, "write response")
 		w.Header().Set("Content-Type", "application/json")
-		protocol.WriteData(w, resp)
+		if request.Conversion != nil {
+			protocol.WriteDataWithCurrency(w, resp, request.Conversion)
+		} else {
+			protocol.WriteData(w, resp)
+		}
 		spanResp.End()
 	}
 }
+
+// parseConversion parses the optional 'currency' parameter, returning the
+// Conversion of costs to it, or nil if costs are not converted
+func (qs *QueryService) parseConversion(qp httputil.QueryParams) (*currency.Conversion, error) {
+	if !qp.Has("currency") {
+		return nil, nil
+	}
+	if qs.Converter == nil {
+		return nil, fmt.Errorf("invalid 'currency' parameter: currency conversion is not enabled")
+	}
+
+	conversion, err := qs.Converter.NewConversion(qp.Get("currency", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'currency' parameter: %w", err)
+	}
+	return conversion, nil
+}
@@ -66,6 +66,10 @@ func (rq *RepositoryQuerier) QueryTotal(ctx context.Context, request CostTotalRe
 			customCosts := ParseCustomCostResponse(ccResponse)
 			for _, customCost := range customCosts {
 				if matcher.Matches(customCost) {
+					err = customCost.Convert(request.Conversion, queryStart)
+					if err != nil {
+						return nil, fmt.Errorf("QueryTotal: failed to convert currency: %w", err)
+					}
 					ccs.Add(customCost)
 				}
 			}
@@ -110,6 +114,7 @@ func (rq *RepositoryQuerier) QueryTimeseries(ctx context.Context, request CostTi
 				AggregateBy: request.AggregateBy,
 				Filter:      request.Filter,
 				Accumulate:  accumulate,
+				Conversion:  request.Conversion,
 			})
 		}(i, w, totals)
 	}
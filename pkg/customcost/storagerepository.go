@@ -0,0 +1,150 @@
+package customcost
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/model/pb"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+const storageRepositoryDir = "customcost"
+
+// StorageRepository is an implementation of Repository that persists protobuf encoded CustomCostResponses to a
+// storage.Storage, with one directory per domain and one file per window start, so that plugin data survives restarts.
+type StorageRepository struct {
+	store storage.Storage
+	dir   string
+}
+
+// NewStorageRepository creates a StorageRepository for responses of the given resolution, which writes to the given
+// storage under customcost/<resolution>
+func NewStorageRepository(store storage.Storage, resolution time.Duration) *StorageRepository {
+	return &StorageRepository{
+		store: store,
+		dir:   path.Join(storageRepositoryDir, timeutil.FormatStoreResolution(resolution)),
+	}
+}
+
+func (s *StorageRepository) Has(startTime time.Time, domain string) (bool, error) {
+	return s.store.Exists(s.filePath(startTime, domain))
+}
+
+func (s *StorageRepository) Get(startTime time.Time, domain string) (*pb.CustomCostResponse, error) {
+	b, err := s.store.Read(s.filePath(startTime, domain))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return &pb.CustomCostResponse{}, nil
+		}
+		return nil, fmt.Errorf("error reading data: %w", err)
+	}
+
+	ccr := &pb.CustomCostResponse{}
+	err = proto.Unmarshal(b, ccr)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+	return ccr, nil
+}
+
+// Keys returns the domains which have a directory in storage
+func (s *StorageRepository) Keys() ([]string, error) {
+	dirs, err := s.store.ListDirectories(s.dir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("StorageRepository: Keys: failed to list %s: %w", s.dir, err)
+	}
+
+	keys := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		name := path.Base(strings.TrimSuffix(dir.Name, "/"))
+		key, err := storage.DecodeDirName(name)
+		if err != nil {
+			log.Debugf("StorageRepository: Keys: skipping unrecognized directory %s", dir.Name)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s *StorageRepository) Put(ccr *pb.CustomCostResponse) error {
+	if ccr == nil {
+		return fmt.Errorf("StorageRepository: Put: cannot save nil")
+	}
+
+	if ccr.Start == nil || ccr.End == nil {
+		return fmt.Errorf("StorageRepository: Put: custom cost response has invalid window")
+	}
+
+	if ccr.GetDomain() == "" {
+		return fmt.Errorf("StorageRepository: Put: custom cost response does not have a domain value")
+	}
+
+	b, err := proto.Marshal(ccr)
+	if err != nil {
+		return fmt.Errorf("StorageRepository: Put: custom cost could not be marshalled")
+	}
+
+	err = s.store.Write(s.filePath(ccr.Start.AsTime(), ccr.GetDomain()), b)
+	if err != nil {
+		return fmt.Errorf("StorageRepository: Put: failed to write custom cost response: %w", err)
+	}
+
+	return nil
+}
+
+// Expire deletes all responses with a start time before the given limit
+func (s *StorageRepository) Expire(limit time.Time) error {
+	domains, err := s.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range domains {
+		dir := s.domainDir(domain)
+		files, err := s.store.List(dir)
+		if err != nil {
+			if storage.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("StorageRepository: Expire: failed to list %s: %w", dir, err)
+		}
+
+		for _, file := range files {
+			start, err := strconv.ParseInt(file.Name, 10, 64)
+			if err != nil {
+				log.Debugf("StorageRepository: Expire: skipping unrecognized file %s", file.Name)
+				continue
+			}
+
+			if time.Unix(start, 0).Before(limit) {
+				err = s.store.Remove(path.Join(dir, file.Name))
+				if err != nil && !storage.IsNotExist(err) {
+					return fmt.Errorf("StorageRepository: Expire: failed to remove %s: %w", file.Name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// domainDir returns the directory holding the responses for the given domain, which is encoded to make it safe to
+// use as a directory name
+func (s *StorageRepository) domainDir(domain string) string {
+	return path.Join(s.dir, storage.EncodeDirName(domain))
+}
+
+func (s *StorageRepository) filePath(startTime time.Time, domain string) string {
+	return path.Join(s.domainDir(domain), strconv.FormatInt(startTime.UTC().Unix(), 10))
+}
@@ -0,0 +1,151 @@
+package customcost
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/model/pb"
+	"github.com/opencost/opencost/pkg/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newMockCustomCostResponse(start time.Time, resolution time.Duration, domain string) *pb.CustomCostResponse {
+	return &pb.CustomCostResponse{
+		Metadata:   map[string]string{"api_client_version": "v2"},
+		CostSource: "observability",
+		Domain:     domain,
+		Version:    "v1",
+		Currency:   "USD",
+		Start:      timestamppb.New(start),
+		End:        timestamppb.New(start.Add(resolution)),
+		Costs: []*pb.CustomCost{
+			{
+				ResourceName:   "hosts",
+				BilledCost:     12.5,
+				ListCost:       15,
+				ListUnitPrice:  1.5,
+				UsageQuantity:  10,
+				UsageUnit:      "hosts",
+				ResourceType:   "infra_hosts",
+				ProviderId:     "42",
+				ChargeCategory: "usage",
+			},
+		},
+	}
+}
+
+func TestStorageRepository_PutGet(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := NewStorageRepository(storage.NewFileStorage(t.TempDir()), time.Hour)
+
+	tests := map[string]struct {
+		input   *pb.CustomCostResponse
+		wantErr bool
+	}{
+		"nil response": {
+			input:   nil,
+			wantErr: true,
+		},
+		"invalid window": {
+			input:   &pb.CustomCostResponse{Domain: "datadog"},
+			wantErr: true,
+		},
+		"missing domain": {
+			input:   newMockCustomCostResponse(start, time.Hour, ""),
+			wantErr: true,
+		},
+		"valid": {
+			input:   newMockCustomCostResponse(start, time.Hour, "datadog"),
+			wantErr: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := repo.Put(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Put() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	has, err := repo.Has(start, "datadog")
+	if err != nil || !has {
+		t.Fatalf("Has() got = %t, %v, want true", has, err)
+	}
+
+	got, err := repo.Get(start, "datadog")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %s", err)
+	}
+	if got.GetDomain() != "datadog" || len(got.GetCosts()) != 1 || got.GetCosts()[0].GetBilledCost() != 12.5 {
+		t.Errorf("Get() got = %v", got)
+	}
+	if !got.GetStart().AsTime().Equal(start) || !got.GetEnd().AsTime().Equal(start.Add(time.Hour)) {
+		t.Errorf("Get() got window %s - %s", got.GetStart().AsTime(), got.GetEnd().AsTime())
+	}
+
+	// Missing data returns an empty response, as with the MemoryRepository
+	got, err = repo.Get(start, "snowflake")
+	if err != nil || got == nil || got.GetDomain() != "" {
+		t.Errorf("Get() got = %v, %v, want empty response", got, err)
+	}
+}
+
+func TestStorageRepository_KeysExpire(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := storage.NewFileStorage(t.TempDir())
+	hourly := NewStorageRepository(store, time.Hour)
+	daily := NewStorageRepository(store, 24*time.Hour)
+
+	keys, err := hourly.Keys()
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("Keys() got = %v, %v, want empty", keys, err)
+	}
+
+	for _, domain := range []string{"datadog", "snowflake"} {
+		for i := 0; i < 3; i++ {
+			err = hourly.Put(newMockCustomCostResponse(start.Add(time.Duration(i)*time.Hour), time.Hour, domain))
+			if err != nil {
+				t.Fatalf("Put() unexpected error: %s", err)
+			}
+		}
+	}
+	err = daily.Put(newMockCustomCostResponse(start, 24*time.Hour, "datadog"))
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %s", err)
+	}
+
+	keys, err = hourly.Keys()
+	if err != nil {
+		t.Fatalf("Keys() unexpected error: %s", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "datadog" || keys[1] != "snowflake" {
+		t.Errorf("Keys() got = %v", keys)
+	}
+
+	err = hourly.Expire(start.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Expire() unexpected error: %s", err)
+	}
+
+	for _, domain := range keys {
+		for i, want := range []bool{false, false, true} {
+			s := start.Add(time.Duration(i) * time.Hour)
+			has, err := hourly.Has(s, domain)
+			if err != nil {
+				t.Fatalf("Has() unexpected error: %s", err)
+			}
+			if has != want {
+				t.Errorf("Has(%s, %s) got = %t, want %t", s, domain, has, want)
+			}
+		}
+	}
+
+	// Resolutions are stored independently
+	has, err := daily.Has(start, "datadog")
+	if err != nil || !has {
+		t.Errorf("expected daily data to be unaffected by hourly expiry, got %t, %v", has, err)
+	}
+}
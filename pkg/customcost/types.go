@@ -8,6 +8,7 @@ import (
 	"github.com/opencost/opencost/core/pkg/filter"
 	"github.com/opencost/opencost/core/pkg/model/pb"
 	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/currency"
 )
 
 type CostTotalRequest struct {
@@ -16,6 +17,8 @@ type CostTotalRequest struct {
 	AggregateBy []CustomCostProperty
 	Accumulate  opencost.AccumulateOption
 	Filter      filter.Filter
+	// Conversion, if set, converts costs from the currency of each response
+	Conversion *currency.Conversion
 }
 
 type CostTimeseriesRequest struct {
@@ -24,6 +27,8 @@ type CostTimeseriesRequest struct {
 	AggregateBy []CustomCostProperty
 	Accumulate  opencost.AccumulateOption
 	Filter      filter.Filter
+	// Conversion, if set, converts costs from the currency of each response
+	Conversion *currency.Conversion
 }
 
 type CostResponse struct {
@@ -49,6 +54,7 @@ type CustomCost struct {
 	UsageUnit      string  `json:"usage_unit"`
 	Domain         string  `json:"domain"`
 	CostSource     string  `json:"cost_source"`
+	Currency       string  `json:"currency"`
 	Aggregate      string  `json:"aggregate"`
 }
 
@@ -93,6 +99,7 @@ func ParseCustomCostResponse(ccResponse *pb.CustomCostResponse) []*CustomCost {
 			UsageUnit:      cost.GetUsageUnit(),
 			Domain:         ccResponse.GetDomain(),
 			CostSource:     ccResponse.GetCostSource(),
+			Currency:       ccResponse.GetCurrency(),
 		}
 	}
 
@@ -151,12 +158,35 @@ func (cc *CustomCost) Add(other *CustomCost) {
 		cc.CostSource = ""
 	}
 
+	if cc.Currency != other.Currency {
+		cc.Currency = ""
+	}
+
 	if cc.Aggregate != other.Aggregate {
 		cc.Aggregate = ""
 	}
 
 }
 
+// Convert converts the costs of the CustomCost from its currency, or the base
+// currency if it has none, at the rate of the given time
+func (cc *CustomCost) Convert(conversion *currency.Conversion, at time.Time) error {
+	if conversion == nil {
+		return nil
+	}
+
+	rate, err := conversion.Rate(cc.Currency, at)
+	if err != nil {
+		return err
+	}
+
+	cc.BilledCost *= float32(rate)
+	cc.ListCost *= float32(rate)
+	cc.ListUnitPrice *= float32(rate)
+	cc.Currency = conversion.To
+	return nil
+}
+
 type CustomCostSet struct {
 	CustomCosts []*CustomCost
 	Window      opencost.Window
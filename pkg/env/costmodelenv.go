@@ -2,6 +2,7 @@ package env
 
 import (
 	"fmt"
+	"path"
 	"time"
 
 	"github.com/opencost/opencost/core/pkg/env"
@@ -105,6 +106,49 @@ const (
 
 	ETLReadOnlyMode = "ETL_READ_ONLY"
 
+	QueryJobWorkersEnvVar           = "QUERY_JOB_WORKERS"
+	QueryJobResultTTLMinutesEnvVar  = "QUERY_JOB_RESULT_TTL_MINUTES"
+	QueryJobMaxPendingEnvVar        = "QUERY_JOB_MAX_PENDING"
+	QueryJobPendingTTLMinutesEnvVar = "QUERY_JOB_PENDING_TTL_MINUTES"
+
+	AnomalyDetectionEnabledEnvVar         = "ANOMALY_DETECTION_ENABLED"
+	AnomalyDetectionIntervalMinutesEnvVar = "ANOMALY_DETECTION_INTERVAL_MINUTES"
+	AnomalyDetectionDaysEnvVar            = "ANOMALY_DETECTION_DAYS"
+	AnomalyBaselineDaysEnvVar             = "ANOMALY_BASELINE_DAYS"
+	AnomalyThresholdEnvVar                = "ANOMALY_THRESHOLD"
+	AnomalyMinCostDeltaEnvVar             = "ANOMALY_MIN_COST_DELTA"
+	AnomalyAllocationAggregationsEnvVar   = "ANOMALY_ALLOCATION_AGGREGATIONS"
+	AnomalyCloudCostAggregationsEnvVar    = "ANOMALY_CLOUD_COST_AGGREGATIONS"
+
+	BudgetsEnabledEnvVar                  = "BUDGETS_ENABLED"
+	BudgetEvaluationIntervalMinutesEnvVar = "BUDGET_EVALUATION_INTERVAL_MINUTES"
+	BudgetWebhookTimeoutSecondsEnvVar     = "BUDGET_WEBHOOK_TIMEOUT_SECONDS"
+
+	AllocationReconciliationEnabledEnvVar = "ALLOCATION_RECONCILIATION_ENABLED"
+	ReconciliationCostMetricEnvVar        = "RECONCILIATION_COST_METRIC"
+
+	CloudCostKubernetesAttributionEnabledEnvVar         = "CLOUD_COST_KUBERNETES_ATTRIBUTION_ENABLED"
+	CloudCostKubernetesAttributionIntervalMinutesEnvVar = "CLOUD_COST_KUBERNETES_ATTRIBUTION_INTERVAL_MINUTES"
+	CloudCostKubernetesAttributionDaysEnvVar            = "CLOUD_COST_KUBERNETES_ATTRIBUTION_DAYS"
+
+	UnitMetricsConfigPathEnvVar = "UNIT_METRICS_CONFIG_PATH"
+
+	CurrencyConversionEnabledEnvVar   = "CURRENCY_CONVERSION_ENABLED"
+	CurrencyRatesPathEnvVar           = "CURRENCY_RATES_PATH"
+	CurrencyRatesStoragePathEnvVar    = "CURRENCY_RATES_STORAGE_PATH"
+	CurrencyRatesRefreshMinutesEnvVar = "CURRENCY_RATES_REFRESH_MINUTES"
+
+	FOCUSExportEnabledEnvVar         = "FOCUS_EXPORT_ENABLED"
+	FOCUSExportFormatEnvVar          = "FOCUS_EXPORT_FORMAT"
+	FOCUSExportPathEnvVar            = "FOCUS_EXPORT_PATH"
+	FOCUSExportIntervalMinutesEnvVar = "FOCUS_EXPORT_INTERVAL_MINUTES"
+	FOCUSExportDaysEnvVar            = "FOCUS_EXPORT_DAYS"
+	FOCUSBillingCurrencyEnvVar       = "FOCUS_BILLING_CURRENCY"
+
+	ETLStoreEnabledEnvVar  = "ETL_STORE_ENABLED"
+	ETLBucketConfigEnvVar  = "ETL_BUCKET_CONFIG"
+	ETLFileStorePathEnvVar = "ETL_FILE_STORE_PATH"
+
 	AllocationNodeLabelsEnabled     = "ALLOCATION_NODE_LABELS_ENABLED"
 	AllocationNodeLabelsIncludeList = "ALLOCATION_NODE_LABELS_INCLUDE_LIST"
 
@@ -147,6 +191,199 @@ func IsETLReadOnlyMode() bool {
 	return env.GetBool(ETLReadOnlyMode, false)
 }
 
+// GetQueryJobWorkers returns the number of asynchronous query jobs which may run concurrently
+func GetQueryJobWorkers() int {
+	return env.GetInt(QueryJobWorkersEnvVar, 2)
+}
+
+// GetQueryJobResultTTL returns how long the results of finished asynchronous query jobs are retained
+func GetQueryJobResultTTL() time.Duration {
+	return time.Duration(env.GetInt64(QueryJobResultTTLMinutesEnvVar, 60)) * time.Minute
+}
+
+// GetQueryJobMaxPending returns the number of asynchronous query jobs which may wait for a worker
+func GetQueryJobMaxPending() int {
+	return env.GetInt(QueryJobMaxPendingEnvVar, 20)
+}
+
+// GetQueryJobPendingTTL returns how long asynchronous query jobs may wait for a worker before they are failed
+func GetQueryJobPendingTTL() time.Duration {
+	return time.Duration(env.GetInt64(QueryJobPendingTTLMinutesEnvVar, 60)) * time.Minute
+}
+
+// IsAnomalyDetectionEnabled returns true if daily allocation and cloud costs should be watched for anomalous spikes
+func IsAnomalyDetectionEnabled() bool {
+	return env.GetBool(AnomalyDetectionEnabledEnvVar, false)
+}
+
+// GetAnomalyDetectionInterval returns how often anomaly detection runs
+func GetAnomalyDetectionInterval() time.Duration {
+	return time.Duration(env.GetInt64(AnomalyDetectionIntervalMinutesEnvVar, 60)) * time.Minute
+}
+
+// GetAnomalyDetectionDays returns the number of most recent complete days which anomaly detection evaluates on each
+// run, so that anomalies in late arriving cost data are detected
+func GetAnomalyDetectionDays() int {
+	return env.GetInt(AnomalyDetectionDaysEnvVar, 3)
+}
+
+// GetAnomalyBaselineDays returns the number of days preceding a day which its costs are compared to
+func GetAnomalyBaselineDays() int {
+	return env.GetInt(AnomalyBaselineDaysEnvVar, 14)
+}
+
+// GetAnomalyThreshold returns the number of standard deviations above its baseline a cost must be to be anomalous
+func GetAnomalyThreshold() float64 {
+	return env.GetFloat64(AnomalyThresholdEnvVar, 3)
+}
+
+// GetAnomalyMinCostDelta returns the amount a cost must exceed its baseline by to be anomalous
+func GetAnomalyMinCostDelta() float64 {
+	return env.GetFloat64(AnomalyMinCostDeltaEnvVar, 1)
+}
+
+// GetAnomalyAllocationAggregations returns the aggregations of allocations which are watched for anomalies. Each
+// aggregation is a comma separated list of properties, and aggregations are separated by semicolons, e.g.
+// "namespace;controller;label:team".
+func GetAnomalyAllocationAggregations() []string {
+	aggregations := env.GetList(AnomalyAllocationAggregationsEnvVar, ";")
+	if len(aggregations) == 0 {
+		return []string{"namespace", "controller"}
+	}
+	return aggregations
+}
+
+// GetAnomalyCloudCostAggregations returns the aggregations of cloud costs which are watched for anomalies, in the
+// same form as GetAnomalyAllocationAggregations, e.g. "service;accountID"
+func GetAnomalyCloudCostAggregations() []string {
+	aggregations := env.GetList(AnomalyCloudCostAggregationsEnvVar, ";")
+	if len(aggregations) == 0 {
+		return []string{"service", "accountID"}
+	}
+	return aggregations
+}
+
+// IsBudgetsEnabled returns true if budgets may be managed and are evaluated against allocation and cloud costs
+func IsBudgetsEnabled() bool {
+	return env.GetBool(BudgetsEnabledEnvVar, false)
+}
+
+// GetBudgetEvaluationInterval returns how often budgets are evaluated
+func GetBudgetEvaluationInterval() time.Duration {
+	return time.Duration(env.GetInt64(BudgetEvaluationIntervalMinutesEnvVar, 60)) * time.Minute
+}
+
+// GetBudgetWebhookTimeout returns the timeout of requests sending budget notifications to webhooks
+func GetBudgetWebhookTimeout() time.Duration {
+	return time.Duration(env.GetInt64(BudgetWebhookTimeoutSecondsEnvVar, 10)) * time.Second
+}
+
+// IsAllocationReconciliationEnabled returns true if allocation costs should be adjusted to the costs billed by the
+// cloud provider, as ingested by cloud costs
+func IsAllocationReconciliationEnabled() bool {
+	return env.GetBool(AllocationReconciliationEnabledEnvVar, false)
+}
+
+// GetReconciliationCostMetric returns the cloud cost metric, e.g. "amortizedNetCost", which allocations are
+// reconciled against
+func GetReconciliationCostMetric() string {
+	return env.Get(ReconciliationCostMetricEnvVar, "amortizedNetCost")
+}
+
+// IsCloudCostKubernetesAttributionEnabled returns true if the cloud costs of resources used by Kubernetes should be
+// attributed to the allocations which use them
+func IsCloudCostKubernetesAttributionEnabled() bool {
+	return env.GetBool(CloudCostKubernetesAttributionEnabledEnvVar, false)
+}
+
+// GetCloudCostKubernetesAttributionInterval returns how often cloud costs are attributed to Kubernetes
+func GetCloudCostKubernetesAttributionInterval() time.Duration {
+	return time.Duration(env.GetInt64(CloudCostKubernetesAttributionIntervalMinutesEnvVar, 60)) * time.Minute
+}
+
+// GetCloudCostKubernetesAttributionDays returns the number of most recent days, including the current day, whose
+// cloud costs are attributed to Kubernetes on each run, so that re-ingested cloud costs are attributed again
+func GetCloudCostKubernetesAttributionDays() int {
+	return env.GetInt(CloudCostKubernetesAttributionDaysEnvVar, 3)
+}
+
+// GetUnitMetricsConfigPath returns the path of the JSON file which defines the business metrics that allocation
+// costs may be divided by to compute unit costs
+func GetUnitMetricsConfigPath() string {
+	return env.Get(UnitMetricsConfigPathEnvVar, path.Join(GetConfigPathWithDefault(DefaultConfigMountPath), "unit-metrics.json"))
+}
+
+// IsCurrencyConversionEnabled returns true if costs may be converted to other currencies using an exchange-rate table
+func IsCurrencyConversionEnabled() bool {
+	return env.GetBool(CurrencyConversionEnabledEnvVar, false)
+}
+
+// GetCurrencyRatesPath returns the path of the JSON file holding the exchange-rate table
+func GetCurrencyRatesPath() string {
+	return env.Get(CurrencyRatesPathEnvVar, path.Join(GetConfigPathWithDefault(DefaultConfigMountPath), "currency-rates.json"))
+}
+
+// GetCurrencyRatesStoragePath returns the path in the ETL storage of the exchange-rate table. If set, the table is read
+// from the storage rather than the file system.
+func GetCurrencyRatesStoragePath() string {
+	return env.Get(CurrencyRatesStoragePathEnvVar, "")
+}
+
+// GetCurrencyRatesRefreshInterval returns how often the exchange-rate table is reloaded
+func GetCurrencyRatesRefreshInterval() time.Duration {
+	return time.Duration(env.GetInt64(CurrencyRatesRefreshMinutesEnvVar, 60)) * time.Minute
+}
+
+// IsFOCUSExportEnabled returns true if cloud costs and allocations should be exported to FOCUS files in the ETL storage
+func IsFOCUSExportEnabled() bool {
+	return env.GetBool(FOCUSExportEnabledEnvVar, false)
+}
+
+// GetFOCUSExportFormat returns the file format of FOCUS exports, either "csv" or "parquet"
+func GetFOCUSExportFormat() string {
+	return env.Get(FOCUSExportFormatEnvVar, "csv")
+}
+
+// GetFOCUSExportPath returns the directory in the ETL storage to which FOCUS files are exported
+func GetFOCUSExportPath() string {
+	return env.Get(FOCUSExportPathEnvVar, "focus")
+}
+
+// GetFOCUSExportInterval returns how often FOCUS files are exported
+func GetFOCUSExportInterval() time.Duration {
+	return time.Duration(env.GetInt64(FOCUSExportIntervalMinutesEnvVar, 360)) * time.Minute
+}
+
+// GetFOCUSExportDays returns the number of most recent complete days which are exported on each run, so that costs
+// which change after the day has ended are exported again
+func GetFOCUSExportDays() int {
+	return env.GetInt(FOCUSExportDaysEnvVar, 3)
+}
+
+// GetFOCUSBillingCurrency returns the BillingCurrency of FOCUS exports of costs which are not converted to another
+// currency
+func GetFOCUSBillingCurrency() string {
+	return env.Get(FOCUSBillingCurrencyEnvVar, "USD")
+}
+
+// IsETLStoreEnabled returns true if the persistent ETL store should be used to
+// build and serve allocation and asset data, and to retain ingested cloud and custom costs.
+func IsETLStoreEnabled() bool {
+	return env.GetBool(ETLStoreEnabledEnvVar, false)
+}
+
+// GetETLBucketConfig returns a file location for a mounted bucket configuration which is used
+// to persist ETL data. If empty, ETL data is written to the local file system.
+func GetETLBucketConfig() string {
+	return env.Get(ETLBucketConfigEnvVar, "")
+}
+
+// GetETLFileStorePath returns the local directory used to persist ETL data when no bucket
+// configuration is provided.
+func GetETLFileStorePath() string {
+	return env.Get(ETLFileStorePathEnvVar, path.Join(GetCostAnalyzerVolumeMountPath(), "db"))
+}
+
 func GetExportCSVFile() string {
 	return env.Get(ExportCSVFile, "")
 }
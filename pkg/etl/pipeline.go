@@ -0,0 +1,264 @@
+package etl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/stringutil"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/errors"
+)
+
+// BuildFunc computes the set for the given window
+type BuildFunc[T Set] func(start, end time.Time) (T, error)
+
+// PipelineStatus includes diagnostic values for a given Pipeline
+type PipelineStatus struct {
+	Resolution string          `json:"resolution"`
+	Created    time.Time       `json:"created"`
+	LastRun    time.Time       `json:"lastRun"`
+	NextRun    time.Time       `json:"nextRun"`
+	Runs       int             `json:"runs"`
+	Coverage   opencost.Window `json:"coverage"`
+}
+
+// PipelineConfig is a configuration struct for a Pipeline
+type PipelineConfig struct {
+	// Resolution is the duration of each set built by the Pipeline
+	Resolution time.Duration
+	// Duration is how far into the past sets are built and retained
+	Duration time.Duration
+	// RefreshRate is the interval at which the Pipeline checks for new
+	// windows to build
+	RefreshRate time.Duration
+}
+
+// Pipeline periodically builds the complete windows of a single resolution
+// which are missing from its Repository, and expires those which fall outside
+// of the configured Duration.
+type Pipeline[T Set] struct {
+	name         string
+	config       PipelineConfig
+	repo         Repository[T]
+	build        BuildFunc[T]
+	runID        string
+	lastRun      time.Time
+	runs         int
+	creationTime time.Time
+	coverage     opencost.Window
+	statusLock   sync.Mutex
+	runLock      sync.Mutex
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// NewPipeline is an initializer for Pipeline
+func NewPipeline[T Set](name string, config PipelineConfig, repo Repository[T], build BuildFunc[T]) (*Pipeline[T], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("ETL: NewPipeline: repository cannot be nil")
+	}
+	if build == nil {
+		return nil, fmt.Errorf("ETL: NewPipeline: build function cannot be nil")
+	}
+	if config.Resolution <= 0 {
+		return nil, fmt.Errorf("ETL: NewPipeline: invalid resolution %s", config.Resolution)
+	}
+	if config.RefreshRate <= 0 {
+		config.RefreshRate = config.Resolution
+	}
+
+	now := time.Now().UTC()
+	end := opencost.RoundBack(now, config.Resolution)
+	return &Pipeline[T]{
+		name:         fmt.Sprintf("%s[%s]", name, timeutil.FormatStoreResolution(config.Resolution)),
+		config:       config,
+		repo:         repo,
+		build:        build,
+		creationTime: now,
+		coverage:     opencost.NewClosedWindow(end, end),
+	}, nil
+}
+
+// Resolution returns the resolution of the sets built by the Pipeline
+func (p *Pipeline[T]) Resolution() time.Duration {
+	return p.config.Resolution
+}
+
+// Limit returns the start of the oldest window retained by the Pipeline
+func (p *Pipeline[T]) Limit() time.Time {
+	return opencost.RoundBack(time.Now().UTC(), p.config.Resolution).Add(-p.config.Duration)
+}
+
+// Repository returns the Repository backing the Pipeline
+func (p *Pipeline[T]) Repository() Repository[T] {
+	return p.repo
+}
+
+func (p *Pipeline[T]) Start() {
+	p.runLock.Lock()
+	defer p.runLock.Unlock()
+
+	// If already running, log that and return.
+	if p.stop != nil {
+		log.Infof("ETL: %s: is already running", p.name)
+		return
+	}
+
+	p.runID = stringutil.RandSeq(5)
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go p.run(p.runID, p.stop, p.done)
+}
+
+// Stop interrupts the running Pipeline, waiting for it to exit. The stop
+// channel is closed, rather than sent to, so that Stop cannot block on a run
+// loop which has already exited.
+func (p *Pipeline[T]) Stop() {
+	p.runLock.Lock()
+	defer p.runLock.Unlock()
+
+	if p.stop == nil {
+		log.Infof("ETL: %s: is not running", p.name)
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+
+	// Declare that the pipeline is officially no longer running. This allows
+	// Start to be called again.
+	p.stop = nil
+	p.done = nil
+}
+
+// Status returns a PipelineStatus that describes the current state of the Pipeline
+func (p *Pipeline[T]) Status() PipelineStatus {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	return PipelineStatus{
+		Resolution: timeutil.FormatStoreResolution(p.config.Resolution),
+		Created:    p.creationTime,
+		LastRun:    p.lastRun,
+		NextRun:    p.lastRun.Add(p.config.RefreshRate).UTC(),
+		Runs:       p.runs,
+		Coverage:   p.coverage.Clone(),
+	}
+}
+
+func (p *Pipeline[T]) run(runID string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer errors.HandlePanic()
+
+	ticker := timeutil.NewJobTicker()
+	defer ticker.Close()
+	ticker.TickIn(0)
+
+	for {
+		// If an exit instruction is received, break the run loop
+		select {
+		case <-stop:
+			log.Debugf("ETL: %s: run[%s]: exiting", p.name, runID)
+			return
+		case <-ticker.Ch:
+			// Wait for next tick
+		}
+
+		if !p.runUntil(stop) {
+			log.Debugf("ETL: %s: run[%s]: exiting", p.name, runID)
+			return
+		}
+
+		ticker.TickIn(p.config.RefreshRate)
+	}
+}
+
+// Run builds every complete window within the configured Duration which is
+// missing from the Repository, working backward from the most recent, then
+// expires the windows which have fallen outside of the Duration.
+func (p *Pipeline[T]) Run() {
+	p.runUntil(nil)
+}
+
+// runUntil performs a Run, returning false if it was interrupted by the stop
+// channel being closed
+func (p *Pipeline[T]) runUntil(stop <-chan struct{}) bool {
+	runStart := time.Now()
+
+	limit := p.Limit()
+	e := opencost.RoundBack(time.Now().UTC(), p.config.Resolution)
+	s := e.Add(-p.config.Resolution)
+
+	built := 0
+	for !s.Before(limit) {
+		// If exit instruction is received, log and return
+		select {
+		case <-stop:
+			return false
+		default:
+		}
+
+		has, err := p.repo.Has(s)
+		if err != nil {
+			log.Errorf("ETL: %s: failed to check for window %s: %s", p.name, opencost.NewClosedWindow(s, e), err)
+		}
+
+		if !has {
+			if p.buildWindow(s, e) {
+				built++
+			}
+		} else {
+			p.expandCoverage(s, e)
+		}
+
+		e = s
+		s = s.Add(-p.config.Resolution)
+	}
+
+	err := p.repo.Expire(limit)
+	if err != nil {
+		log.Errorf("ETL: %s: failed to expire data: %s", p.name, err)
+	}
+
+	p.statusLock.Lock()
+	p.coverage = p.coverage.ContractStart(limit)
+	p.lastRun = time.Now().UTC()
+	p.runs++
+	p.statusLock.Unlock()
+
+	log.Infof("ETL: %s: built %d windows back to %s in %v", p.name, built, limit, time.Since(runStart))
+
+	return true
+}
+
+func (p *Pipeline[T]) buildWindow(start, end time.Time) bool {
+	window := opencost.NewClosedWindow(start, end)
+	log.Debugf("ETL: %s: building window %s", p.name, window)
+
+	set, err := p.build(start, end)
+	if err != nil {
+		log.Errorf("ETL: %s: build failed for window %s: %s", p.name, window, err)
+		return false
+	}
+
+	err = p.repo.Put(set)
+	if err != nil {
+		log.Errorf("ETL: %s: failed to save set with window %s: %s", p.name, window, err)
+		return false
+	}
+
+	p.expandCoverage(start, end)
+	return true
+}
+
+func (p *Pipeline[T]) expandCoverage(start, end time.Time) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	coverage := p.coverage.ExpandStart(start)
+	p.coverage = coverage.ExpandEnd(end)
+}
@@ -0,0 +1,47 @@
+package etl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func TestPipeline_StartStop(t *testing.T) {
+	built := make(chan struct{}, 16)
+	// failed builds are retried by each run
+	build := func(start, end time.Time) (*opencost.AllocationSet, error) {
+		built <- struct{}{}
+		return nil, fmt.Errorf("prometheus unavailable")
+	}
+
+	repo := NewStorageRepository(storage.NewFileStorage(t.TempDir()), "allocation", time.Hour, newAllocationSet)
+	p, err := NewPipeline("Allocation", PipelineConfig{Resolution: time.Hour, Duration: time.Hour, RefreshRate: time.Hour}, repo, build)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		// Stopping a pipeline which is not running has no effect
+		p.Stop()
+
+		for i := 0; i < 2; i++ {
+			p.Start()
+			p.Start()
+			<-built
+			p.Stop()
+			p.Stop()
+		}
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected pipeline to start and stop")
+	}
+}
@@ -0,0 +1,166 @@
+package etl
+
+import (
+	"encoding"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// Set is the constraint satisfied by the sets that can be stored by the ETL,
+// e.g. *opencost.AllocationSet and *opencost.AssetSet. Sets are serialized
+// using their bingen codecs.
+type Set interface {
+	comparable
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	GetWindow() opencost.Window
+}
+
+// Repository is an interface for storing and retrieving sets of a single
+// resolution, keyed by the start of their window.
+type Repository[T Set] interface {
+	Has(start time.Time) (bool, error)
+	Get(start time.Time) (T, error)
+	Put(set T) error
+	Expire(limit time.Time) error
+}
+
+// StorageRepository is an implementation of Repository that writes sets of a
+// single resolution to a storage.Storage, one file per window.
+type StorageRepository[T Set] struct {
+	store      storage.Storage
+	dir        string
+	resolution time.Duration
+	newSet     func() T
+}
+
+// NewStorageRepository creates a StorageRepository which stores sets of the
+// given resolution under <dir>/<resolution> in the given storage. newSet must
+// return an empty set into which stored data can be unmarshaled.
+func NewStorageRepository[T Set](store storage.Storage, dir string, resolution time.Duration, newSet func() T) *StorageRepository[T] {
+	return &StorageRepository[T]{
+		store:      store,
+		dir:        path.Join(dir, timeutil.FormatStoreResolution(resolution)),
+		resolution: resolution,
+		newSet:     newSet,
+	}
+}
+
+// Resolution returns the resolution of the sets stored by the repository
+func (sr *StorageRepository[T]) Resolution() time.Duration {
+	return sr.resolution
+}
+
+func (sr *StorageRepository[T]) Has(start time.Time) (bool, error) {
+	return sr.store.Exists(sr.filePath(start))
+}
+
+// Get returns the set starting at the given time, or the zero value of T if
+// no such set has been stored.
+func (sr *StorageRepository[T]) Get(start time.Time) (T, error) {
+	var zero T
+
+	b, err := sr.store.Read(sr.filePath(start))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return zero, nil
+		}
+		return zero, fmt.Errorf("StorageRepository: Get: failed to read %s: %w", sr.filePath(start), err)
+	}
+
+	set := sr.newSet()
+	err = set.UnmarshalBinary(b)
+	if err != nil {
+		return zero, fmt.Errorf("StorageRepository: Get: failed to unmarshal %s: %w", sr.filePath(start), err)
+	}
+
+	return set, nil
+}
+
+func (sr *StorageRepository[T]) Put(set T) error {
+	var zero T
+	if set == zero {
+		return fmt.Errorf("StorageRepository: Put: cannot save nil set")
+	}
+
+	window := set.GetWindow()
+	if window.IsOpen() {
+		return fmt.Errorf("StorageRepository: Put: set has invalid window %s", window.String())
+	}
+
+	if window.Duration() != sr.resolution {
+		return fmt.Errorf("StorageRepository: Put: window %s does not match resolution %s", window.String(), timeutil.FormatStoreResolution(sr.resolution))
+	}
+
+	b, err := set.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("StorageRepository: Put: failed to marshal set with window %s: %w", window.String(), err)
+	}
+
+	err = sr.store.Write(sr.filePath(*window.Start()), b)
+	if err != nil {
+		return fmt.Errorf("StorageRepository: Put: failed to write set with window %s: %w", window.String(), err)
+	}
+
+	return nil
+}
+
+// Expire removes all sets with a start time before the given limit
+func (sr *StorageRepository[T]) Expire(limit time.Time) error {
+	files, err := sr.store.List(sr.dir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("StorageRepository: Expire: failed to list %s: %w", sr.dir, err)
+	}
+
+	for _, file := range files {
+		start, ok := parseFileName(file.Name)
+		if !ok {
+			log.Debugf("StorageRepository: Expire: skipping unrecognized file %s", file.Name)
+			continue
+		}
+
+		if start.Before(limit) {
+			err = sr.store.Remove(path.Join(sr.dir, file.Name))
+			if err != nil && !storage.IsNotExist(err) {
+				return fmt.Errorf("StorageRepository: Expire: failed to remove %s: %w", file.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// filePath returns the path of the file holding the set which starts at the
+// given time, e.g. allocation/1d/1704067200-1704153600
+func (sr *StorageRepository[T]) filePath(start time.Time) string {
+	start = start.UTC()
+	end := start.Add(sr.resolution)
+	return path.Join(sr.dir, fmt.Sprintf("%d-%d", start.Unix(), end.Unix()))
+}
+
+// parseFileName returns the start time encoded in a file name written by
+// StorageRepository
+func parseFileName(name string) (time.Time, bool) {
+	startStr, _, found := strings.Cut(name, "-")
+	if !found {
+		return time.Time{}, false
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(start, 0).UTC(), true
+}
@@ -0,0 +1,142 @@
+package etl
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func newAllocationSet() *opencost.AllocationSet {
+	return &opencost.AllocationSet{}
+}
+
+func newTestRepository(t *testing.T, resolution time.Duration) *StorageRepository[*opencost.AllocationSet] {
+	return NewStorageRepository(storage.NewFileStorage(t.TempDir()), "allocation", resolution, newAllocationSet)
+}
+
+func TestStorageRepository_PutGet(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := newTestRepository(t, timeutil.Day)
+
+	// Missing windows return nil without error
+	got, err := repo.Get(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != nil {
+		t.Fatalf("expected nil set, got %s", got.Window)
+	}
+
+	has, err := repo.Has(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if has {
+		t.Fatalf("expected Has to be false before Put")
+	}
+
+	want := opencost.GenerateMockAllocationSet(start)
+	err = repo.Put(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	has, err = repo.Has(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !has {
+		t.Fatalf("expected Has to be true after Put")
+	}
+
+	got, err = repo.Get(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got == nil {
+		t.Fatalf("expected set, got nil")
+	}
+	if !got.Window.Equal(want.Window) {
+		t.Errorf("expected window %s, got %s", want.Window, got.Window)
+	}
+	if got.Length() != want.Length() {
+		t.Errorf("expected %d allocations, got %d", want.Length(), got.Length())
+	}
+	if math.Abs(got.TotalCost()-want.TotalCost()) > 0.0001 {
+		t.Errorf("expected total cost %f, got %f", want.TotalCost(), got.TotalCost())
+	}
+}
+
+func TestStorageRepository_Put(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		set     *opencost.AllocationSet
+		wantErr bool
+	}{
+		"nil set": {
+			set:     nil,
+			wantErr: true,
+		},
+		"open window": {
+			set:     &opencost.AllocationSet{Window: opencost.NewWindow(&start, nil)},
+			wantErr: true,
+		},
+		"wrong resolution": {
+			set:     opencost.NewAllocationSet(start, start.Add(time.Hour)),
+			wantErr: true,
+		},
+		"valid": {
+			set:     opencost.NewAllocationSet(start, start.Add(timeutil.Day)),
+			wantErr: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := newTestRepository(t, timeutil.Day)
+			err := repo.Put(tt.set)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Put() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStorageRepository_Expire(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := newTestRepository(t, timeutil.Day)
+
+	// Expiring an empty repository is a no-op
+	err := repo.Expire(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		s := start.Add(time.Duration(i) * timeutil.Day)
+		err = repo.Put(opencost.NewAllocationSet(s, s.Add(timeutil.Day)))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err = repo.Expire(start.Add(2 * timeutil.Day))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i, want := range []bool{false, false, true} {
+		s := start.Add(time.Duration(i) * timeutil.Day)
+		has, err := repo.Has(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if has != want {
+			t.Errorf("Has(%s) = %t, want %t", s, has, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/timeutil"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+// DefaultPipelineConfigs returns the daily and hourly PipelineConfigs, with
+// retention determined by DATA_RETENTION_DAILY_RESOLUTION_DAYS and
+// DATA_RETENTION_HOURLY_RESOLUTION_HOURS.
+func DefaultPipelineConfigs() []PipelineConfig {
+	return []PipelineConfig{
+		{
+			Resolution:  timeutil.Day,
+			Duration:    timeutil.Day * time.Duration(env.GetDataRetentionDailyResolutionDays()),
+			RefreshRate: time.Hour,
+		},
+		{
+			Resolution:  time.Hour,
+			Duration:    time.Hour * time.Duration(env.GetDataRetentionHourlyResolutionHours()),
+			RefreshRate: 10 * time.Minute,
+		},
+	}
+}
+
+// Store groups Pipelines of the same set type at multiple resolutions, e.g.
+// daily and hourly, and serves queries from the coarsest resolution which
+// can satisfy them.
+type Store[T Set] struct {
+	pipelines []*Pipeline[T]
+}
+
+// NewStore creates a Store from the given Pipelines, which are ordered from
+// coarsest to finest resolution.
+func NewStore[T Set](pipelines ...*Pipeline[T]) *Store[T] {
+	sorted := make([]*Pipeline[T], len(pipelines))
+	copy(sorted, pipelines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Resolution() > sorted[j].Resolution()
+	})
+
+	return &Store[T]{
+		pipelines: sorted,
+	}
+}
+
+// NewStorageStore creates a Store with a Pipeline for each of the given
+// configs, each writing to a StorageRepository under the given directory.
+func NewStorageStore[T Set](name string, store storage.Storage, dir string, configs []PipelineConfig, newSet func() T, build BuildFunc[T]) (*Store[T], error) {
+	if store == nil {
+		return nil, fmt.Errorf("ETL: NewStorageStore: storage cannot be nil")
+	}
+
+	pipelines := make([]*Pipeline[T], 0, len(configs))
+	for _, config := range configs {
+		// Skip resolutions for which retention has been disabled
+		if config.Duration <= 0 {
+			continue
+		}
+
+		repo := NewStorageRepository(store, dir, config.Resolution, newSet)
+		p, err := NewPipeline[T](name, config, repo, build)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, p)
+	}
+
+	return NewStore(pipelines...), nil
+}
+
+func (s *Store[T]) Start() {
+	for _, p := range s.pipelines {
+		p.Start()
+	}
+}
+
+func (s *Store[T]) Stop() {
+	for _, p := range s.pipelines {
+		p.Stop()
+	}
+}
+
+// Status returns the status of each Pipeline in the Store, from coarsest to
+// finest resolution
+func (s *Store[T]) Status() []PipelineStatus {
+	statuses := make([]PipelineStatus, 0, len(s.pipelines))
+	for _, p := range s.pipelines {
+		statuses = append(statuses, p.Status())
+	}
+	return statuses
+}
+
+// Query returns the stored sets which exactly cover the window [start, end),
+// in chronological order. The coarsest resolution whose windows align with
+// start and end, fall within the retention limit and are all present in the
+// store is used. If no resolution can satisfy the query, false is returned
+// and the caller is expected to compute the window itself.
+func (s *Store[T]) Query(start, end time.Time) ([]T, bool, error) {
+	if s == nil {
+		return nil, false, nil
+	}
+
+	if !start.Before(end) {
+		return nil, false, fmt.Errorf("ETL: Store: invalid window %s", opencost.NewClosedWindow(start, end))
+	}
+
+	for _, p := range s.pipelines {
+		res := p.Resolution()
+		if !start.Equal(opencost.RoundBack(start, res)) || !end.Equal(opencost.RoundBack(end, res)) {
+			continue
+		}
+
+		if start.Before(p.Limit()) {
+			continue
+		}
+
+		sets, ok, err := s.query(p.Repository(), start, end, res)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return sets, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+func (s *Store[T]) query(repo Repository[T], start, end time.Time, resolution time.Duration) ([]T, bool, error) {
+	var zero T
+
+	sets := []T{}
+	for t := start; t.Before(end); t = t.Add(resolution) {
+		set, err := repo.Get(t)
+		if err != nil {
+			return nil, false, fmt.Errorf("ETL: Store: failed to get set starting at %s: %w", t, err)
+		}
+		if set == zero {
+			return nil, false, nil
+		}
+		sets = append(sets, set)
+	}
+
+	return sets, true, nil
+}
@@ -0,0 +1,114 @@
+package etl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/pkg/storage"
+)
+
+func TestStore_Query(t *testing.T) {
+	builds := 0
+	build := func(start, end time.Time) (*opencost.AllocationSet, error) {
+		builds++
+		return opencost.NewAllocationSet(start, end, opencost.NewMockUnitAllocation("cluster1/namespace1/pod1/container1", start, end.Sub(start), nil)), nil
+	}
+
+	configs := []PipelineConfig{
+		{Resolution: time.Hour, Duration: 3 * time.Hour},
+	}
+	store, err := NewStorageStore("Allocation", storage.NewFileStorage(t.TempDir()), "allocation", configs, newAllocationSet, build)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	end := opencost.RoundBack(time.Now().UTC(), time.Hour)
+
+	// Nothing has been built yet
+	_, ok, err := store.Query(end.Add(-time.Hour), end)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok {
+		t.Fatalf("expected query to miss before build")
+	}
+
+	store.pipelines[0].Run()
+	if builds != 3 {
+		t.Fatalf("expected 3 builds, got %d", builds)
+	}
+
+	// A second run only builds missing windows
+	store.pipelines[0].Run()
+	if builds != 3 {
+		t.Fatalf("expected 3 builds after second run, got %d", builds)
+	}
+
+	status := store.Status()
+	if len(status) != 1 || !status[0].Coverage.Equal(opencost.NewClosedWindow(end.Add(-3*time.Hour), end)) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	tests := []struct {
+		start  time.Time
+		end    time.Time
+		wantOk bool
+		want   int
+	}{
+		{start: end.Add(-3 * time.Hour), end: end, wantOk: true, want: 3},
+		{start: end.Add(-2 * time.Hour), end: end.Add(-time.Hour), wantOk: true, want: 1},
+		{start: end.Add(-4 * time.Hour), end: end, wantOk: false},
+		{start: end.Add(-time.Hour), end: end.Add(time.Hour), wantOk: false},
+		{start: end.Add(-90 * time.Minute), end: end, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(opencost.NewClosedWindow(tt.start, tt.end).String(), func(t *testing.T) {
+			sets, ok, err := store.Query(tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("Query() ok = %t, want %t", ok, tt.wantOk)
+			}
+			if len(sets) != tt.want {
+				t.Fatalf("Query() returned %d sets, want %d", len(sets), tt.want)
+			}
+			for i, set := range sets {
+				s := tt.start.Add(time.Duration(i) * time.Hour)
+				if !set.Start().Equal(s) {
+					t.Errorf("set %d: expected start %s, got %s", i, s, set.Start())
+				}
+				if set.Length() != 1 {
+					t.Errorf("set %d: expected 1 allocation, got %d", i, set.Length())
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_Run_Error(t *testing.T) {
+	build := func(start, end time.Time) (*opencost.AllocationSet, error) {
+		return nil, fmt.Errorf("prometheus unavailable")
+	}
+
+	repo := NewStorageRepository(storage.NewFileStorage(t.TempDir()), "allocation", time.Hour, newAllocationSet)
+	p, err := NewPipeline[*opencost.AllocationSet]("Allocation", PipelineConfig{Resolution: time.Hour, Duration: 2 * time.Hour}, repo, build)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.Run()
+
+	has, err := repo.Has(p.Limit())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if has {
+		t.Errorf("expected failed builds not to be stored")
+	}
+	if p.Status().Runs != 1 {
+		t.Errorf("expected 1 run, got %d", p.Status().Runs)
+	}
+}
@@ -0,0 +1,266 @@
+// Package forecast projects daily costs forward in time, using a linear trend
+// with weekly seasonality fit to the daily costs of a historical window.
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultConfidence is the confidence level of the prediction bands of a
+// forecast if none is given
+const DefaultConfidence = 0.95
+
+// minSeasonalDays is the number of days of history required to estimate a
+// seasonal offset for each day of the week. Shorter histories are forecast
+// using the trend alone.
+const minSeasonalDays = 14
+
+const day = 24 * time.Hour
+
+// Point is the forecast cost of a single day, with the lower and upper bounds
+// of its prediction band
+type Point struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Cost  float64   `json:"cost"`
+	Lower float64   `json:"lower"`
+	Upper float64   `json:"upper"`
+}
+
+// Band is a cost with the lower and upper bounds of its prediction band
+type Band struct {
+	Cost  float64 `json:"cost"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// Forecast is the daily forecast of a series of costs. Total is the forecast
+// of the sum of all days, whose band is narrower than the sum of the daily
+// bands. Trend is the change in daily cost per day, and Seasonality the
+// offset of each day of the week from the trend, indexed by time.Weekday. It
+// is nil if the history was too short to estimate.
+type Forecast struct {
+	Points      []Point   `json:"points"`
+	Total       Band      `json:"total"`
+	Trend       float64   `json:"trend"`
+	Seasonality []float64 `json:"seasonality"`
+}
+
+// Model is a linear trend with weekly seasonality, fit to a series of daily
+// costs by least squares
+type Model struct {
+	start       time.Time
+	n           int
+	intercept   float64
+	slope       float64
+	meanX       float64
+	sxx         float64
+	seasonality []float64
+	stdDev      float64
+}
+
+// Fit fits a Model to the daily costs, the first of which is the cost of the
+// day beginning at start. At least two days are required.
+func Fit(start time.Time, costs []float64) (*Model, error) {
+	n := len(costs)
+	if n < 2 {
+		return nil, fmt.Errorf("at least 2 days of history are required, got %d", n)
+	}
+
+	m := &Model{
+		start: start,
+		n:     n,
+		meanX: float64(n-1) / 2,
+	}
+
+	for i := 0; i < n; i++ {
+		dx := float64(i) - m.meanX
+		m.sxx += dx * dx
+	}
+
+	// The trend and seasonality are fit together, by least squares of cost
+	// over the day index and the day of the week. Fitting them separately
+	// would skew the trend whenever the history does not cover whole weeks.
+	// The day of the week is effect coded, so that the seasonal offsets sum
+	// to zero and do not shift the trend.
+	seasonal := n >= minSeasonalDays
+	params := 2
+	if seasonal {
+		params += 6
+	}
+
+	xtx := make([][]float64, params)
+	for j := range xtx {
+		xtx[j] = make([]float64, params)
+	}
+	xty := make([]float64, params)
+	for i, cost := range costs {
+		x := m.regressors(i, params)
+		for j := range x {
+			for k := range x {
+				xtx[j][k] += x[j] * x[k]
+			}
+			xty[j] += x[j] * cost
+		}
+	}
+
+	beta, err := solve(xtx, xty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fit model: %w", err)
+	}
+
+	m.slope = beta[1]
+	m.intercept = beta[0] - m.slope*m.meanX
+	if seasonal {
+		m.seasonality = make([]float64, 7)
+		for wd := 0; wd < 6; wd++ {
+			m.seasonality[wd] = beta[2+wd]
+			m.seasonality[6] -= beta[2+wd]
+		}
+	}
+
+	// Standard deviation of the remaining residuals, which is zero if there
+	// are no more observations than parameters
+	if n > params {
+		var sse float64
+		for i, cost := range costs {
+			r := cost - m.predict(i)
+			sse += r * r
+		}
+		m.stdDev = math.Sqrt(sse / float64(n-params))
+	}
+
+	return m, nil
+}
+
+// regressors returns the values of the explanatory variables of the ith day:
+// a constant, the distance from the mean day index, and if the model is
+// seasonal, an effect coding of all but the last day of the week.
+func (m *Model) regressors(i int, params int) []float64 {
+	x := make([]float64, params)
+	x[0] = 1
+	x[1] = float64(i) - m.meanX
+	if params > 2 {
+		wd := m.weekday(i)
+		for k := 0; k < 6; k++ {
+			switch {
+			case int(wd) == k:
+				x[2+k] = 1
+			case wd == time.Saturday:
+				x[2+k] = -1
+			}
+		}
+	}
+	return x
+}
+
+// solve solves the linear system a·x = b by Gaussian elimination with partial
+// pivoting. Both a and b are modified.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, fmt.Errorf("singular system")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+// weekday returns the day of the week of the ith day
+func (m *Model) weekday(i int) time.Weekday {
+	return m.start.Add(time.Duration(i) * day).Weekday()
+}
+
+func (m *Model) trend(i int) float64 {
+	return m.intercept + m.slope*float64(i)
+}
+
+func (m *Model) predict(i int) float64 {
+	cost := m.trend(i)
+	if m.seasonality != nil {
+		cost += m.seasonality[m.weekday(i)]
+	}
+	return cost
+}
+
+// Forecast forecasts the cost of each of the given number of days following
+// the history, with prediction bands at the given confidence level, between 0
+// and 1. Costs and bounds are not forecast below zero.
+func (m *Model) Forecast(days int, confidence float64) (*Forecast, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("invalid number of days to forecast: %d", days)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("invalid confidence %f, must be between 0 and 1", confidence)
+	}
+
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	n := float64(m.n)
+
+	f := &Forecast{
+		Points:      make([]Point, 0, days),
+		Trend:       m.slope,
+		Seasonality: m.seasonality,
+	}
+
+	// Sum of the distances of each forecast day from the mean of the history,
+	// for the variance of the total
+	var sumDX float64
+
+	for d := 0; d < days; d++ {
+		i := m.n + d
+		dx := float64(i) - m.meanX
+		sumDX += dx
+
+		cost := m.predict(i)
+		margin := z * m.stdDev * math.Sqrt(1+1/n+dx*dx/m.sxx)
+
+		start := m.start.Add(time.Duration(i) * day)
+		f.Points = append(f.Points, Point{
+			Start: start,
+			End:   start.Add(day),
+			Cost:  math.Max(cost, 0),
+			Lower: math.Max(cost-margin, 0),
+			Upper: math.Max(cost+margin, 0),
+		})
+		f.Total.Cost += cost
+	}
+
+	h := float64(days)
+	margin := z * m.stdDev * math.Sqrt(h+h*h/n+sumDX*sumDX/m.sxx)
+	cost := f.Total.Cost
+	f.Total = Band{
+		Cost:  math.Max(cost, 0),
+		Lower: math.Max(cost-margin, 0),
+		Upper: math.Max(cost+margin, 0),
+	}
+
+	return f, nil
+}
@@ -0,0 +1,358 @@
+package forecast
+
+import (
+	"math"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+)
+
+// monday is the start of a week, so that weekday offsets are easy to follow
+var monday = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestFit_LinearTrend(t *testing.T) {
+	costs := []float64{}
+	for i := 0; i < 10; i++ {
+		costs = append(costs, 10+2*float64(i))
+	}
+
+	model, err := Fit(monday, costs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := model.Forecast(3, DefaultConfidence)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !approxEqual(f.Trend, 2) {
+		t.Errorf("expected trend 2, got %f", f.Trend)
+	}
+	if f.Seasonality != nil {
+		t.Errorf("expected no seasonality for %d days of history, got %v", len(costs), f.Seasonality)
+	}
+	if len(f.Points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(f.Points))
+	}
+
+	for i, p := range f.Points {
+		expected := 10 + 2*float64(10+i)
+		if !approxEqual(p.Cost, expected) {
+			t.Errorf("point %d: expected cost %f, got %f", i, expected, p.Cost)
+		}
+		// A perfect fit has no residuals, so no band
+		if !approxEqual(p.Lower, p.Cost) || !approxEqual(p.Upper, p.Cost) {
+			t.Errorf("point %d: expected no band, got [%f, %f]", i, p.Lower, p.Upper)
+		}
+		expectedStart := monday.Add(time.Duration(10+i) * day)
+		if !p.Start.Equal(expectedStart) || !p.End.Equal(expectedStart.Add(day)) {
+			t.Errorf("point %d: expected start %s, got %s - %s", i, expectedStart, p.Start, p.End)
+		}
+	}
+
+	if !approxEqual(f.Total.Cost, 30+32+34) {
+		t.Errorf("expected total %f, got %f", 30.0+32+34, f.Total.Cost)
+	}
+}
+
+func TestFit_WeeklySeasonality(t *testing.T) {
+	// Weekends cost 20 less than weekdays, on a flat trend
+	costs := []float64{}
+	for i := 0; i < 28; i++ {
+		cost := 100.0
+		if wd := monday.Add(time.Duration(i) * day).Weekday(); wd == time.Saturday || wd == time.Sunday {
+			cost = 80
+		}
+		costs = append(costs, cost)
+	}
+
+	model, err := Fit(monday, costs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := model.Forecast(7, DefaultConfidence)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(f.Seasonality) != 7 {
+		t.Fatalf("expected seasonality for 7 days, got %v", f.Seasonality)
+	}
+	if !approxEqual(f.Seasonality[time.Monday]-f.Seasonality[time.Sunday], 20) {
+		t.Errorf("expected weekday offset 20 above weekend, got %v", f.Seasonality)
+	}
+
+	for _, p := range f.Points {
+		expected := 100.0
+		if wd := p.Start.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			expected = 80
+		}
+		if !approxEqual(p.Cost, expected) {
+			t.Errorf("%s: expected cost %f, got %f", p.Start.Weekday(), expected, p.Cost)
+		}
+	}
+}
+
+func TestFit_Bands(t *testing.T) {
+	// Alternating noise around a flat trend
+	costs := []float64{}
+	for i := 0; i < 10; i++ {
+		costs = append(costs, 50+5*math.Pow(-1, float64(i)))
+	}
+
+	model, err := Fit(monday, costs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	narrow, err := model.Forecast(5, 0.5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wide, err := model.Forecast(5, 0.99)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := range wide.Points {
+		p := wide.Points[i]
+		if !(p.Lower < p.Cost && p.Cost < p.Upper) {
+			t.Errorf("point %d: expected cost %f within band [%f, %f]", i, p.Cost, p.Lower, p.Upper)
+		}
+		if p.Upper-p.Lower <= narrow.Points[i].Upper-narrow.Points[i].Lower {
+			t.Errorf("point %d: expected higher confidence to widen the band", i)
+		}
+		if i > 0 && p.Upper-p.Lower <= wide.Points[i-1].Upper-wide.Points[i-1].Lower {
+			t.Errorf("point %d: expected band to widen further from the history", i)
+		}
+	}
+
+	var sumWidths float64
+	for _, p := range wide.Points {
+		sumWidths += p.Upper - p.Lower
+	}
+	if total := wide.Total.Upper - wide.Total.Lower; total <= 0 || total >= sumWidths {
+		t.Errorf("expected total band %f to be positive and narrower than the sum of daily bands %f", total, sumWidths)
+	}
+}
+
+func TestFit_NonNegative(t *testing.T) {
+	model, err := Fit(monday, []float64{30, 20, 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := model.Forecast(5, DefaultConfidence)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i, p := range f.Points {
+		if p.Cost < 0 || p.Lower < 0 || p.Upper < 0 {
+			t.Errorf("point %d: expected non-negative forecast, got %+v", i, p)
+		}
+	}
+	if f.Total.Cost != 0 {
+		t.Errorf("expected total 0, got %f", f.Total.Cost)
+	}
+}
+
+func TestFit_Errors(t *testing.T) {
+	_, err := Fit(monday, []float64{1})
+	if err == nil {
+		t.Errorf("expected error fitting a single day")
+	}
+
+	model, err := Fit(monday, []float64{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, confidence := range []float64{0, 1, -0.5, 1.5} {
+		if _, err := model.Forecast(1, confidence); err == nil {
+			t.Errorf("expected error for confidence %f", confidence)
+		}
+	}
+	if _, err := model.Forecast(-1, DefaultConfidence); err == nil {
+		t.Errorf("expected error for negative days")
+	}
+}
+
+func TestHistoryWindow(t *testing.T) {
+	offset := 2 * time.Hour
+	loc := time.FixedZone("", int(offset.Seconds()))
+
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, loc)
+	now := time.Date(2024, time.March, 10, 15, 30, 0, 0, loc)
+
+	testCases := map[string]struct {
+		window   opencost.Window
+		expected opencost.Window
+		err      bool
+	}{
+		"month to date": {
+			window:   opencost.NewClosedWindow(start, now),
+			expected: opencost.NewClosedWindow(start, time.Date(2024, time.March, 10, 0, 0, 0, 0, loc)),
+		},
+		"ends in the future": {
+			window:   opencost.NewClosedWindow(start, now.Add(48*time.Hour)),
+			expected: opencost.NewClosedWindow(start, time.Date(2024, time.March, 10, 0, 0, 0, 0, loc)),
+		},
+		"partial first day": {
+			window:   opencost.NewClosedWindow(start.Add(time.Hour), time.Date(2024, time.March, 5, 0, 0, 0, 0, loc)),
+			expected: opencost.NewClosedWindow(start.Add(day), time.Date(2024, time.March, 5, 0, 0, 0, 0, loc)),
+		},
+		"too short": {
+			window: opencost.NewClosedWindow(start, start.Add(36*time.Hour)),
+			err:    true,
+		},
+		"open": {
+			window: opencost.NewWindow(&start, nil),
+			err:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			history, err := HistoryWindow(tc.window, now, offset)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", history)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !history.Equal(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, history)
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	historyEnd := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	opts, err := ParseOptions(httputil.NewQueryParams(url.Values{}), historyEnd, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC); !opts.End.Equal(expected) {
+		t.Errorf("expected default end %s, got %s", expected, opts.End)
+	}
+	if opts.Confidence != DefaultConfidence {
+		t.Errorf("expected default confidence %f, got %f", DefaultConfidence, opts.Confidence)
+	}
+
+	opts, err = ParseOptions(httputil.NewQueryParams(url.Values{"horizon": {"7d"}, "confidence": {"0.8"}}), historyEnd, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := historyEnd.Add(7 * day); !opts.End.Equal(expected) {
+		t.Errorf("expected end %s, got %s", expected, opts.End)
+	}
+	if opts.Confidence != 0.8 {
+		t.Errorf("expected confidence 0.8, got %f", opts.Confidence)
+	}
+
+	for _, values := range []url.Values{{"horizon": {"-1d"}}, {"horizon": {"x"}}, {"confidence": {"1"}}} {
+		if _, err := ParseOptions(httputil.NewQueryParams(values), historyEnd, 0); err == nil {
+			t.Errorf("expected error for %v", values)
+		}
+	}
+}
+
+func TestForecastAllocations(t *testing.T) {
+	asr := opencost.NewAllocationSetRange()
+	for i := 0; i < 14; i++ {
+		start := monday.Add(time.Duration(i) * day)
+		as := opencost.NewAllocationSet(start, start.Add(day))
+
+		a := opencost.NewMockUnitAllocation("a", start, day, nil)
+		a.CPUCost = 10
+		as.Set(a)
+
+		// b only has costs in the second week
+		if i >= 7 {
+			b := opencost.NewMockUnitAllocation("b", start, day, nil)
+			b.CPUCost = 5
+			as.Set(b)
+		}
+
+		asr.Append(as)
+	}
+
+	result, err := ForecastAllocations(asr, Options{End: monday.Add(21 * day), Confidence: DefaultConfidence})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !result.ForecastWindow.Equal(opencost.NewClosedWindow(monday.Add(14*day), monday.Add(21*day))) {
+		t.Errorf("unexpected forecast window %s", result.ForecastWindow)
+	}
+	if len(result.Forecasts) != 2 {
+		t.Fatalf("expected 2 forecasts, got %d", len(result.Forecasts))
+	}
+
+	a := result.Forecasts["a"]
+	aCost := a.Actual / 14
+	if len(a.Forecast.Points) != 7 || !approxEqual(a.Forecast.Points[0].Cost, aCost) {
+		t.Errorf("expected a to be forecast flat at %f, got %+v", aCost, a.Forecast.Points)
+	}
+	if !approxEqual(a.ProjectedTotal.Cost, 21*aCost) {
+		t.Errorf("expected a projected total %f, got %f", 21*aCost, a.ProjectedTotal.Cost)
+	}
+
+	b := result.Forecasts["b"]
+	if b.Forecast.Trend <= 0 {
+		t.Errorf("expected b to trend upwards, got %f", b.Forecast.Trend)
+	}
+
+	if !approxEqual(result.Total.Actual, a.Actual+b.Actual) {
+		t.Errorf("expected total actual %f, got %f", a.Actual+b.Actual, result.Total.Actual)
+	}
+
+	// Steps of other than a day cannot be forecast
+	asr = opencost.NewAllocationSetRange(opencost.NewAllocationSet(monday, monday.Add(2*day)))
+	if _, err := ForecastAllocations(asr, Options{End: monday.Add(3 * day), Confidence: DefaultConfidence}); err == nil {
+		t.Errorf("expected error forecasting steps of 2 days")
+	}
+}
+
+func TestForecastCloudCosts(t *testing.T) {
+	ccsr, err := opencost.NewCloudCostSetRange(monday, monday.Add(7*day), opencost.AccumulateOptionDay, "integration")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, ccs := range ccsr.CloudCostSets {
+		ccs.Insert(&opencost.CloudCost{
+			Properties:       &opencost.CloudCostProperties{ProviderID: "vm", Service: "compute"},
+			Window:           ccs.Window,
+			ListCost:         opencost.CostMetric{Cost: 100},
+			AmortizedNetCost: opencost.CostMetric{Cost: 10 + float64(i)},
+		})
+	}
+
+	result, err := ForecastCloudCosts(ccsr, opencost.CostMetricAmortizedNetCost, Options{End: monday.Add(8 * day), Confidence: DefaultConfidence})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(result.Forecasts) != 1 {
+		t.Fatalf("expected 1 forecast, got %d", len(result.Forecasts))
+	}
+	for _, f := range result.Forecasts {
+		if len(f.Forecast.Points) != 1 || !approxEqual(f.Forecast.Points[0].Cost, 17) {
+			t.Errorf("expected forecast of 17, got %+v", f.Forecast.Points)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/opencost/opencost/core/pkg/opencost"
+	"github.com/opencost/opencost/core/pkg/util/httputil"
+)
+
+// Options configures the forecast of a set range
+type Options struct {
+	// End is the time up to which costs are forecast, which is rounded up to
+	// a whole number of days after the history
+	End time.Time
+
+	// Confidence is the confidence level of the prediction bands, between 0
+	// and 1
+	Confidence float64
+}
+
+// ParseOptions parses the 'horizon' and 'confidence' parameters of a forecast
+// request. The horizon is the duration after the end of the history to
+// forecast, defaulting to the end of the month in which the history ends.
+// Errors caused by invalid parameters are prefixed with "bad request".
+func ParseOptions(qp httputil.QueryParams, historyEnd time.Time, offset time.Duration) (Options, error) {
+	opts := Options{
+		End:        MonthEnd(historyEnd, offset),
+		Confidence: qp.GetFloat64("confidence", DefaultConfidence),
+	}
+
+	if qp.Has("horizon") {
+		horizon := qp.GetDuration("horizon", 0)
+		if horizon <= 0 {
+			return opts, fmt.Errorf("bad request - invalid 'horizon' parameter: %s", qp.Get("horizon", ""))
+		}
+		opts.End = historyEnd.Add(horizon)
+	}
+
+	if opts.Confidence <= 0 || opts.Confidence >= 1 {
+		return opts, fmt.Errorf("bad request - invalid 'confidence' parameter: %s, must be between 0 and 1", qp.Get("confidence", ""))
+	}
+
+	return opts, nil
+}
+
+// HistoryWindow returns the whole days of the window, in the timezone of the
+// given UTC offset, which have completed by now. Days which are incomplete,
+// either because the window does not start or end at midnight, or because they
+// have not yet ended, would understate costs, so are excluded from the
+// history a forecast is fit to.
+func HistoryWindow(window opencost.Window, now time.Time, offset time.Duration) (opencost.Window, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return window, fmt.Errorf("window must be closed: %s", window)
+	}
+
+	start := truncateDay(*window.Start(), offset)
+	if start.Before(*window.Start()) {
+		start = start.Add(day)
+	}
+
+	end := *window.End()
+	if now.Before(end) {
+		end = now
+	}
+	end = truncateDay(end, offset)
+
+	if end.Sub(start) < 2*day {
+		return window, fmt.Errorf("window must contain at least 2 complete days: %s", window)
+	}
+
+	return opencost.NewClosedWindow(start, end), nil
+}
+
+// MonthEnd returns midnight at the start of the month following the one in
+// which t falls, in the timezone of the given UTC offset. A time at midnight
+// on the 1st is considered to fall in the month which starts then.
+func MonthEnd(t time.Time, offset time.Duration) time.Time {
+	loc := time.FixedZone("", int(offset.Seconds()))
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month()+1, 1, 0, 0, 0, 0, loc)
+}
+
+func truncateDay(t time.Time, offset time.Duration) time.Time {
+	return t.Add(offset).Truncate(day).Add(-offset)
+}
+
+// CostForecast is the forecast of the daily costs of a single aggregation
+// key. Actual is its cost over the history, and ProjectedTotal the sum of
+// Actual and the forecast total, e.g. the projected spend at the end of the
+// month if the history is the month to date.
+type CostForecast struct {
+	Name           string    `json:"name"`
+	Actual         float64   `json:"actual"`
+	Forecast       *Forecast `json:"forecast"`
+	ProjectedTotal Band      `json:"projectedTotal"`
+}
+
+// Result is the forecast of each aggregation key of a set range. Total is the
+// forecast of the sum of all keys, rather than the sum of their forecasts.
+type Result struct {
+	Window         opencost.Window          `json:"window"`
+	ForecastWindow opencost.Window          `json:"forecastWindow"`
+	Confidence     float64                  `json:"confidence"`
+	Total          *CostForecast            `json:"total"`
+	Forecasts      map[string]*CostForecast `json:"forecasts"`
+}
+
+// series is the daily costs of each key over a window of whole days
+type series struct {
+	start time.Time
+	end   time.Time
+	days  int
+	costs map[string][]float64
+	total []float64
+}
+
+// newSeries creates a series over the windows of a set range, which must each
+// be a single day
+func newSeries(windows []opencost.Window) (*series, error) {
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no history to forecast")
+	}
+
+	s := &series{costs: map[string][]float64{}}
+	for i, w := range windows {
+		if w.IsOpen() || w.Duration() != day {
+			return nil, fmt.Errorf("history must be in steps of 1 day, got %s", w)
+		}
+		if i == 0 || w.Start().Before(s.start) {
+			s.start = *w.Start()
+		}
+		if i == 0 || w.End().After(s.end) {
+			s.end = *w.End()
+		}
+	}
+	s.days = int(s.end.Sub(s.start) / day)
+	s.total = make([]float64, s.days)
+
+	return s, nil
+}
+
+// add adds the cost of the key on the day starting at the given time
+func (s *series) add(name string, start time.Time, cost float64) {
+	i := int(start.Sub(s.start) / day)
+
+	if _, ok := s.costs[name]; !ok {
+		s.costs[name] = make([]float64, s.days)
+	}
+	s.costs[name][i] += cost
+	s.total[i] += cost
+}
+
+func (s *series) forecast(opts Options) (*Result, error) {
+	if !opts.End.After(s.end) {
+		return nil, fmt.Errorf("forecast end %s must be after the end of the history %s", opts.End, s.end)
+	}
+	days := int(math.Ceil(float64(opts.End.Sub(s.end)) / float64(day)))
+
+	result := &Result{
+		Window:         opencost.NewClosedWindow(s.start, s.end),
+		ForecastWindow: opencost.NewClosedWindow(s.end, s.end.Add(time.Duration(days)*day)),
+		Confidence:     opts.Confidence,
+		Forecasts:      make(map[string]*CostForecast, len(s.costs)),
+	}
+
+	var err error
+	result.Total, err = s.forecastCosts("", s.total, days, opts.Confidence)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, costs := range s.costs {
+		result.Forecasts[name], err = s.forecastCosts(name, costs, days, opts.Confidence)
+		if err != nil {
+			return nil, fmt.Errorf("error forecasting %s: %w", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *series) forecastCosts(name string, costs []float64, days int, confidence float64) (*CostForecast, error) {
+	model, err := Fit(s.start, costs)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := model.Forecast(days, confidence)
+	if err != nil {
+		return nil, err
+	}
+
+	var actual float64
+	for _, cost := range costs {
+		actual += cost
+	}
+
+	return &CostForecast{
+		Name:     name,
+		Actual:   actual,
+		Forecast: f,
+		ProjectedTotal: Band{
+			Cost:  actual + f.Total.Cost,
+			Lower: actual + f.Total.Lower,
+			Upper: actual + f.Total.Upper,
+		},
+	}, nil
+}
+
+// ForecastAllocations forecasts the total cost of each allocation of the
+// range, which must be in steps of 1 day. An allocation missing from a day is
+// considered to have cost nothing that day.
+func ForecastAllocations(asr *opencost.AllocationSetRange, opts Options) (*Result, error) {
+	if asr == nil {
+		return nil, fmt.Errorf("no history to forecast")
+	}
+
+	windows := make([]opencost.Window, 0, len(asr.Allocations))
+	for _, as := range asr.Allocations {
+		windows = append(windows, as.Window)
+	}
+
+	s, err := newSeries(windows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, as := range asr.Allocations {
+		for name, alloc := range as.Allocations {
+			s.add(name, *as.Window.Start(), alloc.TotalCost())
+		}
+	}
+
+	return s.forecast(opts)
+}
+
+// ForecastCloudCosts forecasts the given cost metric of each cloud cost of
+// the range, which must be in steps of 1 day. A cloud cost missing from a day
+// is considered to have cost nothing that day.
+func ForecastCloudCosts(ccsr *opencost.CloudCostSetRange, costMetricName opencost.CostMetricName, opts Options) (*Result, error) {
+	if ccsr == nil {
+		return nil, fmt.Errorf("no history to forecast")
+	}
+
+	windows := make([]opencost.Window, 0, len(ccsr.CloudCostSets))
+	for _, ccs := range ccsr.CloudCostSets {
+		windows = append(windows, ccs.Window)
+	}
+
+	s, err := newSeries(windows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ccs := range ccsr.CloudCostSets {
+		for name, cc := range ccs.CloudCosts {
+			costMetric, err := cc.GetCostMetric(costMetricName)
+			if err != nil {
+				return nil, err
+			}
+			s.add(name, *ccs.Window.Start(), costMetric.Cost)
+		}
+	}
+
+	return s.forecast(opts)
+}
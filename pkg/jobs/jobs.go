@@ -0,0 +1,453 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencost/opencost/core/pkg/log"
+	"github.com/opencost/opencost/core/pkg/util/worker"
+	"github.com/patrickmn/go-cache"
+)
+
+// Status describes the state of a Job
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// IsDone returns true if the Status is final
+func (s Status) IsDone() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCancelled
+}
+
+// ErrNotFound is returned when a job does not exist, or its result has expired
+var ErrNotFound = errors.New("job not found")
+
+// ErrNotDone is returned when the result of a job is requested before it has finished
+var ErrNotDone = errors.New("job has not finished")
+
+// ErrTooManyPending is returned when a job is submitted while the maximum
+// number of jobs are already waiting to run
+var ErrTooManyPending = errors.New("too many pending jobs")
+
+// ErrPendingExpired is the error of a job which waited longer than the pending
+// TTL to start
+var ErrPendingExpired = errors.New("job expired before it started")
+
+// Func is the work performed by a Job. It should stop promptly when the
+// context is cancelled, and may report its progress using the Progress
+// returned by ProgressFromContext.
+type Func func(ctx context.Context) (any, error)
+
+// JobStatus is the externally visible state of a Job
+type JobStatus struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Query    string       `json:"query,omitempty"`
+	Status   Status       `json:"status"`
+	Progress StepProgress `json:"progress"`
+	Error    string       `json:"error,omitempty"`
+	Created  time.Time    `json:"created"`
+	Started  *time.Time   `json:"started,omitempty"`
+	Finished *time.Time   `json:"finished,omitempty"`
+	Expires  *time.Time   `json:"expires,omitempty"`
+}
+
+// Job is a unit of asynchronous work tracked by a Manager
+type Job struct {
+	lock     sync.Mutex
+	id       string
+	jobType  string
+	query    string
+	status   Status
+	progress *Progress
+	err      error
+	result   any
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	expires  time.Time
+	fn       Func
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// Status returns a snapshot of the state of the Job
+func (j *Job) Status() JobStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	js := JobStatus{
+		ID:       j.id,
+		Type:     j.jobType,
+		Query:    j.query,
+		Status:   j.status,
+		Progress: j.progress.Snapshot(),
+		Created:  j.created,
+	}
+	if j.err != nil {
+		js.Error = j.err.Error()
+	}
+	if !j.started.IsZero() {
+		started := j.started
+		js.Started = &started
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		js.Finished = &finished
+	}
+	if !j.expires.IsZero() {
+		expires := j.expires
+		js.Expires = &expires
+	}
+	return js
+}
+
+// StepState describes the state of a single step of a Job
+type StepState string
+
+const (
+	StepPending StepState = "pending"
+	StepRunning StepState = "running"
+	StepDone    StepState = "done"
+	StepFailed  StepState = "failed"
+)
+
+// Progress tracks the state of each step of a Job. A nil Progress is valid
+// and ignores all updates, so work can report progress whether or not it is
+// running as a Job.
+type Progress struct {
+	lock  sync.Mutex
+	steps []StepStatus
+}
+
+type progressKey struct{}
+
+// ProgressFromContext returns the Progress of the Job running with the given
+// context, or nil if there is none.
+func ProgressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// AddStep adds a pending step with the given name, e.g. the window it
+// computes, and returns the Step with which to report its state
+func (p *Progress) AddStep(name string) *Step {
+	if p == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.steps = append(p.steps, StepStatus{Name: name, State: StepPending})
+	return &Step{progress: p, index: len(p.steps) - 1}
+}
+
+// Step reports the state of a single step of a Progress. A nil Step is valid
+// and ignores all updates.
+type Step struct {
+	progress *Progress
+	index    int
+}
+
+// Start marks the step as running
+func (s *Step) Start() {
+	s.set(StepRunning, nil)
+}
+
+// Done marks the step as done
+func (s *Step) Done() {
+	s.set(StepDone, nil)
+}
+
+// Fail marks the step as failed with the given error
+func (s *Step) Fail(err error) {
+	s.set(StepFailed, err)
+}
+
+func (s *Step) set(state StepState, err error) {
+	if s == nil {
+		return
+	}
+
+	s.progress.lock.Lock()
+	defer s.progress.lock.Unlock()
+
+	s.progress.steps[s.index].State = state
+	if err != nil {
+		s.progress.steps[s.index].Error = err.Error()
+	}
+}
+
+// StepStatus is the state of a single step of a Job
+type StepStatus struct {
+	Name  string    `json:"name"`
+	State StepState `json:"state"`
+	Error string    `json:"error,omitempty"`
+}
+
+// StepProgress is a point-in-time view of a Progress
+type StepProgress struct {
+	StepsCompleted int64        `json:"stepsCompleted"`
+	StepsTotal     int64        `json:"stepsTotal"`
+	Steps          []StepStatus `json:"steps,omitempty"`
+}
+
+// Snapshot returns the current state of each step, and the completed and
+// total step counts
+func (p *Progress) Snapshot() StepProgress {
+	if p == nil {
+		return StepProgress{}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sp := StepProgress{
+		StepsTotal: int64(len(p.steps)),
+		Steps:      append([]StepStatus(nil), p.steps...),
+	}
+	for _, step := range p.steps {
+		if step.State == StepDone {
+			sp.StepsCompleted++
+		}
+	}
+	return sp
+}
+
+// Manager runs Jobs on a bounded pool of workers and retains their results
+// for a TTL after they finish. The number of Jobs waiting for a worker is
+// bounded, and Jobs which wait longer than the pending TTL are failed without
+// being run.
+type Manager struct {
+	pool       worker.WorkerPool[*Job, struct{}]
+	jobs       *cache.Cache
+	pending    atomic.Int64
+	maxPending int64
+	pendingTTL time.Duration
+	ttl        time.Duration
+}
+
+// NewManager creates a Manager which runs at most the given number of Jobs
+// concurrently and queues at most maxPending more. Jobs which have not started
+// within pendingTTL are failed, and finished Jobs are retained for the given
+// TTL.
+func NewManager(workers, maxPending int, pendingTTL, ttl time.Duration) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxPending < 1 {
+		maxPending = 1
+	}
+	if pendingTTL <= 0 {
+		pendingTTL = time.Hour
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	m := &Manager{
+		jobs:       cache.New(ttl, ttl),
+		maxPending: int64(maxPending),
+		pendingTTL: pendingTTL,
+		ttl:        ttl,
+	}
+	m.pool = worker.NewWorkerPool(workers, m.run)
+	return m
+}
+
+// Submit queues a new Job of the given type. The query describes the request
+// which created the Job, for display purposes. ErrTooManyPending is returned
+// if the maximum number of Jobs are already waiting to run.
+func (m *Manager) Submit(jobType, query string, fn Func) (JobStatus, error) {
+	if m.pending.Add(1) > m.maxPending {
+		m.pending.Add(-1)
+		return JobStatus{}, ErrTooManyPending
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := &Progress{}
+
+	job := &Job{
+		id:       uuid.NewString(),
+		jobType:  jobType,
+		query:    query,
+		status:   StatusPending,
+		progress: progress,
+		created:  time.Now().UTC(),
+		fn:       fn,
+		ctx:      context.WithValue(ctx, progressKey{}, progress),
+		cancel:   cancel,
+	}
+
+	// Pending jobs are failed by the worker once the pending TTL has passed,
+	// so keep them slightly longer for their status to remain visible
+	m.jobs.Set(job.id, job, m.pendingTTL+m.ttl)
+
+	err := m.pool.Run(job, nil)
+	if err != nil {
+		m.pending.Add(-1)
+		m.jobs.Delete(job.id)
+		cancel()
+		return JobStatus{}, fmt.Errorf("failed to submit job: %w", err)
+	}
+
+	log.Debugf("Jobs: submitted %s job %s", jobType, job.id)
+	return job.Status(), nil
+}
+
+// Status returns the status of the Job with the given ID
+func (m *Manager) Status(id string) (JobStatus, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return job.Status(), nil
+}
+
+// Result returns the result of the Job with the given ID. ErrNotDone is
+// returned if the Job has not finished, and the Job's own error if it failed
+// or was cancelled.
+func (m *Manager) Result(id string) (any, JobStatus, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return nil, JobStatus{}, err
+	}
+
+	status := job.Status()
+
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if !job.status.IsDone() {
+		return nil, status, ErrNotDone
+	}
+	if job.err != nil {
+		return nil, status, job.err
+	}
+	return job.result, status, nil
+}
+
+// Cancel stops the Job with the given ID, if it has not already finished
+func (m *Manager) Cancel(id string) (JobStatus, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	job.lock.Lock()
+	if !job.status.IsDone() {
+		job.cancel()
+
+		// Pending jobs will never be started, so finish them immediately
+		if job.status == StatusPending {
+			m.finish(job, nil, context.Canceled)
+		}
+	}
+	job.lock.Unlock()
+
+	return job.Status(), nil
+}
+
+// Shutdown cancels all unfinished Jobs and stops the workers
+func (m *Manager) Shutdown() {
+	for id := range m.jobs.Items() {
+		m.Cancel(id)
+	}
+	m.pool.Shutdown()
+}
+
+func (m *Manager) get(id string) (*Job, error) {
+	v, ok := m.jobs.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v.(*Job), nil
+}
+
+// run is the worker func which executes each Job
+func (m *Manager) run(job *Job) struct{} {
+	// Cancelled jobs remain queued until a worker skips them, so the pending
+	// count is released here rather than on cancellation to bound the queue
+	m.pending.Add(-1)
+
+	job.lock.Lock()
+	if job.status != StatusPending {
+		job.lock.Unlock()
+		return struct{}{}
+	}
+
+	if time.Since(job.created) > m.pendingTTL {
+		m.finish(job, nil, ErrPendingExpired)
+		job.lock.Unlock()
+		return struct{}{}
+	}
+
+	// Running jobs are bounded by the number of workers, so they do not
+	// expire until they have finished
+	job.status = StatusRunning
+	job.started = time.Now().UTC()
+	m.jobs.Set(job.id, job, cache.NoExpiration)
+	job.lock.Unlock()
+
+	result, err := m.execute(job)
+
+	job.lock.Lock()
+	m.finish(job, result, err)
+	job.lock.Unlock()
+
+	return struct{}{}
+}
+
+// execute calls the Job's Func, recovering from any panic so that a single
+// bad query cannot take down a worker
+func (m *Manager) execute(job *Job) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Jobs: %s job %s panicked: %v", job.jobType, job.id, r)
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return job.fn(job.ctx)
+}
+
+// finish records the outcome of the Job and starts its TTL. The Job's lock
+// must be held.
+func (m *Manager) finish(job *Job, result any, err error) {
+	if job.status.IsDone() {
+		return
+	}
+
+	now := time.Now().UTC()
+	job.finished = now
+	job.expires = now.Add(m.ttl)
+
+	switch {
+	case job.ctx.Err() != nil:
+		job.status = StatusCancelled
+		job.err = context.Canceled
+	case err != nil:
+		job.status = StatusFailed
+		job.err = err
+	default:
+		job.status = StatusCompleted
+		job.result = result
+	}
+
+	job.cancel()
+	m.jobs.Set(job.id, job, m.ttl)
+
+	log.Debugf("Jobs: %s job %s %s", job.jobType, job.id, job.status)
+}
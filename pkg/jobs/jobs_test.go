@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls the status of the job until it is done, or fails the test
+func waitFor(t *testing.T, m *Manager, id string) JobStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := m.Status(id)
+		if err != nil {
+			t.Fatalf("Status() unexpected error: %s", err)
+		}
+		if status.Status.IsDone() {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for job %s", id)
+	return JobStatus{}
+}
+
+func TestManager_Complete(t *testing.T) {
+	m := NewManager(1, 10, time.Hour, time.Hour)
+	defer m.Shutdown()
+
+	release := make(chan struct{})
+	status, err := m.Submit("test", "window=1d", func(ctx context.Context) (any, error) {
+		progress := ProgressFromContext(ctx)
+		first := progress.AddStep("first")
+		second := progress.AddStep("second")
+		first.Start()
+		first.Done()
+		second.Start()
+		<-release
+		second.Done()
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+	if status.ID == "" || status.Type != "test" || status.Query != "window=1d" {
+		t.Errorf("Submit() got status %+v", status)
+	}
+
+	_, _, err = m.Result(status.ID)
+	if !errors.Is(err, ErrNotDone) {
+		t.Errorf("Result() expected ErrNotDone before completion, got %v", err)
+	}
+
+	// Wait for the job to report its first step
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ = m.Status(status.ID)
+		if status.Progress.StepsCompleted == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status.Status != StatusRunning || status.Progress.StepsTotal != 2 || status.Progress.StepsCompleted != 1 {
+		t.Errorf("expected running job with 1/2 steps, got %+v", status)
+	}
+	if len(status.Progress.Steps) != 2 || status.Progress.Steps[0] != (StepStatus{Name: "first", State: StepDone}) {
+		t.Errorf("expected first step to be done, got %+v", status.Progress.Steps)
+	}
+	// the second step may not have started yet
+	if len(status.Progress.Steps) == 2 && status.Progress.Steps[1].State == StepDone {
+		t.Errorf("expected second step not to be done, got %+v", status.Progress.Steps[1])
+	}
+
+	close(release)
+	status = waitFor(t, m, status.ID)
+	if status.Status != StatusCompleted || status.Progress.StepsCompleted != 2 || status.Expires == nil {
+		t.Errorf("expected completed job with 2/2 steps, got %+v", status)
+	}
+
+	result, _, err := m.Result(status.ID)
+	if err != nil || result != 42 {
+		t.Errorf("Result() got = %v, %v, want 42", result, err)
+	}
+}
+
+func TestManager_Failed(t *testing.T) {
+	m := NewManager(1, 10, time.Hour, time.Hour)
+	defer m.Shutdown()
+
+	errBoom := errors.New("boom")
+	status, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		progress := ProgressFromContext(ctx)
+		done := progress.AddStep("done")
+		failed := progress.AddStep("failed")
+		progress.AddStep("pending")
+		done.Start()
+		done.Done()
+		failed.Start()
+		failed.Fail(errBoom)
+		return nil, errBoom
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+
+	status = waitFor(t, m, status.ID)
+	if status.Status != StatusFailed || status.Error != "boom" {
+		t.Errorf("expected failed job, got %+v", status)
+	}
+	expectedSteps := []StepStatus{
+		{Name: "done", State: StepDone},
+		{Name: "failed", State: StepFailed, Error: "boom"},
+		{Name: "pending", State: StepPending},
+	}
+	if len(status.Progress.Steps) != len(expectedSteps) {
+		t.Fatalf("expected %d steps, got %+v", len(expectedSteps), status.Progress.Steps)
+	}
+	for i, step := range status.Progress.Steps {
+		if step != expectedSteps[i] {
+			t.Errorf("expected step %+v, got %+v", expectedSteps[i], step)
+		}
+	}
+	if status.Progress.StepsCompleted != 1 || status.Progress.StepsTotal != 3 {
+		t.Errorf("expected 1/3 steps, got %+v", status.Progress)
+	}
+
+	_, _, err = m.Result(status.ID)
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Result() expected %v, got %v", errBoom, err)
+	}
+
+	// A panic fails the job without taking down the worker
+	status, _ = m.Submit("test", "", func(ctx context.Context) (any, error) {
+		panic("oops")
+	})
+	status = waitFor(t, m, status.ID)
+	if status.Status != StatusFailed {
+		t.Errorf("expected panicking job to fail, got %+v", status)
+	}
+
+	status, _ = m.Submit("test", "", func(ctx context.Context) (any, error) {
+		return "ok", nil
+	})
+	status = waitFor(t, m, status.ID)
+	if status.Status != StatusCompleted {
+		t.Errorf("expected job after panic to complete, got %+v", status)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := NewManager(1, 10, time.Hour, time.Hour)
+	defer m.Shutdown()
+
+	started := make(chan struct{})
+	running, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+	<-started
+
+	// With a single worker, this job stays pending behind the running one
+	pending, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		t.Errorf("cancelled job should never run")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+
+	status, err := m.Cancel(pending.ID)
+	if err != nil || status.Status != StatusCancelled {
+		t.Errorf("Cancel() got = %+v, %v, want cancelled", status, err)
+	}
+
+	_, err = m.Cancel(running.ID)
+	if err != nil {
+		t.Fatalf("Cancel() unexpected error: %s", err)
+	}
+	status = waitFor(t, m, running.ID)
+	if status.Status != StatusCancelled {
+		t.Errorf("expected cancelled job, got %+v", status)
+	}
+
+	_, _, err = m.Result(running.ID)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Result() expected context.Canceled, got %v", err)
+	}
+
+	_, err = m.Cancel("missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel() expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestManager_Expire(t *testing.T) {
+	m := NewManager(1, 10, time.Hour, 50*time.Millisecond)
+	defer m.Shutdown()
+
+	status, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+	waitFor(t, m, status.ID)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = m.Status(status.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Status() expected ErrNotFound after TTL, got %v", err)
+	}
+}
+
+func TestManager_TooManyPending(t *testing.T) {
+	m := NewManager(1, 1, time.Hour, time.Hour)
+	defer m.Shutdown()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+	<-started
+
+	// With a single worker, this job fills the only pending slot
+	pending, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+
+	_, err = m.Submit("test", "", func(ctx context.Context) (any, error) {
+		t.Errorf("rejected job should never run")
+		return nil, nil
+	})
+	if !errors.Is(err, ErrTooManyPending) {
+		t.Errorf("Submit() expected ErrTooManyPending, got %v", err)
+	}
+
+	close(release)
+	waitFor(t, m, running.ID)
+	waitFor(t, m, pending.ID)
+
+	_, err = m.Submit("test", "", func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Errorf("Submit() unexpected error once the queue drained: %s", err)
+	}
+}
+
+func TestManager_PendingExpired(t *testing.T) {
+	m := NewManager(1, 10, 20*time.Millisecond, time.Hour)
+	defer m.Shutdown()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+	<-started
+
+	pending, err := m.Submit("test", "", func(ctx context.Context) (any, error) {
+		t.Errorf("expired job should never run")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() unexpected error: %s", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	status := waitFor(t, m, running.ID)
+	if status.Status != StatusCompleted {
+		t.Errorf("expected running job to complete past the pending TTL, got %+v", status)
+	}
+
+	status = waitFor(t, m, pending.ID)
+	if status.Status != StatusFailed {
+		t.Errorf("expected expired job to fail, got %+v", status)
+	}
+
+	_, _, err = m.Result(pending.ID)
+	if !errors.Is(err, ErrPendingExpired) {
+		t.Errorf("Result() expected ErrPendingExpired, got %v", err)
+	}
+}
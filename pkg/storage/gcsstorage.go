@@ -135,6 +135,16 @@ func (gs *GCSStorage) Read(name string) ([]byte, error) {
 	return data, nil
 }
 
+// ReadStream uses the relative path of the storage combined with the provided path to
+// open a reader of the contents, which the caller must close.
+func (gs *GCSStorage) ReadStream(name string) (io.ReadCloser, error) {
+	name = trimLeading(name)
+	log.Debugf("GCSStorage::ReadStream(%s)", name)
+
+	ctx := context.Background()
+	return gs.bucket.Object(name).NewReader(ctx)
+}
+
 // Write uses the relative path of the storage combined with the provided path
 // to write a new file or overwrite an existing file.
 func (gs *GCSStorage) Write(name string, data []byte) error {
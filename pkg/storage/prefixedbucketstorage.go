@@ -5,6 +5,7 @@ package storage
 // https://github.com/thanos-io/objstore/blob/main/prefixed_bucket.go
 
 import (
+	"io"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -72,6 +73,11 @@ func (pbs *PrefixedBucketStorage) Read(name string) ([]byte, error) {
 	return pbs.storage.Read(conditionalPrefix(pbs.prefix, name))
 }
 
+// ReadStream returns a reader for the given object name, which the caller must close.
+func (pbs *PrefixedBucketStorage) ReadStream(name string) (io.ReadCloser, error) {
+	return pbs.storage.ReadStream(conditionalPrefix(pbs.prefix, name))
+}
+
 // Remove deletes the object with the given name.
 func (pbs *PrefixedBucketStorage) Remove(name string) error {
 	return pbs.storage.Remove(conditionalPrefix(pbs.prefix, name))
@@ -354,6 +354,39 @@ func (s3 *S3Storage) Read(name string) ([]byte, error) {
 
 }
 
+// ReadStream returns a reader for the given object name, which the caller must close.
+func (s3 *S3Storage) ReadStream(name string) (io.ReadCloser, error) {
+	name = trimLeading(name)
+
+	log.Tracef("S3Storage::ReadStream(%s)", name)
+	ctx := context.Background()
+
+	sse, err := s3.getServerSideEncryption(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s3.client.GetObject(ctx, s3.name, name, minio.GetObjectOptions{ServerSideEncryption: sse})
+	if err != nil {
+		if s3.isObjNotFound(err) {
+			return nil, DoesNotExistError
+		}
+		return nil, err
+	}
+
+	// NotFoundObject error is revealed only after the first Read, so the object is stat'd here
+	// instead, which does the initial GetRequest
+	if _, err := r.Stat(); err != nil {
+		r.Close()
+		if s3.isObjNotFound(err) {
+			return nil, DoesNotExistError
+		}
+		return nil, errors.Wrap(err, "Read from S3 failed")
+	}
+
+	return r, nil
+}
+
 // Exists checks if the given object exists.
 func (s3 *S3Storage) Exists(name string) (bool, error) {
 	name = trimLeading(name)
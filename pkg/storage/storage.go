@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"encoding/base64"
+	"io"
 	"os"
 	"time"
 
@@ -37,6 +39,10 @@ type Storage interface {
 	// read the contents.
 	Read(path string) ([]byte, error)
 
+	// ReadStream uses the relative path of the storage combined with the provided path to
+	// open a reader of the contents, which the caller must close.
+	ReadStream(path string) (io.ReadCloser, error)
+
 	// Write uses the relative path of the storage combined with the provided path
 	// to write a new file or overwrite an existing file.
 	Write(path string, data []byte) error
@@ -96,11 +102,27 @@ func Validate(storage Storage) error {
 	return nil
 }
 
-// IsNotExist returns true if the error provided from a storage object is DoesNotExist
+// IsNotExist returns true if the error provided from a storage object is DoesNotExist. Errors which wrap it are
+// matched as well as unwrapped copies of it, as the not-found errors of the Storage implementations differ.
 func IsNotExist(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	return err.Error() == DoesNotExistError.Error()
+	return errors.Is(err, DoesNotExistError) || err.Error() == DoesNotExistError.Error()
+}
+
+// EncodeDirName encodes a key, which may contain path separators, as a name which is safe to use as a directory in
+// any Storage
+func EncodeDirName(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// DecodeDirName returns the key of a directory name encoded by EncodeDirName
+func DecodeDirName(name string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
 }
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsNotExist(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"nil":                 {err: nil, expected: false},
+		"does not exist":      {err: DoesNotExistError, expected: true},
+		"wrapped":             {err: fmt.Errorf("failed to read: %w", os.ErrNotExist), expected: true},
+		"copy of message":     {err: fmt.Errorf("%s", os.ErrNotExist), expected: true},
+		"other error":         {err: fmt.Errorf("permission denied"), expected: false},
+		"wrapped other error": {err: fmt.Errorf("failed to read: %w", os.ErrPermission), expected: false},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := IsNotExist(testCase.err); actual != testCase.expected {
+				t.Errorf("expected %t, got %t", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeDirName(t *testing.T) {
+	for _, key := range []string{"", "key", "account/bucket/prefix", "https://example.com:8080/a?b=c"} {
+		name := EncodeDirName(key)
+		if strings.Contains(name, DirDelim) {
+			t.Errorf("expected encoded name of '%s' to have no delimiter, got '%s'", key, name)
+		}
+		decoded, err := DecodeDirName(name)
+		if err != nil {
+			t.Fatalf("failed to decode '%s': %s", name, err)
+		}
+		if decoded != key {
+			t.Errorf("expected '%s', got '%s'", key, decoded)
+		}
+	}
+
+	if _, err := DecodeDirName("not/encoded"); err == nil {
+		t.Errorf("expected error decoding a name which was not encoded")
+	}
+}
@@ -0,0 +1,74 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// CSVWriter is a Writer which writes a header row followed by one row per
+// call to Write. Floats are written in their shortest exact decimal form.
+type CSVWriter struct {
+	columns []Column
+	writer  *csv.Writer
+	row     []string
+}
+
+// NewCSVWriter creates a CSVWriter and writes the header row
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	cw := &CSVWriter{
+		columns: columns,
+		writer:  csv.NewWriter(w),
+		row:     make([]string, len(columns)),
+	}
+
+	for i, column := range columns {
+		cw.row[i] = column.Name
+	}
+	err := cw.writer.Write(cw.row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return cw, nil
+}
+
+func (cw *CSVWriter) Write(row []any) error {
+	if len(row) != len(cw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(cw.columns), len(row))
+	}
+
+	for i, value := range row {
+		switch v := value.(type) {
+		case string:
+			if cw.columns[i].Type != StringColumn {
+				return fmt.Errorf("unsupported value of type %T for column %s", value, cw.columns[i].Name)
+			}
+			cw.row[i] = v
+		case float64:
+			if cw.columns[i].Type != FloatColumn {
+				return fmt.Errorf("unsupported value of type %T for column %s", value, cw.columns[i].Name)
+			}
+			cw.row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Errorf("unsupported value of type %T for column %s", value, cw.columns[i].Name)
+		}
+	}
+
+	err := cw.writer.Write(cw.row)
+	if err != nil {
+		return fmt.Errorf("failed to write csv row: %w", err)
+	}
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying io.Writer
+func (cw *CSVWriter) Flush() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+func (cw *CSVWriter) Close() error {
+	return cw.Flush()
+}
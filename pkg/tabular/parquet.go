@@ -0,0 +1,125 @@
+package tabular
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+// parquetRowGroupSize is the number of rows buffered in memory before they
+// are written out as a row group
+const parquetRowGroupSize = 10_000
+
+// ParquetWriter is a Writer which writes a snappy compressed Parquet file,
+// buffering at most one row group in memory at a time.
+type ParquetWriter struct {
+	columns []Column
+	writer  *pqarrow.FileWriter
+	builder *array.RecordBuilder
+	rows    int
+}
+
+// NewParquetWriter creates a ParquetWriter. String columns are written as
+// UTF8 byte arrays and float columns as doubles.
+func NewParquetWriter(w io.Writer, columns []Column) (*ParquetWriter, error) {
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, column := range columns {
+		var dataType arrow.DataType
+		switch column.Type {
+		case StringColumn:
+			dataType = arrow.BinaryTypes.String
+		case FloatColumn:
+			dataType = arrow.PrimitiveTypes.Float64
+		default:
+			return nil, fmt.Errorf("unsupported type for column %s", column.Name)
+		}
+		fields = append(fields, arrow.Field{Name: column.Name, Type: dataType})
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	writer, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+
+	return &ParquetWriter{
+		columns: columns,
+		writer:  writer,
+		builder: array.NewRecordBuilder(memory.DefaultAllocator, schema),
+	}, nil
+}
+
+func (pw *ParquetWriter) Write(row []any) error {
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.columns), len(row))
+	}
+
+	// Check every value before appending any, so that an invalid row does
+	// not leave the columns with differing lengths
+	for i, value := range row {
+		var ok bool
+		switch pw.columns[i].Type {
+		case StringColumn:
+			_, ok = value.(string)
+		case FloatColumn:
+			_, ok = value.(float64)
+		}
+		if !ok {
+			return fmt.Errorf("unsupported value of type %T for column %s", value, pw.columns[i].Name)
+		}
+	}
+
+	for i, value := range row {
+		switch b := pw.builder.Field(i).(type) {
+		case *array.StringBuilder:
+			b.Append(value.(string))
+		case *array.Float64Builder:
+			b.Append(value.(float64))
+		}
+	}
+
+	pw.rows++
+	if pw.rows >= parquetRowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group
+func (pw *ParquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	record := pw.builder.NewRecord()
+	defer record.Release()
+	pw.rows = 0
+
+	err := pw.writer.Write(record)
+	if err != nil {
+		return fmt.Errorf("failed to write parquet row group: %w", err)
+	}
+	return nil
+}
+
+func (pw *ParquetWriter) Close() error {
+	defer pw.builder.Release()
+
+	err := pw.flush()
+	if err != nil {
+		return err
+	}
+
+	err = pw.writer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return nil
+}
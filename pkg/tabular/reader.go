@@ -0,0 +1,285 @@
+package tabular
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+// parquetReadBatchSize is the number of rows read from a Parquet file at a time
+const parquetReadBatchSize = 10_000
+
+// Row is a row of a table by column name. Values of CSV rows are strings,
+// while values of Parquet rows have the Go type of their column, see
+// NewParquetReader.
+type Row map[string]any
+
+// String returns the value of the column as a string, or an empty string if
+// the row has no value for it
+func (r Row) String(column string) string {
+	switch v := r[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Float returns the value of the column as a float64. Missing and empty
+// values are 0.
+func (r Row) Float(column string) (float64, error) {
+	switch v := r[column].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("column %s: invalid number '%s'", column, v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("column %s: value of type %T is not a number", column, v)
+	}
+}
+
+// rowTimeLayouts are the layouts of times in CSV rows
+var rowTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Time returns the value of the column as a time.Time in UTC
+func (r Row) Time(column string) (time.Time, error) {
+	switch v := r[column].(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case string:
+		for _, layout := range rowTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("column %s: invalid time '%s'", column, v)
+	default:
+		return time.Time{}, fmt.Errorf("column %s: value of type %T is not a time", column, v)
+	}
+}
+
+// Map returns the value of the column as a map of strings, which is
+// encoded as a JSON object in CSV files. Missing and empty values are nil.
+func (r Row) Map(column string) (map[string]string, error) {
+	switch v := r[column].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		var values map[string]any
+		err := json.Unmarshal([]byte(v), &values)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: invalid JSON object: %w", column, err)
+		}
+		m := make(map[string]string, len(values))
+		for key, value := range values {
+			if s, ok := value.(string); ok {
+				m[key] = s
+			} else {
+				m[key] = fmt.Sprintf("%v", value)
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("column %s: value of type %T is not a map", column, v)
+	}
+}
+
+// Reader reads the rows of a table
+type Reader interface {
+	// Read returns the next row, or io.EOF after the last row
+	Read() (Row, error)
+
+	// Close releases the resources of the Reader. It does not close the
+	// underlying io.Reader.
+	Close() error
+}
+
+// ReadSeekerAt is the source of a Reader, as Parquet files are read from
+// their footer
+type ReadSeekerAt interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// NewReader creates a Reader for the given format
+func NewReader(format Format, r ReadSeekerAt) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r)
+	case FormatParquet:
+		return NewParquetReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// CSVReader is a Reader of a CSV file with a header row
+type CSVReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+// NewCSVReader creates a CSVReader, reading the header row
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	// remove the byte order mark which some exports begin with
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	return &CSVReader{reader: reader, header: header}, nil
+}
+
+func (cr *CSVReader) Read() (Row, error) {
+	record, err := cr.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make(Row, len(cr.header))
+	for i, column := range cr.header {
+		if i < len(record) {
+			row[column] = record[i]
+		}
+	}
+	return row, nil
+}
+
+func (cr *CSVReader) Close() error {
+	return nil
+}
+
+// ParquetReader is a Reader of a Parquet file, which reads a batch of rows
+// into memory at a time
+type ParquetReader struct {
+	reader  pqarrow.RecordReader
+	record  arrow.Record
+	current int
+}
+
+// NewParquetReader creates a ParquetReader. Values of string columns are
+// strings, numeric and decimal columns are float64, timestamp and date
+// columns are time.Time, and map columns are map[string]string. Values of
+// other types are formatted as strings. Null values are nil.
+func NewParquetReader(r ReadSeekerAt) (*ParquetReader, error) {
+	pf, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet: %w", err)
+	}
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: parquetReadBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet: %w", err)
+	}
+	reader, err := fr.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet: %w", err)
+	}
+	return &ParquetReader{reader: reader}, nil
+}
+
+func (pr *ParquetReader) Read() (Row, error) {
+	for pr.record == nil || pr.current >= int(pr.record.NumRows()) {
+		if !pr.reader.Next() {
+			if err := pr.reader.Err(); err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read parquet: %w", err)
+			}
+			return nil, io.EOF
+		}
+		pr.record = pr.reader.Record()
+		pr.current = 0
+	}
+
+	row := make(Row, pr.record.NumCols())
+	for i, field := range pr.record.Schema().Fields() {
+		row[field.Name] = arrowValue(pr.record.Column(i), pr.current)
+	}
+	pr.current++
+	return row, nil
+}
+
+func (pr *ParquetReader) Close() error {
+	pr.reader.Release()
+	return nil
+}
+
+// arrowValue returns the value at index i of the array as the Go type
+// documented by NewParquetReader
+func arrowValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Int64:
+		return float64(a.Value(i))
+	case *array.Int32:
+		return float64(a.Value(i))
+	case *array.Decimal128:
+		return a.Value(i).ToFloat64(a.DataType().(*arrow.Decimal128Type).Scale)
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit).UTC()
+	case *array.Date32:
+		return a.Value(i).ToTime().UTC()
+	case *array.Map:
+		start, end := a.ValueOffsets(i)
+		keys, items := a.Keys(), a.Items()
+		m := make(map[string]string, end-start)
+		for j := int(start); j < int(end); j++ {
+			if items.IsNull(j) {
+				m[keys.ValueStr(j)] = ""
+				continue
+			}
+			m[keys.ValueStr(j)] = items.ValueStr(j)
+		}
+		return m
+	default:
+		return arr.ValueStr(i)
+	}
+}
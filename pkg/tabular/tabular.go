@@ -0,0 +1,182 @@
+// Package tabular reads and writes rows of typed columns as CSV or Parquet, for
+// endpoints and exports which return cost data in a spreadsheet friendly form,
+// and for billing files which are read by cloud cost integrations.
+package tabular
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opencost/opencost/core/pkg/log"
+)
+
+// Format is a tabular output format
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat returns the Format with the given name, ignoring case
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s', must be one of '%s' or '%s'", format, FormatCSV, FormatParquet)
+	}
+}
+
+// ContentType returns the media type of the Format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv"
+	}
+}
+
+// FileName returns the file name for the given base name in the Format
+func (f Format) FileName(name string) string {
+	return name + "." + string(f)
+}
+
+// ColumnType is the type of the values of a Column
+type ColumnType int
+
+const (
+	// StringColumn values are of type string
+	StringColumn ColumnType = iota
+	// FloatColumn values are of type float64
+	FloatColumn
+)
+
+// Column describes a single column of a table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer writes rows to a table. Each value in a row must be a string or
+// float64 according to the type of its column.
+type Writer interface {
+	Write(row []any) error
+
+	// Close flushes any buffered rows and completes the table. It does not
+	// close the underlying io.Writer.
+	Close() error
+}
+
+// NewWriter creates a Writer for the given format
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	var writer Writer
+	var err error
+	switch format {
+	case FormatCSV:
+		writer, err = NewCSVWriter(w, columns)
+	case FormatParquet:
+		writer, err = NewParquetWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// ColumnDef defines a column whose value is computed from a row of type T
+type ColumnDef[T any] struct {
+	Column
+	Value func(T) any
+}
+
+// StringColumnDef creates a ColumnDef for a string column
+func StringColumnDef[T any](name string, value func(T) string) ColumnDef[T] {
+	return ColumnDef[T]{
+		Column: Column{Name: name, Type: StringColumn},
+		Value:  func(row T) any { return value(row) },
+	}
+}
+
+// FloatColumnDef creates a ColumnDef for a float column
+func FloatColumnDef[T any](name string, value func(T) float64) ColumnDef[T] {
+	return ColumnDef[T]{
+		Column: Column{Name: name, Type: FloatColumn},
+		Value:  func(row T) any { return value(row) },
+	}
+}
+
+// LabelColumnDefs creates the label columns used by OpenCost's tabular
+// outputs: if labelsAll is true, a "Labels" column containing all labels in
+// JSON format, followed by a column for each of the given labels prefixed
+// with "Label_".
+func LabelColumnDefs[T any](labels []string, labelsAll bool, getLabels func(T) map[string]string) []ColumnDef[T] {
+	defs := []ColumnDef[T]{}
+	if labelsAll {
+		defs = append(defs, StringColumnDef("Labels", func(row T) string {
+			return FormatLabels(getLabels(row))
+		}))
+	}
+	for i := range labels {
+		label := labels[i] // copy the label name, otherwise all closures would reference the same label
+		defs = append(defs, StringColumnDef("Label_"+label, func(row T) string {
+			return getLabels(row)[label]
+		}))
+	}
+	return defs
+}
+
+// FormatLabels returns the labels in JSON format, or an empty string if there
+// are none
+func FormatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(labels)
+	if err != nil {
+		log.Errorf("failed to marshal labels: %s", err)
+		return ""
+	}
+	return string(data)
+}
+
+// Columns returns the columns of the given ColumnDefs
+func Columns[T any](defs []ColumnDef[T]) []Column {
+	columns := make([]Column, 0, len(defs))
+	for _, def := range defs {
+		columns = append(columns, def.Column)
+	}
+	return columns
+}
+
+// WriteRow computes the values of each of the ColumnDefs for the row, and
+// writes them
+func WriteRow[T any](w Writer, defs []ColumnDef[T], row T) error {
+	values := make([]any, 0, len(defs))
+	for _, def := range defs {
+		values = append(values, def.Value(row))
+	}
+	return w.Write(values)
+}
+
+// MapColumnDefs adapts ColumnDefs for rows of type U to rows of type T, using
+// the given func to get the U of each T
+func MapColumnDefs[T, U any](defs []ColumnDef[U], get func(T) U) []ColumnDef[T] {
+	mapped := make([]ColumnDef[T], 0, len(defs))
+	for i := range defs {
+		value := defs[i].Value
+		mapped = append(mapped, ColumnDef[T]{
+			Column: defs[i].Column,
+			Value:  func(row T) any { return value(get(row)) },
+		})
+	}
+	return mapped
+}
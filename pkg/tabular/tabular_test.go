@@ -0,0 +1,213 @@
+package tabular
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+type testRow struct {
+	name   string
+	cost   float64
+	labels map[string]string
+}
+
+func testColumnDefs() []ColumnDef[testRow] {
+	defs := []ColumnDef[testRow]{
+		StringColumnDef("Name", func(row testRow) string { return row.name }),
+		FloatColumnDef("TotalCost", func(row testRow) float64 { return row.cost }),
+	}
+	return append(defs, LabelColumnDefs([]string{"app", "team"}, true, func(row testRow) map[string]string {
+		return row.labels
+	})...)
+}
+
+var testRows = []testRow{
+	{name: "a", cost: 1.5, labels: map[string]string{"app": "web"}},
+	{name: "b", cost: 0.1, labels: nil},
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]Format{"csv": FormatCSV, "CSV": FormatCSV, "parquet": FormatParquet} {
+		got, err := ParseFormat(input)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%s) = %s, %v, want %s", input, got, err, want)
+		}
+	}
+
+	_, err := ParseFormat("xlsx")
+	if err == nil {
+		t.Errorf("ParseFormat(xlsx) expected error")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	defs := testColumnDefs()
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(FormatCSV, buf, Columns(defs))
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %s", err)
+	}
+	for _, row := range testRows {
+		err = WriteRow(w, defs, row)
+		if err != nil {
+			t.Fatalf("WriteRow() unexpected error: %s", err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close() unexpected error: %s", err)
+	}
+
+	want := `Name,TotalCost,Labels,Label_app,Label_team
+a,1.5,"{""app"":""web""}",web,
+b,0.1,,,
+`
+	if buf.String() != want {
+		t.Errorf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	err = w.Write([]any{"c", "not a float", "", "", ""})
+	if err == nil {
+		t.Errorf("Write() expected error for invalid value type")
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	defs := testColumnDefs()
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(FormatParquet, buf, Columns(defs))
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %s", err)
+	}
+	for _, row := range testRows {
+		err = WriteRow(w, defs, row)
+		if err != nil {
+			t.Fatalf("WriteRow() unexpected error: %s", err)
+		}
+	}
+	err = w.Write([]any{"c", "not a float", "", "", ""})
+	if err == nil {
+		t.Errorf("Write() expected error for invalid value type")
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close() unexpected error: %s", err)
+	}
+
+	pf, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read parquet: %s", err)
+	}
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("failed to read parquet: %s", err)
+	}
+	table, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read parquet table: %s", err)
+	}
+	defer table.Release()
+
+	if table.NumRows() != 2 || table.NumCols() != 5 {
+		t.Fatalf("expected 2 rows and 5 columns, got %d and %d", table.NumRows(), table.NumCols())
+	}
+
+	names := []string{}
+	for i := 0; i < int(table.NumCols()); i++ {
+		names = append(names, table.Schema().Field(i).Name)
+	}
+	if strings.Join(names, ",") != "Name,TotalCost,Labels,Label_app,Label_team" {
+		t.Errorf("unexpected columns: %v", names)
+	}
+
+	costs := table.Column(1).Data().Chunk(0).(*array.Float64)
+	if costs.Value(0) != 1.5 || costs.Value(1) != 0.1 {
+		t.Errorf("unexpected costs: %v", costs)
+	}
+	apps := table.Column(3).Data().Chunk(0).(*array.String)
+	if apps.Value(0) != "web" || apps.Value(1) != "" {
+		t.Errorf("unexpected Label_app values: %v", apps)
+	}
+}
+
+func TestReader(t *testing.T) {
+	defs := testColumnDefs()
+	for _, format := range []Format{FormatCSV, FormatParquet} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w, err := NewWriter(format, buf, Columns(defs))
+			if err != nil {
+				t.Fatalf("NewWriter() unexpected error: %s", err)
+			}
+			for _, row := range testRows {
+				err = WriteRow(w, defs, row)
+				if err != nil {
+					t.Fatalf("WriteRow() unexpected error: %s", err)
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatalf("Close() unexpected error: %s", err)
+			}
+
+			r, err := NewReader(format, bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("NewReader() unexpected error: %s", err)
+			}
+			defer r.Close()
+
+			for _, want := range testRows {
+				row, err := r.Read()
+				if err != nil {
+					t.Fatalf("Read() unexpected error: %s", err)
+				}
+				if row.String("Name") != want.name {
+					t.Errorf("Name = %s, want %s", row.String("Name"), want.name)
+				}
+				cost, err := row.Float("TotalCost")
+				if err != nil || cost != want.cost {
+					t.Errorf("Float(TotalCost) = %f, %v, want %f", cost, err, want.cost)
+				}
+				labels, err := row.Map("Labels")
+				if err != nil {
+					t.Fatalf("Map(Labels) unexpected error: %s", err)
+				}
+				if len(labels) != len(want.labels) || labels["app"] != want.labels["app"] {
+					t.Errorf("Map(Labels) = %v, want %v", labels, want.labels)
+				}
+			}
+			if _, err := r.Read(); err != io.EOF {
+				t.Errorf("Read() expected io.EOF after the last row, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRow(t *testing.T) {
+	row := Row{"start": "2024-05-01T00:00Z", "cost": "x", "tags": "[]"}
+
+	start, err := row.Time("start")
+	if err != nil || !start.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time(start) = %s, %v", start, err)
+	}
+	if _, err := row.Float("cost"); err == nil {
+		t.Errorf("Float(cost) expected error for invalid number")
+	}
+	if f, err := row.Float("missing"); err != nil || f != 0 {
+		t.Errorf("Float(missing) = %f, %v, want 0", f, err)
+	}
+	if _, err := row.Map("tags"); err == nil {
+		t.Errorf("Map(tags) expected error for JSON array")
+	}
+}